@@ -0,0 +1,114 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// BenchmarkCommand replays recorded requests as an API load test of an archive.
+var BenchmarkCommand = cli.Command{
+	Action:    benchmark,
+	Name:      "benchmark",
+	Usage:     "replays recorded requests as a load test measuring latencies and throughput of an archive",
+	ArgsUsage: "<blockNumFirst> <blockNumLast>",
+	Flags: []cli.Flag{
+		&utils.RpcRecordingFileFlag,
+		&utils.StateDbSrcFlag,
+		&utils.AidaDbFlag,
+		&utils.ChainIDFlag,
+		&utils.VmImplementation,
+		&utils.BenchmarkModeFlag,
+		&utils.BenchmarkClientsFlag,
+		&utils.BenchmarkRateFlag,
+		&utils.BenchmarkRateStepFlag,
+		&utils.BenchmarkSpeedupFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The aida-rpc benchmark command replays requests recorded between the given blocks against
+the archive of the StateDB given by --db-src with concurrent clients. Requests are sent at
+the recorded inter-arrival timing of blocks, at a fixed rate or at a ramped rate. Latency
+percentiles and errors are reported per method together with the throughput at which the
+archive saturated. Results are not validated; use aida-rpc for validation.`,
+}
+
+func benchmark(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.BlockRangeArgs)
+	if err != nil {
+		return err
+	}
+	if cfg.StateDbSrc == "" {
+		return fmt.Errorf("missing StateDB; use --%v", utils.StateDbSrcFlag.Name)
+	}
+	cfg.SrcDbReadonly = true
+	log := logger.NewLogger(cfg.LogLevel, "RPC-Benchmark")
+
+	provider, err := executor.OpenRpcRecording(cfg, ctx)
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	db, _, err := utils.PrepareStateDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var (
+		logs *rpc.LogIndex
+		envs rpc.BlockEnvSource
+	)
+	if cfg.AidaDb != "" {
+		substateDb, err := executor.OpenSubstateDb(cfg, ctx)
+		if err != nil {
+			return err
+		}
+		defer substateDb.Close()
+		logs = rpc.NewLogIndex(rpc.SubstateLogSource)
+		envs = rpc.SubstateBlockEnvSource
+	}
+
+	b, err := rpc.NewBenchmark(db, logs, envs, rpc.BenchmarkConfig{
+		Mode:     ctx.String(utils.BenchmarkModeFlag.Name),
+		Clients:  ctx.Int(utils.BenchmarkClientsFlag.Name),
+		Rate:     ctx.Float64(utils.BenchmarkRateFlag.Name),
+		RateStep: ctx.Float64(utils.BenchmarkRateStepFlag.Name),
+		Speedup:  ctx.Float64(utils.BenchmarkSpeedupFlag.Name),
+	}, cfg)
+	if err != nil {
+		return err
+	}
+
+	report, err := b.Run(func(consume func(*rpc.RequestAndResults) error) error {
+		return provider.Run(int(cfg.First), int(cfg.Last)+1, func(tx executor.TransactionInfo[*rpc.RequestAndResults]) error {
+			return consume(tx.Data)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	report.Log(log)
+	return nil
+}
@@ -33,6 +33,12 @@ func main() {
 		Usage: "Sends real API requests recorded on rpcapi.fantom.network to StateDB then compares recorded" +
 			"result with result returned by DB.",
 		Copyright: "(c) 2023 Fantom Foundation",
+		Commands: []*cli.Command{
+			&ServeCommand,
+			&RecordCommand,
+			&BenchmarkCommand,
+			&RecordingCommand,
+		},
 		Flags: []cli.Flag{
 			&utils.RpcRecordingFileFlag,
 			&substate.WorkersFlag,
@@ -45,8 +51,12 @@ func main() {
 			&utils.ChainIDFlag,
 			&utils.ContinueOnFailureFlag,
 			&utils.ValidateFlag,
+			&utils.EstimateGasToleranceFlag,
 			&utils.NoHeartbeatLoggingFlag,
 			&utils.ErrorLoggingFlag,
+			&utils.RpcReportFlag,
+			&utils.MaxReportSamplesFlag,
+			&utils.RpcRulesFlag,
 			&utils.TrackProgressFlag,
 
 			// Register
@@ -56,6 +66,9 @@ func main() {
 			// ShadowDB
 			&utils.ShadowDb,
 
+			// Substate (receipts for getLogs, block environment for estimateGas)
+			&utils.AidaDbFlag,
+
 			// StateDB
 			&utils.StateDbSrcFlag,
 			&utils.StateDbLoggingFlag,
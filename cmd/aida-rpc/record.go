@@ -0,0 +1,101 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// shutdownTimeout is the time requests in flight are given to complete when recording stops.
+const shutdownTimeout = 10 * time.Second
+
+// RecordCommand records JSON-RPC traffic passing through a reverse proxy.
+var RecordCommand = cli.Command{
+	Action: record,
+	Name:   "record",
+	Usage:  "records JSON-RPC requests and responses passing through a reverse proxy",
+	Flags: []cli.Flag{
+		&utils.RpcAddressFlag,
+		&utils.RpcUpstreamFlag,
+		&utils.RpcRecordingFileFlag,
+		&utils.ChainIDFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The aida-rpc record command starts a reverse proxy at --rpc-addr forwarding JSON-RPC
+requests to --rpc-upstream (e.g. a node or aida-rpc serve). Recordable requests and their
+responses are written into --rpc-recording (gzipped if the file ends with .gz), which can
+be replayed by aida-rpc. The proxy runs until it is interrupted.`,
+}
+
+func record(ctx *cli.Context) error {
+	log := logger.NewLogger(ctx.String(logger.LogLevelFlag.Name), "RPC-Record")
+
+	path := ctx.Path(utils.RpcRecordingFileFlag.Name)
+	if path == "" {
+		return fmt.Errorf("missing recording file; use --%v", utils.RpcRecordingFileFlag.Name)
+	}
+	upstream := ctx.String(utils.RpcUpstreamFlag.Name)
+	if upstream == "" {
+		var err error
+		if upstream, err = utils.GetProvider(utils.ChainID(ctx.Int(utils.ChainIDFlag.Name))); err != nil {
+			return fmt.Errorf("cannot find upstream; use --%v; %v", utils.RpcUpstreamFlag.Name, err)
+		}
+	}
+
+	writer, err := rpc.NewFileWriter(path)
+	if err != nil {
+		return fmt.Errorf("cannot create recording; %v", err)
+	}
+
+	addr := ctx.String(utils.RpcAddressFlag.Name)
+	server := &http.Server{Addr: addr, Handler: rpc.NewRecordingProxy(upstream, writer, log)}
+
+	// the recording must be closed to be complete, hence the proxy stops on interrupt;
+	// requests in flight are still recorded before the recording is closed
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	shutdown := make(chan error, 1)
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	log.Noticef("Recording JSON-RPC requests to %v at http://%v into %v", upstream, addr, path)
+	if err = server.ListenAndServe(); err != http.ErrServerClosed {
+		writer.Close()
+		return err
+	}
+	if err = <-shutdown; err != nil {
+		log.Warningf("cannot wait for requests in flight; %v", err)
+	}
+	return writer.Close()
+}
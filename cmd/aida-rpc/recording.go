@@ -0,0 +1,265 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// RecordingCommand inspects and converts RPC recordings.
+var RecordingCommand = cli.Command{
+	Name:  "recording",
+	Usage: "inspects and converts RPC recordings",
+	Subcommands: []*cli.Command{
+		&recordingStatsCommand,
+		&recordingFilterCommand,
+		&recordingConvertCommand,
+		&recordingSplitCommand,
+		&recordingMergeCommand,
+	},
+	Description: `
+Recordings are binary files (gzipped if they end with .gz) or JSON lines files
+ending with .jsonl or .jsonl.gz with one request per line:
+{"method":"eth_getBalance","params":["0x..","latest"],"block":1,"timestamp":1,"result":"0x0"}
+Failed requests have an "error" with its "code" instead of a "result". A directory
+as input is read file by file.`,
+}
+
+var recordingStatsCommand = cli.Command{
+	Action:    recordingStats,
+	Name:      "stats",
+	Usage:     "prints method histogram, block range, error rates and payload sizes of a recording",
+	ArgsUsage: "<recording>",
+	Flags: []cli.Flag{
+		&logger.LogLevelFlag,
+	},
+}
+
+var recordingFilterCommand = cli.Command{
+	Action:    recordingFilter,
+	Name:      "filter",
+	Usage:     "writes requests of selected methods and blocks into a new recording",
+	ArgsUsage: "<input> <output>",
+	Flags: []cli.Flag{
+		&utils.RecordingMethodsFlag,
+		&utils.RecordingFirstBlockFlag,
+		&utils.RecordingLastBlockFlag,
+		&logger.LogLevelFlag,
+	},
+}
+
+var recordingConvertCommand = cli.Command{
+	Action:    recordingConvert,
+	Name:      "convert",
+	Usage:     "converts a recording between the binary and the JSON lines format given by the file extensions",
+	ArgsUsage: "<input> <output>",
+	Flags: []cli.Flag{
+		&logger.LogLevelFlag,
+	},
+}
+
+var recordingSplitCommand = cli.Command{
+	Action:    recordingSplit,
+	Name:      "split",
+	Usage:     "splits a recording into files of limited number of requests or blocks",
+	ArgsUsage: "<input> <output-dir>",
+	Flags: []cli.Flag{
+		&utils.SplitRequestsFlag,
+		&utils.SplitBlocksFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+Split files are named after the input file with an increasing index, e.g. calls.gz
+is split into calls-00000.gz, calls-00001.gz, ... in the output directory.`,
+}
+
+var recordingMergeCommand = cli.Command{
+	Action:    recordingMerge,
+	Name:      "merge",
+	Usage:     "merges recordings into one ordered by the recorded block",
+	ArgsUsage: "<output> <input>...",
+	Flags: []cli.Flag{
+		&logger.LogLevelFlag,
+	},
+}
+
+func recordingStats(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		return fmt.Errorf("stats command requires exactly 1 argument")
+	}
+	log := logger.NewLogger(ctx.String(logger.LogLevelFlag.Name), "RPC-Recording")
+
+	iter, err := rpc.OpenRecordingFiles(ctx.Context, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	stats := rpc.NewRecordingStats()
+	for iter.Next() {
+		stats.Add(iter.Value())
+	}
+	if err = iter.Error(); err != nil {
+		return err
+	}
+	stats.Log(log)
+	return nil
+}
+
+func recordingFilter(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("filter command requires exactly 2 arguments")
+	}
+	filter := rpc.NewRecordingFilter(ctx.StringSlice(utils.RecordingMethodsFlag.Name)...)
+	filter.FirstBlock = ctx.Uint64(utils.RecordingFirstBlockFlag.Name)
+	if ctx.IsSet(utils.RecordingLastBlockFlag.Name) {
+		filter.LastBlock = ctx.Uint64(utils.RecordingLastBlockFlag.Name)
+	}
+	if filter.FirstBlock > filter.LastBlock {
+		return fmt.Errorf("first block %v is after last block %v", filter.FirstBlock, filter.LastBlock)
+	}
+	return copyRecording(ctx, filter)
+}
+
+func recordingConvert(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("convert command requires exactly 2 arguments")
+	}
+	return copyRecording(ctx, nil)
+}
+
+// copyRecording writes the requests of the input recording selected by the filter into the output recording.
+func copyRecording(ctx *cli.Context, filter *rpc.RecordingFilter) error {
+	log := logger.NewLogger(ctx.String(logger.LogLevelFlag.Name), "RPC-Recording")
+	input, output := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	iter, err := rpc.OpenRecordingFiles(ctx.Context, input)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	w, err := rpc.CreateRecording(output)
+	if err != nil {
+		return fmt.Errorf("cannot create recording %v; %v", output, err)
+	}
+	n, err := rpc.CopyRecording(iter, w, filter)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("cannot close recording %v; %v", output, err)
+	}
+	log.Noticef("Wrote %v requests into %v", n, output)
+	return nil
+}
+
+func recordingSplit(ctx *cli.Context) error {
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("split command requires exactly 2 arguments")
+	}
+	requests := ctx.Int(utils.SplitRequestsFlag.Name)
+	blocks := ctx.Uint64(utils.SplitBlocksFlag.Name)
+	if requests <= 0 && blocks == 0 {
+		return fmt.Errorf("missing split size; use --%v or --%v", utils.SplitRequestsFlag.Name, utils.SplitBlocksFlag.Name)
+	}
+	log := logger.NewLogger(ctx.String(logger.LogLevelFlag.Name), "RPC-Recording")
+	input, dir := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	iter, err := rpc.OpenRecordingFiles(ctx.Context, input)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create output directory; %v", err)
+	}
+
+	files, err := rpc.SplitRecording(iter, requests, blocks, func(index int) (rpc.RecordingWriter, error) {
+		path := splitFileName(input, dir, index)
+		w, err := rpc.CreateRecording(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create recording %v; %v", path, err)
+		}
+		return w, nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Noticef("Split %v into %v files", input, files)
+	return nil
+}
+
+// splitFileName returns the name of the split file with the given index keeping the extensions of the input.
+func splitFileName(input, dir string, index int) string {
+	base := filepath.Base(strings.TrimSuffix(input, string(filepath.Separator)))
+	var ext string
+	for _, e := range []string{".gz", ".jsonl"} {
+		if strings.HasSuffix(strings.ToLower(base), e) {
+			ext = base[len(base)-len(e):] + ext
+			base = base[:len(base)-len(e)]
+		}
+	}
+	return filepath.Join(dir, fmt.Sprintf("%v-%05d%v", base, index, ext))
+}
+
+func recordingMerge(ctx *cli.Context) error {
+	if ctx.Args().Len() < 2 {
+		return fmt.Errorf("merge command requires an output and at least 1 input")
+	}
+	log := logger.NewLogger(ctx.String(logger.LogLevelFlag.Name), "RPC-Recording")
+	output := ctx.Args().Get(0)
+
+	var iters []rpc.Iterator
+	defer func() {
+		for _, iter := range iters {
+			iter.Close()
+		}
+	}()
+	for _, input := range ctx.Args().Slice()[1:] {
+		iter, err := rpc.OpenRecordingFiles(ctx.Context, input)
+		if err != nil {
+			return err
+		}
+		iters = append(iters, iter)
+	}
+
+	w, err := rpc.CreateRecording(output)
+	if err != nil {
+		return fmt.Errorf("cannot create recording %v; %v", output, err)
+	}
+	n, err := rpc.MergeRecordings(iters, w)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("cannot close recording %v; %v", output, err)
+	}
+	log.Noticef("Merged %v requests into %v", n, output)
+	return nil
+}
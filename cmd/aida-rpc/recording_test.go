@@ -0,0 +1,67 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+func TestRecording_SplitFileNameKeepsExtensions(t *testing.T) {
+	tests := map[string]string{
+		"calls":          "calls-00003",
+		"calls.gz":       "calls-00003.gz",
+		"a/calls.jsonl":  "calls-00003.jsonl",
+		"calls.jsonl.gz": "calls-00003.jsonl.gz",
+		"dir/":           "dir-00003",
+	}
+	for input, want := range tests {
+		if got := splitFileName(input, "out", 3); got != filepath.Join("out", want) {
+			t.Errorf("unexpected split file of %v; got %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestRecording_SplitCreatesOutputDirectory(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "calls.jsonl")
+	if err := os.WriteFile(input, []byte(`{"method":"eth_getBalance","params":["0x1","latest"],"block":1,"timestamp":1,"result":"0x0"}`+"\n"), 0644); err != nil {
+		t.Fatalf("cannot write recording; %v", err)
+	}
+	output := filepath.Join(dir, "split", "nested")
+
+	app := cli.NewApp()
+	app.Commands = []*cli.Command{&RecordingCommand}
+	if err := app.Run([]string{"aida-rpc", "recording", "split", "--" + utils.SplitRequestsFlag.Name, "1", input, output}); err != nil {
+		t.Fatalf("cannot split recording; %v", err)
+	}
+
+	iter, err := rpc.OpenRecordingFiles(context.Background(), output)
+	if err != nil {
+		t.Fatalf("cannot open split recording; %v", err)
+	}
+	defer iter.Close()
+	if !iter.Next() || iter.Value().Query.Method != "eth_getBalance" || iter.Next() {
+		t.Errorf("unexpected requests in split recording")
+	}
+}
@@ -93,7 +93,7 @@ func TestRpc_AllDbEventsAreIssuedInOrder_Sequential(t *testing.T) {
 		archiveFour.EXPECT().Release(),
 	)
 
-	if err := run(cfg, provider, db, rpcProcessor{cfg}, nil); err != nil {
+	if err := run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil); err != nil {
 		t.Errorf("run failed: %v", err)
 	}
 }
@@ -154,7 +154,7 @@ func TestRpc_AllDbEventsAreIssuedInOrder_Parallel(t *testing.T) {
 		archiveThree.EXPECT().Release(),
 	)
 
-	if err := run(cfg, provider, db, rpcProcessor{cfg}, nil); err != nil {
+	if err := run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil); err != nil {
 		t.Errorf("run failed: %v", err)
 	}
 }
@@ -360,7 +360,7 @@ func TestRpc_ValidationDoesNotFailOnValidTransaction_Sequential(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err != nil {
 		t.Errorf("run must not fail")
 	}
@@ -402,7 +402,7 @@ func TestRpc_ValidationDoesNotFailOnValidTransaction_Parallel(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err != nil {
 		t.Errorf("run must not fail")
 	}
@@ -444,7 +444,7 @@ func TestRpc_ValidationFailsOnValidTransaction_Sequential(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err == nil {
 		t.Errorf("run must fail")
 	}
@@ -490,7 +490,7 @@ func TestRpc_ValidationFailsOnValidTransaction_Parallel(t *testing.T) {
 	)
 
 	// run fails but not on validation
-	err = run(cfg, provider, db, rpcProcessor{cfg}, nil)
+	err = run(cfg, provider, db, rpcProcessor{cfg: cfg}, nil)
 	if err == nil {
 		t.Errorf("run must fail")
 	}
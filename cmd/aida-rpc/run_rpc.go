@@ -47,21 +47,46 @@ func RunRpc(ctx *cli.Context) error {
 
 	defer rpcSource.Close()
 
-	return run(cfg, rpcSource, nil, makeRpcProcessor(cfg), nil)
+	// getLogs requests are served from the receipts of the substate DB,
+	// gas estimations use the environment of recorded blocks and
+	// traced transactions are found among the recorded transactions
+	var (
+		logs *rpc.LogIndex
+		envs rpc.BlockEnvSource
+		txs  rpc.TransactionSource
+	)
+	if cfg.AidaDb != "" {
+		substateDb, err := executor.OpenSubstateDb(cfg, ctx)
+		if err != nil {
+			return err
+		}
+		defer substateDb.Close()
+		logs = rpc.NewLogIndex(rpc.SubstateLogSource)
+		envs = rpc.SubstateBlockEnvSource
+		txs = rpc.NewSubstateTransactionIndex().Find
+	}
+
+	return run(cfg, rpcSource, nil, makeRpcProcessor(cfg, logs, envs, txs), nil)
 }
 
-func makeRpcProcessor(cfg *utils.Config) rpcProcessor {
+func makeRpcProcessor(cfg *utils.Config, logs *rpc.LogIndex, envs rpc.BlockEnvSource, txs rpc.TransactionSource) rpcProcessor {
 	return rpcProcessor{
-		cfg: cfg,
+		cfg:  cfg,
+		logs: logs,
+		envs: envs,
+		txs:  txs,
 	}
 }
 
 type rpcProcessor struct {
-	cfg *utils.Config
+	cfg  *utils.Config
+	logs *rpc.LogIndex         // log index for getLogs requests; nil if no substate DB is given
+	envs rpc.BlockEnvSource    // environment of blocks for estimateGas requests; nil if no substate DB is given
+	txs  rpc.TransactionSource // transactions of debug_traceTransaction requests; nil if no substate DB is given
 }
 
 func (p rpcProcessor) Process(state executor.State[*rpc.RequestAndResults], ctx *executor.Context) error {
-	ctx.ExecutionResult = rpc.Execute(uint64(state.Block), state.Data, ctx.Archive, p.cfg)
+	ctx.ExecutionResult = rpc.Execute(uint64(state.Block), state.Data, ctx.Archive, p.logs, p.envs, p.txs, p.cfg)
 	return nil
 }
 
@@ -84,6 +109,7 @@ func run(
 		logger.MakeErrorLogger[*rpc.RequestAndResults](cfg),
 		tracker.MakeRequestProgressTracker(cfg, 100_000),
 		statedb.MakeTemporaryArchivePrepper(),
+		validator.MakeRpcRequestFilter(cfg),
 		validator.MakeRpcComparator(cfg),
 	}
 
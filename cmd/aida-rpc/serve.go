@@ -0,0 +1,84 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// ServeCommand serves JSON-RPC requests from the archive of a StateDB.
+var ServeCommand = cli.Command{
+	Action: serve,
+	Name:   "serve",
+	Usage:  "serves JSON-RPC requests over HTTP from the archive of a StateDB",
+	Flags: []cli.Flag{
+		&utils.RpcAddressFlag,
+		&utils.StateDbSrcFlag,
+		&utils.AidaDbFlag,
+		&utils.ChainIDFlag,
+		&utils.VmImplementation,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The aida-rpc serve command serves eth_blockNumber, eth_getBlockByNumber, eth_getBalance,
+eth_getTransactionCount, eth_getCode, eth_getStorageAt and eth_call requests (also in the ftm
+namespace) from the archive of the StateDB given by --db-src. Any block held by the archive
+can be requested. Block headers and calls require --aida-db for the timestamps of blocks.`,
+}
+
+func serve(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.NoArgs)
+	if err != nil {
+		return err
+	}
+	if cfg.StateDbSrc == "" {
+		return fmt.Errorf("missing StateDB; use --%v", utils.StateDbSrcFlag.Name)
+	}
+	cfg.SrcDbReadonly = true
+	log := logger.NewLogger(cfg.LogLevel, "RPC-Serve")
+
+	db, _, err := utils.PrepareStateDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !cfg.ArchiveMode {
+		return fmt.Errorf("StateDB %v has no archive", cfg.StateDbSrc)
+	}
+
+	// timestamps of blocks needed by calls are read from the substate DB
+	var envs rpc.BlockEnvSource
+	if cfg.AidaDb != "" {
+		substateDb, err := executor.OpenSubstateDb(cfg, ctx)
+		if err != nil {
+			return err
+		}
+		defer substateDb.Close()
+		envs = rpc.SubstateBlockEnvSource
+	}
+
+	addr := ctx.String(utils.RpcAddressFlag.Name)
+	log.Noticef("Serving JSON-RPC requests at http://%v", addr)
+	return http.ListenAndServe(addr, rpc.NewServer(db, envs, cfg))
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	log "github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/Fantom-foundation/Aida/utils"
+	substate "github.com/Fantom-foundation/Substate"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/urfave/cli/v2"
+)
+
+// ConvertSubstateCommand data structure for the convert-substate app
+var ConvertSubstateCommand = cli.Command{
+	Action:    ConvertSubstateToTrace,
+	Name:      "convert-substate",
+	Usage:     "converts substates into approximate storage traces without executing transactions",
+	ArgsUsage: "<blockNumFirst> <blockNumLast>",
+	Flags: []cli.Flag{
+		&utils.CpuProfileFlag,
+		&utils.SyncPeriodLengthFlag,
+		&substate.WorkersFlag,
+		&utils.ChainIDFlag,
+		&utils.TraceFileFlag,
+		&utils.TraceDebugFlag,
+		&utils.TraceValidationFlag,
+		&utils.DebugFromFlag,
+		&utils.ValidateTxStateFlag,
+		&utils.AidaDbFlag,
+		&log.LogLevelFlag,
+	},
+	Description: `
+The trace convert-substate command requires two arguments:
+<blockNumFirst> <blockNumLast>
+<blockNumFirst> and <blockNumLast> are the first and
+last block of the inclusive range of blocks to convert.
+
+Instead of executing transactions, the trace is derived from the input
+and output allocs of the substates: every touched account and storage
+slot is read, and the differences between the allocs are written.`,
+}
+
+func ConvertSubstateToTrace(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.BlockRangeArgs)
+	if err != nil {
+		return err
+	}
+
+	substate.RecordReplay = true
+	substateDb, err := executor.OpenSubstateDb(cfg, ctx)
+	if err != nil {
+		return err
+	}
+	defer substateDb.Close()
+
+	return record(cfg, substateDb, substateConverter{}, nil)
+}
+
+// substateConverter issues the StateDB operations derived from the input and
+// output allocs of a substate instead of executing its transaction.
+type substateConverter struct{}
+
+func (substateConverter) Process(state executor.State[txcontext.TxContext], ctx *executor.Context) error {
+	convertSubstate(state.Data.GetInputState(), state.Data.GetOutputState(), ctx.State)
+	return nil
+}
+
+// convertSubstate reads all accounts and storage slots touched by a transaction
+// and writes the differences between its input and output allocs. Accounts and
+// slots are visited in ascending order so that conversions are deterministic.
+func convertSubstate(input, output txcontext.WorldState, db state.StateDB) {
+	addresses := touchedAddresses(input, output)
+
+	// reads of the pre-transaction state
+	for _, addr := range addresses {
+		db.Exist(addr)
+		if !input.Has(addr) {
+			continue
+		}
+		db.GetBalance(addr)
+		db.GetNonce(addr)
+		db.GetCodeHash(addr)
+		for _, key := range touchedKeys(input.Get(addr), output.Get(addr)) {
+			db.GetState(addr, key)
+		}
+	}
+
+	// writes of the differences
+	for _, addr := range addresses {
+		in, out := input.Get(addr), output.Get(addr)
+		if out == nil {
+			// accounts missing in the output alloc have been deleted
+			db.Suicide(addr)
+			continue
+		}
+		if in == nil {
+			db.CreateAccount(addr)
+			in = txcontext.NewAccount(nil, nil, new(big.Int), 0)
+		}
+
+		switch diff := new(big.Int).Sub(out.GetBalance(), in.GetBalance()); diff.Sign() {
+		case 1:
+			db.AddBalance(addr, diff)
+		case -1:
+			db.SubBalance(addr, diff.Neg(diff))
+		}
+
+		if in.GetNonce() != out.GetNonce() {
+			db.SetNonce(addr, out.GetNonce())
+		}
+		if !bytes.Equal(in.GetCode(), out.GetCode()) {
+			db.SetCode(addr, out.GetCode())
+		}
+		for _, key := range touchedKeys(in, out) {
+			if value := out.GetStorageAt(key); value != in.GetStorageAt(key) {
+				db.SetState(addr, key, value)
+			}
+		}
+	}
+}
+
+// touchedAddresses returns the sorted addresses of both allocs.
+func touchedAddresses(input, output txcontext.WorldState) []common.Address {
+	seen := make(map[common.Address]struct{})
+	for _, alloc := range []txcontext.WorldState{input, output} {
+		alloc.ForEachAccount(func(addr common.Address, _ txcontext.Account) {
+			seen[addr] = struct{}{}
+		})
+	}
+	addresses := make([]common.Address, 0, len(seen))
+	for addr := range seen {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return bytes.Compare(addresses[i][:], addresses[j][:]) < 0 })
+	return addresses
+}
+
+// touchedKeys returns the sorted storage keys of both accounts; nil accounts are ignored.
+func touchedKeys(accounts ...txcontext.Account) []common.Hash {
+	seen := make(map[common.Hash]struct{})
+	for _, acc := range accounts {
+		if acc == nil {
+			continue
+		}
+		acc.ForEachStorage(func(key common.Hash, _ common.Hash) {
+			seen[key] = struct{}{}
+		})
+	}
+	keys := make([]common.Hash, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i][:], keys[j][:]) < 0 })
+	return keys
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	substatecontext "github.com/Fantom-foundation/Aida/txcontext/substate"
+	"github.com/Fantom-foundation/Aida/utils"
+	substate "github.com/Fantom-foundation/Substate"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSdbConvert_ReadsTouchedStateAndWritesDifferences(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+
+	updated := common.Address{1}
+	created := common.Address{2}
+	deleted := common.Address{3}
+	key1 := common.Hash{1}
+	key2 := common.Hash{2}
+
+	in := substate.NewSubstateAccount(1, big.NewInt(100), []byte{})
+	in.Storage[key1] = common.Hash{1}
+	in.Storage[key2] = common.Hash{2}
+	out := substate.NewSubstateAccount(2, big.NewInt(60), []byte{})
+	out.Storage[key1] = common.Hash{1}
+	out.Storage[key2] = common.Hash{3}
+
+	input := substatecontext.NewWorldState(substate.SubstateAlloc{
+		updated: in,
+		deleted: substate.NewSubstateAccount(0, big.NewInt(0), []byte{}),
+	})
+	output := substatecontext.NewWorldState(substate.SubstateAlloc{
+		updated: out,
+		created: substate.NewSubstateAccount(0, big.NewInt(5), []byte{1}),
+	})
+
+	gomock.InOrder(
+		// reads
+		db.EXPECT().Exist(updated),
+		db.EXPECT().GetBalance(updated),
+		db.EXPECT().GetNonce(updated),
+		db.EXPECT().GetCodeHash(updated),
+		db.EXPECT().GetState(updated, key1),
+		db.EXPECT().GetState(updated, key2),
+		db.EXPECT().Exist(created),
+		db.EXPECT().Exist(deleted),
+		db.EXPECT().GetBalance(deleted),
+		db.EXPECT().GetNonce(deleted),
+		db.EXPECT().GetCodeHash(deleted),
+
+		// writes
+		db.EXPECT().SubBalance(updated, big.NewInt(40)),
+		db.EXPECT().SetNonce(updated, uint64(2)),
+		db.EXPECT().SetState(updated, key2, common.Hash{3}),
+		db.EXPECT().CreateAccount(created),
+		db.EXPECT().AddBalance(created, big.NewInt(5)),
+		db.EXPECT().SetCode(created, []byte{1}),
+		db.EXPECT().Suicide(deleted),
+	)
+
+	convertSubstate(input, output, db)
+}
+
+func TestSdbConvert_TraceIsWrittenWithoutExecution(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := executor.NewMockProvider[txcontext.TxContext](ctrl)
+	path := t.TempDir() + "test_trace"
+	cfg := &utils.Config{
+		First:            10,
+		Last:             10,
+		ChainID:          utils.MainnetChainID,
+		SkipPriming:      true,
+		TraceFile:        path,
+		SyncPeriodLength: 1,
+	}
+
+	provider.EXPECT().
+		Run(10, 11, gomock.Any()).
+		DoAndReturn(func(from int, to int, consumer executor.Consumer[txcontext.TxContext]) error {
+			return consumer(executor.TransactionInfo[txcontext.TxContext]{Block: 10, Transaction: 0, Data: substatecontext.NewTxContext(testTx)})
+		})
+
+	if err := record(cfg, provider, substateConverter{}, nil); err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+
+	stats, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if stats.Size() <= 0 {
+		t.Fatalf("size of trace file is 0")
+	}
+}
@@ -34,8 +34,11 @@ func initTraceApp() *cli.App {
 		Flags:     []cli.Flag{},
 		Commands: []*cli.Command{
 			&RecordCommand,
+			&ConvertSubstateCommand,
 			&trace.TraceReplayCommand,
 			&trace.TraceReplaySubstateCommand,
+			&trace.TraceGenerateTestCommand,
+			&trace.TraceSimulateCacheCommand,
 		},
 	}
 }
@@ -43,6 +43,7 @@ var RecordCommand = cli.Command{
 		&utils.ChainIDFlag,
 		&utils.TraceFileFlag,
 		&utils.TraceDebugFlag,
+		&utils.TraceValidationFlag,
 		&utils.DebugFromFlag,
 		&utils.AidaDbFlag,
 		&log.LogLevelFlag,
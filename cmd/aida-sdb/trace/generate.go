@@ -0,0 +1,111 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/tracer/testgen"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// GenerateTest replays a segment of a storage trace against a reference StateDB
+// and writes the operations and their results as a Go regression test.
+func GenerateTest(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.BlockRangeArgs)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Output == "" {
+		return fmt.Errorf("output file for the generated test is not set (--%v)", utils.OutputFlag.Name)
+	}
+
+	operationProvider, err := executor.OpenOperations(cfg)
+	if err != nil {
+		return err
+	}
+
+	defer operationProvider.Close()
+
+	// we need to open substate if we are priming
+	if cfg.First > 0 && !cfg.SkipPriming {
+		substateDb, err := executor.OpenSubstateDb(cfg, ctx)
+		if err != nil {
+			return err
+		}
+
+		defer substateDb.Close()
+	}
+
+	gen := testgen.NewGenerator(cfg.TestPackage, testName(cfg), cfg.First)
+	processor := testGenerationProcessor{
+		operationProcessor: operationProcessor{cfg, context.NewReplay()},
+		gen:                gen,
+	}
+
+	if err = replay(cfg, operationProvider, processor, nil); err != nil {
+		return err
+	}
+
+	if gen.NumOperations() == 0 {
+		return fmt.Errorf("no operations found for block range %v - %v", cfg.First, cfg.Last)
+	}
+
+	file, err := os.Create(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("cannot create %v; %v", cfg.Output, err)
+	}
+	if err = gen.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// testName derives the name of the generated test from the selected trace segment.
+func testName(cfg *utils.Config) string {
+	name := fmt.Sprintf("Block%d", cfg.First)
+	if cfg.Last != cfg.First {
+		name = fmt.Sprintf("Block%d_%d", cfg.First, cfg.Last)
+	}
+	if cfg.TraceTransaction >= 0 {
+		name = fmt.Sprintf("%s_Tx%d", name, cfg.TraceTransaction)
+	}
+	return name
+}
+
+// testGenerationProcessor replays the selected transactions through a generator
+// proxy; all other transactions are replayed directly to keep the reference
+// StateDB up to date.
+type testGenerationProcessor struct {
+	operationProcessor
+	gen *testgen.Generator
+}
+
+func (p testGenerationProcessor) Process(state executor.State[[]operation.Operation], ctx *executor.Context) error {
+	db := ctx.State
+	if p.cfg.TraceTransaction < 0 || (state.Block == int(p.cfg.First) && state.Transaction == p.cfg.TraceTransaction) {
+		db = testgen.NewGeneratorProxy(ctx.State, p.gen)
+	}
+	return p.runTransaction(uint64(state.Block), state.Transaction, state.Data, db)
+}
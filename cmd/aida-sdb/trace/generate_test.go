@@ -0,0 +1,76 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/tracer/testgen"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+func TestSdbGenerateTest_TestNameReflectsSegment(t *testing.T) {
+	tests := []struct {
+		first, last uint64
+		tx          int
+		want        string
+	}{
+		{10, 10, -1, "Block10"},
+		{10, 12, -1, "Block10_12"},
+		{10, 10, 3, "Block10_Tx3"},
+	}
+
+	for _, test := range tests {
+		cfg := &utils.Config{First: test.first, Last: test.last, TraceTransaction: test.tx}
+		if got := testName(cfg); got != test.want {
+			t.Errorf("unexpected test name; got %v, want %v", got, test.want)
+		}
+	}
+}
+
+func TestSdbGenerateTest_OnlySelectedTransactionIsRecorded(t *testing.T) {
+	db, err := state.MakeEmptyGethInMemoryStateDB("")
+	if err != nil {
+		t.Fatalf("cannot create state DB; %v", err)
+	}
+
+	cfg := &utils.Config{First: 0, Last: 0, TraceTransaction: 1}
+	gen := testgen.NewGenerator("state_test", testName(cfg), cfg.First)
+	processor := testGenerationProcessor{
+		operationProcessor: operationProcessor{cfg, context.NewReplay()},
+		gen:                gen,
+	}
+
+	ctx := &executor.Context{State: db}
+	if err := processor.Process(executor.State[[]operation.Operation]{Block: 0, Transaction: 0, Data: testOperationsA}, ctx); err != nil {
+		t.Fatalf("cannot process transaction; %v", err)
+	}
+	if got := gen.NumOperations(); got != 0 {
+		t.Errorf("unselected transaction must not be recorded; got %d operations", got)
+	}
+
+	if err := processor.Process(executor.State[[]operation.Operation]{Block: 0, Transaction: 1, Data: testOperationsB}, ctx); err != nil {
+		t.Fatalf("cannot process transaction; %v", err)
+	}
+	if got, want := gen.NumOperations(), len(testOperationsB); got != want {
+		t.Errorf("unexpected number of recorded operations; got %v, want %v", got, want)
+	}
+}
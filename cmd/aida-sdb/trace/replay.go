@@ -17,6 +17,8 @@
 package trace
 
 import (
+	"fmt"
+
 	"github.com/Fantom-foundation/Aida/executor"
 	"github.com/Fantom-foundation/Aida/executor/extension/logger"
 	"github.com/Fantom-foundation/Aida/executor/extension/primer"
@@ -35,14 +37,29 @@ func ReplayTrace(ctx *cli.Context) error {
 		return err
 	}
 
+	// we need to open substate if we are priming
+	if cfg.First > 0 && !cfg.SkipPriming || cfg.ShardLength > 0 && shardsNeedPriming(cfg) {
+		substateDb, err := executor.OpenSubstateDb(cfg, ctx)
+		if err != nil {
+			return err
+		}
+
+		defer substateDb.Close()
+	}
+
+	if cfg.ShardLength > 0 {
+		return replayShards(cfg)
+	}
+
 	operationProvider, err := executor.OpenOperations(cfg)
 	if err != nil {
-
+		return err
 	}
 
 	defer operationProvider.Close()
 
 	rCtx := context.NewReplay()
+	rCtx.Validate = cfg.TraceValidation
 
 	processor := operationProcessor{cfg, rCtx}
 
@@ -50,16 +67,6 @@ func ReplayTrace(ctx *cli.Context) error {
 		profiler.MakeReplayProfiler[[]operation.Operation](cfg, rCtx),
 	}
 
-	// we need to open substate if we are priming
-	if cfg.First > 0 && !cfg.SkipPriming {
-		substateDb, err := executor.OpenSubstateDb(cfg, ctx)
-		if err != nil {
-			return err
-		}
-
-		defer substateDb.Close()
-	}
-
 	return replay(cfg, operationProvider, processor, extra)
 }
 
@@ -69,17 +76,27 @@ type operationProcessor struct {
 }
 
 func (p operationProcessor) Process(state executor.State[[]operation.Operation], ctx *executor.Context) error {
-	p.runTransaction(uint64(state.Block), state.Data, ctx.State)
-	return nil
+	return p.runTransaction(uint64(state.Block), state.Transaction, state.Data, ctx.State)
 }
 
-func (p operationProcessor) runTransaction(block uint64, operations []operation.Operation, stateDb state.StateDB) {
-	for _, op := range operations {
+// runTransaction executes the operations of a transaction and reports the first
+// read operation whose result differs from the result recorded in the trace.
+func (p operationProcessor) runTransaction(block uint64, tx int, operations []operation.Operation, stateDb state.StateDB) error {
+	for i, op := range operations {
 		operation.Execute(op, stateDb, p.rCtx)
 		if p.cfg.Debug && block >= p.cfg.DebugFrom {
 			operation.Debug(&p.rCtx.Context, op)
 		}
+		if err := p.rCtx.Mismatch(); err != nil {
+			// results are recorded right after their read operation
+			read := i
+			if i > 0 {
+				read = i - 1
+			}
+			return fmt.Errorf("block %d, transaction %d: operation %d (%v) failed validation; %v", block, tx, read, operation.GetLabel(operations[read].GetId()), err)
+		}
 	}
+	return nil
 }
 
 func replay(
@@ -0,0 +1,347 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/executor/extension"
+	"github.com/Fantom-foundation/Aida/executor/extension/primer"
+	"github.com/Fantom-foundation/Aida/executor/extension/statedb"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/tracer"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// shard is an inclusive block range of a trace replayed independently of other shards.
+type shard struct {
+	first, last uint64
+}
+
+// shardResult contains the timing of a replayed shard.
+type shardResult struct {
+	shard
+	priming      time.Duration // time spent preparing and priming the StateDB
+	replay       time.Duration // time spent replaying the trace
+	transactions int           // number of replayed transactions
+	err          error
+}
+
+// makeShards splits the block range into shards. The shard length is rounded up
+// to a multiple of the sync-period length so that shards start and end at
+// sync-period boundaries.
+func makeShards(first, last, length, syncPeriod uint64) []shard {
+	if syncPeriod > 0 {
+		length = (length + syncPeriod - 1) / syncPeriod * syncPeriod
+	}
+	if length == 0 {
+		return []shard{{first, last}}
+	}
+
+	var shards []shard
+	for start := first; start <= last; {
+		end := (start/length+1)*length - 1
+		if end > last {
+			end = last
+		}
+		shards = append(shards, shard{start, end})
+		start = end + 1
+	}
+	return shards
+}
+
+// shardsNeedPriming returns true if a shard of the block range is primed from the substate DB.
+func shardsNeedPriming(cfg *utils.Config) bool {
+	if cfg.SkipPriming {
+		return false
+	}
+	// shards after the first one are primed to their first block even if the range starts at block 0
+	return cfg.First > 0 || len(makeShards(cfg.First, cfg.Last, cfg.ShardLength, cfg.SyncPeriodLength)) > 1
+}
+
+// replayShards replays the block range in shards on cfg.Workers workers. Each shard
+// is replayed on its own StateDB primed to the shard's first block. The trace is read
+// once and split into a trace file per shard; a shard is replayed as soon as its trace
+// file is complete.
+func replayShards(cfg *utils.Config) error {
+	log := logger.NewLogger(cfg.LogLevel, "Shard-Replay")
+
+	if cfg.StateDbSrc != "" && cfg.SrcDbReadonly {
+		return fmt.Errorf("sharded replay cannot use a read-only source StateDB")
+	}
+
+	shards := makeShards(cfg.First, cfg.Last, cfg.ShardLength, cfg.SyncPeriodLength)
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	log.Noticef("Replaying blocks %v - %v in %v shards using %v workers", cfg.First, cfg.Last, len(shards), workers)
+
+	traceDir, err := os.MkdirTemp(cfg.DbTmp, "shard_traces_")
+	if err != nil {
+		return fmt.Errorf("cannot create directory for shard traces; %v", err)
+	}
+	defer os.RemoveAll(traceDir)
+
+	start := time.Now()
+	results := make([]shardResult, len(shards))
+	jobs := make(chan int, len(shards))
+	var splitErr error
+	go func() {
+		defer close(jobs)
+		splitErr = splitTrace(cfg, shards, traceDir, jobs)
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				traceFile := shardTraceFile(traceDir, i)
+				results[i] = replayShard(cfg, shards[i], traceFile)
+				// the trace of a shard is not needed anymore once it has been replayed
+				os.Remove(traceFile)
+				r := results[i]
+				if r.err != nil {
+					log.Errorf("Shard %v - %v failed; %v", r.first, r.last, r.err)
+					continue
+				}
+				log.Noticef("Shard %v - %v: %v transactions replayed in %v (priming %v)", r.first, r.last, r.transactions, r.replay, r.priming)
+			}
+		}()
+	}
+	wg.Wait()
+	if splitErr != nil {
+		return fmt.Errorf("cannot split trace into shards; %v", splitErr)
+	}
+
+	var (
+		errs         []error
+		priming      time.Duration
+		replay       time.Duration
+		transactions int
+	)
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("shard %v - %v; %w", r.first, r.last, r.err))
+			continue
+		}
+		priming += r.priming
+		replay += r.replay
+		transactions += r.transactions
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	elapsed := time.Since(start)
+	log.Noticef("Replayed %v transactions of %v shards in %v", transactions, len(shards), elapsed)
+	log.Noticef("Total priming time: %v, total replay time: %v", priming, replay)
+	if replay > 0 {
+		log.Noticef("Replay throughput: %.2f tx/s", float64(transactions)/replay.Seconds())
+	}
+	return nil
+}
+
+// shardTraceFile returns the path of the trace file of the i-th shard.
+func shardTraceFile(dir string, i int) string {
+	return filepath.Join(dir, fmt.Sprintf("shard_%v.dat", i))
+}
+
+// splitTrace reads the trace of the block range once and writes the operations of every
+// shard into a trace file of its own in dir, hence no shard needs to skip the operations
+// preceding it. Operations are assigned to the block of the last BeginBlock operation,
+// which is the range a shard would read from the whole trace. The index of a shard is
+// sent to ready as soon as its trace file is complete.
+func splitTrace(cfg *utils.Config, shards []shard, dir string, ready chan<- int) error {
+	if len(shards) == 0 {
+		return nil
+	}
+	traceFiles, err := tracer.GetTraceFiles(cfg)
+	if err != nil {
+		return err
+	}
+	iter := tracer.NewTraceIterator(traceFiles, cfg.First)
+	defer iter.Release()
+
+	current := 0
+	rec, err := context.NewRecord(shardTraceFile(dir, current), shards[current].first)
+	if err != nil {
+		return err
+	}
+	// completeShard closes the trace file of the current shard and opens the next one
+	completeShard := func() error {
+		rec.Close()
+		ready <- current
+		current++
+		if current == len(shards) {
+			return nil
+		}
+		rec, err = context.NewRecord(shardTraceFile(dir, current), shards[current].first)
+		return err
+	}
+
+	for iter.Next() {
+		op := iter.Value()
+		if bb, ok := op.(*operation.BeginBlock); ok {
+			for current < len(shards) && bb.BlockNumber > shards[current].last {
+				if err = completeShard(); err != nil {
+					return err
+				}
+			}
+			if current == len(shards) {
+				return nil
+			}
+		}
+		operation.Write(rec.ZFile, op)
+	}
+
+	// shards beyond the end of the trace have empty traces
+	for current < len(shards) {
+		if err = completeShard(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayShard replays a single shard from its own trace file on its own StateDB.
+func replayShard(cfg *utils.Config, s shard, traceFile string) shardResult {
+	res := shardResult{shard: s}
+
+	// every shard needs its own config since preparing the StateDB modifies it
+	shardCfg := *cfg
+	shardCfg.First = s.first
+	shardCfg.Last = s.last
+	shardCfg.KeepDb = false
+	shardCfg.TraceFile = traceFile
+	shardCfg.TraceDirectory = ""
+	if cfg.StateDbSrc != "" {
+		src, err := selectShardDbSrc(cfg.StateDbSrc, s.first)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		shardCfg.StateDbSrc = src
+	}
+
+	operationProvider, err := executor.OpenOperations(&shardCfg)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	defer operationProvider.Close()
+
+	timer := &shardTimer{start: time.Now()}
+	processor := operationProcessor{&shardCfg, context.NewReplay()}
+	processor.rCtx.Validate = cfg.TraceValidation
+
+	extensionList := []executor.Extension[[]operation.Operation]{
+		statedb.MakeStateDbManager[[]operation.Operation](&shardCfg, ""),
+		primer.MakeStateDbPrimer[[]operation.Operation](&shardCfg),
+		timer,
+	}
+
+	res.err = executor.NewExecutor(operationProvider, cfg.LogLevel).Run(
+		executor.Params{
+			From: int(shardCfg.First),
+			To:   int(shardCfg.Last) + 1,
+		},
+		processor,
+		extensionList,
+	)
+	res.priming = timer.priming
+	res.replay = timer.replay
+	res.transactions = timer.transactions
+	return res
+}
+
+// selectShardDbSrc returns the StateDB a shard starts from. If src contains
+// several StateDB snapshots, the most advanced snapshot preceding the first
+// block of the shard is selected.
+func selectShardDbSrc(src string, first uint64) (string, error) {
+	if _, err := os.Stat(filepath.Join(src, utils.PathToDbInfo)); err == nil {
+		return src, nil
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return "", fmt.Errorf("cannot read state-db snapshots in %v; %v", src, err)
+	}
+
+	type snapshot struct {
+		path  string
+		block uint64
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(src, entry.Name())
+		info, err := utils.ReadStateDbInfo(filepath.Join(path, utils.PathToDbInfo))
+		if err != nil {
+			continue
+		}
+		if info.Block < first {
+			snapshots = append(snapshots, snapshot{path, info.Block})
+		}
+	}
+	if len(snapshots) == 0 {
+		return "", fmt.Errorf("no state-db snapshot in %v precedes block %v", src, first)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].block > snapshots[j].block })
+	return snapshots[0].path, nil
+}
+
+// shardTimer measures the time spent priming and replaying a shard. It must be
+// registered after the StateDB manager and the primer.
+type shardTimer struct {
+	extension.NilExtension[[]operation.Operation]
+	start        time.Time
+	replayStart  time.Time
+	priming      time.Duration
+	replay       time.Duration
+	transactions int
+}
+
+func (t *shardTimer) PreRun(executor.State[[]operation.Operation], *executor.Context) error {
+	t.replayStart = time.Now()
+	t.priming = t.replayStart.Sub(t.start)
+	return nil
+}
+
+func (t *shardTimer) PostTransaction(executor.State[[]operation.Operation], *executor.Context) error {
+	t.transactions++
+	return nil
+}
+
+func (t *shardTimer) PostRun(executor.State[[]operation.Operation], *executor.Context, error) error {
+	t.replay = time.Since(t.replayStart)
+	return nil
+}
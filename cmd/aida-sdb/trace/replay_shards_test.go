@@ -0,0 +1,207 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/tracer"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSdbReplayShards_ShardsEndAtSyncPeriodBoundaries(t *testing.T) {
+	tests := []struct {
+		first, last, length, syncPeriod uint64
+		want                            []shard
+	}{
+		{0, 9, 5, 0, []shard{{0, 4}, {5, 9}}},
+		{3, 12, 5, 0, []shard{{3, 4}, {5, 9}, {10, 12}}},
+		// shard length is rounded up to whole sync periods
+		{0, 11, 3, 4, []shard{{0, 3}, {4, 7}, {8, 11}}},
+		{6, 20, 10, 4, []shard{{6, 11}, {12, 20}}},
+		{7, 7, 10, 4, []shard{{7, 7}}},
+	}
+
+	for _, test := range tests {
+		got := makeShards(test.first, test.last, test.length, test.syncPeriod)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected shards for %v - %v; got %v, want %v", test.first, test.last, got, test.want)
+		}
+	}
+}
+
+func TestSdbReplayShards_MostAdvancedPrecedingSnapshotIsSelected(t *testing.T) {
+	src := t.TempDir()
+	for _, block := range []uint64{99, 199, 299} {
+		dir := filepath.Join(src, "snapshot_"+string(rune('a'+block/100)))
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatalf("cannot create snapshot directory; %v", err)
+		}
+		if err := utils.WriteStateDbInfo(dir, &utils.Config{}, block, common.Hash{}); err != nil {
+			t.Fatalf("cannot write state-db info; %v", err)
+		}
+	}
+
+	got, err := selectShardDbSrc(src, 250)
+	if err != nil {
+		t.Fatalf("cannot select snapshot; %v", err)
+	}
+	if want := filepath.Join(src, "snapshot_b"); got != want {
+		t.Errorf("unexpected snapshot; got %v, want %v", got, want)
+	}
+
+	if _, err = selectShardDbSrc(src, 50); err == nil {
+		t.Errorf("selecting a snapshot without a preceding block must fail")
+	}
+
+	// a single StateDB is used by all shards
+	got, err = selectShardDbSrc(filepath.Join(src, "snapshot_c"), 250)
+	if err != nil {
+		t.Fatalf("cannot select snapshot; %v", err)
+	}
+	if want := filepath.Join(src, "snapshot_c"); got != want {
+		t.Errorf("unexpected snapshot; got %v, want %v", got, want)
+	}
+}
+
+func TestSdbReplayShards_AllShardsAreReplayed(t *testing.T) {
+	traceFile := filepath.Join(t.TempDir(), "trace.dat")
+	rCtx, err := context.NewRecord(traceFile, 0)
+	if err != nil {
+		t.Fatalf("cannot create trace; %v", err)
+	}
+	for block := uint64(0); block < 6; block++ {
+		if block%2 == 0 {
+			operation.WriteOp(rCtx, operation.NewBeginSyncPeriod(block/2))
+		}
+		operation.WriteOp(rCtx, operation.NewBeginBlock(block))
+		operation.WriteOp(rCtx, operation.NewBeginTransaction(0))
+		operation.WriteOp(rCtx, operation.NewCreateAccount(common.Address{byte(block)}))
+		operation.WriteOp(rCtx, operation.NewEndTransaction())
+		operation.WriteOp(rCtx, operation.NewEndBlock())
+		if block%2 == 1 {
+			operation.WriteOp(rCtx, operation.NewEndSyncPeriod())
+		}
+	}
+	rCtx.Close()
+
+	cfg := &utils.Config{
+		ChainID:          utils.MainnetChainID,
+		First:            0,
+		Last:             5,
+		DbImpl:           "geth",
+		DbTmp:            t.TempDir(),
+		SkipPriming:      true,
+		ShardLength:      2,
+		SyncPeriodLength: 2,
+		TraceFile:        traceFile,
+		Workers:          2,
+		LogLevel:         "critical",
+	}
+
+	if err := replayShards(cfg); err != nil {
+		t.Fatalf("sharded replay failed; %v", err)
+	}
+
+	shards := makeShards(cfg.First, cfg.Last, cfg.ShardLength, cfg.SyncPeriodLength)
+	dir := t.TempDir()
+	ready := make(chan int, len(shards))
+	if err := splitTrace(cfg, shards, dir, ready); err != nil {
+		t.Fatalf("cannot split trace; %v", err)
+	}
+	if got, want := len(ready), len(shards); got != want {
+		t.Fatalf("unexpected number of split shards; got %v, want %v", got, want)
+	}
+	for i, s := range shards {
+		if got := <-ready; got != i {
+			t.Errorf("shards must be split in order; got %v, want %v", got, i)
+		}
+		res := replayShard(cfg, s, shardTraceFile(dir, i))
+		if res.err != nil {
+			t.Fatalf("cannot replay shard %v; %v", s, res.err)
+		}
+		if got, want := res.transactions, int(s.last-s.first+1); got != want {
+			t.Errorf("unexpected number of transactions in shard %v; got %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestSdbReplayShards_SplitTraceContainsOnlyBlocksOfShard(t *testing.T) {
+	traceFile := filepath.Join(t.TempDir(), "trace.dat")
+	rCtx, err := context.NewRecord(traceFile, 0)
+	if err != nil {
+		t.Fatalf("cannot create trace; %v", err)
+	}
+	for block := uint64(0); block < 6; block++ {
+		operation.WriteOp(rCtx, operation.NewBeginBlock(block))
+		operation.WriteOp(rCtx, operation.NewEndBlock())
+	}
+	rCtx.Close()
+
+	// the last shard lies beyond the end of the trace
+	shards := []shard{{1, 2}, {3, 5}, {6, 7}}
+	dir := t.TempDir()
+	ready := make(chan int, len(shards))
+	if err = splitTrace(&utils.Config{First: 1, Last: 7, TraceFile: traceFile}, shards, dir, ready); err != nil {
+		t.Fatalf("cannot split trace; %v", err)
+	}
+
+	for i, s := range shards {
+		var blocks []uint64
+		iter := tracer.NewTraceIterator([]string{shardTraceFile(dir, i)}, 0)
+		for iter.Next() {
+			if bb, ok := iter.Value().(*operation.BeginBlock); ok {
+				blocks = append(blocks, bb.BlockNumber)
+			}
+		}
+		iter.Release()
+
+		var want []uint64
+		for b := s.first; b <= s.last && b < 6; b++ {
+			want = append(want, b)
+		}
+		if !reflect.DeepEqual(blocks, want) {
+			t.Errorf("unexpected blocks in trace of shard %v; got %v, want %v", s, blocks, want)
+		}
+	}
+}
+
+func TestSdbReplayShards_ShardsAfterBlockZeroNeedPriming(t *testing.T) {
+	tests := []struct {
+		first, last uint64
+		skip        bool
+		want        bool
+	}{
+		{0, 9, false, true},
+		{0, 4, false, false},
+		{5, 9, false, true},
+		{0, 9, true, false},
+	}
+
+	for _, test := range tests {
+		cfg := &utils.Config{First: test.first, Last: test.last, ShardLength: 5, SkipPriming: test.skip}
+		if got := shardsNeedPriming(cfg); got != test.want {
+			t.Errorf("unexpected priming of blocks %v - %v (skip %v); got %v, want %v", test.first, test.last, test.skip, got, test.want)
+		}
+	}
+}
@@ -50,6 +50,7 @@ func ReplaySubstate(ctx *cli.Context) error {
 	defer substateProvider.Close()
 
 	rCtx := context.NewReplay()
+	rCtx.Validate = cfg.TraceValidation
 
 	processor := makeSubstateProcessor(cfg, rCtx, operationProvider)
 
@@ -73,9 +74,12 @@ type substateProcessor struct {
 }
 
 func (p substateProcessor) Process(state executor.State[txcontext.TxContext], ctx *executor.Context) error {
-	return p.operationProvider.Run(state.Block, state.Block, func(t executor.TransactionInfo[[]operation.Operation]) error {
-		p.runTransaction(uint64(state.Block), t.Data, ctx.State)
-		return nil
+	// only the traced transaction of the substate is replayed
+	return p.operationProvider.Run(state.Block, state.Block+1, func(t executor.TransactionInfo[[]operation.Operation]) error {
+		if t.Transaction != state.Transaction {
+			return nil
+		}
+		return p.runTransaction(uint64(state.Block), t.Transaction, t.Data, ctx.State)
 	})
 }
 
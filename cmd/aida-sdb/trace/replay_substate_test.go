@@ -101,7 +101,7 @@ func TestSdbReplaySubstate_StateDbPrepperIsAddedIfDbImplIsMemory(t *testing.T) {
 			return nil
 		})
 	operationProvider.EXPECT().
-		Run(0, 0, gomock.Any()).
+		Run(0, 1, gomock.Any()).
 		DoAndReturn(func(from int, to int, consumer executor.Consumer[[]operation.Operation]) error {
 			for i := from; i < to; i++ {
 				consumer(executor.TransactionInfo[[]operation.Operation]{Block: 0, Transaction: 0, Data: testOperationsA})
@@ -114,6 +114,12 @@ func TestSdbReplaySubstate_StateDbPrepperIsAddedIfDbImplIsMemory(t *testing.T) {
 	// if DbPrepper is added PrepareSubstate is called
 	db.EXPECT().PrepareSubstate(gomock.Any(), uint64(0))
 
+	// operations of the traced transaction
+	db.EXPECT().BeginBlock(uint64(0))
+	db.EXPECT().BeginTransaction(uint32(0))
+	db.EXPECT().Exist(common.Address{})
+	db.EXPECT().EndTransaction()
+
 	if err := replaySubstate(cfg, substateProvider, processor, db, nil); err != nil {
 		t.Errorf("record failed: %v", err)
 	}
@@ -142,7 +148,7 @@ func TestSdbReplaySubstate_TxPrimerIsAddedIfDbImplIsNotMemory(t *testing.T) {
 			return nil
 		})
 	operationProvider.EXPECT().
-		Run(1, 1, gomock.Any()).
+		Run(1, 2, gomock.Any()).
 		DoAndReturn(func(from int, to int, consumer executor.Consumer[[]operation.Operation]) error {
 			for i := from; i < to; i++ {
 				consumer(executor.TransactionInfo[[]operation.Operation]{Block: 1, Transaction: 0, Data: testOperationsA})
@@ -152,9 +158,11 @@ func TestSdbReplaySubstate_TxPrimerIsAddedIfDbImplIsNotMemory(t *testing.T) {
 
 	processor := makeSubstateProcessor(cfg, context.NewReplay(), operationProvider)
 
-	db.EXPECT().BeginBlock(uint64(0))
-	db.EXPECT().BeginTransaction(uint32(0))
-	db.EXPECT().EndTransaction()
+	// priming and operations of the traced transaction
+	db.EXPECT().BeginBlock(uint64(0)).Times(2)
+	db.EXPECT().BeginTransaction(uint32(0)).Times(2)
+	db.EXPECT().Exist(common.Address{})
+	db.EXPECT().EndTransaction().Times(2)
 	db.EXPECT().EndBlock()
 	db.EXPECT().StartBulkLoad(uint64(1)).Return(bulkLoad, nil)
 	bulkLoad.EXPECT().Close()
@@ -164,6 +172,35 @@ func TestSdbReplaySubstate_TxPrimerIsAddedIfDbImplIsNotMemory(t *testing.T) {
 	}
 }
 
+func TestSdbReplaySubstate_OnlyTracedTransactionOfSubstateIsReplayed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	operationProvider := executor.NewMockProvider[[]operation.Operation](ctrl)
+	db := state.NewMockStateDB(ctrl)
+
+	operationProvider.EXPECT().
+		Run(0, 1, gomock.Any()).
+		DoAndReturn(func(from int, to int, consumer executor.Consumer[[]operation.Operation]) error {
+			if err := consumer(executor.TransactionInfo[[]operation.Operation]{Block: 0, Transaction: 0, Data: testOperationsA}); err != nil {
+				return err
+			}
+			return consumer(executor.TransactionInfo[[]operation.Operation]{Block: 0, Transaction: 1, Data: testOperationsB})
+		})
+
+	// only the operations of transaction 1 are executed
+	gomock.InOrder(
+		db.EXPECT().BeginTransaction(uint32(1)),
+		db.EXPECT().Exist(common.Address{}),
+		db.EXPECT().EndTransaction(),
+		db.EXPECT().EndBlock(),
+	)
+
+	processor := makeSubstateProcessor(&utils.Config{}, context.NewReplay(), operationProvider)
+	s := executor.State[txcontext.TxContext]{Block: 0, Transaction: 1, Data: substatecontext.NewTxContext(testTx)}
+	if err := processor.Process(s, &executor.Context{State: db}); err != nil {
+		t.Errorf("process failed: %v", err)
+	}
+}
+
 var testOperationsA = []operation.Operation{
 	operation.NewBeginBlock(0),
 	operation.NewBeginTransaction(0),
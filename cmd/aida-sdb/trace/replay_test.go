@@ -17,11 +17,15 @@
 package trace
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/tracer/context"
 	"github.com/Fantom-foundation/Aida/tracer/operation"
 	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
 	"go.uber.org/mock/gomock"
 )
 
@@ -69,3 +73,51 @@ func TestSdbReplay_AllDbEventsAreIssuedInOrder(t *testing.T) {
 		t.Errorf("record failed: %v", err)
 	}
 }
+
+func TestSdbReplay_RecordedResultsAreValidated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+
+	rCtx := context.NewReplay()
+	rCtx.Validate = true
+	processor := operationProcessor{&utils.Config{}, rCtx}
+
+	db.EXPECT().Exist(common.Address{}).Return(true)
+	db.EXPECT().GetNonce(common.Address{}).Return(uint64(2))
+
+	data := []operation.Operation{
+		operation.NewExist(common.Address{}),
+		operation.NewResult([]byte{1}),
+		operation.NewGetNonce(common.Address{}),
+		operation.NewResult([]byte{1, 0, 0, 0, 0, 0, 0, 0}),
+		operation.NewEndTransaction(),
+	}
+
+	err := processor.Process(executor.State[[]operation.Operation]{Block: 5, Transaction: 3, Data: data}, &executor.Context{State: db})
+	if err == nil {
+		t.Fatal("process must fail on unexpected result")
+	}
+
+	want := "block 5, transaction 3: operation 2 (GetNonce) failed validation"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("unexpected error; got %v, want %v", err, want)
+	}
+}
+
+func TestSdbReplay_RecordedResultsAreIgnoredWithoutValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+
+	processor := operationProcessor{&utils.Config{}, context.NewReplay()}
+
+	db.EXPECT().GetNonce(common.Address{}).Return(uint64(2))
+
+	data := []operation.Operation{
+		operation.NewGetNonce(common.Address{}),
+		operation.NewResult([]byte{1, 0, 0, 0, 0, 0, 0, 0}),
+	}
+
+	if err := processor.Process(executor.State[[]operation.Operation]{Data: data}, &executor.Context{State: db}); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/tracer/cachesim"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// SimulateCache replays the accesses of a storage trace on simulated caches
+// and reports their hit rates.
+func SimulateCache(ctx *cli.Context) error {
+	cfg, err := utils.NewConfig(ctx, utils.BlockRangeArgs)
+	if err != nil {
+		return err
+	}
+
+	policies, sizes, err := parseCacheParameters(cfg.CachePolicies, cfg.CacheSizes)
+	if err != nil {
+		return err
+	}
+
+	sim, err := cachesim.NewSimulator(policies, sizes, cfg.First, cfg.CacheWindow)
+	if err != nil {
+		return err
+	}
+
+	operationProvider, err := executor.OpenOperations(cfg)
+	if err != nil {
+		return err
+	}
+	defer operationProvider.Close()
+
+	if err = simulateCache(cfg, operationProvider, sim); err != nil {
+		return err
+	}
+
+	if err = sim.WriteReport(os.Stdout); err != nil {
+		return err
+	}
+
+	if cfg.Output == "" {
+		return nil
+	}
+	file, err := os.Create(cfg.Output)
+	if err != nil {
+		return fmt.Errorf("cannot create %v; %v", cfg.Output, err)
+	}
+	if err = sim.WriteCsv(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// simulateCache feeds all operations of the block range into the simulator.
+func simulateCache(cfg *utils.Config, provider executor.Provider[[]operation.Operation], sim *cachesim.Simulator) error {
+	return provider.Run(int(cfg.First), int(cfg.Last)+1, func(t executor.TransactionInfo[[]operation.Operation]) error {
+		for _, op := range t.Data {
+			sim.Process(op)
+		}
+		return nil
+	})
+}
+
+// parseCacheParameters parses the comma-separated lists of cache policies and sizes.
+func parseCacheParameters(policyList, sizeList string) ([]string, []int, error) {
+	var policies []string
+	for _, policy := range strings.Split(policyList, ",") {
+		if policy = strings.TrimSpace(policy); policy != "" {
+			policies = append(policies, strings.ToLower(policy))
+		}
+	}
+	if len(policies) == 0 {
+		return nil, nil, fmt.Errorf("no cache policy given (--%v)", utils.CachePoliciesFlag.Name)
+	}
+
+	var sizes []int
+	for _, size := range strings.Split(sizeList, ",") {
+		if size = strings.TrimSpace(size); size == "" {
+			continue
+		}
+		n, err := strconv.Atoi(size)
+		if err != nil || n <= 0 {
+			return nil, nil, fmt.Errorf("invalid cache size %q", size)
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) == 0 {
+		return nil, nil, fmt.Errorf("no cache size given (--%v)", utils.CacheSizesFlag.Name)
+	}
+	return policies, sizes, nil
+}
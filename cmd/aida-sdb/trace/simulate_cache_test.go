@@ -0,0 +1,75 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package trace
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/tracer/cachesim"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSdbSimulateCache_ParametersAreParsed(t *testing.T) {
+	policies, sizes, err := parseCacheParameters("LRU, arc", "10,200 ,")
+	if err != nil {
+		t.Fatalf("cannot parse parameters; %v", err)
+	}
+	if want := []string{"lru", "arc"}; !reflect.DeepEqual(policies, want) {
+		t.Errorf("unexpected policies; got %v, want %v", policies, want)
+	}
+	if want := []int{10, 200}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("unexpected sizes; got %v, want %v", sizes, want)
+	}
+
+	if _, _, err = parseCacheParameters("lru", "10,-1"); err == nil {
+		t.Errorf("negative cache size must be rejected")
+	}
+	if _, _, err = parseCacheParameters("", "10"); err == nil {
+		t.Errorf("missing policy must be rejected")
+	}
+}
+
+func TestSdbSimulateCache_AllOperationsAreSimulated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	provider := executor.NewMockProvider[[]operation.Operation](ctrl)
+
+	cfg := &utils.Config{First: 0, Last: 0}
+	provider.EXPECT().
+		Run(0, 1, gomock.Any()).
+		DoAndReturn(func(from int, to int, consumer executor.Consumer[[]operation.Operation]) error {
+			consumer(executor.TransactionInfo[[]operation.Operation]{Block: 0, Transaction: 0, Data: testOperationsA})
+			return consumer(executor.TransactionInfo[[]operation.Operation]{Block: 0, Transaction: 1, Data: testOperationsB})
+		})
+
+	sim, err := cachesim.NewSimulator([]string{"lru"}, []int{1}, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot create simulator; %v", err)
+	}
+	if err := simulateCache(cfg, provider, sim); err != nil {
+		t.Fatalf("simulation failed; %v", err)
+	}
+
+	// both transactions check the existence of the same account
+	if got, want := sim.HitRate(cachesim.AddressStream, 0, 0), 0.5; got != want {
+		t.Errorf("unexpected hit rate of %v; got %v, want %v", common.Address{}, got, want)
+	}
+}
@@ -58,7 +58,9 @@ var TraceReplayCommand = cli.Command{
 		&utils.TraceFileFlag,
 		&utils.TraceDirectoryFlag,
 		&utils.TraceDebugFlag,
+		&utils.TraceValidationFlag,
 		&utils.DebugFromFlag,
+		&utils.ShardLengthFlag,
 		//&utils.ValidateFlag,
 		//&utils.ValidateTxStateFlag,
 		&utils.AidaDbFlag,
@@ -69,7 +71,11 @@ The trace replay command requires two arguments:
 <blockNumFirst> <blockNumLast>
 
 <blockNumFirst> and <blockNumLast> are the first and
-last block of the inclusive range of blocks to replay storage traces.`,
+last block of the inclusive range of blocks to replay storage traces.
+
+If --shard-length is set, the range is split into shards at sync-period
+boundaries which are replayed in parallel by --workers workers, each on
+its own StateDB primed to the first block of its shard.`,
 }
 
 // TraceReplaySubstateCommand data structure for the replay-substate app
@@ -95,6 +101,7 @@ var TraceReplaySubstateCommand = cli.Command{
 		&utils.TraceFileFlag,
 		&utils.TraceDirectoryFlag,
 		&utils.TraceDebugFlag,
+		&utils.TraceValidationFlag,
 		&utils.DebugFromFlag,
 		//&utils.ValidateFlag,
 		//&utils.ValidateTxStateFlag,
@@ -108,3 +115,72 @@ The trace replay-substate command requires two arguments:
 <blockNumFirst> and <blockNumLast> are the first and
 last block of the inclusive range of blocks to replay storage traces.`,
 }
+
+// TraceGenerateTestCommand data structure for the generate-test app
+var TraceGenerateTestCommand = cli.Command{
+	Action:    GenerateTest,
+	Name:      "generate-test",
+	Usage:     "generates a Go regression test from a storage trace segment",
+	ArgsUsage: "<blockNumFirst> <blockNumLast>",
+	Flags: []cli.Flag{
+		&utils.CarmenSchemaFlag,
+		&utils.ChainIDFlag,
+		&utils.SyncPeriodLengthFlag,
+		&utils.RandomSeedFlag,
+		&utils.PrimeThresholdFlag,
+		&utils.RandomizePrimingFlag,
+		&utils.SkipPrimingFlag,
+		&utils.StateDbImplementationFlag,
+		&utils.StateDbVariantFlag,
+		&utils.StateDbSrcFlag,
+		&utils.DbTmpFlag,
+		&utils.UpdateBufferSizeFlag,
+		&substate.WorkersFlag,
+		&utils.TraceFileFlag,
+		&utils.TraceDirectoryFlag,
+		&utils.TraceTransactionFlag,
+		&utils.TestPackageFlag,
+		&utils.OutputFlag,
+		&utils.AidaDbFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The trace generate-test command requires two arguments:
+<blockNumFirst> <blockNumLast>
+
+<blockNumFirst> and <blockNumLast> are the first and
+last block of the inclusive range of the trace segment.
+The segment is replayed on the selected StateDB and the
+resulting operations, their read results and the state
+read by the segment are written as a self-contained Go
+test to the file given by --output. Use --trace-tx to
+select a single transaction of the segment.`,
+}
+
+// TraceSimulateCacheCommand data structure for the simulate-cache app
+var TraceSimulateCacheCommand = cli.Command{
+	Action:    SimulateCache,
+	Name:      "simulate-cache",
+	Usage:     "simulates StateDB caches of several policies and sizes on a storage trace",
+	ArgsUsage: "<blockNumFirst> <blockNumLast>",
+	Flags: []cli.Flag{
+		&utils.CachePoliciesFlag,
+		&utils.CacheSizesFlag,
+		&utils.CacheWindowFlag,
+		&utils.TraceFileFlag,
+		&utils.TraceDirectoryFlag,
+		&utils.OutputFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The trace simulate-cache command requires two arguments:
+<blockNumFirst> <blockNumLast>
+
+<blockNumFirst> and <blockNumLast> are the first and
+last block of the inclusive range of the simulated trace.
+The accounts, storage slots and codes accessed by the trace
+are fed into caches of the policies and sizes given by
+--cache-policies and --cache-sizes. Hit rates are reported
+per operation type and per range of --cache-window blocks;
+all counters are written as CSV to the file given by --output.`,
+}
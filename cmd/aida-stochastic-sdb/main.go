@@ -32,8 +32,11 @@ func initStochasticApp() *cli.App {
 		Copyright: "(c) 2022-23 Fantom Foundation",
 		Flags:     []cli.Flag{},
 		Commands: []*cli.Command{
+			&stochastic.StochasticCompareCommand,
 			&stochastic.StochasticEstimateCommand,
+			&stochastic.StochasticFuzzCommand,
 			&stochastic.StochasticGenerateCommand,
+			&stochastic.StochasticMergeCommand,
 			&stochastic.StochasticRecordCommand,
 			&stochastic.StochasticReplayCommand,
 			&stochastic.StochasticVisualizeCommand,
@@ -0,0 +1,87 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// StochasticCompareCommand data structure for the compare app.
+var StochasticCompareCommand = cli.Command{
+	Action:    stochasticCompareAction,
+	Name:      "compare",
+	Usage:     "compares two stochastic models and reports their goodness of fit",
+	ArgsUsage: "<events-or-simulation-file> <events-or-simulation-file>",
+	Flags: []cli.Flag{
+		&utils.OutputFlag,
+	},
+	Description: `
+The stochastic compare command requires two arguments:
+<A.json> <B.json>
+
+<A.json> and <B.json> are event files produced by the stochastic
+recorder or simulation files produced by the stochastic estimator.
+The command reports the KL and JS divergence of the operation
+frequencies, the distance of the stationary distributions, and the
+lambda differences of the contract, key, value and snapshot-delta
+distributions. With --output, the report is also written as JSON.`,
+}
+
+// stochasticCompareAction implements the compare command.
+func stochasticCompareAction(ctx *cli.Context) error {
+	log := logger.NewLogger("INFO", "StochasticCompare")
+
+	// parse arguments
+	if ctx.Args().Len() != 2 {
+		return fmt.Errorf("compare requires two files")
+	}
+	fileA := ctx.Args().Get(0)
+	fileB := ctx.Args().Get(1)
+
+	log.Infof("Read file %v", fileA)
+	a, err := stochastic.ReadModelSummary(fileA)
+	if err != nil {
+		return err
+	}
+	log.Infof("Read file %v", fileB)
+	b, err := stochastic.ReadModelSummary(fileB)
+	if err != nil {
+		return err
+	}
+
+	report, err := stochastic.Compare(fileA, a, fileB, b)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteReport(os.Stdout); err != nil {
+		return err
+	}
+
+	if outputFileName := ctx.Path(utils.OutputFlag.Name); outputFileName != "" {
+		log.Noticef("Write comparison report %v", outputFileName)
+		if err := report.WriteJSON(outputFileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
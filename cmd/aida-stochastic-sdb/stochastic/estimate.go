@@ -30,12 +30,22 @@ var StochasticEstimateCommand = cli.Command{
 	Action:    stochasticEstimateAction,
 	Name:      "estimate",
 	Usage:     "estimates parameters of access distributions and produces a simulation file",
-	ArgsUsage: "<event-file>",
+	ArgsUsage: "<event-file> [<event-file>...]",
+	Flags: []cli.Flag{
+		&utils.OutputFlag,
+		&utils.WeightsFlag,
+		&utils.PhaseLengthFlag,
+		&utils.PhaseThresholdFlag,
+	},
 	Description: `
-The stochastic estimator command requires one argument:
+The stochastic estimator command requires at least one argument:
 <events.json>
 
-<events.json> is the event file produced by the stochastic recorder.`,
+<events.json> is the event file produced by the stochastic recorder.
+Several event files are merged before the estimation; their weights
+are given by --weights. With --phase-length or --phase-threshold,
+the segments of the recording are grouped into phases, each with
+its own stochastic matrix and duration in blocks.`,
 }
 
 // stochasticEstimateAction implements estimator command for computing statistical parameters.
@@ -43,14 +53,12 @@ func stochasticEstimateAction(ctx *cli.Context) error {
 	log := logger.NewLogger("INFO", "StochasticEstimate")
 
 	// parse arguments
-	if ctx.Args().Len() != 1 {
+	if ctx.Args().Len() < 1 {
 		return fmt.Errorf("missing events file")
 	}
-	inputFileName := ctx.Args().Get(0)
 
-	// read event file in JSON format
-	log.Infof("Read events file %v", inputFileName)
-	eventRegistryJSON, err := stochastic.ReadEvents(inputFileName)
+	// read event files in JSON format and merge them
+	eventRegistryJSON, err := readAndMergeEvents(ctx, log)
 	if err != nil {
 		return err
 	}
@@ -59,6 +67,18 @@ func stochasticEstimateAction(ctx *cli.Context) error {
 	log.Info("Estimate parameters")
 	estimationModel := stochastic.NewEstimationModelJSON(eventRegistryJSON)
 
+	// estimate phases of the workload
+	phaseLength := ctx.Int(utils.PhaseLengthFlag.Name)
+	phaseThreshold := ctx.Float64(utils.PhaseThresholdFlag.Name)
+	if phaseLength != 0 || phaseThreshold != 0 {
+		log.Info("Estimate phases")
+		estimationModel.Phases, err = stochastic.EstimatePhases(eventRegistryJSON, phaseLength, phaseThreshold)
+		if err != nil {
+			return err
+		}
+		log.Noticef("Found %v phases", len(estimationModel.Phases))
+	}
+
 	// write simulation file
 	outputFileName := ctx.String(utils.OutputFlag.Name)
 	if outputFileName == "" {
@@ -0,0 +1,165 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// StochasticFuzzCommand data structure for the fuzz app.
+var StochasticFuzzCommand = cli.Command{
+	Action:    stochasticFuzzAction,
+	Name:      "fuzz",
+	Usage:     "runs differential fuzzing of a StateDB against a shadow StateDB and shrinks failures",
+	ArgsUsage: "<simulation-length> <simulation-file>",
+	Flags: []cli.Flag{
+		&utils.BalanceRangeFlag,
+		&utils.CarmenSchemaFlag,
+		&utils.FuzzArtifactFlag,
+		&utils.FuzzSeedsFlag,
+		&utils.NonceRangeFlag,
+		&utils.OutputFlag,
+		&utils.RandomSeedFlag,
+		&utils.StateDbImplementationFlag,
+		&utils.StateDbVariantFlag,
+		&utils.DbTmpFlag,
+		&utils.ShadowDbImplementationFlag,
+		&utils.ShadowDbVariantFlag,
+		&logger.LogLevelFlag,
+	},
+	Description: `
+The stochastic fuzz command requires two arguments:
+<simulation-length> <simulation.json>
+
+<simulation-length> determines the number of blocks per seed
+<simulation.json> contains the simulation parameters produced by the stochastic estimator.
+
+The fuzzer replays the simulation for consecutive seeds (starting with
+--random-seed) against the primary and the shadow StateDB until a StateDB
+panics or the StateDBs diverge. The operations of the failing run are
+shrunk to fewer blocks, transactions and operations that still fail, and
+written with a report to the --output directory (default: ./fuzz).
+With --artifact, a fuzzing artifact is replayed instead.`,
+}
+
+// stochasticFuzzAction implements the fuzz command.
+func stochasticFuzzAction(ctx *cli.Context) error {
+	artifactFile := ctx.Path(utils.FuzzArtifactFlag.Name)
+	argMode := utils.LastBlockArg
+	if artifactFile != "" {
+		argMode = utils.NoArgs
+	} else if ctx.Args().Len() != 2 {
+		return fmt.Errorf("missing simulation file and simulation length as parameter")
+	}
+
+	// process configuration
+	cfg, err := utils.NewConfig(ctx, argMode)
+	if err != nil {
+		return err
+	}
+	if cfg.ShadowImpl == "" {
+		return fmt.Errorf("fuzzing requires a shadow StateDB (--%v)", utils.ShadowDbImplementationFlag.Name)
+	}
+	cfg.ShadowDb = true
+	log := logger.NewLogger(cfg.LogLevel, "Stochastic Fuzz")
+
+	// create fresh StateDBs for each run
+	makeDb := func() (state.StateDB, func(), error) {
+		db, dir, err := utils.PrepareStateDB(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, func() {
+			defer os.RemoveAll(dir)
+			if err := db.Close(); err != nil {
+				log.Warningf("Failed to close StateDB; %v", err)
+			}
+		}, nil
+	}
+
+	// replay an artifact
+	if artifactFile != "" {
+		artifact, err := stochastic.ReadFuzzArtifact(artifactFile)
+		if err != nil {
+			return err
+		}
+		db, release, err := makeDb()
+		if err != nil {
+			return err
+		}
+		defer release()
+		log.Noticef("Replay %v operations of seed %v", len(artifact.Operations), artifact.Seed)
+		if failure := stochastic.ExecuteFuzzOperations(db, artifact.Operations); failure != nil {
+			return fmt.Errorf("operation %v failed: %v", failure.Index, failure.Message)
+		}
+		log.Notice("No failure")
+		return nil
+	}
+
+	simLength, err := strconv.Atoi(ctx.Args().Get(0))
+	if err != nil {
+		return fmt.Errorf("simulation length is not an integer; %v", err)
+	}
+	simulation, err := stochastic.ReadSimulation(ctx.Args().Get(1))
+	if err != nil {
+		return fmt.Errorf("failed reading simulation; %v", err)
+	}
+
+	// run seeds until a failure is found
+	log.Noticef("Fuzzing %v against %v from seed %v", cfg.DbImpl, cfg.ShadowImpl, cfg.RandomSeed)
+	artifact, err := stochastic.Fuzz(simulation, simLength, ctx.Int(utils.FuzzSeedsFlag.Name), cfg, makeDb, log)
+	if err != nil {
+		return err
+	}
+	if artifact == nil {
+		log.Notice("No failure found")
+		return nil
+	}
+
+	// write artifact and report
+	outputDir := ctx.Path(utils.OutputFlag.Name)
+	if outputDir == "" {
+		outputDir = "./fuzz"
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("cannot create output directory; %v", err)
+	}
+	artifactFile = filepath.Join(outputDir, "failure.json")
+	if err := artifact.WriteJSON(artifactFile); err != nil {
+		return err
+	}
+	reportFile := filepath.Join(outputDir, "report.txt")
+	f, err := os.Create(reportFile)
+	if err != nil {
+		return fmt.Errorf("cannot create report; %v", err)
+	}
+	defer f.Close()
+	if err := artifact.WriteReport(f); err != nil {
+		return fmt.Errorf("cannot write report; %v", err)
+	}
+	log.Noticef("Wrote artifact %v and report %v", artifactFile, reportFile)
+	return fmt.Errorf("seed %v failed: %v", artifact.Seed, artifact.Failure.Message)
+}
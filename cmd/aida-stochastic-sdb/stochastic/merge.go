@@ -0,0 +1,116 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
+)
+
+// StochasticMergeCommand data structure for the merge app.
+var StochasticMergeCommand = cli.Command{
+	Action:    stochasticMergeAction,
+	Name:      "merge",
+	Usage:     "merges event files of several recordings into a single event file",
+	ArgsUsage: "<event-file> <event-file> [<event-file>...]",
+	Flags: []cli.Flag{
+		&utils.OutputFlag,
+		&utils.WeightsFlag,
+	},
+	Description: `
+The stochastic merge command requires at least two arguments:
+<events-1.json> <events-2.json> ...
+
+The event files produced by the stochastic recorder are merged into
+a single event file (default: ./events.json). The frequencies of each
+recording are scaled by its weight given by --weights, e.g. --weights 1,3
+counts the second recording three times.`,
+}
+
+// stochasticMergeAction implements the merge command.
+func stochasticMergeAction(ctx *cli.Context) error {
+	log := logger.NewLogger("INFO", "StochasticMerge")
+
+	if ctx.Args().Len() < 2 {
+		return fmt.Errorf("merge requires at least two event files")
+	}
+
+	merged, err := readAndMergeEvents(ctx, log)
+	if err != nil {
+		return err
+	}
+
+	outputFileName := ctx.Path(utils.OutputFlag.Name)
+	if outputFileName == "" {
+		outputFileName = "./events.json"
+	}
+	log.Noticef("Write events file %v", outputFileName)
+	return merged.WriteJSON(outputFileName)
+}
+
+// readAndMergeEvents reads the event files given as arguments and merges them
+// with the weights of the weights flag. A single event file is returned as is.
+func readAndMergeEvents(ctx *cli.Context, log logger.Logger) (*stochastic.EventRegistryJSON, error) {
+	weights, err := parseWeights(ctx.String(utils.WeightsFlag.Name), ctx.Args().Len())
+	if err != nil {
+		return nil, err
+	}
+	events := make([]*stochastic.EventRegistryJSON, ctx.Args().Len())
+	for i, inputFileName := range ctx.Args().Slice() {
+		log.Infof("Read events file %v", inputFileName)
+		if events[i], err = stochastic.ReadEvents(inputFileName); err != nil {
+			return nil, err
+		}
+	}
+	if len(events) == 1 {
+		return events[0], nil
+	}
+	log.Infof("Merge %v event files with weights %v", len(events), weights)
+	return stochastic.MergeEvents(events, weights)
+}
+
+// parseWeights parses comma-separated weights for n files.
+func parseWeights(s string, n int) ([]float64, error) {
+	weights := make([]float64, n)
+	if s == "" {
+		for i := range weights {
+			weights[i] = 1.0
+		}
+		return weights, nil
+	}
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %v weights but got %v", n, len(parts))
+	}
+	for i, part := range parts {
+		w, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight %v; %v", part, err)
+		}
+		if w <= 0 {
+			return nil, fmt.Errorf("weight %v must be positive", part)
+		}
+		weights[i] = w
+	}
+	return weights, nil
+}
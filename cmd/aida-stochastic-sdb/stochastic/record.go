@@ -46,13 +46,20 @@ var StochasticRecordCommand = cli.Command{
 		&utils.ChainIDFlag,
 		&utils.AidaDbFlag,
 		&utils.CacheFlag,
+		&utils.MarkovOrderFlag,
+		&utils.SegmentLengthFlag,
 	},
 	Description: `
 The stochastic record command requires two arguments:
 <blockNumFirst> <blockNumLast>
 
 <blockNumFirst> and <blockNumLast> are the first and
-last block for recording events.`,
+last block for recording events. With --markov-order k,
+transitions are additionally counted for the last k
+operations so that a Markov chain of order k can be
+estimated. With --segment-length n, transitions are
+also counted per segment of n blocks so that the estimator
+can detect phases of the workload.`,
 }
 
 // stochasticRecordAction implements recording of events.
@@ -92,7 +99,14 @@ func stochasticRecordAction(ctx *cli.Context) error {
 	lastSec = time.Since(start).Seconds()
 
 	// create a new event registry
-	eventRegistry := stochastic.NewEventRegistry()
+	if cfg.MarkovOrder < 1 {
+		return fmt.Errorf("invalid order of Markov chain %v", cfg.MarkovOrder)
+	}
+	eventRegistry := stochastic.NewEventRegistryWithOrder(cfg.MarkovOrder)
+	if cfg.SegmentLength < 0 {
+		return fmt.Errorf("invalid segment length %v", cfg.SegmentLength)
+	}
+	eventRegistry.EnableSegments(cfg.SegmentLength)
 
 	curSyncPeriod := cfg.First / cfg.SyncPeriodLength
 	eventRegistry.RegisterOp(stochastic.BeginSyncPeriodID)
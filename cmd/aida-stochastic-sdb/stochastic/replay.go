@@ -20,13 +20,14 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Fantom-foundation/Aida/logger"
-	"github.com/Fantom-foundation/Aida/state/proxy"
 	"github.com/Fantom-foundation/Aida/stochastic"
 	"github.com/Fantom-foundation/Aida/tracer/context"
 	"github.com/Fantom-foundation/Aida/utils"
+	substate "github.com/Fantom-foundation/Substate"
 	"github.com/urfave/cli/v2"
 )
 
@@ -37,6 +38,11 @@ var StochasticReplayCommand = cli.Command{
 	Usage:     "Simulates StateDB operations using a random generator with realistic distributions",
 	ArgsUsage: "<simulation-length> <simulation-file>",
 	Flags: []cli.Flag{
+		&utils.ArchiveModeFlag,
+		&utils.ArchiveMaxQueryAgeFlag,
+		&utils.ArchiveQueryAgeLambdaFlag,
+		&utils.ArchiveQueryRateFlag,
+		&utils.ArchiveVariantFlag,
 		&utils.BalanceRangeFlag,
 		&utils.CarmenSchemaFlag,
 		&utils.ContinueOnFailureFlag,
@@ -52,8 +58,10 @@ var StochasticReplayCommand = cli.Command{
 		&utils.TraceFileFlag,
 		&utils.TraceDebugFlag,
 		&utils.TraceFlag,
+		&utils.TraceValidationFlag,
 		&utils.ShadowDbImplementationFlag,
 		&utils.ShadowDbVariantFlag,
+		&substate.WorkersFlag,
 		&logger.LogLevelFlag,
 	},
 	Description: `
@@ -61,7 +69,13 @@ The stochastic replay command requires two argument:
 <simulation-length> <simulation.json> 
 
 <simulation-length> determines the number of blocks
-<simulation.json> contains the simulation parameters produced by the stochastic estimator.`,
+<simulation.json> contains the simulation parameters produced by the stochastic estimator.
+
+With --archive and --archive-query-rate, --workers goroutines concurrently query
+historic blocks of the archive with read operations sampled from the model.
+
+With --trace, the generated operations are written as storage trace to
+--trace-file, which can be replayed with aida-sdb replay 0 <simulation-length>.`,
 }
 
 // stochasticReplayAction implements the replay command. The user provides simulation file and
@@ -108,14 +122,20 @@ func stochasticReplayAction(ctx *cli.Context) error {
 	}
 	defer os.RemoveAll(stateDbDir)
 
-	// Enable tracing if debug flag is set
+	// record the generated operations in a storage trace if tracing is enabled
 	if cfg.Trace {
 		rCtx, err := context.NewRecord(cfg.TraceFile, uint64(0))
 		if err != nil {
 			return err
 		}
 		defer rCtx.Close()
-		db = proxy.NewRecorderProxy(db, rCtx)
+		rCtx.Debug = cfg.Debug
+		rCtx.Validate = cfg.TraceValidation
+		db = stochastic.NewTracedStateDB(db, rCtx)
+		log.Noticef("Record storage trace %v", cfg.TraceFile)
+		if ops := stochastic.UntracedOperations(simulation); len(ops) > 0 {
+			log.Warningf("Operations %v of the simulation are not recorded in the storage trace", strings.Join(ops, ", "))
+		}
 	}
 
 	// run simulation.
@@ -34,12 +34,19 @@ var StochasticVisualizeCommand = cli.Command{
 	ArgsUsage: "<event-file>",
 	Flags: []cli.Flag{
 		&utils.PortFlag,
+		&utils.HeldOutFlag,
+		&utils.OutputFlag,
 	},
 	Description: `
 The stochastic visualize command requires one argument:
 <events.json>
 
-<events.json> is the event file produced by the stochastic recorder.`,
+<events.json> is the event file produced by the stochastic recorder.
+With --held-out, the Markov chains of order 1 up to the recorded order
+are evaluated on a held-out events file recorded with --markov-order.
+With --output, all views are exported as static HTML instead of starting
+a web server: into a single report if the output ends with .html, and into
+a directory with an index page otherwise.`,
 }
 
 // stochasticVisualizeAction implements the visualize command for computing statistical parameters.
@@ -59,6 +66,22 @@ func stochasticVisualizeAction(ctx *cli.Context) error {
 		return err
 	}
 
+	// read held-out events file
+	var heldOut *stochastic.EventRegistryJSON
+	if heldOutFileName := ctx.Path(utils.HeldOutFlag.Name); heldOutFileName != "" {
+		log.Infof("Read held-out event file %v", heldOutFileName)
+		heldOut, err = stochastic.ReadEvents(heldOutFileName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// export static HTML
+	if output := ctx.Path(utils.OutputFlag.Name); output != "" {
+		log.Noticef("Export views to %v", output)
+		return visualizer.ExportStatic(eventRegistry, heldOut, output)
+	}
+
 	// fire-up web-server and visualize events
 	port := ctx.String(utils.PortFlag.Name)
 	if port == "" {
@@ -66,7 +89,5 @@ func stochasticVisualizeAction(ctx *cli.Context) error {
 	}
 	log.Noticef("Open web browser with http://localhost:" + port)
 	log.Notice("Cancel visualize with ^C")
-	visualizer.FireUpWeb(eventRegistry, port)
-
-	return nil
+	return visualizer.FireUpWeb(eventRegistry, heldOut, port)
 }
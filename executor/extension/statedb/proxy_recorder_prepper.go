@@ -54,6 +54,7 @@ func (p *proxyRecorderPrepper[T]) PreRun(state executor.State[T], _ *executor.Co
 	}
 
 	p.rCtx.Debug = p.cfg.Debug
+	p.rCtx.Validate = p.cfg.TraceValidation
 
 	// write the first sync period
 	p.syncPeriod = uint64(state.Block) / p.cfg.SyncPeriodLength
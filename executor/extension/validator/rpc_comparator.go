@@ -17,9 +17,11 @@
 package validator
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -30,6 +32,7 @@ import (
 	"github.com/Fantom-foundation/Aida/txcontext"
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 )
 
@@ -45,6 +48,10 @@ const (
 	cannotUnmarshalResult
 	cannotSendRpcRequest
 	internalError
+	noMatchingLogs
+	noMatchingProof
+	noMatchingTrace
+	proofNotCompared
 )
 
 const (
@@ -101,11 +108,41 @@ func makeRPCComparator(cfg *utils.Config, log logger.Logger) *rpcComparator {
 
 type rpcComparator struct {
 	extension.NilExtension[*rpc.RequestAndResults]
-	cfg                     *utils.Config
-	log                     logger.Logger
-	numberOfRetriedRequests int
-	totalNumberOfRequests   int
-	numberOfErrors          int
+	cfg                      *utils.Config
+	log                      logger.Logger
+	numberOfRetriedRequests  int
+	totalNumberOfRequests    int
+	numberOfErrors           int
+	numberOfUncomparedProofs int // getProof requests whose proofs the StateDB could not create
+	report                   *rpcMismatchReport
+}
+
+// PreRun opens the report of mismatches.
+func (c *rpcComparator) PreRun(executor.State[*rpc.RequestAndResults], *executor.Context) error {
+	var err error
+	c.report, err = newRpcMismatchReport(c.cfg.RpcReport, c.cfg.MaxReportSamples)
+	return err
+}
+
+// PostRun summarizes the mismatches and closes their report.
+func (c *rpcComparator) PostRun(executor.State[*rpc.RequestAndResults], *executor.Context, error) error {
+	if n := c.numberOfUncomparedProofs; n > 0 {
+		c.log.Warningf("proofs of %v getProof requests were not compared; the StateDB cannot create proofs of archive states", n)
+	}
+	if c.report == nil {
+		return nil
+	}
+	return c.report.close(c.log)
+}
+
+// reportMismatch adds a comparator error to the report of mismatches.
+func (c *rpcComparator) reportMismatch(compareErr *comparatorError, state executor.State[*rpc.RequestAndResults], ctx *executor.Context) {
+	if c.report == nil {
+		return
+	}
+	if err := c.report.add(compareErr, state.Data, state.Block, ctx.ExecutionResult); err != nil {
+		c.log.Warning(err)
+	}
 }
 
 // PostTransaction compares result with recording. If ContinueOnFailure
@@ -118,7 +155,13 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 		return nil
 	}
 
-	compareErr := compare(ctx.ExecutionResult, state)
+	compareErr := compare(ctx.ExecutionResult, state, c.cfg.EstimateGasTolerance)
+	// account data of the proof match, but the proofs themselves could not be validated
+	if compareErr != nil && compareErr.typ == proofNotCompared {
+		c.log.Debug(compareErr)
+		c.numberOfUncomparedProofs++
+		return nil
+	}
 	if compareErr != nil {
 		// request method base 'call' cannot be resent, because we need timestamp of the block that executed
 		// this request. As of right now there we cannot get the timestamp, hence we skip these requests
@@ -128,11 +171,13 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 			if state.Data.Error != nil {
 				return nil
 			} else {
+				c.reportMismatch(compareErr, state, ctx)
 				return compareErr
 			}
 		}
-		// lot errors are recorded wrongly, for this case we resend the request and compare it again
-		if !state.Data.IsRecovered {
+		// lot errors are recorded wrongly, for this case we resend the request and compare it again;
+		// logs and proofs are objects which cannot be recovered this way
+		if !state.Data.IsRecovered && isResendable(state.Data.Query.MethodBase) {
 			c.log.Debugf("retrying %v request", state.Data.Query.Method)
 			c.numberOfRetriedRequests++
 			c.log.Debugf("current ration retried against total %v/%v", c.numberOfRetriedRequests, c.totalNumberOfRequests)
@@ -140,7 +185,7 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 			if err := c.resendRequest(ctx.ExecutionResult, state); err != nil {
 				return err
 			}
-			compareErr = compare(ctx.ExecutionResult, state)
+			compareErr = compare(ctx.ExecutionResult, state, c.cfg.EstimateGasTolerance)
 			if compareErr == nil {
 				return nil
 			}
@@ -149,6 +194,7 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 		if compareErr.typ == cannotUnmarshalResult {
 			return nil
 		}
+		c.reportMismatch(compareErr, state, ctx)
 
 		if !c.cfg.ContinueOnFailure {
 			return compareErr
@@ -170,7 +216,9 @@ func (c *rpcComparator) PostTransaction(state executor.State[*rpc.RequestAndResu
 	return nil
 }
 
-func compare(result txcontext.Result, state executor.State[*rpc.RequestAndResults]) *comparatorError {
+// compare compares the result of StateDB with the recorded result of the request. Gas estimations
+// match if they differ at most by the relative tolerance.
+func compare(result txcontext.Result, state executor.State[*rpc.RequestAndResults], tolerance float64) *comparatorError {
 	switch state.Data.Query.MethodBase {
 	case "getBalance":
 		return compareBalance(result, state.Data, state.Block)
@@ -179,17 +227,31 @@ func compare(result txcontext.Result, state executor.State[*rpc.RequestAndResult
 	case "call":
 		return compareCall(result, state.Data, state.Block)
 	case "estimateGas":
-		// estimateGas is currently not suitable for replay since the estimation  in geth is always calculated
-		// for current state that means recorded result and result returned by StateDB are not comparable
+		return compareEstimateGas(result, state.Data, state.Block, tolerance)
 	case "getCode":
 		return compareCode(result, state.Data, state.Block)
 	case "getStorageAt":
 		return compareStorageAt(result, state.Data, state.Block)
+	case "getLogs":
+		return compareLogs(result, state.Data, state.Block)
+	case "getProof":
+		return compareProof(result, state.Data, state.Block)
+	case "traceCall", "traceTransaction":
+		return compareTrace(result, state.Data, state.Block)
 	}
 
 	return nil
 }
 
+// isResendable returns true if a request of the given method can be resent for a specific block.
+func isResendable(method string) bool {
+	switch method {
+	case "getLogs", "getProof", "traceCall", "traceTransaction":
+		return false
+	}
+	return true
+}
+
 func (c *rpcComparator) resendRequest(result txcontext.Result, state executor.State[*rpc.RequestAndResults]) *comparatorError {
 	var payload []byte
 
@@ -459,10 +521,10 @@ func compareEVMStateDBError(result txcontext.Result, err error, data *rpc.Reques
 }
 
 // compareEstimateGas compares recorded data for estimateGas method with result from StateDB
-func compareEstimateGas(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
+func compareEstimateGas(result txcontext.Result, data *rpc.RequestAndResults, block int, tolerance float64) *comparatorError {
 	res, err := result.GetRawResult()
 	if res != nil {
-		return compareEstimateGasStateDBResult(result, res, data, block)
+		return compareEstimateGasStateDBResult(result, res, data, block, tolerance)
 	}
 
 	if err != nil {
@@ -473,7 +535,8 @@ func compareEstimateGas(result txcontext.Result, data *rpc.RequestAndResults, bl
 }
 
 // compareEstimateGasStateDBResult compares estimateGas data recorded on API server with data returned by StateDB
-func compareEstimateGasStateDBResult(result txcontext.Result, res []byte, data *rpc.RequestAndResults, block int) *comparatorError {
+// Results match if they differ at most by the relative tolerance of the recorded result.
+func compareEstimateGasStateDBResult(result txcontext.Result, res []byte, data *rpc.RequestAndResults, block int, tolerance float64) *comparatorError {
 	stateDBGas := littleendian.BytesToUint64(res)
 
 	// did we receive an error
@@ -507,13 +570,22 @@ func compareEstimateGasStateDBResult(result txcontext.Result, res []byte, data *
 		return newComparatorError(result, recordedResult, string(data.Response.Result), data, block, cannotUnmarshalResult)
 	}
 
-	if stateDBGas != recordedResult {
+	if !withinTolerance(stateDBGas, recordedResult, tolerance) {
 		return newComparatorError(result, recordedResult, recordedString, data, block, noMatchingResult)
 	}
 
 	return nil
 }
 
+// withinTolerance returns true if the value differs from the expected value at most by the relative tolerance.
+func withinTolerance(value, expected uint64, tolerance float64) bool {
+	diff := value - expected
+	if value < expected {
+		diff = expected - value
+	}
+	return float64(diff) <= tolerance*float64(expected)
+}
+
 // compareCode compares getCode data recorded on API server with data returned by StateDB
 func compareCode(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
 	res, _ := result.GetRawResult()
@@ -571,6 +643,241 @@ func compareStorageAt(result txcontext.Result, data *rpc.RequestAndResults, bloc
 	return nil
 }
 
+// compareLogs compares getLogs data recorded on API server with logs returned by the log index
+func compareLogs(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
+	res, err := result.GetRawResult()
+
+	// recorded errors are caused by limits of the API server (e.g. too many logs), not by the state
+	if data.Error != nil {
+		return nil
+	}
+	if err != nil {
+		return newComparatorError(result, err, string(data.Response.Result), data, block, expectedResultGotError)
+	}
+
+	var dbLogs, recordedLogs []rpc.LogJSON
+	if err = json.Unmarshal(res, &dbLogs); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+	if err = json.Unmarshal(data.Response.Result, &recordedLogs); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+
+	if len(dbLogs) != len(recordedLogs) {
+		return newNoMatchingLogsErr("number of logs", len(dbLogs), len(recordedLogs), data, block)
+	}
+	for i := range dbLogs {
+		db, recorded := dbLogs[i], recordedLogs[i]
+		if db.Address != recorded.Address ||
+			db.BlockNumber != recorded.BlockNumber ||
+			!bytes.Equal(db.Data, recorded.Data) ||
+			!slices.Equal(db.Topics, recorded.Topics) {
+			return newNoMatchingLogsErr(fmt.Sprintf("log %v", i), db, recorded, data, block)
+		}
+	}
+
+	return nil
+}
+
+// compareProof compares getProof data recorded on API server with data returned by StateDB.
+// Proofs and the storage hash are only compared if the StateDB produced them.
+func compareProof(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
+	res, err := result.GetRawResult()
+	if err != nil {
+		if data.Error != nil {
+			return nil
+		}
+		return newComparatorError(result, err, string(data.Response.Result), data, block, expectedResultGotError)
+	}
+
+	if data.Error != nil {
+		if data.Error.Error.Code == internalErrorCode {
+			return newComparatorError(result, string(res), data.Error.Error, data, block, internalError)
+		}
+		return newComparatorError(result, string(res), data.Error.Error, data, block, expectedErrorGotResult)
+	}
+
+	var dbProof, recordedProof rpc.ProofJSON
+	if err = json.Unmarshal(res, &dbProof); err != nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+	if err = json.Unmarshal(data.Response.Result, &recordedProof); err != nil || recordedProof.Balance == nil {
+		return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+	}
+
+	if dbProof.Balance.ToInt().Cmp(recordedProof.Balance.ToInt()) != 0 {
+		return newNoMatchingProofErr("balance", dbProof.Balance, recordedProof.Balance, data, block)
+	}
+	if dbProof.Nonce != recordedProof.Nonce {
+		return newNoMatchingProofErr("nonce", dbProof.Nonce, recordedProof.Nonce, data, block)
+	}
+	if dbProof.CodeHash != recordedProof.CodeHash {
+		return newNoMatchingProofErr("codeHash", dbProof.CodeHash, recordedProof.CodeHash, data, block)
+	}
+
+	if len(dbProof.StorageProof) != len(recordedProof.StorageProof) {
+		return newNoMatchingProofErr("number of storage proofs", len(dbProof.StorageProof), len(recordedProof.StorageProof), data, block)
+	}
+	for i := range dbProof.StorageProof {
+		db, recorded := dbProof.StorageProof[i], recordedProof.StorageProof[i]
+		if recorded.Value == nil || db.Value.ToInt().Cmp(recorded.Value.ToInt()) != 0 {
+			return newNoMatchingProofErr(fmt.Sprintf("value of storage key %v", db.Key), db.Value, recorded.Value, data, block)
+		}
+	}
+
+	// archive states cannot create proofs, hence the recorded ones are not validated
+	if recordedProof.AccountProof != nil || recordedProof.StorageHash != nil {
+		return newProofNotComparedErr(data, block)
+	}
+
+	return nil
+}
+
+// compareTrace compares debug traces recorded on API server with traces created over StateDB.
+// Call trees of the callTracer and accounts of the prestateTracer are compared structurally.
+func compareTrace(result txcontext.Result, data *rpc.RequestAndResults, block int) *comparatorError {
+	res, err := result.GetRawResult()
+	if err != nil {
+		if data.Error != nil {
+			return nil
+		}
+		return newComparatorError(result, err, string(data.Response.Result), data, block, expectedResultGotError)
+	}
+
+	if data.Error != nil {
+		if data.Error.Error.Code == internalErrorCode {
+			return newComparatorError(result, string(res), data.Error.Error, data, block, internalError)
+		}
+		return newComparatorError(result, string(res), data.Error.Error, data, block, expectedErrorGotResult)
+	}
+
+	switch rpc.TracerOf(data.Query) {
+	case rpc.CallTracer:
+		var dbCall, recordedCall rpc.CallFrame
+		if err = json.Unmarshal(res, &dbCall); err != nil {
+			return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+		}
+		if err = json.Unmarshal(data.Response.Result, &recordedCall); err != nil {
+			return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+		}
+		if path, db, recorded, ok := compareCallFrames(&dbCall, &recordedCall, "call"); !ok {
+			return newNoMatchingTraceErr(path, db, recorded, data, block)
+		}
+	case rpc.PrestateTracer:
+		var dbAccounts, recordedAccounts map[common.Address]rpc.PrestateAccount
+		if err = json.Unmarshal(res, &dbAccounts); err != nil {
+			return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+		}
+		if err = json.Unmarshal(data.Response.Result, &recordedAccounts); err != nil {
+			return newComparatorError(result, string(res), string(data.Response.Result), data, block, cannotUnmarshalResult)
+		}
+		if path, db, recorded, ok := comparePrestate(dbAccounts, recordedAccounts); !ok {
+			return newNoMatchingTraceErr(path, db, recorded, data, block)
+		}
+	}
+
+	return nil
+}
+
+// compareCallFrames compares call trees ignoring the gas given to calls, which depends on the gas cap
+// of the API server, and error messages, which depend on the client. The path of the first mismatch is
+// returned together with the mismatching values.
+func compareCallFrames(db, recorded *rpc.CallFrame, path string) (string, any, any, bool) {
+	if !strings.EqualFold(db.Type, recorded.Type) {
+		return path + ".type", db.Type, recorded.Type, false
+	}
+	if db.From != recorded.From {
+		return path + ".from", db.From, recorded.From, false
+	}
+	if traceAddress(db.To) != traceAddress(recorded.To) {
+		return path + ".to", traceAddress(db.To), traceAddress(recorded.To), false
+	}
+	if traceValue(db.Value).Cmp(traceValue(recorded.Value)) != 0 {
+		return path + ".value", traceValue(db.Value), traceValue(recorded.Value), false
+	}
+	if !bytes.Equal(db.Input, recorded.Input) {
+		return path + ".input", db.Input, recorded.Input, false
+	}
+	if db.GasUsed != recorded.GasUsed {
+		return path + ".gasUsed", db.GasUsed, recorded.GasUsed, false
+	}
+	if (db.Error == "") != (recorded.Error == "") {
+		return path + ".error", db.Error, recorded.Error, false
+	}
+	// failed calls report the output only if they were reverted
+	if db.Error == "" && !bytes.Equal(db.Output, recorded.Output) {
+		return path + ".output", db.Output, recorded.Output, false
+	}
+	if len(db.Calls) != len(recorded.Calls) {
+		return path + ".calls", len(db.Calls), len(recorded.Calls), false
+	}
+	for i := range db.Calls {
+		if p, d, r, ok := compareCallFrames(&db.Calls[i], &recorded.Calls[i], fmt.Sprintf("%v.calls[%v]", path, i)); !ok {
+			return p, d, r, false
+		}
+	}
+	return "", nil, nil, true
+}
+
+// comparePrestate compares accounts of prestate traces. Missing fields and slots are empty. Accounts
+// of the zero address (the coinbase) are reported by some clients only, hence they are not required.
+func comparePrestate(db, recorded map[common.Address]rpc.PrestateAccount) (string, any, any, bool) {
+	addresses := make(map[common.Address]struct{}, len(recorded))
+	for a := range db {
+		addresses[a] = struct{}{}
+	}
+	for a := range recorded {
+		addresses[a] = struct{}{}
+	}
+
+	for a := range addresses {
+		dbAcc, inDb := db[a]
+		recordedAcc, inRecorded := recorded[a]
+		if inDb != inRecorded {
+			if a == (common.Address{}) {
+				continue
+			}
+			return a.Hex(), inDb, inRecorded, false
+		}
+		if traceValue(dbAcc.Balance).Cmp(traceValue(recordedAcc.Balance)) != 0 {
+			return a.Hex() + ".balance", traceValue(dbAcc.Balance), traceValue(recordedAcc.Balance), false
+		}
+		if dbAcc.Nonce != recordedAcc.Nonce {
+			return a.Hex() + ".nonce", dbAcc.Nonce, recordedAcc.Nonce, false
+		}
+		if !bytes.Equal(dbAcc.Code, recordedAcc.Code) {
+			return a.Hex() + ".code", dbAcc.Code, recordedAcc.Code, false
+		}
+		for k, v := range dbAcc.Storage {
+			if recordedAcc.Storage[k] != v {
+				return fmt.Sprintf("%v.storage[%v]", a.Hex(), k.Hex()), v, recordedAcc.Storage[k], false
+			}
+		}
+		for k, v := range recordedAcc.Storage {
+			if dbAcc.Storage[k] != v {
+				return fmt.Sprintf("%v.storage[%v]", a.Hex(), k.Hex()), dbAcc.Storage[k], v, false
+			}
+		}
+	}
+	return "", nil, nil, true
+}
+
+// traceAddress returns the address of a call; calls without address have the zero address.
+func traceAddress(a *common.Address) common.Address {
+	if a == nil {
+		return common.Address{}
+	}
+	return *a
+}
+
+// traceValue returns the value of a call or balance; missing values are zero.
+func traceValue(v *hexutil.Big) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v.ToInt()
+}
+
 // newComparatorError returns new comparatorError with given StateDB and recorded data based on the typ.
 func newComparatorError(result txcontext.Result, stateDB, expected any, data *rpc.RequestAndResults, block int, typ comparatorErrorType) *comparatorError {
 	switch typ {
@@ -645,6 +952,63 @@ func newNoMatchingResultErr(stateDBData, expectedData any, data *rpc.RequestAndR
 	}
 }
 
+// newNoMatchingLogsErr returns new comparatorError
+// It is returned when logs of the log index do not match with recorded logs
+func newNoMatchingLogsErr(what string, stateDBData, expectedData any, data *rpc.RequestAndResults, block int) *comparatorError {
+	return &comparatorError{
+		error: fmt.Errorf("logs do not match"+
+			"\nMethod: %v"+
+			"\nBlockID: 0x%v"+
+			"\nMismatch: %v"+
+			"\n\tSubstate: %v"+
+			"\n\tRecorded: %v"+
+			"\n\n\tParams: %v", data.Query.Method, strconv.FormatInt(int64(block), 16), what, stateDBData, expectedData, string(data.ParamsRaw)),
+		typ: noMatchingLogs,
+	}
+}
+
+// newProofNotComparedErr returns new comparatorError
+// It is returned when the recorded proof contains proofs which the StateDB cannot create
+func newProofNotComparedErr(data *rpc.RequestAndResults, block int) *comparatorError {
+	return &comparatorError{
+		error: fmt.Errorf("proofs were not compared; StateDB cannot create proofs"+
+			"\nMethod: %v"+
+			"\nBlockID: 0x%v"+
+			"\n\n\tParams: %v", data.Query.Method, strconv.FormatInt(int64(block), 16), string(data.ParamsRaw)),
+		typ: proofNotCompared,
+	}
+}
+
+// newNoMatchingProofErr returns new comparatorError
+// It is returned when a field of the StateDB proof does not match with the recorded proof
+func newNoMatchingProofErr(field string, stateDBData, expectedData any, data *rpc.RequestAndResults, block int) *comparatorError {
+	return &comparatorError{
+		error: fmt.Errorf("proofs do not match"+
+			"\nMethod: %v"+
+			"\nBlockID: 0x%v"+
+			"\nField: %v"+
+			"\n\tCarmen: %v"+
+			"\n\tRecorded: %v"+
+			"\n\n\tParams: %v", data.Query.Method, strconv.FormatInt(int64(block), 16), field, stateDBData, expectedData, string(data.ParamsRaw)),
+		typ: noMatchingProof,
+	}
+}
+
+// newNoMatchingTraceErr returns new comparatorError
+// It is returned when a trace created by StateDB does not match the recorded trace
+func newNoMatchingTraceErr(path string, stateDBData, expectedData any, data *rpc.RequestAndResults, block int) *comparatorError {
+	return &comparatorError{
+		error: fmt.Errorf("traces do not match"+
+			"\nMethod: %v"+
+			"\nBlockID: 0x%v"+
+			"\nMismatch: %v"+
+			"\n\tCarmen: %v"+
+			"\n\tRecorded: %v"+
+			"\n\n\tParams: %v", data.Query.Method, strconv.FormatInt(int64(block), 16), path, stateDBData, expectedData, string(data.ParamsRaw)),
+		typ: noMatchingTrace,
+	}
+}
+
 // newNoMatchingErrorsErr returns new comparatorError
 // It is returned when StateDB error does not match with recorded error
 func newNoMatchingErrorsErr(stateDBError, expectedError any, data *rpc.RequestAndResults, block int) *comparatorError {
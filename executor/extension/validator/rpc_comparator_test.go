@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -27,8 +29,12 @@ import (
 	"github.com/Fantom-foundation/Aida/executor/extension"
 	"github.com/Fantom-foundation/Aida/logger"
 	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/status-im/keycard-go/hexutils"
 )
 
@@ -334,7 +340,7 @@ func Test_compareEstimateGasOK(t *testing.T) {
 	}
 
 	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(1)), nil, 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err != nil {
 		t.Errorf("error must be nil; err: %v", err)
 	}
@@ -355,7 +361,7 @@ func Test_compareEstimateGasErrorNoMatchingResult(t *testing.T) {
 	}
 
 	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(0)), nil, 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err == nil {
 		t.Errorf("error must not be null")
 		return
@@ -380,7 +386,7 @@ func Test_compareEstimateGasErrorExpectedResultGotErr(t *testing.T) {
 	}
 
 	res := rpc.NewResult(nil, errors.New("error"), 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err == nil {
 		t.Errorf("error must be nil; err: %v", err)
 		return
@@ -406,7 +412,7 @@ func Test_compareEstimateGasErrorExpectedErrGotResult(t *testing.T) {
 		},
 	}
 	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(0)), nil, 10)
-	err := compareEstimateGas(res, data, 0)
+	err := compareEstimateGas(res, data, 0, 0)
 	if err == nil {
 		t.Errorf("error must not be null")
 		return
@@ -514,3 +520,369 @@ func Test_compareStorageAtErrorNoMatchingResult(t *testing.T) {
 	}
 
 }
+
+// Test_compareLogsOK tests compare func for getLogs method
+// It expects no error since logs are same
+func Test_compareLogsOK(t *testing.T) {
+	logs := []rpc.LogJSON{{Address: common.HexToAddress("0x1"), Topics: []common.Hash{{1}}, Data: []byte{1}, BlockNumber: 10}}
+	rec, _ := json.Marshal(logs)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getLogs",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(rec, nil, 0)
+	err := compareLogs(res, data, 10)
+	if err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+}
+
+// Test_compareLogsErrorNoMatchingLogs tests compare func for getLogs method
+// It expects an error of no matching logs since data of logs are different
+func Test_compareLogsErrorNoMatchingLogs(t *testing.T) {
+	logs := []rpc.LogJSON{{Address: common.HexToAddress("0x1"), Topics: []common.Hash{{1}}, Data: []byte{1}, BlockNumber: 10}}
+	rec, _ := json.Marshal(logs)
+	logs[0].Data = []byte{2}
+	db, _ := json.Marshal(logs)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getLogs",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(db, nil, 0)
+	err := compareLogs(res, data, 10)
+	if err == nil {
+		t.Errorf("error must not be nil; err: %v", err)
+		return
+	}
+
+	if err.typ != noMatchingLogs {
+		t.Errorf("error must be type 'noMatchingLogs'; err: %v", err)
+	}
+}
+
+// Test_compareProofOK tests compare func for getProof method
+// It expects no error since account data match and no proofs are recorded
+func Test_compareProofOK(t *testing.T) {
+	proof := rpc.ProofJSON{
+		Balance:      (*hexutil.Big)(big.NewInt(1)),
+		StorageProof: []rpc.StorageProofJSON{{Key: hexOne, Value: (*hexutil.Big)(big.NewInt(2))}},
+	}
+	rec, _ := json.Marshal(proof)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getProof",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(rec, nil, 0)
+	err := compareProof(res, data, 10)
+	if err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+}
+
+// Test_compareProofNotCompared tests compare func for getProof method
+// It expects an error of proofs not being compared since the StateDB has no proofs
+func Test_compareProofNotCompared(t *testing.T) {
+	data, res := makeProofWithoutStateDbProofs()
+	err := compareProof(res, data, 10)
+	if err == nil {
+		t.Errorf("error must not be nil; err: %v", err)
+		return
+	}
+
+	if err.typ != proofNotCompared {
+		t.Errorf("error must be type 'proofNotCompared'; err: %v", err)
+	}
+}
+
+// TestRPCComparator_PostTransactionCountsProofsNotCompared tests that proofs which could not be compared
+// neither fail the replay nor are reported as mismatches, but are counted.
+func TestRPCComparator_PostTransactionCountsProofsNotCompared(t *testing.T) {
+	cfg := &utils.Config{}
+	cfg.Validate = true
+	cfg.ContinueOnFailure = false
+
+	data, res := makeProofWithoutStateDbProofs()
+	data.Query.MethodBase = "getProof"
+	s := executor.State[*rpc.RequestAndResults]{
+		Data: data,
+	}
+
+	c := makeRPCComparator(cfg, logger.NewLogger("critical", "rpc-test"))
+	if err := c.PreRun(s, nil); err != nil {
+		t.Fatalf("cannot open report; %v", err)
+	}
+	if err := c.PostTransaction(s, &executor.Context{ExecutionResult: res}); err != nil {
+		t.Errorf("unexpected error in post transaction; %v", err)
+	}
+	if got := c.numberOfUncomparedProofs; got != 1 {
+		t.Errorf("unexpected number of uncompared proofs; got: %v, want: 1", got)
+	}
+	if groups := c.report.summary(); len(groups) != 0 {
+		t.Errorf("uncompared proofs must not be reported as mismatches; got: %v", groups)
+	}
+}
+
+// makeProofWithoutStateDbProofs returns a recorded getProof request with proofs
+// and the matching result of a StateDB which cannot create proofs.
+func makeProofWithoutStateDbProofs() (*rpc.RequestAndResults, txcontext.Result) {
+	root := common.Hash{1}
+	proof := rpc.ProofJSON{
+		Balance:      (*hexutil.Big)(big.NewInt(1)),
+		AccountProof: []string{"0xab"},
+		StorageHash:  &root,
+		StorageProof: []rpc.StorageProofJSON{{Key: hexOne, Value: (*hexutil.Big)(big.NewInt(2)), Proof: []string{"0xcd"}}},
+	}
+	rec, _ := json.Marshal(proof)
+	proof.AccountProof, proof.StorageHash, proof.StorageProof[0].Proof = nil, nil, nil
+	db, _ := json.Marshal(proof)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getProof",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+	return data, rpc.NewResult(db, nil, 0)
+}
+
+// Test_compareProofErrorNoMatchingProof tests compare func for getProof method
+// It expects an error of no matching proof since values of the storage slot are different
+func Test_compareProofErrorNoMatchingProof(t *testing.T) {
+	proof := rpc.ProofJSON{
+		Balance:      (*hexutil.Big)(big.NewInt(1)),
+		StorageProof: []rpc.StorageProofJSON{{Key: hexOne, Value: (*hexutil.Big)(big.NewInt(2))}},
+	}
+	rec, _ := json.Marshal(proof)
+	proof.StorageProof[0].Value = (*hexutil.Big)(big.NewInt(3))
+	db, _ := json.Marshal(proof)
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_getProof",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(db, nil, 0)
+	err := compareProof(res, data, 10)
+	if err == nil {
+		t.Errorf("error must not be nil; err: %v", err)
+		return
+	}
+
+	if err.typ != noMatchingProof {
+		t.Errorf("error must be type 'noMatchingProof'; err: %v", err)
+	}
+}
+
+// Test_compareEstimateGasWithinTolerance tests compare func for estimateGas method
+// It expects no error only if results differ at most by the tolerance
+func Test_compareEstimateGasWithinTolerance(t *testing.T) {
+	rec, _ := json.Marshal("0x64")
+
+	data := &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method: "eth_estimateGas",
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+
+	res := rpc.NewResult(littleendian.Uint64ToBytes(uint64(98)), nil, 10)
+	if err := compareEstimateGas(res, data, 0, 0.02); err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+
+	res = rpc.NewResult(littleendian.Uint64ToBytes(uint64(97)), nil, 10)
+	err := compareEstimateGas(res, data, 0, 0.02)
+	if err == nil {
+		t.Errorf("error must not be null")
+		return
+	}
+
+	if err.typ != noMatchingResult {
+		t.Errorf("error must be type 'noMatchingResult'; err: %v", err)
+	}
+}
+
+// makeTraceTestData returns a recorded debug_traceCall request of the given tracer with the given result.
+func makeTraceTestData(tracer string, rec []byte) *rpc.RequestAndResults {
+	return &rpc.RequestAndResults{
+		Query: &rpc.Body{
+			Method:     "debug_traceCall",
+			MethodBase: "traceCall",
+			Params:     []interface{}{map[string]interface{}{}, "latest", map[string]interface{}{"tracer": tracer}},
+		},
+		Response: &rpc.Response{
+			Result: rec,
+		},
+	}
+}
+
+// Test_compareTraceCallTracerOK tests compare func for traceCall method with callTracer
+// It expects no error since call trees differ only in gas and error messages
+func Test_compareTraceCallTracerOK(t *testing.T) {
+	to := common.HexToAddress("0x2")
+	call := rpc.CallFrame{Type: "CALL", From: common.HexToAddress("0x1"), To: &to, Gas: 100, GasUsed: 10,
+		Calls: []rpc.CallFrame{{Type: "STATICCALL", From: to, To: &to, Gas: 50, GasUsed: 5, Error: "execution reverted"}},
+	}
+	rec, _ := json.Marshal(call)
+	call.Gas, call.Calls[0].Gas, call.Calls[0].Error = 200, 80, "revert"
+	db, _ := json.Marshal(call)
+
+	res := rpc.NewResult(db, nil, 0)
+	if err := compareTrace(res, makeTraceTestData(rpc.CallTracer, rec), 10); err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+}
+
+// Test_compareTraceCallTracerErrorNoMatchingTrace tests compare func for traceCall method with callTracer
+// It expects an error of no matching trace since the inner calls have different callees
+func Test_compareTraceCallTracerErrorNoMatchingTrace(t *testing.T) {
+	to, other := common.HexToAddress("0x2"), common.HexToAddress("0x3")
+	call := rpc.CallFrame{Type: "CALL", From: common.HexToAddress("0x1"), To: &to,
+		Calls: []rpc.CallFrame{{Type: "CALL", From: to, To: &to}},
+	}
+	rec, _ := json.Marshal(call)
+	call.Calls[0].To = &other
+	db, _ := json.Marshal(call)
+
+	res := rpc.NewResult(db, nil, 0)
+	err := compareTrace(res, makeTraceTestData(rpc.CallTracer, rec), 10)
+	if err == nil {
+		t.Errorf("error must not be nil; err: %v", err)
+		return
+	}
+
+	if err.typ != noMatchingTrace {
+		t.Errorf("error must be type 'noMatchingTrace'; err: %v", err)
+	}
+}
+
+// Test_comparePrestateTracerOK tests compare func for traceCall method with prestateTracer
+// It expects no error since the prestates differ only in the zero address and empty slots
+func Test_comparePrestateTracerOK(t *testing.T) {
+	accounts := map[common.Address]rpc.PrestateAccount{
+		common.HexToAddress("0x1"): {Balance: (*hexutil.Big)(big.NewInt(1)), Nonce: 2},
+		common.HexToAddress("0x2"): {Balance: (*hexutil.Big)(big.NewInt(0)), Code: []byte{1}, Storage: map[common.Hash]common.Hash{{1}: {2}}},
+	}
+	rec, _ := json.Marshal(accounts)
+	accounts[common.Address{}] = rpc.PrestateAccount{Balance: (*hexutil.Big)(big.NewInt(3))}
+	db, _ := json.Marshal(accounts)
+
+	res := rpc.NewResult(db, nil, 0)
+	if err := compareTrace(res, makeTraceTestData(rpc.PrestateTracer, rec), 10); err != nil {
+		t.Errorf("error must be nil; err: %v", err)
+	}
+}
+
+// Test_comparePrestateTracerErrorNoMatchingTrace tests compare func for traceCall method with prestateTracer
+// It expects an error of no matching trace since values of the storage slot are different
+func Test_comparePrestateTracerErrorNoMatchingTrace(t *testing.T) {
+	accounts := map[common.Address]rpc.PrestateAccount{
+		common.HexToAddress("0x2"): {Balance: (*hexutil.Big)(big.NewInt(0)), Storage: map[common.Hash]common.Hash{{1}: {2}}},
+	}
+	rec, _ := json.Marshal(accounts)
+	accounts[common.HexToAddress("0x2")].Storage[common.Hash{1}] = common.Hash{3}
+	db, _ := json.Marshal(accounts)
+
+	res := rpc.NewResult(db, nil, 0)
+	err := compareTrace(res, makeTraceTestData(rpc.PrestateTracer, rec), 10)
+	if err == nil {
+		t.Errorf("error must not be nil; err: %v", err)
+		return
+	}
+
+	if err.typ != noMatchingTrace {
+		t.Errorf("error must be type 'noMatchingTrace'; err: %v", err)
+	}
+}
+
+// traceFixture is a debug request together with the response of a node using the native tracers of geth.
+type traceFixture struct {
+	Request struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	} `json:"request"`
+	Response struct {
+		Result json.RawMessage `json:"result"`
+	} `json:"response"`
+}
+
+// traceTestArchive is an archive of an in-memory StateDB.
+type traceTestArchive struct {
+	state.StateDB
+}
+
+func (a traceTestArchive) Release() error {
+	return nil
+}
+
+// TestRPCComparator_TracesOfReplayedTracersMatchNativeTracers replays recorded traces of the native
+// callTracer and prestateTracer of geth with the tracers used by the replay and compares them.
+func TestRPCComparator_TracesOfReplayedTracersMatchNativeTracers(t *testing.T) {
+	for _, fixture := range []string{"trace_call_tracer.json", "trace_prestate_tracer.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", fixture))
+			if err != nil {
+				t.Fatalf("cannot read fixture; %v", err)
+			}
+			var f traceFixture
+			if err = json.Unmarshal(content, &f); err != nil {
+				t.Fatalf("cannot decode fixture; %v", err)
+			}
+			namespace, method, _ := strings.Cut(f.Request.Method, "_")
+			data := &rpc.RequestAndResults{
+				Query:     &rpc.Body{Method: f.Request.Method, Namespace: namespace, MethodBase: method},
+				ParamsRaw: f.Request.Params,
+				Response:  &rpc.Response{BlockID: 1, Timestamp: 1e9, Result: f.Response.Result},
+			}
+			if err = json.Unmarshal(f.Request.Params, &data.Query.Params); err != nil {
+				t.Fatalf("cannot decode params; %v", err)
+			}
+			data.DecodeInfo()
+
+			db, err := state.MakeEmptyGethInMemoryStateDB("")
+			if err != nil {
+				t.Fatalf("cannot create state db; %v", err)
+			}
+			db.BeginBlock(1)
+			db.BeginTransaction(0)
+			db.CreateAccount(common.HexToAddress("0x1"))
+			db.AddBalance(common.HexToAddress("0x1"), big.NewInt(1e18))
+			db.EndTransaction()
+			db.EndBlock()
+
+			res := rpc.Execute(1, data, traceTestArchive{db}, nil, nil, nil, &utils.Config{ChainID: utils.MainnetChainID})
+			if res == nil {
+				t.Fatal("trace was not executed")
+			}
+			if err := compareTrace(res, data, 1); err != nil {
+				t.Errorf("trace does not match the native tracer; %v", err)
+			}
+		})
+	}
+}
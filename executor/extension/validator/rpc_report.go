@@ -0,0 +1,223 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// comparatorErrorNames are the names of comparator error types used in reports.
+var comparatorErrorNames = map[comparatorErrorType]string{
+	defaultErrorType:       "default",
+	noMatchingResult:       "noMatchingResult",
+	noMatchingErrors:       "noMatchingErrors",
+	expectedErrorGotResult: "expectedErrorGotResult",
+	expectedResultGotError: "expectedResultGotError",
+	unexpectedDataType:     "unexpectedDataType",
+	cannotUnmarshalResult:  "cannotUnmarshalResult",
+	cannotSendRpcRequest:   "cannotSendRpcRequest",
+	internalError:          "internalError",
+	noMatchingLogs:         "noMatchingLogs",
+	noMatchingProof:        "noMatchingProof",
+	noMatchingTrace:        "noMatchingTrace",
+	proofNotCompared:       "proofNotCompared",
+}
+
+func (t comparatorErrorType) String() string {
+	if name, ok := comparatorErrorNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", t)
+}
+
+// rpcMismatch is a comparator error of a single request in a structured form.
+type rpcMismatch struct {
+	Method         string          `json:"method"`
+	Params         json.RawMessage `json:"params"`
+	Block          int             `json:"block"`
+	RequestedBlock int             `json:"requestedBlock"`
+	Contract       string          `json:"contract,omitempty"`
+	Expected       any             `json:"expected"`
+	Actual         any             `json:"actual"`
+	ErrorType      string          `json:"errorType"`
+	Resent         bool            `json:"resent"`
+	Message        string          `json:"message"`
+}
+
+// rpcMismatchGroup summarizes mismatches of the same method, contract and error type.
+type rpcMismatchGroup struct {
+	Method     string `json:"method"`
+	Contract   string `json:"contract,omitempty"`
+	ErrorType  string `json:"errorType"`
+	Count      int    `json:"count"`
+	FirstBlock int    `json:"firstBlock"`
+	LastBlock  int    `json:"lastBlock"`
+}
+
+// rpcMismatchReport collects mismatches of the rpc comparator. Mismatches are written as
+// JSON lines into the report file, at most maxSamples per group, and summarized by group.
+// It is safe for concurrent use.
+type rpcMismatchReport struct {
+	mutex      sync.Mutex
+	path       string // path of the report; empty if only the summary is logged
+	file       *os.File
+	encoder    *json.Encoder
+	maxSamples int
+	groups     map[string]*rpcMismatchGroup
+}
+
+// newRpcMismatchReport creates a report of mismatches written into the given path, if any.
+func newRpcMismatchReport(path string, maxSamples int) (*rpcMismatchReport, error) {
+	r := &rpcMismatchReport{
+		path:       path,
+		maxSamples: maxSamples,
+		groups:     map[string]*rpcMismatchGroup{},
+	}
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create rpc report; %v", err)
+		}
+		r.file = f
+		r.encoder = json.NewEncoder(f)
+	}
+	return r, nil
+}
+
+// add records a comparator error of a request.
+func (r *rpcMismatchReport) add(cErr *comparatorError, data *rpc.RequestAndResults, block int, result txcontext.Result) error {
+	m := rpcMismatch{
+		Method:         data.Query.Method,
+		Params:         data.ParamsRaw,
+		Block:          block,
+		RequestedBlock: data.RequestedBlock,
+		Contract:       data.Query.TargetAddress(),
+		Expected:       recordedOutcome(data),
+		Actual:         executedOutcome(result),
+		ErrorType:      cErr.typ.String(),
+		Resent:         data.IsRecovered,
+		Message:        cErr.Error(),
+	}
+	if m.Params == nil {
+		m.Params, _ = json.Marshal(data.Query.Params)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := m.Method + "|" + m.Contract + "|" + m.ErrorType
+	g, ok := r.groups[key]
+	if !ok {
+		g = &rpcMismatchGroup{Method: m.Method, Contract: m.Contract, ErrorType: m.ErrorType, FirstBlock: block, LastBlock: block}
+		r.groups[key] = g
+	}
+	g.Count++
+	g.FirstBlock = min(g.FirstBlock, block)
+	g.LastBlock = max(g.LastBlock, block)
+
+	if r.encoder == nil || (r.maxSamples > 0 && g.Count > r.maxSamples) {
+		return nil
+	}
+	if err := r.encoder.Encode(m); err != nil {
+		return fmt.Errorf("cannot write rpc report; %v", err)
+	}
+	return nil
+}
+
+// summary returns the groups of mismatches ordered by their number of mismatches.
+func (r *rpcMismatchReport) summary() []*rpcMismatchGroup {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	groups := make([]*rpcMismatchGroup, 0, len(r.groups))
+	for _, g := range r.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Method+groups[i].Contract+groups[i].ErrorType < groups[j].Method+groups[j].Contract+groups[j].ErrorType
+	})
+	return groups
+}
+
+// summaryPath returns the path of the summary written next to the report.
+func (r *rpcMismatchReport) summaryPath() string {
+	return strings.TrimSuffix(r.path, filepath.Ext(r.path)) + ".summary.json"
+}
+
+// close logs the summary, writes it next to the report and closes the report.
+func (r *rpcMismatchReport) close(log logger.Logger) error {
+	groups := r.summary()
+	for _, g := range groups {
+		log.Noticef("%v mismatches of %v (contract %v) with %v in blocks %v-%v",
+			g.Count, g.Method, g.Contract, g.ErrorType, g.FirstBlock, g.LastBlock)
+	}
+	if r.file == nil {
+		return nil
+	}
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("cannot close rpc report; %v", err)
+	}
+
+	summary, err := json.MarshalIndent(groups, "", "    ")
+	if err != nil {
+		return fmt.Errorf("cannot encode rpc report summary; %v", err)
+	}
+	if err = os.WriteFile(r.summaryPath(), summary, 0644); err != nil {
+		return fmt.Errorf("cannot write rpc report summary; %v", err)
+	}
+	return nil
+}
+
+// recordedOutcome returns the recorded result or error of a request.
+func recordedOutcome(data *rpc.RequestAndResults) any {
+	if data.Error != nil {
+		return map[string]any{"error": data.Error.Error}
+	}
+	if data.Response != nil && json.Valid(data.Response.Result) {
+		return json.RawMessage(data.Response.Result)
+	}
+	return nil
+}
+
+// executedOutcome returns the result or error of the StateDB.
+func executedOutcome(result txcontext.Result) any {
+	if result == nil {
+		return nil
+	}
+	res, err := result.GetRawResult()
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	// logs and proofs are JSON objects
+	if len(res) > 0 && (res[0] == '[' || res[0] == '{') && json.Valid(res) {
+		return json.RawMessage(res)
+	}
+	return hexutil.Bytes(res)
+}
@@ -0,0 +1,111 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"bufio"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// makeReportTestState returns a getBalance request of the given address recorded with balance zero.
+func makeReportTestState(address string, block int) executor.State[*rpc.RequestAndResults] {
+	rec, _ := json.Marshal(hexZero)
+	return executor.State[*rpc.RequestAndResults]{
+		Block: block,
+		Data: &rpc.RequestAndResults{
+			Query: &rpc.Body{
+				MethodBase: "getBalance",
+				Method:     "eth_getBalance",
+				Params:     []interface{}{address, "latest"},
+			},
+			Response:    &rpc.Response{Result: rec},
+			IsRecovered: true,
+		},
+	}
+}
+
+func TestRPCComparator_ReportsMismatchesAsJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.jsonl")
+	cfg := &utils.Config{Validate: true, ContinueOnFailure: true, RpcReport: path, MaxReportSamples: 2}
+
+	c := makeRPCComparator(cfg, logger.NewLogger("critical", "rpc-test"))
+	if err := c.PreRun(executor.State[*rpc.RequestAndResults]{}, nil); err != nil {
+		t.Fatalf("cannot open report; %v", err)
+	}
+
+	ctx := &executor.Context{ErrorInput: make(chan error, 10), ExecutionResult: rpc.NewResult(big.NewInt(1).Bytes(), nil, 0)}
+	for block := 10; block < 13; block++ {
+		if err := c.PostTransaction(makeReportTestState("0xA", block), ctx); err != nil {
+			t.Fatalf("unexpected error; %v", err)
+		}
+	}
+	if err := c.PostTransaction(makeReportTestState("0xb", 20), ctx); err != nil {
+		t.Fatalf("unexpected error; %v", err)
+	}
+	if err := c.PostRun(executor.State[*rpc.RequestAndResults]{}, ctx, nil); err != nil {
+		t.Fatalf("cannot close report; %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open report; %v", err)
+	}
+	defer f.Close()
+	var mismatches []rpcMismatch
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		var m rpcMismatch
+		if err = json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("cannot decode mismatch; %v", err)
+		}
+		mismatches = append(mismatches, m)
+	}
+	// the third mismatch of contract 0xa exceeds the samples of its group
+	if len(mismatches) != 3 {
+		t.Fatalf("unexpected number of reported mismatches; got: %v, want: 3", len(mismatches))
+	}
+	m := mismatches[0]
+	if m.Method != "eth_getBalance" || m.Contract != "0xa" || m.Block != 10 || m.ErrorType != "noMatchingResult" || !m.Resent {
+		t.Errorf("unexpected mismatch %+v", m)
+	}
+	if m.Actual != "0x01" || m.Expected != hexZero {
+		t.Errorf("unexpected expected %v or actual %v", m.Expected, m.Actual)
+	}
+
+	summary, err := os.ReadFile(filepath.Join(filepath.Dir(path), "report.summary.json"))
+	if err != nil {
+		t.Fatalf("cannot read summary; %v", err)
+	}
+	var groups []rpcMismatchGroup
+	if err = json.Unmarshal(summary, &groups); err != nil {
+		t.Fatalf("cannot decode summary; %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("unexpected number of groups %v", len(groups))
+	}
+	if g := groups[0]; g.Contract != "0xa" || g.Count != 3 || g.FirstBlock != 10 || g.LastBlock != 12 {
+		t.Errorf("unexpected group %+v", g)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/executor/extension"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// MakeRpcRequestFilter returns extension which excludes requests from validation according to
+// the rules file given by RpcRules and reports how often each rule was hit at the end of the run.
+func MakeRpcRequestFilter(cfg *utils.Config) executor.Extension[*rpc.RequestAndResults] {
+	if !cfg.Validate {
+		return extension.NilExtension[*rpc.RequestAndResults]{}
+	}
+
+	log := logger.NewLogger(cfg.LogLevel, "rpc-request-filter")
+
+	return makeRpcRequestFilter(cfg, log)
+}
+
+func makeRpcRequestFilter(cfg *utils.Config, log logger.Logger) *rpcRequestFilter {
+	return &rpcRequestFilter{cfg: cfg, log: log}
+}
+
+type rpcRequestFilter struct {
+	extension.NilExtension[*rpc.RequestAndResults]
+	cfg   *utils.Config
+	log   logger.Logger
+	rules *rpc.Rules
+}
+
+// PreRun loads the rules.
+func (f *rpcRequestFilter) PreRun(executor.State[*rpc.RequestAndResults], *executor.Context) error {
+	var err error
+	f.rules, err = rpc.LoadRules(f.cfg.RpcRules)
+	return err
+}
+
+// PreTransaction marks requests excluded by the rules as not validatable.
+func (f *rpcRequestFilter) PreTransaction(state executor.State[*rpc.RequestAndResults], _ *executor.Context) error {
+	if f.rules.Skip(state.Data) {
+		state.Data.SkipValidation = true
+	}
+	return nil
+}
+
+// PostRun reports the number of requests hit by each rule.
+func (f *rpcRequestFilter) PostRun(executor.State[*rpc.RequestAndResults], *executor.Context, error) error {
+	if f.rules == nil {
+		return nil
+	}
+	hits, notIncluded := f.rules.Hits()
+	for _, h := range hits {
+		f.log.Noticef("Rule %v (%v) hit %v requests", h.Name, h.Action, h.Hits)
+	}
+	if notIncluded > 0 {
+		f.log.Noticef("%v requests matched no include rule", notIncluded)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/executor"
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/rpc"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+func TestRpcRequestFilter_SkipsValidationOfMatchingRequests(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - action: skip\n    methods: [getCode]\n"), 0644); err != nil {
+		t.Fatalf("cannot write rules; %v", err)
+	}
+	cfg := &utils.Config{Validate: true, RpcRules: path}
+	f := makeRpcRequestFilter(cfg, logger.NewLogger("critical", "rpc-test"))
+	if err := f.PreRun(executor.State[*rpc.RequestAndResults]{}, nil); err != nil {
+		t.Fatalf("cannot load rules; %v", err)
+	}
+
+	code := executor.State[*rpc.RequestAndResults]{Data: &rpc.RequestAndResults{
+		Query: &rpc.Body{Method: "eth_getCode", MethodBase: "getCode", Params: []interface{}{"0x1", "latest"}},
+	}}
+	balance := executor.State[*rpc.RequestAndResults]{Data: &rpc.RequestAndResults{
+		Query: &rpc.Body{Method: "eth_getBalance", MethodBase: "getBalance", Params: []interface{}{"0x1", "latest"}},
+	}}
+	for _, s := range []executor.State[*rpc.RequestAndResults]{code, balance} {
+		if err := f.PreTransaction(s, nil); err != nil {
+			t.Fatalf("unexpected error; %v", err)
+		}
+	}
+	if !code.Data.SkipValidation {
+		t.Error("getCode must be skipped")
+	}
+	if balance.Data.SkipValidation {
+		t.Error("getBalance must not be skipped")
+	}
+	if err := f.PostRun(executor.State[*rpc.RequestAndResults]{}, nil, nil); err != nil {
+		t.Errorf("unexpected error; %v", err)
+	}
+}
+
+func TestRpcRequestFilter_MissingRulesFileFails(t *testing.T) {
+	cfg := &utils.Config{Validate: true, RpcRules: filepath.Join(t.TempDir(), "missing.yaml")}
+	f := makeRpcRequestFilter(cfg, logger.NewLogger("critical", "rpc-test"))
+	if err := f.PreRun(executor.State[*rpc.RequestAndResults]{}, nil); err == nil {
+		t.Error("missing rules file must fail")
+	}
+}
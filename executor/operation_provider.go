@@ -28,7 +28,7 @@ type operationProvider struct {
 	traceFiles []string
 }
 
-// Run unifies operation by transactions. If operation.BeginTransactionID
+// Run unifies operation by transactions of blocks in range [from, to). If operation.BeginTransactionID
 // appears current slice of operations is sent to the consumer
 func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.Operation]) error {
 	iter := tracer.NewTraceIterator(p.traceFiles, uint64(from))
@@ -56,12 +56,6 @@ func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.O
 				return err
 			}
 
-			// this condition must be kept for replay_substate tool;
-			// it indicates that we require only one transaction to be passed to consumer
-			if from == to {
-				return nil
-			}
-
 			tx = make([]operation.Operation, 0)
 			lastOperation = false
 
@@ -81,7 +75,8 @@ func (p operationProvider) Run(from int, to int, consumer Consumer[[]operation.O
 		case *operation.BeginBlock:
 			// extract block number with operation.BeginBlock
 			currentBlockNumber = int(t.BlockNumber)
-			if currentBlockNumber > to {
+			// the block range is exclusive
+			if currentBlockNumber >= to {
 				return nil
 			}
 		default:
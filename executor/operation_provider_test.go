@@ -151,3 +151,85 @@ func TestOperationProvider_BeginTransactionSetsTransactionNumber(t *testing.T) {
 		t.Fatalf("failed to iterate through states: %v", err)
 	}
 }
+
+func TestOperationProvider_BlocksAfterRangeAreNotProvided(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	consumer := NewMockOperationConsumer(ctrl)
+
+	cfg := &utils.Config{}
+	cfg.First = 1
+	cfg.Last = 1
+
+	cfg.TraceFile = t.TempDir() + "file"
+	rCtx, err := context.NewRecord(cfg.TraceFile, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation.WriteOp(rCtx, operation.NewBeginBlock(1))
+	operation.WriteOp(rCtx, operation.NewBeginTransaction(0))
+	operation.WriteOp(rCtx, operation.NewEndTransaction())
+	operation.WriteOp(rCtx, operation.NewEndBlock())
+	operation.WriteOp(rCtx, operation.NewBeginBlock(2))
+	operation.WriteOp(rCtx, operation.NewBeginTransaction(0))
+	operation.WriteOp(rCtx, operation.NewEndTransaction())
+	operation.WriteOp(rCtx, operation.NewEndBlock())
+	rCtx.Close()
+
+	provider, err := OpenOperations(cfg)
+	if err != nil {
+		t.Fatalf("failed to open trace file: %v", err)
+	}
+	defer provider.Close()
+
+	consumer.EXPECT().Consume(1, 0, gomock.Any())
+
+	if err := provider.Run(1, 2, toOperationConsumer(consumer)); err != nil {
+		t.Fatalf("failed to iterate through states: %v", err)
+	}
+}
+
+func TestOperationProvider_RangeOfSingleBlockProvidesAllItsTransactions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	consumer := NewMockOperationConsumer(ctrl)
+
+	cfg := &utils.Config{}
+	cfg.First = 1
+	cfg.Last = 2
+	cfg.TraceFile = t.TempDir() + "file"
+	rCtx, err := context.NewRecord(cfg.TraceFile, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation.WriteOp(rCtx, operation.NewBeginBlock(1))
+	operation.WriteOp(rCtx, operation.NewBeginTransaction(0))
+	operation.WriteOp(rCtx, operation.NewEndTransaction())
+	operation.WriteOp(rCtx, operation.NewBeginTransaction(1))
+	operation.WriteOp(rCtx, operation.NewEndTransaction())
+	operation.WriteOp(rCtx, operation.NewEndBlock())
+	operation.WriteOp(rCtx, operation.NewBeginBlock(2))
+	operation.WriteOp(rCtx, operation.NewBeginTransaction(0))
+	operation.WriteOp(rCtx, operation.NewEndTransaction())
+	operation.WriteOp(rCtx, operation.NewEndBlock())
+	rCtx.Close()
+
+	provider, err := OpenOperations(cfg)
+	if err != nil {
+		t.Fatalf("failed to open trace file: %v", err)
+	}
+	defer provider.Close()
+
+	gomock.InOrder(
+		consumer.EXPECT().Consume(1, 0, gomock.Any()),
+		consumer.EXPECT().Consume(1, 1, gomock.Any()),
+	)
+
+	if err := provider.Run(1, 2, toOperationConsumer(consumer)); err != nil {
+		t.Fatalf("failed to iterate through states: %v", err)
+	}
+	// an empty range provides nothing
+	if err := provider.Run(1, 1, toOperationConsumer(consumer)); err != nil {
+		t.Fatalf("failed to iterate through states: %v", err)
+	}
+}
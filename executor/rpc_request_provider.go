@@ -19,7 +19,6 @@ package executor
 import (
 	"errors"
 	"fmt"
-	"os"
 
 	"github.com/Fantom-foundation/Aida/logger"
 	"github.com/Fantom-foundation/Aida/rpc"
@@ -28,36 +27,16 @@ import (
 )
 
 func OpenRpcRecording(cfg *utils.Config, ctx *cli.Context) (Provider[*rpc.RequestAndResults], error) {
-	fileInfo, err := os.Stat(cfg.RpcRecordingPath)
+	iter, err := rpc.OpenRecordingFiles(ctx.Context, cfg.RpcRecordingPath)
 	if err != nil {
-		return nil, fmt.Errorf("cannot stat the rpc path; %w", err)
+		return nil, err
 	}
-
 	log := logger.NewLogger(cfg.LogLevel, "rpc-provider")
-	if !fileInfo.IsDir() {
-		iter, err := rpc.NewFileReader(ctx.Context, cfg.RpcRecordingPath)
-		if err != nil {
-			return nil, fmt.Errorf("cannot open rpc recording file; %v", err)
-		}
-		return openRpcRecording(iter, cfg, log, ctx, []string{cfg.RpcRecordingPath}), nil
-	}
-
-	files, err := utils.GetDirectoryFiles("", cfg.RpcRecordingPath)
-	if err != nil {
-		return nil, fmt.Errorf("cannot get files from dir %v; %w", cfg.RpcRecordingPath, err)
-	}
-
-	iter, err := rpc.NewFileReader(ctx.Context, files[0])
-	if err != nil {
-		return nil, fmt.Errorf("cannot open rpc recording file; %v", err)
-	}
-	return openRpcRecording(iter, cfg, log, ctx, files), nil
-
+	return openRpcRecording(iter, cfg, log, iter.Files()), nil
 }
 
-func openRpcRecording(iter rpc.Iterator, cfg *utils.Config, log logger.Logger, ctxt *cli.Context, files []string) Provider[*rpc.RequestAndResults] {
+func openRpcRecording(iter rpc.Iterator, cfg *utils.Config, log logger.Logger, files []string) Provider[*rpc.RequestAndResults] {
 	return &rpcRequestProvider{
-		ctxt:     ctxt,
 		fileName: cfg.RpcRecordingPath,
 		iter:     iter,
 		files:    files,
@@ -65,21 +44,24 @@ func openRpcRecording(iter rpc.Iterator, cfg *utils.Config, log logger.Logger, c
 	}
 }
 
+// fileIterator is implemented by iterators of several recording files.
+type fileIterator interface {
+	// File returns the index of the file of the current request.
+	File() int
+}
+
 type rpcRequestProvider struct {
-	ctxt     *cli.Context
 	fileName string
 	iter     rpc.Iterator
 	log      logger.Logger
 	files    []string
-	nextFile int
+	file     int // index of the iterated file
 }
 
 func (r *rpcRequestProvider) Run(from int, to int, consumer Consumer[*rpc.RequestAndResults]) (err error) {
-	r.nextFile++
-
 	defer func() {
 		if err != nil {
-			r.log.Infof("Last iterated file: %v", r.files[r.nextFile-1])
+			r.log.Infof("Last iterated file: %v", r.files[r.file])
 		}
 	}()
 
@@ -98,9 +80,9 @@ func (r *rpcRequestProvider) Run(from int, to int, consumer Consumer[*rpc.Reques
 			return errors.New("iterator returned nil request")
 		}
 
-		// get logs is not yet implemented, skip these for now
-		if req.Query.MethodBase == "getLogs" {
-			continue
+		if f, ok := r.iter.(fileIterator); ok && f.File() != r.file {
+			r.file = f.File()
+			r.log.Noticef("Iterating file %v/%v path: %v", r.file+1, len(r.files), r.files[r.file])
 		}
 
 		req.DecodeInfo()
@@ -118,15 +100,6 @@ func (r *rpcRequestProvider) Run(from int, to int, consumer Consumer[*rpc.Reques
 		}
 	}
 
-	if r.nextFile < len(r.files) {
-		var err error
-		r.iter, err = rpc.NewFileReader(r.ctxt.Context, r.files[r.nextFile])
-		if err != nil {
-			return fmt.Errorf("cannot open rpc recording file %v; %w", r.files[r.nextFile], err)
-		}
-		return r.Run(from, to, consumer)
-	}
-
 	return nil
 }
 
@@ -147,7 +120,7 @@ func (r *rpcRequestProvider) processFirst(from int, consumer Consumer[*rpc.Reque
 		}
 
 		req.DecodeInfo()
-		r.log.Noticef("Iterating file %v/%v path: %v", r.nextFile, len(r.files), r.files[r.nextFile-1])
+		r.log.Noticef("Iterating file %v/%v path: %v", r.file+1, len(r.files), r.files[r.file])
 		r.log.Noticef("First block of recording: %v", req.RecordedBlock)
 
 		// are we skipping requests?
@@ -157,11 +130,6 @@ func (r *rpcRequestProvider) processFirst(from int, consumer Consumer[*rpc.Reque
 			return nil
 		}
 
-		// get logs is not yet implemented, skip these for now
-		if req.Query.MethodBase == "getLogs" {
-			return nil
-		}
-
 		if err := consumer(TransactionInfo[*rpc.RequestAndResults]{req.RecordedBlock, 0, req}); err != nil {
 			return err
 		}
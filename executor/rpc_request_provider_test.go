@@ -17,14 +17,19 @@
 package executor
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/Fantom-foundation/Aida/logger"
 	"github.com/Fantom-foundation/Aida/rpc"
 	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/urfave/cli/v2"
 	"go.uber.org/mock/gomock"
 )
 
@@ -35,7 +40,7 @@ func TestRPCRequestProvider_WorksWithValidResponse(t *testing.T) {
 
 	cfg := &utils.Config{}
 
-	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), nil, []string{"testfile"})
+	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), []string{"testfile"})
 
 	defer provider.Close()
 
@@ -60,7 +65,7 @@ func TestRPCRequestProvider_WorksWithErrorResponse(t *testing.T) {
 
 	cfg := &utils.Config{}
 
-	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), nil, []string{"testfile"})
+	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), []string{"testfile"})
 
 	defer provider.Close()
 
@@ -85,7 +90,7 @@ func TestRPCRequestProvider_NilRequestDoesNotGetToConsumer(t *testing.T) {
 
 	cfg := &utils.Config{}
 
-	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), nil, []string{"testfile"})
+	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), []string{"testfile"})
 
 	defer provider.Close()
 
@@ -117,7 +122,7 @@ func TestRPCRequestProvider_ErrorReturnedByIteratorEndsTheApp(t *testing.T) {
 
 	cfg := &utils.Config{}
 
-	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), nil, []string{"testfile"})
+	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), []string{"testfile"})
 
 	defer provider.Close()
 
@@ -136,14 +141,14 @@ func TestRPCRequestProvider_ErrorReturnedByIteratorEndsTheApp(t *testing.T) {
 	}
 }
 
-func TestRPCRequestProvider_GetLogMethodDoesNotEndIteration(t *testing.T) {
+func TestRPCRequestProvider_GetLogMethodIsPassedToConsumer(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	consumer := NewMockRPCReqConsumer(ctrl)
 	i := rpc.NewMockIterator(ctrl)
 
 	cfg := &utils.Config{}
 
-	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), nil, []string{"testfile"})
+	provider := openRpcRecording(i, cfg, logger.NewLogger("critical", "rpc-provider-test"), []string{"testfile"})
 
 	defer provider.Close()
 
@@ -151,9 +156,11 @@ func TestRPCRequestProvider_GetLogMethodDoesNotEndIteration(t *testing.T) {
 		i.EXPECT().Next().Return(true),
 		i.EXPECT().Error().Return(nil),
 		i.EXPECT().Value().Return(logResp),
+		consumer.EXPECT().Consume(10, gomock.Any(), logResp),
 		i.EXPECT().Next().Return(true),
 		i.EXPECT().Error().Return(nil),
 		i.EXPECT().Value().Return(logResp),
+		consumer.EXPECT().Consume(10, gomock.Any(), logResp),
 		i.EXPECT().Next().Return(false),
 		i.EXPECT().Close(),
 	)
@@ -172,7 +179,7 @@ func TestRPCRequestProvider_ReportsAboutRun(t *testing.T) {
 	cfg := &utils.Config{}
 	cfg.RpcRecordingPath = "test_file"
 
-	provider := openRpcRecording(i, cfg, log, nil, []string{cfg.RpcRecordingPath})
+	provider := openRpcRecording(i, cfg, log, []string{cfg.RpcRecordingPath})
 
 	defer provider.Close()
 
@@ -194,6 +201,48 @@ func TestRPCRequestProvider_ReportsAboutRun(t *testing.T) {
 	}
 }
 
+func TestRPCRequestProvider_IteratesAllFilesOfDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for i, blocks := range [][]uint64{{1, 2}, {3}} {
+		w, err := rpc.CreateRecording(filepath.Join(dir, fmt.Sprintf("%v.jsonl", i)))
+		if err != nil {
+			t.Fatalf("cannot create recording; %v", err)
+		}
+		for _, block := range blocks {
+			req := &rpc.RequestAndResults{
+				Query:     &rpc.Body{Namespace: "eth", MethodBase: "getBalance", Method: "eth_getBalance"},
+				ParamsRaw: json.RawMessage(`["0x1","latest"]`),
+				Response:  &rpc.Response{BlockID: block, Timestamp: block, Result: json.RawMessage(`"0x1"`)},
+			}
+			if err = w.Write(req); err != nil {
+				t.Fatalf("cannot write recording; %v", err)
+			}
+		}
+		if err = w.Close(); err != nil {
+			t.Fatalf("cannot close recording; %v", err)
+		}
+	}
+
+	cfg := &utils.Config{RpcRecordingPath: dir, LogLevel: "critical"}
+	provider, err := OpenRpcRecording(cfg, &cli.Context{Context: context.Background()})
+	if err != nil {
+		t.Fatalf("cannot open recording; %v", err)
+	}
+	defer provider.Close()
+
+	var blocks []int
+	err = provider.Run(2, 10, func(info TransactionInfo[*rpc.RequestAndResults]) error {
+		blocks = append(blocks, info.Block)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("run failed; %v", err)
+	}
+	if !slices.Equal(blocks, []int{2, 3}) {
+		t.Errorf("unexpected blocks %v", blocks)
+	}
+}
+
 var validResp = &rpc.RequestAndResults{
 	Query: &rpc.Body{},
 	Response: &rpc.Response{
@@ -0,0 +1,329 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// Scheduling modes of a benchmark.
+const (
+	RecordedRate = "recorded" // inter-arrival timing of the recorded blocks
+	FixedRate    = "fixed"    // fixed request rate
+	RampedRate   = "ramp"     // request rate increasing every second
+)
+
+// saturationLag is the delay behind schedule from which the archive is considered saturated.
+const saturationLag = 100 * time.Millisecond
+
+// BenchmarkConfig configures the load generated by a benchmark.
+type BenchmarkConfig struct {
+	Mode     string  // scheduling mode
+	Clients  int     // number of concurrent clients
+	Rate     float64 // requests per second of the fixed rate; initial rate of the ramped rate
+	RateStep float64 // requests per second added every second to the ramped rate
+	Speedup  float64 // speed-up factor of the recorded timing
+}
+
+// MethodStats summarizes the latencies of the requests of a method.
+type MethodStats struct {
+	Method   string
+	Requests int
+	Errors   int
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+}
+
+// BenchmarkReport summarizes a benchmark run.
+type BenchmarkReport struct {
+	Duration       time.Duration
+	Requests       int
+	Errors         int     // failed executions of requests which did not fail when recorded
+	Throughput     float64 // completed requests per second
+	PeakThroughput float64 // most requests completed within a second
+	SaturationRate float64 // offered requests per second when requests first fell behind schedule; 0 if never
+	Methods        []MethodStats
+}
+
+// Benchmark replays recorded requests against the archive of a StateDB at a scheduled rate
+// with concurrent clients. Latencies are measured from the scheduled time of a request to
+// its completion, hence they include the time a request waits for a free client.
+type Benchmark struct {
+	db   state.StateDB
+	logs *LogIndex      // log index for getLogs requests; nil if not available
+	envs BlockEnvSource // environment of blocks for estimateGas requests; nil if not available
+	cfg  *utils.Config
+	bc   BenchmarkConfig
+	log  logger.Logger
+}
+
+// NewBenchmark creates a benchmark of the archive of the given StateDB.
+func NewBenchmark(db state.StateDB, logs *LogIndex, envs BlockEnvSource, bc BenchmarkConfig, cfg *utils.Config) (*Benchmark, error) {
+	if bc.Clients < 1 {
+		return nil, fmt.Errorf("number of clients must be positive")
+	}
+	switch bc.Mode {
+	case RecordedRate:
+		if bc.Speedup <= 0 {
+			return nil, fmt.Errorf("speed-up of the recorded timing must be positive")
+		}
+	case FixedRate:
+		if bc.Rate <= 0 {
+			return nil, fmt.Errorf("request rate must be positive")
+		}
+	case RampedRate:
+		if bc.Rate < 0 || bc.RateStep < 0 || bc.Rate+bc.RateStep == 0 {
+			return nil, fmt.Errorf("request rate and its step must not be negative and one of them must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("unknown benchmark mode %q; use %q, %q or %q", bc.Mode, RecordedRate, FixedRate, RampedRate)
+	}
+	return &Benchmark{
+		db:   db,
+		logs: logs,
+		envs: envs,
+		cfg:  cfg,
+		bc:   bc,
+		log:  logger.NewLogger(cfg.LogLevel, "RPC-Benchmark"),
+	}, nil
+}
+
+// benchmarkJob is a request scheduled for execution.
+type benchmarkJob struct {
+	req       *RequestAndResults
+	scheduled time.Time
+}
+
+// clientStats collects measurements of a single client.
+type clientStats struct {
+	latencies map[string][]time.Duration // latencies by method
+	errors    map[string]int             // errors by method
+	completed map[int64]int              // completed requests by second since start
+}
+
+// RequestFeed passes recorded requests in order to the given consumer until it fails.
+type RequestFeed func(consume func(*RequestAndResults) error) error
+
+// Run replays the requests of the feed recorded in the block range of the configuration.
+func (b *Benchmark) Run(feed RequestFeed) (*BenchmarkReport, error) {
+	jobs := make(chan benchmarkJob, b.bc.Clients)
+	stats := make([]clientStats, b.bc.Clients)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for i := range stats {
+		stats[i] = clientStats{latencies: map[string][]time.Duration{}, errors: map[string]int{}, completed: map[int64]int{}}
+		wg.Add(1)
+		go func(s *clientStats) {
+			defer wg.Done()
+			for job := range jobs {
+				failed := b.execute(job.req)
+				method := job.req.Query.MethodBase
+				s.latencies[method] = append(s.latencies[method], time.Since(job.scheduled))
+				if failed {
+					s.errors[method]++
+				}
+				s.completed[int64(time.Since(start)/time.Second)]++
+			}
+		}(&stats[i])
+	}
+
+	saturation, err := b.schedule(feed, start, jobs)
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return makeBenchmarkReport(stats, time.Since(start), saturation), nil
+}
+
+// schedule dispatches requests to the clients according to the benchmark mode and returns
+// the offered rate at which requests first fell behind schedule.
+func (b *Benchmark) schedule(feed RequestFeed, start time.Time, jobs chan<- benchmarkJob) (float64, error) {
+	var (
+		n          int // number of scheduled requests
+		group      []*RequestAndResults
+		groupStart float64   // offset of the current timestamp group in seconds
+		firstTs    uint64    // first recorded timestamp
+		window     []float64 // offsets of requests scheduled within the last second
+		saturation float64
+	)
+
+	dispatch := func(req *RequestAndResults, offset float64) {
+		scheduled := start.Add(time.Duration(offset * float64(time.Second)))
+		time.Sleep(time.Until(scheduled))
+
+		window = append(window, offset)
+		for len(window) > 0 && window[0] <= offset-1 {
+			window = window[1:]
+		}
+		jobs <- benchmarkJob{req: req, scheduled: scheduled}
+		if saturation == 0 && time.Since(scheduled) > saturationLag {
+			saturation = float64(len(window))
+			b.log.Noticef("Requests fell behind schedule at %.0f Req/s", saturation)
+		}
+		n++
+	}
+
+	// requests of a recorded timestamp are spread evenly over the second of the timestamp
+	flush := func() {
+		for i, req := range group {
+			dispatch(req, groupStart+float64(i)/float64(len(group))/b.bc.Speedup)
+		}
+		group = group[:0]
+	}
+
+	err := feed(func(req *RequestAndResults) error {
+		if req == nil {
+			return nil
+		}
+		req.DecodeInfo()
+		if req.RecordedBlock < int(b.cfg.First) || req.RecordedBlock > int(b.cfg.Last) {
+			return nil
+		}
+
+		switch b.bc.Mode {
+		case RecordedRate:
+			if n == 0 && len(group) == 0 {
+				firstTs = req.Timestamp
+			}
+			offset := float64(max(req.Timestamp, firstTs)-firstTs) / b.bc.Speedup
+			if len(group) > 0 && offset != groupStart {
+				flush()
+			}
+			groupStart = offset
+			group = append(group, req)
+		case FixedRate:
+			dispatch(req, float64(n)/b.bc.Rate)
+		case RampedRate:
+			dispatch(req, rampOffset(n, b.bc.Rate, b.bc.RateStep))
+		}
+		return nil
+	})
+	flush()
+
+	return saturation, err
+}
+
+// rampOffset returns the time in seconds of the i-th request at a rate starting
+// at rate0 and increasing by step every second.
+func rampOffset(i int, rate0, step float64) float64 {
+	if step == 0 {
+		return float64(i) / rate0
+	}
+	// solve rate0*t + step*t^2/2 = i for t
+	return (-rate0 + math.Sqrt(rate0*rate0+2*step*float64(i))) / step
+}
+
+// execute executes a request on the archive and returns true if it failed unexpectedly.
+func (b *Benchmark) execute(req *RequestAndResults) (failed bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.log.Debugf("request %v panicked; %v", req.Query.Method, r)
+			failed = true
+		}
+	}()
+
+	archive, err := b.db.GetArchiveState(uint64(req.RequestedBlock))
+	if err != nil {
+		b.log.Debugf("cannot get archive state of block %v; %v", req.RequestedBlock, err)
+		return true
+	}
+	defer archive.Release()
+
+	res := Execute(uint64(req.RecordedBlock), req, archive, b.logs, b.envs, nil, b.cfg)
+	if res == nil {
+		return false
+	}
+	_, err = res.GetRawResult()
+	return err != nil && req.Error == nil
+}
+
+// makeBenchmarkReport merges the measurements of the clients.
+func makeBenchmarkReport(stats []clientStats, duration time.Duration, saturation float64) *BenchmarkReport {
+	latencies := map[string][]time.Duration{}
+	errors := map[string]int{}
+	completed := map[int64]int{}
+	for _, s := range stats {
+		for method, l := range s.latencies {
+			latencies[method] = append(latencies[method], l...)
+		}
+		for method, e := range s.errors {
+			errors[method] += e
+		}
+		for second, c := range s.completed {
+			completed[second] += c
+		}
+	}
+
+	report := &BenchmarkReport{Duration: duration, SaturationRate: saturation}
+	for method, l := range latencies {
+		sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+		report.Methods = append(report.Methods, MethodStats{
+			Method:   method,
+			Requests: len(l),
+			Errors:   errors[method],
+			P50:      percentile(l, 0.50),
+			P95:      percentile(l, 0.95),
+			P99:      percentile(l, 0.99),
+		})
+		report.Requests += len(l)
+		report.Errors += errors[method]
+	}
+	sort.Slice(report.Methods, func(i, j int) bool { return report.Methods[i].Method < report.Methods[j].Method })
+
+	if duration > 0 {
+		report.Throughput = float64(report.Requests) / duration.Seconds()
+	}
+	for _, c := range completed {
+		report.PeakThroughput = math.Max(report.PeakThroughput, float64(c))
+	}
+	return report
+}
+
+// percentile returns the p-th percentile of sorted latencies (nearest rank).
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// Log prints the report.
+func (r *BenchmarkReport) Log(log logger.Logger) {
+	log.Noticef("Replayed %v requests in %v; %v errors", r.Requests, r.Duration.Round(time.Millisecond), r.Errors)
+	log.Noticef("Throughput: %.1f Req/s; peak: %.0f Req/s", r.Throughput, r.PeakThroughput)
+	if r.SaturationRate > 0 {
+		log.Noticef("Saturated at %.0f Req/s", r.SaturationRate)
+	} else {
+		log.Noticef("Not saturated; requests kept up with the schedule")
+	}
+	for _, m := range r.Methods {
+		log.Noticef("%-20v requests: %8v errors: %6v p50: %10v p95: %10v p99: %10v",
+			m.Method, m.Requests, m.Errors, m.P50.Round(time.Microsecond), m.P95.Round(time.Microsecond), m.P99.Round(time.Microsecond))
+	}
+}
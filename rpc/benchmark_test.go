@@ -0,0 +1,163 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/mock/gomock"
+)
+
+// sliceIterator iterates requests of a slice.
+type sliceIterator struct {
+	reqs []*RequestAndResults
+	next int
+}
+
+func (i *sliceIterator) Next() bool {
+	i.next++
+	return i.next <= len(i.reqs)
+}
+
+func (i *sliceIterator) Value() *RequestAndResults {
+	return i.reqs[i.next-1]
+}
+
+func (i *sliceIterator) Close() {}
+
+func (i *sliceIterator) Error() error {
+	return nil
+}
+
+// feedRequests returns a feed of the requests of a slice.
+func feedRequests(reqs []*RequestAndResults) RequestFeed {
+	return func(consume func(*RequestAndResults) error) error {
+		for _, req := range reqs {
+			if err := consume(req); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// makeBenchmarkTestRequests returns getBalance requests recorded in the given blocks.
+func makeBenchmarkTestRequests(blocks ...uint64) []*RequestAndResults {
+	reqs := make([]*RequestAndResults, len(blocks))
+	for i, block := range blocks {
+		reqs[i] = &RequestAndResults{
+			Query: &Body{
+				MethodBase: "getBalance",
+				Params:     []interface{}{"0x0000000000000000000000000000000000000001", "latest"},
+			},
+			Response: &Response{BlockID: block, Timestamp: block * 1e9, Result: []byte(`"0x1"`)},
+		}
+	}
+	return reqs
+}
+
+func TestBenchmark_ReplaysRequestsOfBlockRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+
+	db.EXPECT().GetArchiveState(uint64(2)).Return(archive, nil).Times(3)
+	db.EXPECT().GetArchiveState(uint64(3)).Return(nil, errors.New("no archive"))
+	archive.EXPECT().GetBalance(common.HexToAddress("0x1")).Return(big.NewInt(1)).Times(3)
+	archive.EXPECT().Release().Times(3)
+
+	cfg := &utils.Config{First: 2, Last: 3}
+	b, err := NewBenchmark(db, nil, nil, BenchmarkConfig{Mode: FixedRate, Clients: 2, Rate: 1000}, cfg)
+	if err != nil {
+		t.Fatalf("cannot create benchmark; %v", err)
+	}
+	report, err := b.Run(feedRequests(makeBenchmarkTestRequests(1, 2, 2, 2, 3, 4)))
+	if err != nil {
+		t.Fatalf("benchmark failed; %v", err)
+	}
+
+	if report.Requests != 4 || report.Errors != 1 {
+		t.Errorf("unexpected number of requests %v or errors %v", report.Requests, report.Errors)
+	}
+	if len(report.Methods) != 1 || report.Methods[0].Method != "getBalance" || report.Methods[0].Requests != 4 {
+		t.Fatalf("unexpected method statistics %v", report.Methods)
+	}
+	if m := report.Methods[0]; m.P50 <= 0 || m.P50 > m.P95 || m.P95 > m.P99 {
+		t.Errorf("unexpected latency percentiles %v", m)
+	}
+	if report.Throughput <= 0 || report.PeakThroughput <= 0 {
+		t.Errorf("unexpected throughput %v (peak %v)", report.Throughput, report.PeakThroughput)
+	}
+}
+
+func TestBenchmark_RecordedTimingFollowsBlockTimestamps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+
+	db.EXPECT().GetArchiveState(gomock.Any()).Return(archive, nil).Times(3)
+	archive.EXPECT().GetBalance(gomock.Any()).Return(big.NewInt(1)).Times(3)
+	archive.EXPECT().Release().Times(3)
+
+	// blocks are one second apart, replayed ten times faster
+	cfg := &utils.Config{First: 0, Last: 10}
+	b, err := NewBenchmark(db, nil, nil, BenchmarkConfig{Mode: RecordedRate, Clients: 1, Speedup: 10}, cfg)
+	if err != nil {
+		t.Fatalf("cannot create benchmark; %v", err)
+	}
+	report, err := b.Run(feedRequests(makeBenchmarkTestRequests(1, 2, 3)))
+	if err != nil {
+		t.Fatalf("benchmark failed; %v", err)
+	}
+	if report.Requests != 3 || report.Duration.Seconds() < 0.2 {
+		t.Errorf("unexpected number of requests %v or duration %v", report.Requests, report.Duration)
+	}
+}
+
+func TestBenchmark_RejectsInvalidConfig(t *testing.T) {
+	cfg := &utils.Config{}
+	for _, bc := range []BenchmarkConfig{
+		{Mode: FixedRate, Clients: 0, Rate: 1},
+		{Mode: FixedRate, Clients: 1},
+		{Mode: RampedRate, Clients: 1, Rate: -1, RateStep: 2},
+		{Mode: RecordedRate, Clients: 1},
+		{Mode: "poisson", Clients: 1, Rate: 1},
+	} {
+		if _, err := NewBenchmark(nil, nil, nil, bc, cfg); err == nil {
+			t.Errorf("config %v must be rejected", bc)
+		}
+	}
+}
+
+func TestBenchmark_RampOffsetIncreasesRate(t *testing.T) {
+	// 10 requests in the first second and 30 in the first two seconds
+	if got := rampOffset(10, 5, 10); math.Abs(got-1) > 1e-9 {
+		t.Errorf("unexpected offset of request 10; got %v, want 1", got)
+	}
+	if got := rampOffset(30, 5, 10); math.Abs(got-2) > 1e-9 {
+		t.Errorf("unexpected offset of request 30; got %v, want 2", got)
+	}
+	if got := rampOffset(4, 2, 0); got != 2 {
+		t.Errorf("unexpected offset of fixed rate; got %v, want 2", got)
+	}
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/Fantom-foundation/Aida/state"
 	"github.com/Fantom-foundation/Aida/utils"
+	substate "github.com/Fantom-foundation/Substate"
 	"github.com/Fantom-foundation/go-opera/ethapi"
 	"github.com/Fantom-foundation/go-opera/evmcore"
 	"github.com/Fantom-foundation/go-opera/opera"
@@ -39,13 +40,39 @@ import (
 
 // EvmExecutor represents requests executed over Ethereum Virtual Machine
 type EvmExecutor struct {
-	args      ethapi.TransactionArgs
-	archive   state.NonCommittableStateDB
-	timestamp uint64 // EVM requests require timestamp for correct execution
-	chainCfg  *params.ChainConfig
-	vmImpl    string
-	blockId   *big.Int
-	rules     opera.EconomyRules
+	args       ethapi.TransactionArgs
+	archive    state.NonCommittableStateDB
+	timestamp  uint64 // EVM requests require timestamp for correct execution
+	chainCfg   *params.ChainConfig
+	vmImpl     string
+	blockId    *big.Int
+	rules      opera.EconomyRules
+	gasLimit   uint64   // gas limit of the block
+	baseFee    *big.Int // base fee of the block
+	coinbase   common.Address
+	difficulty *big.Int
+	tracer     vm.Tracer // traces the execution if set
+}
+
+// BlockEnv is the environment of a recorded block needed for replaying gas estimations.
+type BlockEnv struct {
+	GasLimit  uint64   // gas limit of the block
+	BaseFee   *big.Int // base fee of the block; nil if EIP-1559 is not activated
+	Timestamp uint64   // timestamp of the block in seconds
+}
+
+// BlockEnvSource returns the environment of a block.
+type BlockEnvSource func(block uint64) (*BlockEnv, error)
+
+// SubstateBlockEnvSource returns the environment of a block recorded in the substate DB,
+// which must be opened beforehand.
+func SubstateBlockEnvSource(block uint64) (*BlockEnv, error) {
+	for _, s := range substate.GetBlockSubstates(block) {
+		if s.Env != nil {
+			return &BlockEnv{GasLimit: s.Env.GasLimit, BaseFee: s.Env.BaseFee, Timestamp: s.Env.Timestamp}, nil
+		}
+	}
+	return nil, fmt.Errorf("block %v has no substate", block)
 }
 
 const maxGasLimit = 9995800     // used when request does not specify gas
@@ -53,14 +80,64 @@ const globalGasCap = 50_000_000 // highest gas allowance used for estimateGas
 
 // newEvmExecutor creates EvmExecutor for executing requests into StateDB that demand usage of EVM
 func newEvmExecutor(blockID uint64, archive state.NonCommittableStateDB, cfg *utils.Config, params map[string]interface{}, timestamp uint64) *EvmExecutor {
+	return newTxEvmExecutor(blockID, archive, cfg, newTxArgs(params), timestamp)
+}
+
+// newTxEvmExecutor creates EvmExecutor for executing the given transaction arguments
+func newTxEvmExecutor(blockID uint64, archive state.NonCommittableStateDB, cfg *utils.Config, args ethapi.TransactionArgs, timestamp uint64) *EvmExecutor {
 	return &EvmExecutor{
-		args:      newTxArgs(params),
-		archive:   archive,
-		timestamp: timestamp,
-		chainCfg:  utils.GetChainConfig(cfg.ChainID),
-		vmImpl:    cfg.VmImpl,
-		blockId:   new(big.Int).SetUint64(blockID),
-		rules:     opera.DefaultEconomyRules(),
+		args:       args,
+		archive:    archive,
+		timestamp:  timestamp,
+		chainCfg:   utils.GetChainConfig(cfg.ChainID),
+		vmImpl:     cfg.VmImpl,
+		blockId:    new(big.Int).SetUint64(blockID),
+		rules:      opera.DefaultEconomyRules(),
+		gasLimit:   math.MaxUint64, // evmcore/dummy_block.go
+		baseFee:    opera.DefaultEconomyRules().MinGasPrice,
+		coinbase:   common.Address{}, // opera based value
+		difficulty: big.NewInt(1),    // evmcore/evm.go
+	}
+}
+
+// setBlockEnv configures the gas limit and the base fee of the recorded block. If the request
+// does not specify gas, the gas limit of the block is the highest allowance of gas estimations.
+func (e *EvmExecutor) setBlockEnv(env *BlockEnv, params map[string]interface{}) {
+	e.gasLimit = env.GasLimit
+	if env.BaseFee != nil {
+		e.baseFee = env.BaseFee
+	}
+	if v, ok := params["gas"]; !ok || v == nil {
+		*e.args.Gas = hexutil.Uint64(env.GasLimit)
+	}
+}
+
+// applyOverrides executes the request over an overlay of the archive with the given state overrides
+// and replaces the given fields of the block.
+func (e *EvmExecutor) applyOverrides(stateOverride ethapi.StateOverride, blockOverrides *BlockOverrides) {
+	if len(stateOverride) > 0 {
+		e.archive = newOverrideStateDB(e.archive, stateOverride)
+	}
+	if blockOverrides == nil {
+		return
+	}
+	if blockOverrides.Number != nil {
+		e.blockId = new(big.Int).Set(blockOverrides.Number.ToInt())
+	}
+	if blockOverrides.Difficulty != nil {
+		e.difficulty = new(big.Int).Set(blockOverrides.Difficulty.ToInt())
+	}
+	if blockOverrides.Time != nil {
+		e.timestamp = uint64(*blockOverrides.Time)
+	}
+	if blockOverrides.GasLimit != nil {
+		e.gasLimit = uint64(*blockOverrides.GasLimit)
+	}
+	if blockOverrides.Coinbase != nil {
+		e.coinbase = *blockOverrides.Coinbase
+	}
+	if blockOverrides.BaseFee != nil {
+		e.baseFee = new(big.Int).Set(blockOverrides.BaseFee.ToInt())
 	}
 }
 
@@ -129,18 +206,22 @@ func (e *EvmExecutor) newEVM(msg eth.Message, hashErr *error) *vm.EVM {
 	blockCtx = vm.BlockContext{
 		CanTransfer: core.CanTransfer,
 		Transfer:    core.Transfer,
-		Coinbase:    common.Address{}, // opera based value
+		Coinbase:    e.coinbase,
 		BlockNumber: e.blockId,
-		Difficulty:  big.NewInt(1),  // evmcore/evm.go
-		GasLimit:    math.MaxUint64, // evmcore/dummy_block.go
+		Difficulty:  e.difficulty,
+		GasLimit:    e.gasLimit,
 		GetHash:     getHash,
-		BaseFee:     e.rules.MinGasPrice, // big.NewInt(1e9)
+		BaseFee:     e.baseFee,
 		Time:        new(big.Int).SetUint64(e.timestamp),
 	}
 
 	vmConfig = opera.DefaultVMConfig
 	vmConfig.NoBaseFee = true
 	vmConfig.InterpreterImpl = e.vmImpl
+	if e.tracer != nil {
+		vmConfig.Debug = true
+		vmConfig.Tracer = e.tracer
+	}
 
 	txCtx = evmcore.NewEVMTxContext(msg)
 
@@ -149,6 +230,23 @@ func (e *EvmExecutor) newEVM(msg eth.Message, hashErr *error) *vm.EVM {
 
 // sendCall executes the call method in the EvmExecutor with given archive
 func (e *EvmExecutor) sendCall() (*evmcore.ExecutionResult, error) {
+	executionResult, err := e.applyMessage()
+	if executionResult == nil {
+		return nil, err
+	}
+	if executionResult.Err != nil {
+		return nil, fmt.Errorf("execution returned err; %w", executionResult.Err)
+	}
+	if err != nil {
+		return executionResult, err
+	}
+	return executionResult, nil
+}
+
+// applyMessage executes the request in the EVM. Failures of the execution, such as running
+// out of gas, are reported by the execution result; an error is returned if the request
+// cannot be executed at all.
+func (e *EvmExecutor) applyMessage() (*evmcore.ExecutionResult, error) {
 	var (
 		gp              *evmcore.GasPool
 		executionResult *evmcore.ExecutionResult
@@ -158,7 +256,7 @@ func (e *EvmExecutor) sendCall() (*evmcore.ExecutionResult, error) {
 	)
 
 	gp = new(evmcore.GasPool).AddGas(math.MaxUint64) // based in opera
-	msg, err = e.args.ToMessage(globalGasCap, e.rules.MinGasPrice)
+	msg, err = e.args.ToMessage(globalGasCap, e.baseFee)
 	if err != nil {
 		return nil, err
 	}
@@ -167,8 +265,8 @@ func (e *EvmExecutor) sendCall() (*evmcore.ExecutionResult, error) {
 	evm = e.newEVM(msg, hashErr)
 
 	executionResult, err = evmcore.ApplyMessage(evm, msg, gp)
-	if executionResult.Err != nil {
-		return nil, fmt.Errorf("execution returned err; %w", executionResult.Err)
+	if err != nil {
+		return nil, fmt.Errorf("err: %v (supplied gas %v)", err, e.args.Gas)
 	}
 
 	if hashErr != nil {
@@ -179,11 +277,7 @@ func (e *EvmExecutor) sendCall() (*evmcore.ExecutionResult, error) {
 	if evm.Cancelled() {
 		return nil, fmt.Errorf("execution aborted: timeout")
 	}
-	if err != nil {
-		return executionResult, fmt.Errorf("err: %v (supplied gas %v)", err, e.args.Gas)
-	}
 	return executionResult, nil
-
 }
 
 // sendEstimateGas executes estimateGas method in the EvmExecutor
@@ -235,7 +329,7 @@ func (e *EvmExecutor) sendEstimateGas() (hexutil.Uint64, error) {
 func (e *EvmExecutor) executable(gas uint64) (bool, *evmcore.ExecutionResult, error) {
 	e.args.Gas = (*hexutil.Uint64)(&gas)
 
-	result, err := e.sendCall()
+	result, err := e.applyMessage()
 
 	if err != nil {
 		if strings.Contains(err.Error(), "intrinsic gas too low") {
@@ -0,0 +1,99 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testArchive uses a StateDB as archive state.
+type testArchive struct {
+	state.StateDB
+}
+
+func (a testArchive) Release() error {
+	return nil
+}
+
+// makeEstimateGasTestArchive returns an archive containing a funded sender.
+func makeEstimateGasTestArchive(t *testing.T, sender common.Address) state.NonCommittableStateDB {
+	db, err := state.MakeEmptyGethInMemoryStateDB("")
+	if err != nil {
+		t.Fatalf("cannot create state db; %v", err)
+	}
+	db.BeginBlock(1)
+	db.BeginTransaction(0)
+	db.CreateAccount(sender)
+	db.AddBalance(sender, big.NewInt(1e18))
+	db.EndTransaction()
+	db.EndBlock()
+	return testArchive{db}
+}
+
+// makeEstimateGasTestRequest returns a recorded estimateGas request of a transfer without gas.
+func makeEstimateGasTestRequest(sender, recipient common.Address) *RequestAndResults {
+	return &RequestAndResults{
+		Query: &Body{
+			MethodBase: "estimateGas",
+			Params: []interface{}{map[string]interface{}{
+				"from":  sender.Hex(),
+				"to":    recipient.Hex(),
+				"value": "0x1",
+			}},
+		},
+		Timestamp: 1,
+	}
+}
+
+func TestExecute_EstimateGasOfTransfer(t *testing.T) {
+	sender := common.HexToAddress("0x1")
+	archive := makeEstimateGasTestArchive(t, sender)
+	cfg := &utils.Config{ChainID: utils.MainnetChainID}
+	envs := func(uint64) (*BlockEnv, error) {
+		return &BlockEnv{GasLimit: 1_000_000, BaseFee: big.NewInt(1)}, nil
+	}
+
+	res, err := Execute(1, makeEstimateGasTestRequest(sender, common.HexToAddress("0x1234")), archive, nil, envs, nil, cfg).GetRawResult()
+	if err != nil {
+		t.Fatalf("cannot estimate gas; %v", err)
+	}
+	if got := littleendian.BytesToUint64(res); got != params.TxGas {
+		t.Errorf("unexpected gas estimation; got: %v, want: %v", got, params.TxGas)
+	}
+}
+
+func TestExecute_EstimateGasIsCappedByBlockGasLimit(t *testing.T) {
+	sender := common.HexToAddress("0x1")
+	archive := makeEstimateGasTestArchive(t, sender)
+	cfg := &utils.Config{ChainID: utils.MainnetChainID}
+	envs := func(uint64) (*BlockEnv, error) {
+		return &BlockEnv{GasLimit: params.TxGas}, nil
+	}
+
+	// the transfer to the SHA256 precompile needs more gas than the intrinsic gas
+	_, err := Execute(1, makeEstimateGasTestRequest(sender, common.HexToAddress("0x2")), archive, nil, envs, nil, cfg).GetRawResult()
+	if err == nil {
+		t.Error("gas estimation must fail if the block gas limit is too low")
+	}
+}
@@ -18,7 +18,7 @@ package rpc
 
 import (
 	"encoding/binary"
-	"strings"
+	"fmt"
 	"unsafe"
 
 	"github.com/Fantom-foundation/Aida/state"
@@ -28,10 +28,11 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-// TODO FIX!
-const falsyContract = "0xe0c38b2a8d09aad53f1c67734b9a95e43d5981c0"
-
-func Execute(block uint64, rec *RequestAndResults, archive state.NonCommittableStateDB, cfg *utils.Config) txcontext.Result {
+// Execute executes a recorded request on the archive state of the given block. The getLogs
+// requests are served by the log index; they are not executed if no log index is given.
+// Gas estimations use the gas limit and base fee of the block if a block environment source is given.
+// Traced transactions are located by the transaction source; they are not executed if none is given.
+func Execute(block uint64, rec *RequestAndResults, archive state.NonCommittableStateDB, logs *LogIndex, envs BlockEnvSource, txs TransactionSource, cfg *utils.Config) txcontext.Result {
 	switch rec.Query.MethodBase {
 	case "getBalance":
 		return executeGetBalance(rec.Query.Params[0], archive)
@@ -41,21 +42,46 @@ func Execute(block uint64, rec *RequestAndResults, archive state.NonCommittableS
 		if rec.Timestamp == 0 {
 			return nil
 		}
-		evm := newEvmExecutor(block, archive, cfg, rec.Query.Params[0].(map[string]interface{}), rec.Timestamp)
-		// calls to this contract are excluded for now,
-		// this contract causes issues in validation
-		if strings.Compare(falsyContract, strings.ToLower(evm.args.To.String())) == 0 {
-			rec.SkipValidation = true
+		stateOverride, blockOverrides, err := parseCallOverrides(rec.Query.Params)
+		if err != nil {
+			return &result{err: err}
 		}
+		evm := newEvmExecutor(block, archive, cfg, rec.Query.Params[0].(map[string]interface{}), rec.Timestamp)
+		evm.applyOverrides(stateOverride, blockOverrides)
 		return executeCall(evm)
 
 	case "estimateGas":
-		// estimateGas is currently not suitable for rpc replay since the estimation  in geth is always calculated for current state
-		// that means recorded result and result returned by StateDB are not comparable
+		if rec.Timestamp == 0 {
+			return nil
+		}
+		params := rec.Query.Params[0].(map[string]interface{})
+		evm := newEvmExecutor(block, archive, cfg, params, rec.Timestamp)
+		if envs != nil {
+			env, err := envs(block)
+			if err != nil {
+				return &result{err: fmt.Errorf("cannot get environment of block %v; %v", block, err)}
+			}
+			evm.setBlockEnv(env, params)
+		}
+		return executeEstimateGas(evm)
 	case "getCode":
 		return executeGetCode(rec.Query.Params[0], archive)
 	case "getStorageAt":
 		return executeGetStorageAt(rec.Query.Params, archive)
+	case "getLogs":
+		if logs == nil || len(rec.Query.Params) == 0 {
+			return nil
+		}
+		return executeGetLogs(rec.Query.Params[0], block, logs)
+	case "getProof":
+		return executeGetProof(rec.Query.Params, archive)
+	case "traceCall":
+		if rec.Timestamp == 0 {
+			return nil
+		}
+		return executeTraceCall(block, rec, archive, cfg)
+	case "traceTransaction":
+		return executeTraceTransaction(block, rec, archive, txs, cfg)
 	default:
 		break
 	}
@@ -86,8 +86,9 @@ type Header struct {
 // Each namespace is supposed to be marked by its own bit to allow multi-namespace filtering on the reader.
 // Unlisted namespaces are not recorded.
 var namespaceDictionary = map[string]byte{
-	"eth": 1 << 0,
-	"ftm": 1 << 0, // ftm is a copy of the eth namespace
+	"eth":   1 << 0,
+	"ftm":   1 << 0, // ftm is a copy of the eth namespace
+	"debug": 1 << 1,
 }
 
 // methodDictionary represents a dictionary of methods by namespace for encoding.
@@ -106,6 +107,11 @@ var methodDictionary = map[byte]map[string]byte{
 		"getLogs":             7,
 		"getProof":            8,
 	},
+	1 << 1: {
+		/* debug namespace */
+		"traceCall":        1,
+		"traceTransaction": 2,
+	},
 }
 
 // checksumTable is the table used to calculate the header checksum.
@@ -0,0 +1,282 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Fantom-foundation/Aida/txcontext"
+	substate "github.com/Fantom-foundation/Substate"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxLogBlockRange is the largest block range of a getLogs request served by the log index.
+// Requests for larger ranges are not executed.
+const maxLogBlockRange = 10_000
+
+// logIndexCacheSize is the number of blocks whose logs are kept by the log index.
+const logIndexCacheSize = 100_000
+
+// LogJSON is a log in the result of a getLogs request. Substates do not record
+// transaction and block hashes, hence only the content and the block of logs are
+// compared.
+type LogJSON struct {
+	Address     common.Address `json:"address"`
+	Topics      []common.Hash  `json:"topics"`
+	Data        hexutil.Bytes  `json:"data"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+// LogSource returns the logs of all transactions of a block in execution order.
+type LogSource func(block uint64) ([]*types.Log, error)
+
+// SubstateLogSource returns the logs of a block recorded in the receipts of the
+// substate DB, which must be opened beforehand.
+func SubstateLogSource(block uint64) ([]*types.Log, error) {
+	substates := substate.GetBlockSubstates(block)
+	txs := make([]int, 0, len(substates))
+	for tx := range substates {
+		txs = append(txs, tx)
+	}
+	sort.Ints(txs)
+	logs := []*types.Log{}
+	for _, tx := range txs {
+		if substates[tx].Result == nil {
+			continue
+		}
+		for _, log := range substates[tx].Result.Logs {
+			l := *log
+			l.BlockNumber = block
+			l.TxIndex = uint(tx)
+			logs = append(logs, &l)
+		}
+	}
+	return logs, nil
+}
+
+// LogIndex is a local index of the logs of blocks serving getLogs requests.
+// It is safe for concurrent use.
+type LogIndex struct {
+	source LogSource
+	mutex  sync.Mutex
+	blocks map[uint64][]*types.Log // logs of cached blocks
+	order  []uint64                // cached blocks in insertion order for eviction
+}
+
+// NewLogIndex creates a log index reading the logs of blocks from the given source.
+func NewLogIndex(source LogSource) *LogIndex {
+	return &LogIndex{
+		source: source,
+		blocks: map[uint64][]*types.Log{},
+	}
+}
+
+// blockLogs returns the logs of a block.
+func (i *LogIndex) blockLogs(block uint64) ([]*types.Log, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if logs, ok := i.blocks[block]; ok {
+		return logs, nil
+	}
+	logs, err := i.source(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read logs of block %v; %v", block, err)
+	}
+	if len(i.order) >= logIndexCacheSize {
+		delete(i.blocks, i.order[0])
+		i.order = i.order[1:]
+	}
+	i.blocks[block] = logs
+	i.order = append(i.order, block)
+	return logs, nil
+}
+
+// Filter returns the logs of blocks in the inclusive range [from, to] matching the filter.
+func (i *LogIndex) Filter(from, to uint64, filter *LogFilter) ([]LogJSON, error) {
+	logs := []LogJSON{}
+	for block := from; block <= to; block++ {
+		blockLogs, err := i.blockLogs(block)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range blockLogs {
+			if !filter.Matches(log) {
+				continue
+			}
+			topics := log.Topics
+			if topics == nil {
+				topics = []common.Hash{}
+			}
+			logs = append(logs, LogJSON{
+				Address:     log.Address,
+				Topics:      topics,
+				Data:        log.Data,
+				BlockNumber: hexutil.Uint64(log.BlockNumber),
+			})
+		}
+	}
+	return logs, nil
+}
+
+// LogFilter selects logs by addresses and topics like the filter criteria of getLogs requests.
+type LogFilter struct {
+	Addresses []common.Address // logs of any of the addresses; all logs if empty
+	Topics    [][]common.Hash  // any of the topics at each position; wildcard if empty
+}
+
+// Matches returns true if a log satisfies the filter criteria.
+func (f *LogFilter) Matches(log *types.Log) bool {
+	if len(f.Addresses) > 0 {
+		found := false
+		for _, address := range f.Addresses {
+			if log.Address == address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.Topics) > len(log.Topics) {
+		return false
+	}
+	for i, alternatives := range f.Topics {
+		if len(alternatives) == 0 {
+			continue
+		}
+		found := false
+		for _, topic := range alternatives {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLogFilter parses the filter object of a getLogs request. The block range
+// defaults to the recorded block. Requests for a block hash are not supported.
+func parseLogFilter(param interface{}, recordedBlock uint64) (from uint64, to uint64, filter *LogFilter, err error) {
+	object, ok := param.(map[string]interface{})
+	if !ok {
+		return 0, 0, nil, fmt.Errorf("filter is not an object")
+	}
+	if _, ok := object["blockHash"]; ok {
+		return 0, 0, nil, fmt.Errorf("filter by block hash is not supported")
+	}
+	if from, err = parseLogBlock(object["fromBlock"], recordedBlock); err != nil {
+		return 0, 0, nil, err
+	}
+	if to, err = parseLogBlock(object["toBlock"], recordedBlock); err != nil {
+		return 0, 0, nil, err
+	}
+
+	filter = &LogFilter{}
+	switch address := object["address"].(type) {
+	case nil:
+	case string:
+		filter.Addresses = append(filter.Addresses, common.HexToAddress(address))
+	case []interface{}:
+		for _, a := range address {
+			s, ok := a.(string)
+			if !ok {
+				return 0, 0, nil, fmt.Errorf("address %v is not a string", a)
+			}
+			filter.Addresses = append(filter.Addresses, common.HexToAddress(s))
+		}
+	default:
+		return 0, 0, nil, fmt.Errorf("invalid address %v", address)
+	}
+
+	if object["topics"] != nil {
+		topics, ok := object["topics"].([]interface{})
+		if !ok {
+			return 0, 0, nil, fmt.Errorf("topics are not a list")
+		}
+		for _, t := range topics {
+			alternatives := []common.Hash{}
+			switch topic := t.(type) {
+			case nil:
+			case string:
+				alternatives = append(alternatives, common.HexToHash(topic))
+			case []interface{}:
+				for _, a := range topic {
+					// a null alternative matches any topic
+					if a == nil {
+						alternatives = []common.Hash{}
+						break
+					}
+					s, ok := a.(string)
+					if !ok {
+						return 0, 0, nil, fmt.Errorf("topic %v is not a string", a)
+					}
+					alternatives = append(alternatives, common.HexToHash(s))
+				}
+			default:
+				return 0, 0, nil, fmt.Errorf("invalid topic %v", topic)
+			}
+			filter.Topics = append(filter.Topics, alternatives)
+		}
+	}
+	return from, to, filter, nil
+}
+
+// parseLogBlock parses a block number of a log filter.
+func parseLogBlock(param interface{}, recordedBlock uint64) (uint64, error) {
+	if param == nil {
+		return recordedBlock, nil
+	}
+	str, ok := param.(string)
+	if !ok {
+		return 0, fmt.Errorf("block number %v is not a string", param)
+	}
+	switch str {
+	case "latest", "pending", "safe", "finalized":
+		return recordedBlock, nil
+	case "earliest":
+		return 0, nil
+	}
+	return hexutil.DecodeUint64(str)
+}
+
+// executeGetLogs request into the log index and send result to comparator. Requests whose
+// filter is not supported or whose block range is invalid or too large are not executed.
+func executeGetLogs(param interface{}, recordedBlock uint64, index *LogIndex) txcontext.Result {
+	from, to, filter, err := parseLogFilter(param, recordedBlock)
+	if err != nil || from > to || to-from >= maxLogBlockRange {
+		return nil
+	}
+	logs, err := index.Filter(from, to, filter)
+	if err != nil {
+		return &result{err: err}
+	}
+	res, err := json.Marshal(logs)
+	return &result{
+		result: res,
+		err:    err,
+	}
+}
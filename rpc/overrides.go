@@ -0,0 +1,287 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/go-opera/ethapi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// BlockOverrides is the set of block header fields which can be overridden by an eth_call request.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+	BaseFee    *hexutil.Big    `json:"baseFee"`
+}
+
+// parseCallOverrides decodes the optional state overrides and block overrides following
+// the call object and the block of recorded eth_call params.
+func parseCallOverrides(params []interface{}) (ethapi.StateOverride, *BlockOverrides, error) {
+	var (
+		stateOverride  ethapi.StateOverride
+		blockOverrides *BlockOverrides
+	)
+	if len(params) > 2 && params[2] != nil {
+		if err := remarshal(params[2], &stateOverride); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode state overrides; %v", err)
+		}
+		for addr, account := range stateOverride {
+			if account.State != nil && account.StateDiff != nil {
+				return nil, nil, fmt.Errorf("account %v has both 'state' and 'stateDiff'", addr.Hex())
+			}
+		}
+	}
+	if len(params) > 3 && params[3] != nil {
+		blockOverrides = new(BlockOverrides)
+		if err := remarshal(params[3], blockOverrides); err != nil {
+			return nil, nil, fmt.Errorf("cannot decode block overrides; %v", err)
+		}
+	}
+	return stateOverride, blockOverrides, nil
+}
+
+// remarshal decodes a generically decoded JSON value into the given type.
+func remarshal(v interface{}, out interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// overriddenAccount holds the overridden fields of an account. Fields which are not
+// overridden are read from and written to the archive.
+type overriddenAccount struct {
+	balance        *big.Int // nil if not overridden
+	nonce          *uint64  // nil if not overridden
+	code           []byte
+	hasCode        bool
+	storage        map[common.Hash]common.Hash // nil if storage is not overridden
+	committed      map[common.Hash]common.Hash // overridden slots before execution
+	replaceStorage bool                        // slots which are not overridden are empty
+}
+
+// overrideStateDB is a copy-on-write overlay applying state overrides over an archive state.
+// The archive is never modified for overridden fields; their modifications are kept by the overlay
+// and are reverted together with the snapshots of the archive.
+type overrideStateDB struct {
+	state.NonCommittableStateDB
+	accounts  map[common.Address]*overriddenAccount
+	journal   []func()    // undoes modifications of overridden fields
+	snapshots map[int]int // length of the journal at the time of each snapshot
+}
+
+// newOverrideStateDB creates an overlay of the archive applying the given overrides.
+func newOverrideStateDB(archive state.NonCommittableStateDB, overrides ethapi.StateOverride) *overrideStateDB {
+	db := &overrideStateDB{
+		NonCommittableStateDB: archive,
+		accounts:              make(map[common.Address]*overriddenAccount, len(overrides)),
+		snapshots:             make(map[int]int),
+	}
+	for addr, o := range overrides {
+		acc := new(overriddenAccount)
+		if o.Balance != nil && *o.Balance != nil {
+			acc.balance = new(big.Int).Set((*o.Balance).ToInt())
+		}
+		if o.Nonce != nil {
+			nonce := uint64(*o.Nonce)
+			acc.nonce = &nonce
+		}
+		if o.Code != nil {
+			acc.code = common.CopyBytes(*o.Code)
+			acc.hasCode = true
+		}
+		slots := o.StateDiff
+		if o.State != nil {
+			slots = o.State
+			acc.replaceStorage = true
+		}
+		if slots != nil {
+			acc.storage = make(map[common.Hash]common.Hash, len(*slots))
+			acc.committed = make(map[common.Hash]common.Hash, len(*slots))
+			for k, v := range *slots {
+				acc.storage[k] = v
+				acc.committed[k] = v
+			}
+		}
+		db.accounts[addr] = acc
+	}
+	return db
+}
+
+func (db *overrideStateDB) Exist(addr common.Address) bool {
+	if _, ok := db.accounts[addr]; ok {
+		return true
+	}
+	return db.NonCommittableStateDB.Exist(addr)
+}
+
+func (db *overrideStateDB) Empty(addr common.Address) bool {
+	if _, ok := db.accounts[addr]; !ok {
+		return db.NonCommittableStateDB.Empty(addr)
+	}
+	return db.GetBalance(addr).Sign() == 0 && db.GetNonce(addr) == 0 && db.GetCodeSize(addr) == 0
+}
+
+func (db *overrideStateDB) Suicide(addr common.Address) bool {
+	if acc, ok := db.accounts[addr]; ok && acc.balance != nil {
+		db.setBalance(acc, new(big.Int))
+	}
+	return db.NonCommittableStateDB.Suicide(addr)
+}
+
+func (db *overrideStateDB) GetBalance(addr common.Address) *big.Int {
+	if acc, ok := db.accounts[addr]; ok && acc.balance != nil {
+		return new(big.Int).Set(acc.balance)
+	}
+	return db.NonCommittableStateDB.GetBalance(addr)
+}
+
+func (db *overrideStateDB) AddBalance(addr common.Address, amount *big.Int) {
+	if acc, ok := db.accounts[addr]; ok && acc.balance != nil {
+		db.setBalance(acc, new(big.Int).Add(acc.balance, amount))
+		return
+	}
+	db.NonCommittableStateDB.AddBalance(addr, amount)
+}
+
+func (db *overrideStateDB) SubBalance(addr common.Address, amount *big.Int) {
+	if acc, ok := db.accounts[addr]; ok && acc.balance != nil {
+		db.setBalance(acc, new(big.Int).Sub(acc.balance, amount))
+		return
+	}
+	db.NonCommittableStateDB.SubBalance(addr, amount)
+}
+
+func (db *overrideStateDB) setBalance(acc *overriddenAccount, balance *big.Int) {
+	prev := acc.balance
+	db.journal = append(db.journal, func() { acc.balance = prev })
+	acc.balance = balance
+}
+
+func (db *overrideStateDB) GetNonce(addr common.Address) uint64 {
+	if acc, ok := db.accounts[addr]; ok && acc.nonce != nil {
+		return *acc.nonce
+	}
+	return db.NonCommittableStateDB.GetNonce(addr)
+}
+
+func (db *overrideStateDB) SetNonce(addr common.Address, nonce uint64) {
+	if acc, ok := db.accounts[addr]; ok && acc.nonce != nil {
+		prev := *acc.nonce
+		db.journal = append(db.journal, func() { *acc.nonce = prev })
+		*acc.nonce = nonce
+		return
+	}
+	db.NonCommittableStateDB.SetNonce(addr, nonce)
+}
+
+func (db *overrideStateDB) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	if acc, ok := db.accounts[addr]; ok && acc.storage != nil {
+		if v, found := acc.committed[key]; found || acc.replaceStorage {
+			return v
+		}
+	}
+	return db.NonCommittableStateDB.GetCommittedState(addr, key)
+}
+
+func (db *overrideStateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	if acc, ok := db.accounts[addr]; ok && acc.storage != nil {
+		if v, found := acc.storage[key]; found || acc.replaceStorage {
+			return v
+		}
+	}
+	return db.NonCommittableStateDB.GetState(addr, key)
+}
+
+func (db *overrideStateDB) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	acc, ok := db.accounts[addr]
+	if !ok || acc.storage == nil {
+		db.NonCommittableStateDB.SetState(addr, key, value)
+		return
+	}
+	prev, found := acc.storage[key]
+	if !found && !acc.replaceStorage {
+		db.NonCommittableStateDB.SetState(addr, key, value)
+		return
+	}
+	db.journal = append(db.journal, func() {
+		if found {
+			acc.storage[key] = prev
+		} else {
+			delete(acc.storage, key)
+		}
+	})
+	acc.storage[key] = value
+}
+
+func (db *overrideStateDB) GetCodeHash(addr common.Address) common.Hash {
+	if acc, ok := db.accounts[addr]; ok && acc.hasCode {
+		return crypto.Keccak256Hash(acc.code)
+	}
+	return db.NonCommittableStateDB.GetCodeHash(addr)
+}
+
+func (db *overrideStateDB) GetCode(addr common.Address) []byte {
+	if acc, ok := db.accounts[addr]; ok && acc.hasCode {
+		return acc.code
+	}
+	return db.NonCommittableStateDB.GetCode(addr)
+}
+
+func (db *overrideStateDB) SetCode(addr common.Address, code []byte) {
+	if acc, ok := db.accounts[addr]; ok && acc.hasCode {
+		prev := acc.code
+		db.journal = append(db.journal, func() { acc.code = prev })
+		acc.code = code
+		return
+	}
+	db.NonCommittableStateDB.SetCode(addr, code)
+}
+
+func (db *overrideStateDB) GetCodeSize(addr common.Address) int {
+	if acc, ok := db.accounts[addr]; ok && acc.hasCode {
+		return len(acc.code)
+	}
+	return db.NonCommittableStateDB.GetCodeSize(addr)
+}
+
+func (db *overrideStateDB) Snapshot() int {
+	id := db.NonCommittableStateDB.Snapshot()
+	db.snapshots[id] = len(db.journal)
+	return id
+}
+
+func (db *overrideStateDB) RevertToSnapshot(id int) {
+	if n, ok := db.snapshots[id]; ok {
+		for i := len(db.journal) - 1; i >= n; i-- {
+			db.journal[i]()
+		}
+		db.journal = db.journal[:n]
+	}
+	db.NonCommittableStateDB.RevertToSnapshot(id)
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/go-opera/ethapi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var overrideContract = common.HexToAddress("0xc0de")
+
+const (
+	returnSlotZeroCode  = "0x60005460005260206000f3" // returns storage slot 0
+	returnBalanceCode   = "0x4760005260206000f3"     // returns its own balance
+	returnTimestampCode = "0x4260005260206000f3"     // returns the block timestamp
+)
+
+// makeOverrideTestCall returns an eth_call of the override contract with the given overrides.
+func makeOverrideTestCall(stateOverride map[string]interface{}, blockOverrides map[string]interface{}) *RequestAndResults {
+	return &RequestAndResults{
+		Query: &Body{
+			MethodBase: "call",
+			Params: []interface{}{
+				map[string]interface{}{"to": overrideContract.Hex()},
+				"latest",
+				stateOverride,
+				blockOverrides,
+			},
+		},
+		Timestamp: 1,
+	}
+}
+
+// executeOverrideTestCall executes a call and returns its result as a number.
+func executeOverrideTestCall(t *testing.T, req *RequestAndResults) *big.Int {
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+	cfg := &utils.Config{ChainID: utils.MainnetChainID}
+
+	res, err := Execute(1, req, archive, nil, nil, nil, cfg).GetRawResult()
+	if err != nil {
+		t.Fatalf("cannot execute call; %v", err)
+	}
+	if got := archive.GetCodeSize(overrideContract); got != 0 {
+		t.Errorf("archive must not be modified by overrides; code size %v", got)
+	}
+	return new(big.Int).SetBytes(res)
+}
+
+func TestExecute_CallWithCodeAndStorageOverrides(t *testing.T) {
+	req := makeOverrideTestCall(map[string]interface{}{
+		overrideContract.Hex(): map[string]interface{}{
+			"code":  returnSlotZeroCode,
+			"state": map[string]interface{}{common.Hash{}.Hex(): common.BigToHash(big.NewInt(42)).Hex()},
+		},
+	}, nil)
+
+	if got := executeOverrideTestCall(t, req); got.Int64() != 42 {
+		t.Errorf("unexpected result; got %v, want 42", got)
+	}
+}
+
+func TestExecute_CallWithBalanceOverride(t *testing.T) {
+	req := makeOverrideTestCall(map[string]interface{}{
+		overrideContract.Hex(): map[string]interface{}{"code": returnBalanceCode, "balance": "0x64"},
+	}, nil)
+
+	if got := executeOverrideTestCall(t, req); got.Int64() != 100 {
+		t.Errorf("unexpected result; got %v, want 100", got)
+	}
+}
+
+func TestExecute_CallWithBlockOverrides(t *testing.T) {
+	req := makeOverrideTestCall(map[string]interface{}{
+		overrideContract.Hex(): map[string]interface{}{"code": returnTimestampCode},
+	}, map[string]interface{}{"time": "0x99"})
+
+	if got := executeOverrideTestCall(t, req); got.Int64() != 0x99 {
+		t.Errorf("unexpected result; got %v, want %v", got, 0x99)
+	}
+}
+
+func TestExecute_CallWithStateAndStateDiffOverrideFails(t *testing.T) {
+	req := makeOverrideTestCall(map[string]interface{}{
+		overrideContract.Hex(): map[string]interface{}{
+			"state":     map[string]interface{}{},
+			"stateDiff": map[string]interface{}{},
+		},
+	}, nil)
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+
+	_, err := Execute(1, req, archive, nil, nil, nil, &utils.Config{ChainID: utils.MainnetChainID}).GetRawResult()
+	if err == nil {
+		t.Error("overriding both state and stateDiff must fail")
+	}
+}
+
+func TestOverrideStateDB_StateDiffKeepsOtherSlots(t *testing.T) {
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+	slotA, slotB := common.HexToHash("0xa"), common.HexToHash("0xb")
+	archive.SetState(overrideContract, slotB, common.HexToHash("0x2"))
+
+	diff := map[common.Hash]common.Hash{slotA: common.HexToHash("0x1")}
+	db := newOverrideStateDB(archive, ethapi.StateOverride{overrideContract: {StateDiff: &diff}})
+	if got := db.GetState(overrideContract, slotA); got != common.HexToHash("0x1") {
+		t.Errorf("unexpected overridden slot %v", got)
+	}
+	if got := db.GetState(overrideContract, slotB); got != common.HexToHash("0x2") {
+		t.Errorf("unexpected archive slot %v", got)
+	}
+
+	state := map[common.Hash]common.Hash{slotA: common.HexToHash("0x1")}
+	db = newOverrideStateDB(archive, ethapi.StateOverride{overrideContract: {State: &state}})
+	if got := db.GetState(overrideContract, slotB); got != (common.Hash{}) {
+		t.Errorf("slots not in overridden state must be empty; got %v", got)
+	}
+}
+
+func TestOverrideStateDB_RevertRestoresOverriddenFields(t *testing.T) {
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+	balance := (*hexutil.Big)(big.NewInt(10))
+	state := map[common.Hash]common.Hash{}
+	db := newOverrideStateDB(archive, ethapi.StateOverride{overrideContract: {Balance: &balance, State: &state}})
+
+	id := db.Snapshot()
+	db.AddBalance(overrideContract, big.NewInt(5))
+	db.SetState(overrideContract, common.HexToHash("0x1"), common.HexToHash("0x1"))
+	if got := db.GetBalance(overrideContract); got.Int64() != 15 {
+		t.Errorf("unexpected balance %v", got)
+	}
+	db.RevertToSnapshot(id)
+
+	if got := db.GetBalance(overrideContract); got.Int64() != 10 {
+		t.Errorf("balance must be reverted; got %v", got)
+	}
+	if got := db.GetState(overrideContract, common.HexToHash("0x1")); got != (common.Hash{}) {
+		t.Errorf("storage must be reverted; got %v", got)
+	}
+	if got := archive.GetBalance(overrideContract); got.Sign() != 0 {
+		t.Errorf("archive must not be modified; balance %v", got)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// ProofJSON is the result of a getProof request (EIP-1186). Replayed results contain
+// only the account and storage values since no archive state is able to create proofs.
+type ProofJSON struct {
+	Address      common.Address     `json:"address"`
+	AccountProof []string           `json:"accountProof,omitempty"`
+	Balance      *hexutil.Big       `json:"balance"`
+	CodeHash     common.Hash        `json:"codeHash"`
+	Nonce        hexutil.Uint64     `json:"nonce"`
+	StorageHash  *common.Hash       `json:"storageHash,omitempty"`
+	StorageProof []StorageProofJSON `json:"storageProof"`
+}
+
+// StorageProofJSON is the proof of a storage slot in the result of a getProof request.
+type StorageProofJSON struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof,omitempty"`
+}
+
+// executeGetProof request into given archive and send result to comparator. The result
+// contains no proofs, hence the comparator validates the values and counts the recorded
+// proofs as not compared.
+func executeGetProof(params []interface{}, archive state.NonCommittableStateDB) *result {
+	if len(params) < 2 {
+		return &result{err: fmt.Errorf("missing address or storage keys")}
+	}
+	address := common.HexToAddress(params[0].(string))
+	keys, ok := params[1].([]interface{})
+	if !ok {
+		return &result{err: fmt.Errorf("storage keys are not a list")}
+	}
+
+	proof := ProofJSON{
+		Address:      address,
+		Balance:      (*hexutil.Big)(archive.GetBalance(address)),
+		CodeHash:     archive.GetCodeHash(address),
+		Nonce:        hexutil.Uint64(archive.GetNonce(address)),
+		StorageProof: make([]StorageProofJSON, 0, len(keys)),
+	}
+	for _, k := range keys {
+		key, ok := k.(string)
+		if !ok {
+			return &result{err: fmt.Errorf("storage key %v is not a string", k)}
+		}
+		hash := common.HexToHash(key)
+		proof.StorageProof = append(proof.StorageProof, StorageProofJSON{
+			Key:   key,
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(archive.GetState(address, hash).Bytes())),
+		})
+	}
+
+	res, err := json.Marshal(proof)
+	return &result{
+		result: res,
+		err:    err,
+	}
+}
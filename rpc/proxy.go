@@ -0,0 +1,230 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// HeadSource returns the number and the timestamp (in seconds) of the head block of an endpoint.
+type HeadSource func() (block uint64, timestamp uint64, err error)
+
+// RecordingProxy is a reverse proxy forwarding JSON-RPC requests to an upstream endpoint
+// and recording the requests together with their responses. A request is recorded only if
+// the head block of the upstream did not change while the request was served, hence the
+// block the request was executed in is known.
+type RecordingProxy struct {
+	upstream string
+	client   *http.Client
+	head     HeadSource
+	writer   *Writer
+	log      logger.Logger
+}
+
+// NewRecordingProxy creates a proxy of the upstream endpoint recording into the given writer.
+func NewRecordingProxy(upstream string, writer *Writer, log logger.Logger) *RecordingProxy {
+	p := &RecordingProxy{
+		upstream: upstream,
+		client:   &http.Client{Timeout: time.Minute},
+		writer:   writer,
+		log:      log,
+	}
+	p.head = p.upstreamHead
+	return p
+}
+
+// timestampMethods are the methods whose replay requires the timestamp of their block.
+var timestampMethods = map[string]bool{
+	"call":        true,
+	"estimateGas": true,
+	"traceCall":   true,
+}
+
+// proxyRequest is a JSON-RPC request passing through the proxy.
+type proxyRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// proxyResponse is a JSON-RPC response passing through the proxy.
+type proxyResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *ErrorMessage   `json:"error"`
+}
+
+// ServeHTTP forwards a request to the upstream, relays its response and records both.
+func (p *RecordingProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxServerRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	blockBefore, timestamp, headErr := p.head()
+
+	resp, err := p.client.Post(p.upstream, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot reach upstream; %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot read upstream response; %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.Header.Get("Content-Type"))
+	w.WriteHeader(resp.StatusCode)
+	if _, err = w.Write(response); err != nil {
+		p.log.Errorf("cannot relay response; %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return
+	}
+	if headErr != nil {
+		p.log.Warningf("request not recorded; cannot get head block; %v", headErr)
+		return
+	}
+	blockAfter, _, err := p.head()
+	if err != nil || blockAfter != blockBefore {
+		p.log.Debugf("request not recorded; head block changed while serving it")
+		return
+	}
+	if err = p.record(payload, response, blockBefore, timestamp); err != nil {
+		p.log.Warningf("request not recorded; %v", err)
+	}
+}
+
+// record writes the pairs of requests and responses of a single or a batch call.
+func (p *RecordingProxy) record(payload, response []byte, block, timestamp uint64) error {
+	var (
+		requests  []proxyRequest
+		responses []proxyResponse
+	)
+	if strings.HasPrefix(strings.TrimSpace(string(payload)), "[") {
+		if err := json.Unmarshal(payload, &requests); err != nil {
+			return fmt.Errorf("cannot decode requests; %v", err)
+		}
+		if err := json.Unmarshal(response, &responses); err != nil {
+			return fmt.Errorf("cannot decode responses; %v", err)
+		}
+	} else {
+		requests, responses = make([]proxyRequest, 1), make([]proxyResponse, 1)
+		if err := json.Unmarshal(payload, &requests[0]); err != nil {
+			return fmt.Errorf("cannot decode request; %v", err)
+		}
+		if err := json.Unmarshal(response, &responses[0]); err != nil {
+			return fmt.Errorf("cannot decode response; %v", err)
+		}
+	}
+
+	// responses of batches may come in any order
+	byID := make(map[string]proxyResponse, len(responses))
+	for _, res := range responses {
+		byID[string(res.ID)] = res
+	}
+
+	for _, req := range requests {
+		res, found := byID[string(req.ID)]
+		namespace, method, ok := strings.Cut(req.Method, "_")
+		if !found || !ok || !CanRecord(namespace, method) {
+			continue
+		}
+		if timestamp == 0 && timestampMethods[method] {
+			p.log.Warningf("request %v not recorded; timestamp of block %v is unknown", req.Method, block)
+			continue
+		}
+		params := []byte(req.Params)
+		if len(params) == 0 {
+			params = []byte("[]")
+		}
+
+		rec := &RequestAndResults{
+			Query:     &Body{ID: req.ID, Method: req.Method, Namespace: namespace, MethodBase: method},
+			ParamsRaw: params,
+		}
+		// recordings keep block timestamps in nanoseconds
+		ts := uint64(time.Unix(int64(timestamp), 0).UnixNano())
+		if res.Error != nil {
+			rec.Error = &ErrorResponse{Id: req.ID, BlockID: block, Timestamp: ts, Error: *res.Error}
+		} else {
+			rec.Response = &Response{ID: req.ID, BlockID: block, Timestamp: ts, Result: res.Result}
+		}
+		if err := p.writer.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upstreamHead returns the head block of the upstream. Endpoints without block headers,
+// such as the archive server of aida-rpc without a substate DB, report the block number
+// with a zero timestamp.
+func (p *RecordingProxy) upstreamHead() (uint64, uint64, error) {
+	var block struct {
+		Number    hexutil.Uint64 `json:"number"`
+		Timestamp hexutil.Uint64 `json:"timestamp"`
+	}
+	if err := p.call("eth_getBlockByNumber", []interface{}{"latest", false}, &block); err == nil {
+		return uint64(block.Number), uint64(block.Timestamp), nil
+	}
+
+	var number hexutil.Uint64
+	if err := p.call("eth_blockNumber", []interface{}{}, &number); err != nil {
+		return 0, 0, err
+	}
+	return uint64(number), 0, nil
+}
+
+// call sends a request to the upstream and decodes its result into res.
+func (p *RecordingProxy) call(method string, params []interface{}, res interface{}) error {
+	req, err := json.Marshal(utils.JsonRPCRequest{Method: method, Params: params, ID: 1, JSONRPC: "2.0"})
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Post(p.upstream, "application/json", bytes.NewReader(req))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response proxyResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("cannot decode %v response; %v", method, err)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%v failed; %v", method, response.Error.Message)
+	}
+	if len(response.Result) == 0 || string(response.Result) == "null" {
+		return fmt.Errorf("%v returned no result", method)
+	}
+	return json.Unmarshal(response.Result, res)
+}
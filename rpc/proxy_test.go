@@ -0,0 +1,162 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/logger"
+)
+
+// makeProxyTestUpstream returns an endpoint answering head blocks, balances and failing calls.
+func makeProxyTestUpstream(t *testing.T, head *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req proxyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot decode request; %v", err)
+			return
+		}
+		var res string
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			res = `{"jsonrpc":"2.0","id":1,"result":{"number":"0x` + string(rune('0'+*head)) + `","timestamp":"0x10"}}`
+		case "eth_getBalance":
+			res = `{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":"0xff"}`
+		default:
+			res = `{"jsonrpc":"2.0","id":` + string(req.ID) + `,"error":{"code":-32000,"message":"execution reverted"}}`
+		}
+		_, _ = w.Write([]byte(res))
+	}))
+}
+
+// readProxyTestRecording returns the requests of a recording.
+func readProxyTestRecording(t *testing.T, recording []byte) []*RequestAndResults {
+	iter := newIterator(context.Background(), io.NopCloser(bytes.NewReader(recording)), 10)
+	defer iter.Close()
+	var recs []*RequestAndResults
+	for iter.Next() {
+		recs = append(recs, iter.Value())
+	}
+	if iter.Error() != nil {
+		t.Fatalf("cannot read recording; %v", iter.Error())
+	}
+	return recs
+}
+
+func TestRecordingProxy_RecordsRequestsReadableByIterator(t *testing.T) {
+	head := 5
+	upstream := makeProxyTestUpstream(t, &head)
+	defer upstream.Close()
+
+	recording := new(bytes.Buffer)
+	proxy := NewRecordingProxy(upstream.URL, NewWriter(recording), logger.NewLogger("ERROR", "test"))
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000001","latest"]}`,
+		`{"jsonrpc":"2.0","id":2,"method":"eth_call","params":[{"to":"0x0000000000000000000000000000000000000001"},"latest"]}`,
+		`{"jsonrpc":"2.0","id":3,"method":"eth_chainId","params":[]}`,
+	} {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(req)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %v", rec.Code)
+		}
+	}
+
+	recs := readProxyTestRecording(t, recording.Bytes())
+	if len(recs) != 2 {
+		t.Fatalf("unexpected number of recorded requests; got: %v, want: 2", len(recs))
+	}
+
+	balance := recs[0]
+	balance.DecodeInfo()
+	if balance.Query.MethodBase != "getBalance" || len(balance.Query.Params) != 2 {
+		t.Errorf("unexpected query %v", balance.Query)
+	}
+	if string(balance.Response.Result) != `"0xff"` {
+		t.Errorf("unexpected result %v", string(balance.Response.Result))
+	}
+	if balance.RecordedBlock != 5 || balance.RequestedBlock != 5 || balance.Timestamp != 16 {
+		t.Errorf("unexpected block %v (requested %v) or timestamp %v", balance.RecordedBlock, balance.RequestedBlock, balance.Timestamp)
+	}
+
+	call := recs[1]
+	if call.Query.MethodBase != "call" || call.Error == nil || call.Error.Error.Code != -32000 {
+		t.Errorf("unexpected recorded call %v", call.Error)
+	}
+}
+
+func TestRecordingProxy_SkipsRequestsIfHeadChanges(t *testing.T) {
+	head := 5
+	upstream := makeProxyTestUpstream(t, &head)
+	defer upstream.Close()
+
+	recording := new(bytes.Buffer)
+	proxy := NewRecordingProxy(upstream.URL, NewWriter(recording), logger.NewLogger("ERROR", "test"))
+	heads := proxy.head
+	proxy.head = func() (uint64, uint64, error) {
+		block, timestamp, err := heads()
+		head++
+		return block, timestamp, err
+	}
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(
+		`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000001","latest"]}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "0xff") {
+		t.Fatalf("response must be relayed; got %v", rec.Body.String())
+	}
+	if recording.Len() != 0 {
+		t.Error("request must not be recorded")
+	}
+}
+
+func TestRecordingProxy_SkipsCallsWithoutTimestamp(t *testing.T) {
+	head := 5
+	upstream := makeProxyTestUpstream(t, &head)
+	defer upstream.Close()
+
+	recording := new(bytes.Buffer)
+	proxy := NewRecordingProxy(upstream.URL, NewWriter(recording), logger.NewLogger("ERROR", "test"))
+	// upstream without block headers
+	proxy.head = func() (uint64, uint64, error) {
+		return uint64(head), 0, nil
+	}
+
+	for _, req := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000001","latest"]}`,
+		`{"jsonrpc":"2.0","id":2,"method":"eth_call","params":[{"to":"0x0000000000000000000000000000000000000001"},"latest"]}`,
+	} {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(req)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("unexpected status %v", rec.Code)
+		}
+	}
+
+	recs := readProxyTestRecording(t, recording.Bytes())
+	if len(recs) != 1 || recs[0].Query.MethodBase != "getBalance" {
+		t.Fatalf("only the balance request must be recorded; got %v", recs)
+	}
+}
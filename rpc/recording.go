@@ -0,0 +1,587 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/klauspost/compress/gzip"
+)
+
+// maxJSONLineSize is the longest line of a JSON lines recording.
+const maxJSONLineSize = 64 * 1024 * 1024
+
+// RecordingWriter writes requests into a recording.
+type RecordingWriter interface {
+	Write(rec *RequestAndResults) error
+	Close() error
+}
+
+// IsJSONRecording returns true if the path is a JSON lines recording, i.e. ends with .jsonl or .jsonl.gz.
+func IsJSONRecording(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(strings.ToLower(path), ".gz"), ".jsonl")
+}
+
+// OpenRecording opens a binary or JSON lines recording file depending on its extension.
+func OpenRecording(ctx context.Context, path string) (Iterator, error) {
+	if !IsJSONRecording(path) {
+		return NewFileReader(ctx, path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var in io.ReadCloser = f
+	if strings.EqualFold(filepath.Ext(path), ".gz") {
+		if in, err = gzip.NewReader(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return newJSONIterator(in, f), nil
+}
+
+// RecordingFiles iterates the requests of a recording file or of all recording files
+// of a directory in order of their names.
+type RecordingFiles struct {
+	ctx   context.Context
+	files []string
+	file  int // index of the currently iterated file
+	iter  Iterator
+	err   error
+}
+
+// OpenRecordingFiles opens the recording file or the directory of recording files at the given path.
+func OpenRecordingFiles(ctx context.Context, path string) (*RecordingFiles, error) {
+	files := []string{path}
+	if info, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("cannot stat the rpc path; %w", err)
+	} else if info.IsDir() {
+		if files, err = utils.GetDirectoryFiles("", path); err != nil {
+			return nil, fmt.Errorf("cannot get files from dir %v; %w", path, err)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recording files in %v", path)
+	}
+	r := &RecordingFiles{ctx: ctx, files: files}
+	if err := r.open(0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Files returns the iterated recording files.
+func (r *RecordingFiles) Files() []string {
+	return r.files
+}
+
+// File returns the index of the file of the current request.
+func (r *RecordingFiles) File() int {
+	return r.file
+}
+
+func (r *RecordingFiles) open(file int) error {
+	iter, err := OpenRecording(r.ctx, r.files[file])
+	if err != nil {
+		return fmt.Errorf("cannot open rpc recording file %v; %w", r.files[file], err)
+	}
+	r.file, r.iter = file, iter
+	return nil
+}
+
+func (r *RecordingFiles) Next() bool {
+	for r.err == nil {
+		if r.iter.Next() {
+			return true
+		}
+		if r.err = r.iter.Error(); r.err != nil || r.file+1 >= len(r.files) {
+			return false
+		}
+		// the exhausted iterator is closed once the next file is open, otherwise by Close
+		prev := r.iter
+		if r.err = r.open(r.file + 1); r.err == nil {
+			prev.Close()
+		}
+	}
+	return false
+}
+
+func (r *RecordingFiles) Value() *RequestAndResults {
+	return r.iter.Value()
+}
+
+func (r *RecordingFiles) Close() {
+	r.iter.Close()
+}
+
+func (r *RecordingFiles) Error() error {
+	return r.err
+}
+
+// CreateRecording creates a binary or JSON lines recording file depending on its extension.
+// The recording is gzipped if the file has the .gz extension.
+func CreateRecording(path string) (RecordingWriter, error) {
+	w, err := NewFileWriter(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsJSONRecording(path) {
+		return w, nil
+	}
+	return &jsonWriter{out: w.out, closers: w.closers}, nil
+}
+
+// jsonRecord is a request and its response or error in a JSON lines recording.
+type jsonRecord struct {
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+	Block     uint64          `json:"block"`
+	Timestamp uint64          `json:"timestamp"` // block timestamp in nanoseconds
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *ErrorMessage   `json:"error,omitempty"`
+}
+
+// jsonWriter writes requests as JSON lines.
+type jsonWriter struct {
+	out     io.Writer
+	closers []io.Closer
+}
+
+func (w *jsonWriter) Write(rec *RequestAndResults) error {
+	if !CanRecord(rec.Query.Namespace, rec.Query.MethodBase) {
+		return nil
+	}
+	r := jsonRecord{
+		Method:    rec.Query.Method,
+		Params:    rec.ParamsRaw,
+		Block:     RecordedBlock(rec),
+		Timestamp: recordedTimestamp(rec),
+	}
+	switch {
+	case rec.Error != nil:
+		r.Error = &rec.Error.Error
+	case rec.Response != nil:
+		r.Result = rec.Response.Result
+	default:
+		return fmt.Errorf("request %v has neither response nor error", rec.Query.Method)
+	}
+	if len(r.Params) == 0 {
+		r.Params = json.RawMessage("[]")
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("cannot encode request; %v", err)
+	}
+	if _, err = w.out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("cannot write request; %v", err)
+	}
+	return nil
+}
+
+func (w *jsonWriter) Close() error {
+	for _, c := range w.closers {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	w.closers = nil
+	return nil
+}
+
+// jsonIterator reads requests from JSON lines.
+type jsonIterator struct {
+	scanner *bufio.Scanner
+	closers []io.Closer
+	item    *RequestAndResults
+	line    int
+	err     error
+}
+
+func newJSONIterator(in io.ReadCloser, f io.Closer) *jsonIterator {
+	s := bufio.NewScanner(in)
+	s.Buffer(make([]byte, 64*1024), maxJSONLineSize)
+	closers := []io.Closer{in}
+	if in != f {
+		closers = append(closers, f)
+	}
+	return &jsonIterator{scanner: s, closers: closers}
+}
+
+func (i *jsonIterator) Next() bool {
+	i.item = nil
+	if i.err != nil {
+		return false
+	}
+	for i.scanner.Scan() {
+		i.line++
+		line := i.scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		if i.item, i.err = decodeJSONRecord(line); i.err != nil {
+			i.err = fmt.Errorf("cannot decode line %v; %v", i.line, i.err)
+			return false
+		}
+		return true
+	}
+	i.err = i.scanner.Err()
+	return false
+}
+
+func (i *jsonIterator) Value() *RequestAndResults {
+	return i.item
+}
+
+func (i *jsonIterator) Close() {
+	for _, c := range i.closers {
+		_ = c.Close()
+	}
+	i.closers = nil
+}
+
+func (i *jsonIterator) Error() error {
+	return i.err
+}
+
+// decodeJSONRecord decodes a line of a JSON lines recording the same way a binary recording is decoded.
+func decodeJSONRecord(line []byte) (*RequestAndResults, error) {
+	var r jsonRecord
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, err
+	}
+	namespace, method, found := strings.Cut(r.Method, "_")
+	if !found || !CanRecord(namespace, method) {
+		return nil, fmt.Errorf("unsupported method %q", r.Method)
+	}
+
+	req := &RequestAndResults{
+		Query: &Body{
+			Namespace:  namespace,
+			MethodBase: method,
+			Method:     r.Method,
+		},
+		ParamsRaw: []byte(r.Params),
+	}
+	if err := json.Unmarshal(req.ParamsRaw, &req.Query.Params); err != nil {
+		return nil, fmt.Errorf("cannot decode params; %v", err)
+	}
+	if r.Error != nil {
+		req.Error = &ErrorResponse{BlockID: r.Block, Timestamp: r.Timestamp, Error: *r.Error}
+	} else {
+		req.ResponseRaw = []byte(r.Result)
+		req.Response = &Response{BlockID: r.Block, Timestamp: r.Timestamp, Result: req.ResponseRaw}
+	}
+	return req, nil
+}
+
+// RecordedBlock returns the block in which a request was recorded.
+func RecordedBlock(rec *RequestAndResults) uint64 {
+	if rec.Response != nil {
+		return rec.Response.BlockID
+	}
+	if rec.Error != nil {
+		return rec.Error.BlockID
+	}
+	return 0
+}
+
+// recordedTimestamp returns the timestamp of the block in which a request was recorded in nanoseconds.
+func recordedTimestamp(rec *RequestAndResults) uint64 {
+	if rec.Response != nil {
+		return rec.Response.Timestamp
+	}
+	if rec.Error != nil {
+		return rec.Error.Timestamp
+	}
+	return 0
+}
+
+// RecordingFilter selects recorded requests by their method and recorded block.
+type RecordingFilter struct {
+	Methods    []string // method base (e.g. call) or full method (e.g. eth_call); all methods if empty
+	FirstBlock uint64
+	LastBlock  uint64 // inclusive
+}
+
+// NewRecordingFilter creates a filter of all requests of the given methods.
+func NewRecordingFilter(methods ...string) *RecordingFilter {
+	return &RecordingFilter{Methods: methods, LastBlock: math.MaxUint64}
+}
+
+// Matches returns true if the request is selected by the filter.
+func (f *RecordingFilter) Matches(rec *RequestAndResults) bool {
+	if block := RecordedBlock(rec); block < f.FirstBlock || block > f.LastBlock {
+		return false
+	}
+	return len(f.Methods) == 0 || containsAny(f.Methods, rec.Query.MethodBase, rec.Query.Method)
+}
+
+// CopyRecording writes the requests selected by the filter into the writer and returns
+// the number of written requests. All requests are written if the filter is nil.
+func CopyRecording(iter Iterator, w RecordingWriter, filter *RecordingFilter) (int, error) {
+	n := 0
+	for iter.Next() {
+		rec := iter.Value()
+		if filter != nil && !filter.Matches(rec) {
+			continue
+		}
+		if err := w.Write(rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, iter.Error()
+}
+
+// SplitRecording distributes the requests into consecutive recordings created by the create function
+// with increasing indexes. A new recording is started after the given number of requests or once a
+// request is recorded the given number of blocks after the first request of the current recording;
+// zero disables the respective limit. The number of created recordings is returned.
+func SplitRecording(iter Iterator, requests int, blocks uint64, create func(index int) (RecordingWriter, error)) (int, error) {
+	var (
+		w          RecordingWriter
+		files      int
+		n          int
+		firstBlock uint64
+	)
+	closeCurrent := func() error {
+		if w == nil {
+			return nil
+		}
+		err := w.Close()
+		w = nil
+		return err
+	}
+
+	for iter.Next() {
+		rec := iter.Value()
+		block := RecordedBlock(rec)
+		if w != nil && ((requests > 0 && n >= requests) || (blocks > 0 && block >= firstBlock+blocks)) {
+			if err := closeCurrent(); err != nil {
+				return files, err
+			}
+		}
+		if w == nil {
+			var err error
+			if w, err = create(files); err != nil {
+				return files, err
+			}
+			files++
+			n = 0
+			firstBlock = block
+		}
+		if err := w.Write(rec); err != nil {
+			closeCurrent()
+			return files, err
+		}
+		n++
+	}
+	if err := closeCurrent(); err != nil {
+		return files, err
+	}
+	return files, iter.Error()
+}
+
+// MergeRecordings writes the requests of all recordings ordered by their recorded block into the writer.
+// Requests of the same block keep the order of the recordings. The number of written requests is returned.
+func MergeRecordings(iters []Iterator, w RecordingWriter) (int, error) {
+	h := make(mergeHeap, 0, len(iters))
+	for i, iter := range iters {
+		if iter.Next() {
+			h = append(h, mergeItem{iter: iter, index: i})
+		} else if err := iter.Error(); err != nil {
+			return 0, err
+		}
+	}
+	heap.Init(&h)
+
+	n := 0
+	for h.Len() > 0 {
+		item := h[0]
+		if err := w.Write(item.iter.Value()); err != nil {
+			return n, err
+		}
+		n++
+		if item.iter.Next() {
+			heap.Fix(&h, 0)
+			continue
+		}
+		if err := item.iter.Error(); err != nil {
+			return n, err
+		}
+		heap.Pop(&h)
+	}
+	return n, nil
+}
+
+// mergeItem is a recording positioned at its next request.
+type mergeItem struct {
+	iter  Iterator
+	index int
+}
+
+// mergeHeap orders recordings by the block of their next request and their index.
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	bi, bj := RecordedBlock(h[i].iter.Value()), RecordedBlock(h[j].iter.Value())
+	if bi != bj {
+		return bi < bj
+	}
+	return h[i].index < h[j].index
+}
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// MethodRecordingStats are the statistics of the recorded requests of a method.
+type MethodRecordingStats struct {
+	Method          string
+	Requests        int
+	Errors          int
+	ErrorCodes      map[int]int
+	QueryBytes      int64
+	ResponseBytes   int64
+	MaxResponseSize int
+}
+
+// RecordingStats are the statistics of a recording.
+type RecordingStats struct {
+	Requests       int
+	Errors         int
+	FirstBlock     uint64
+	LastBlock      uint64
+	FirstTimestamp uint64 // nanoseconds
+	LastTimestamp  uint64 // nanoseconds
+	QueryBytes     int64
+	ResponseBytes  int64
+	methods        map[string]*MethodRecordingStats
+}
+
+// NewRecordingStats creates empty recording statistics.
+func NewRecordingStats() *RecordingStats {
+	return &RecordingStats{methods: make(map[string]*MethodRecordingStats)}
+}
+
+// Add accounts a recorded request in the statistics.
+func (s *RecordingStats) Add(rec *RequestAndResults) {
+	block, ts := RecordedBlock(rec), recordedTimestamp(rec)
+	if s.Requests == 0 || block < s.FirstBlock {
+		s.FirstBlock = block
+	}
+	if block > s.LastBlock {
+		s.LastBlock = block
+	}
+	if s.Requests == 0 || ts < s.FirstTimestamp {
+		s.FirstTimestamp = ts
+	}
+	if ts > s.LastTimestamp {
+		s.LastTimestamp = ts
+	}
+	s.Requests++
+
+	m, ok := s.methods[rec.Query.Method]
+	if !ok {
+		m = &MethodRecordingStats{Method: rec.Query.Method, ErrorCodes: make(map[int]int)}
+		s.methods[rec.Query.Method] = m
+	}
+	m.Requests++
+	m.QueryBytes += int64(len(rec.ParamsRaw))
+	s.QueryBytes += int64(len(rec.ParamsRaw))
+	if rec.Error != nil {
+		s.Errors++
+		m.Errors++
+		m.ErrorCodes[rec.Error.Error.Code]++
+	}
+	if rec.Response != nil {
+		size := len(rec.Response.Result)
+		m.ResponseBytes += int64(size)
+		s.ResponseBytes += int64(size)
+		if size > m.MaxResponseSize {
+			m.MaxResponseSize = size
+		}
+	}
+}
+
+// Methods returns the statistics of each method ordered by the number of requests.
+func (s *RecordingStats) Methods() []*MethodRecordingStats {
+	methods := make([]*MethodRecordingStats, 0, len(s.methods))
+	for _, m := range s.methods {
+		methods = append(methods, m)
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		if methods[i].Requests != methods[j].Requests {
+			return methods[i].Requests > methods[j].Requests
+		}
+		return methods[i].Method < methods[j].Method
+	})
+	return methods
+}
+
+// Log prints the statistics.
+func (s *RecordingStats) Log(log logger.Logger) {
+	if s.Requests == 0 {
+		log.Noticef("Recording contains no requests")
+		return
+	}
+	log.Noticef("Requests: %v; errors: %v (%.2f%%)", s.Requests, s.Errors, percent(s.Errors, s.Requests))
+	log.Noticef("Blocks: %v - %v; recorded %v - %v", s.FirstBlock, s.LastBlock,
+		time.Unix(0, int64(s.FirstTimestamp)).UTC().Format(time.RFC3339), time.Unix(0, int64(s.LastTimestamp)).UTC().Format(time.RFC3339))
+	log.Noticef("Payload: queries %v bytes; responses %v bytes", s.QueryBytes, s.ResponseBytes)
+	for _, m := range s.Methods() {
+		log.Noticef("%-25v requests: %10v (%6.2f%%) errors: %8v (%6.2f%%) avg query: %6v B avg response: %8v B max response: %9v B",
+			m.Method, m.Requests, percent(m.Requests, s.Requests), m.Errors, percent(m.Errors, m.Requests),
+			m.QueryBytes/int64(m.Requests), m.ResponseBytes/int64(m.Requests), m.MaxResponseSize)
+		codes := make([]int, 0, len(m.ErrorCodes))
+		for code := range m.ErrorCodes {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			log.Noticef("%-25v error code %v: %v", "", code, m.ErrorCodes[code])
+		}
+	}
+}
+
+// percent returns the percentage of part in total.
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(part) / float64(total)
+}
@@ -0,0 +1,260 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// makeRecordingTestRequest returns a recorded request of the given method; requests with
+// an error code are recorded as failed.
+func makeRecordingTestRequest(method string, block uint64, errCode int) *RequestAndResults {
+	params := []byte(`["0x0000000000000000000000000000000000000001","latest"]`)
+	req := &RequestAndResults{
+		Query:     &Body{Namespace: "eth", MethodBase: method, Method: "eth_" + method, Params: []interface{}{"0x0000000000000000000000000000000000000001", "latest"}},
+		ParamsRaw: params,
+	}
+	if errCode != 0 {
+		req.Error = &ErrorResponse{BlockID: block, Timestamp: block * 1e9, Error: ErrorMessage{Code: errCode}}
+	} else {
+		req.ResponseRaw = []byte(`"0x1"`)
+		req.Response = &Response{BlockID: block, Timestamp: block * 1e9, Result: req.ResponseRaw}
+	}
+	return req
+}
+
+// writeRecording writes the requests into a new recording file.
+func writeRecording(t *testing.T, path string, reqs ...*RequestAndResults) {
+	w, err := CreateRecording(path)
+	if err != nil {
+		t.Fatalf("cannot create recording; %v", err)
+	}
+	if _, err = CopyRecording(&sliceIterator{reqs: reqs}, w, nil); err != nil {
+		t.Fatalf("cannot write recording; %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("cannot close recording; %v", err)
+	}
+}
+
+// readRecording reads all requests of a recording file.
+func readRecording(t *testing.T, path string) []*RequestAndResults {
+	iter, err := OpenRecording(context.Background(), path)
+	if err != nil {
+		t.Fatalf("cannot open recording; %v", err)
+	}
+	defer iter.Close()
+
+	var reqs []*RequestAndResults
+	for iter.Next() {
+		reqs = append(reqs, iter.Value())
+	}
+	if err = iter.Error(); err != nil {
+		t.Fatalf("cannot read recording; %v", err)
+	}
+	return reqs
+}
+
+func TestRecording_ConvertBetweenBinaryAndJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	reqs := []*RequestAndResults{
+		makeRecordingTestRequest("getBalance", 10, 0),
+		makeRecordingTestRequest("getCode", 11, -32000),
+	}
+
+	for _, names := range [][2]string{{"rec.gz", "rec.jsonl"}, {"rec.bin", "rec.jsonl.gz"}} {
+		binary, jsonl := filepath.Join(dir, names[0]), filepath.Join(dir, names[1])
+		writeRecording(t, binary, reqs...)
+
+		// binary -> JSON lines -> binary
+		writeRecording(t, jsonl, readRecording(t, binary)...)
+		back := filepath.Join(dir, "back-"+names[0])
+		writeRecording(t, back, readRecording(t, jsonl)...)
+
+		for _, path := range []string{jsonl, back} {
+			got := readRecording(t, path)
+			if len(got) != len(reqs) {
+				t.Fatalf("%v: unexpected number of requests %v", path, len(got))
+			}
+			for i, req := range got {
+				// the binary format shares the namespace of eth and ftm, hence only the method base is preserved
+				if req.Query.MethodBase != reqs[i].Query.MethodBase || RecordedBlock(req) != RecordedBlock(reqs[i]) ||
+					recordedTimestamp(req) != recordedTimestamp(reqs[i]) || !reflect.DeepEqual(req.Query.Params, reqs[i].Query.Params) {
+					t.Errorf("%v: unexpected request %v %v", path, req.Query.Method, RecordedBlock(req))
+				}
+				if (req.Error == nil) != (reqs[i].Error == nil) {
+					t.Fatalf("%v: unexpected error %v", path, req.Error)
+				}
+				if req.Error != nil && req.Error.Error.Code != reqs[i].Error.Error.Code {
+					t.Errorf("%v: unexpected error code %v", path, req.Error.Error.Code)
+				}
+				if req.Response != nil && string(req.Response.Result) != string(reqs[i].Response.Result) {
+					t.Errorf("%v: unexpected result %s", path, req.Response.Result)
+				}
+			}
+		}
+	}
+}
+
+func TestRecording_FilterSelectsMethodsAndBlocks(t *testing.T) {
+	filter := NewRecordingFilter("getBalance", "eth_getCode")
+	filter.FirstBlock, filter.LastBlock = 10, 20
+
+	tests := []struct {
+		req  *RequestAndResults
+		want bool
+	}{
+		{makeRecordingTestRequest("getBalance", 10, 0), true},
+		{makeRecordingTestRequest("getCode", 20, -32000), true},
+		{makeRecordingTestRequest("getBalance", 21, 0), false},
+		{makeRecordingTestRequest("getBalance", 9, 0), false},
+		{makeRecordingTestRequest("call", 15, 0), false},
+	}
+	for _, test := range tests {
+		if got := filter.Matches(test.req); got != test.want {
+			t.Errorf("unexpected match of %v in block %v; got %v, want %v", test.req.Query.Method, RecordedBlock(test.req), got, test.want)
+		}
+	}
+}
+
+func TestRecording_SplitByRequestsAndBlocks(t *testing.T) {
+	tests := []struct {
+		requests int
+		blocks   uint64
+		want     []int // requests per file
+	}{
+		{requests: 2, want: []int{2, 2, 1}},
+		{blocks: 2, want: []int{2, 3}},
+		{requests: 2, blocks: 2, want: []int{2, 2, 1}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v-%v", test.requests, test.blocks), func(t *testing.T) {
+			dir := t.TempDir()
+			reqs := []*RequestAndResults{
+				makeRecordingTestRequest("getBalance", 1, 0),
+				makeRecordingTestRequest("getBalance", 2, 0),
+				makeRecordingTestRequest("getBalance", 3, 0),
+				makeRecordingTestRequest("getBalance", 3, 0),
+				makeRecordingTestRequest("getBalance", 4, 0),
+			}
+			files, err := SplitRecording(&sliceIterator{reqs: reqs}, test.requests, test.blocks, func(index int) (RecordingWriter, error) {
+				return CreateRecording(filepath.Join(dir, fmt.Sprintf("%v.jsonl", index)))
+			})
+			if err != nil {
+				t.Fatalf("cannot split recording; %v", err)
+			}
+			if files != len(test.want) {
+				t.Fatalf("unexpected number of files; got %v, want %v", files, len(test.want))
+			}
+			for i, want := range test.want {
+				if got := len(readRecording(t, filepath.Join(dir, fmt.Sprintf("%v.jsonl", i)))); got != want {
+					t.Errorf("unexpected number of requests in file %v; got %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRecording_MergeOrdersByBlock(t *testing.T) {
+	a := &sliceIterator{reqs: []*RequestAndResults{
+		makeRecordingTestRequest("getBalance", 1, 0),
+		makeRecordingTestRequest("getBalance", 3, 0),
+	}}
+	b := &sliceIterator{reqs: []*RequestAndResults{
+		makeRecordingTestRequest("getCode", 1, 0),
+		makeRecordingTestRequest("getCode", 2, 0),
+		makeRecordingTestRequest("getCode", 4, 0),
+	}}
+	path := filepath.Join(t.TempDir(), "merged.jsonl")
+	w, err := CreateRecording(path)
+	if err != nil {
+		t.Fatalf("cannot create recording; %v", err)
+	}
+	n, err := MergeRecordings([]Iterator{a, b}, w)
+	if err != nil {
+		t.Fatalf("cannot merge recordings; %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("cannot close recording; %v", err)
+	}
+	if n != 5 {
+		t.Errorf("unexpected number of merged requests %v", n)
+	}
+
+	want := []string{"eth_getBalance@1", "eth_getCode@1", "eth_getCode@2", "eth_getBalance@3", "eth_getCode@4"}
+	for i, req := range readRecording(t, path) {
+		if got := fmt.Sprintf("%v@%v", req.Query.Method, RecordedBlock(req)); got != want[i] {
+			t.Errorf("unexpected request %v; got %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestRecordingFiles_IteratesFilesOfDirectoryInOrder(t *testing.T) {
+	dir := t.TempDir()
+	writeRecording(t, filepath.Join(dir, "a.jsonl"), makeRecordingTestRequest("getBalance", 1, 0), makeRecordingTestRequest("getBalance", 2, 0))
+	writeRecording(t, filepath.Join(dir, "b.jsonl"))
+	writeRecording(t, filepath.Join(dir, "c.jsonl"), makeRecordingTestRequest("getCode", 3, 0))
+
+	iter, err := OpenRecordingFiles(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("cannot open recording files; %v", err)
+	}
+	defer iter.Close()
+
+	var got []string
+	for iter.Next() {
+		got = append(got, fmt.Sprintf("%v@%v in %v", iter.Value().Query.MethodBase, RecordedBlock(iter.Value()), iter.File()))
+	}
+	if err = iter.Error(); err != nil {
+		t.Fatalf("cannot iterate recording files; %v", err)
+	}
+	want := []string{"getBalance@1 in 0", "getBalance@2 in 0", "getCode@3 in 2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected requests; got %v, want %v", got, want)
+	}
+}
+
+func TestRecordingFiles_FailsOnEmptyDirectory(t *testing.T) {
+	if _, err := OpenRecordingFiles(context.Background(), t.TempDir()); err == nil {
+		t.Error("opening an empty directory must fail")
+	}
+}
+
+func TestRecordingStats_CountsMethodsErrorsAndSizes(t *testing.T) {
+	stats := NewRecordingStats()
+	stats.Add(makeRecordingTestRequest("getBalance", 5, 0))
+	stats.Add(makeRecordingTestRequest("getBalance", 3, -32000))
+	stats.Add(makeRecordingTestRequest("getCode", 7, 0))
+
+	if stats.Requests != 3 || stats.Errors != 1 {
+		t.Errorf("unexpected number of requests %v or errors %v", stats.Requests, stats.Errors)
+	}
+	if stats.FirstBlock != 3 || stats.LastBlock != 7 {
+		t.Errorf("unexpected block range %v-%v", stats.FirstBlock, stats.LastBlock)
+	}
+	methods := stats.Methods()
+	if len(methods) != 2 || methods[0].Method != "eth_getBalance" || methods[0].Requests != 2 || methods[0].ErrorCodes[-32000] != 1 {
+		t.Fatalf("unexpected method statistics %+v", methods)
+	}
+	if methods[0].ResponseBytes != 5 || methods[0].MaxResponseSize != 5 {
+		t.Errorf("unexpected response sizes %v, %v", methods[0].ResponseBytes, methods[0].MaxResponseSize)
+	}
+}
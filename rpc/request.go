@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -47,6 +48,28 @@ type Body struct {
 	MethodBase string
 }
 
+// TargetAddress returns the lower-case address targeted by the request, if any.
+func (b *Body) TargetAddress() string {
+	if len(b.Params) == 0 {
+		return ""
+	}
+	switch p := b.Params[0].(type) {
+	case string:
+		// getLogs filter by block hash, traceTransaction by transaction hash
+		if b.MethodBase != "getLogs" && b.MethodBase != "traceTransaction" {
+			return strings.ToLower(p)
+		}
+	case map[string]interface{}:
+		// call and estimateGas target "to", getLogs filter "address"
+		for _, field := range []string{"to", "address"} {
+			if s, ok := p[field].(string); ok {
+				return strings.ToLower(s)
+			}
+		}
+	}
+	return ""
+}
+
 // Response represents decoded request response body.
 type Response struct {
 	Version   string          `json:"jsonrpc,omitempty"`
@@ -92,7 +115,17 @@ func (r *RequestAndResults) findRequestedBlock() {
 		return
 	}
 
-	str := r.Query.Params[l-1].(string)
+	// the block of calls is followed by optional overrides or trace config
+	param := r.Query.Params[l-1]
+	if r.Query.MethodBase == "call" || r.Query.MethodBase == "traceCall" {
+		param = r.Query.Params[1]
+	}
+	str, ok := param.(string)
+	if !ok {
+		// blocks given by hash are not resolvable and traced transactions have no block
+		r.RequestedBlock = r.RecordedBlock
+		return
+	}
 	switch str {
 	case "pending":
 		// validation for pending requests does not work, skip them
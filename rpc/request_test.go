@@ -36,3 +36,41 @@ var r = &RequestAndResults{
 	},
 	SkipValidation: false,
 }
+
+func TestRequestAndResults_DecodeInfoCallWithOverridesUsesBlockParam(t *testing.T) {
+	req := &RequestAndResults{
+		Response: &Response{BlockID: 10},
+		Query: &Body{
+			MethodBase: "call",
+			Params: []interface{}{
+				map[string]interface{}{"to": "0x1"}, "0x5", map[string]interface{}{"0x1": map[string]interface{}{"balance": "0x1"}},
+			},
+		},
+	}
+	req.DecodeInfo()
+	if req.RequestedBlock != 5 {
+		t.Errorf("unexpected requested block; got %v, want 5", req.RequestedBlock)
+	}
+}
+
+func TestBody_TargetAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		body Body
+		want string
+	}{
+		{"account", Body{MethodBase: "getBalance", Params: []interface{}{"0xAbC", "latest"}}, "0xabc"},
+		{"call", Body{MethodBase: "call", Params: []interface{}{map[string]interface{}{"to": "0xAbC"}, "latest"}}, "0xabc"},
+		{"logFilter", Body{MethodBase: "getLogs", Params: []interface{}{map[string]interface{}{"address": "0xAbC"}}}, "0xabc"},
+		{"logBlockHash", Body{MethodBase: "getLogs", Params: []interface{}{"0x01"}}, ""},
+		{"tracedTransaction", Body{MethodBase: "traceTransaction", Params: []interface{}{"0x01"}}, ""},
+		{"noParams", Body{MethodBase: "blockNumber"}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.body.TargetAddress(); got != test.want {
+				t.Errorf("unexpected address; got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
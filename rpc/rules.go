@@ -0,0 +1,265 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of request rules.
+const (
+	SkipRule    = "skip"    // requests matching the rule are not validated
+	IncludeRule = "include" // only requests matching an include rule are validated
+)
+
+// falsyContract causes issues in validation, hence its calls are not validated by default.
+// TODO FIX!
+const falsyContract = "0xe0c38b2a8d09aad53f1c67734b9a95e43d5981c0"
+
+// defaultRules are applied before the rules of a rules file unless the file disables them.
+var defaultRules = []*Rule{
+	{Name: "falsy-contract", Action: SkipRule, Methods: []string{"call"}, Addresses: []string{falsyContract}},
+}
+
+// BlockRange is an inclusive range of blocks; an unset bound is open.
+type BlockRange struct {
+	From *uint64 `yaml:"from" json:"from"`
+	To   *uint64 `yaml:"to" json:"to"`
+}
+
+// Rule selects requests by their method, target address, function selector, recorded block and
+// recorded error code. A request matches a rule if it satisfies all given criteria; a criterion
+// given as a list is satisfied by any of its items.
+type Rule struct {
+	Name       string       `yaml:"name" json:"name"`
+	Action     string       `yaml:"action" json:"action"`
+	Methods    []string     `yaml:"methods" json:"methods"`       // method base (e.g. call) or full method (e.g. eth_call)
+	Addresses  []string     `yaml:"addresses" json:"addresses"`   // address of balances, code, storage and call targets
+	Selectors  []string     `yaml:"selectors" json:"selectors"`   // first four bytes of call data
+	Blocks     []BlockRange `yaml:"blocks" json:"blocks"`         // recorded block
+	ErrorCodes []int        `yaml:"errorCodes" json:"errorCodes"` // recorded error code
+}
+
+// Rules decide which recorded requests are validated and count how often each rule was hit.
+// It is safe for concurrent use.
+type Rules struct {
+	rules       []*Rule
+	hasInclude  bool
+	mutex       sync.Mutex
+	hits        map[*Rule]int
+	notIncluded int // requests matching no include rule
+}
+
+// ruleFile is the content of a rules file.
+type ruleFile struct {
+	Rules           []*Rule  `yaml:"rules" json:"rules"`
+	DisableDefaults []string `yaml:"disableDefaults" json:"disableDefaults"` // names of default rules which are not applied
+}
+
+// NewRules creates rules applying the default rules, except for the disabled ones,
+// followed by the given rules.
+func NewRules(rules []*Rule, disabledDefaults []string) (*Rules, error) {
+	disabled := make(map[string]bool, len(disabledDefaults))
+	for _, name := range disabledDefaults {
+		disabled[name] = true
+	}
+	var all []*Rule
+	for _, rule := range defaultRules {
+		if disabled[rule.Name] {
+			delete(disabled, rule.Name)
+			continue
+		}
+		// rules are normalized in place, hence the default rules are copied
+		c := *rule
+		c.Methods = append([]string{}, rule.Methods...)
+		c.Addresses = append([]string{}, rule.Addresses...)
+		all = append(all, &c)
+	}
+	for name := range disabled {
+		return nil, fmt.Errorf("cannot disable unknown default rule %v", name)
+	}
+
+	r := &Rules{hits: map[*Rule]int{}}
+	for i, rule := range append(all, rules...) {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i-len(all)+1)
+		}
+		switch rule.Action {
+		case SkipRule:
+		case IncludeRule:
+			r.hasInclude = true
+		default:
+			return nil, fmt.Errorf("rule %v has unknown action %q; use %q or %q", rule.Name, rule.Action, SkipRule, IncludeRule)
+		}
+		for j, a := range rule.Addresses {
+			rule.Addresses[j] = strings.ToLower(a)
+		}
+		for j, s := range rule.Selectors {
+			rule.Selectors[j] = strings.ToLower(s)
+		}
+		r.rules = append(r.rules, rule)
+	}
+	return r, nil
+}
+
+// LoadRules reads rules from a YAML or JSON file. Only the default rules are applied if the path is empty.
+func LoadRules(path string) (*Rules, error) {
+	if path == "" {
+		return NewRules(nil, nil)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rules file; %v", err)
+	}
+	// JSON is a subset of YAML
+	var file ruleFile
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse rules file %v; %v", path, err)
+	}
+	return NewRules(file.Rules, file.DisableDefaults)
+}
+
+// Skip returns true if the request must not be validated. A request is skipped if it matches
+// a skip rule, or if include rules are given and it matches none of them.
+func (r *Rules) Skip(req *RequestAndResults) bool {
+	var (
+		skip     bool
+		included bool
+		hits     []*Rule
+	)
+	for _, rule := range r.rules {
+		if !rule.Matches(req) {
+			continue
+		}
+		hits = append(hits, rule)
+		switch rule.Action {
+		case SkipRule:
+			skip = true
+		case IncludeRule:
+			included = true
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, rule := range hits {
+		r.hits[rule]++
+	}
+	if r.hasInclude && !included {
+		r.notIncluded++
+		return true
+	}
+	return skip
+}
+
+// RuleHits is the number of requests matching a rule.
+type RuleHits struct {
+	Name   string
+	Action string
+	Hits   int
+}
+
+// Hits returns the number of requests matching each rule in order of the rules and
+// the number of requests skipped because they matched no include rule.
+func (r *Rules) Hits() ([]RuleHits, int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	hits := make([]RuleHits, len(r.rules))
+	for i, rule := range r.rules {
+		hits[i] = RuleHits{Name: rule.Name, Action: rule.Action, Hits: r.hits[rule]}
+	}
+	return hits, r.notIncluded
+}
+
+// Matches returns true if the request satisfies all criteria of the rule.
+func (rule *Rule) Matches(req *RequestAndResults) bool {
+	if len(rule.Methods) > 0 && !containsAny(rule.Methods, req.Query.MethodBase, req.Query.Method) {
+		return false
+	}
+	if len(rule.Addresses) > 0 && !containsAny(rule.Addresses, req.Query.TargetAddress()) {
+		return false
+	}
+	if len(rule.Selectors) > 0 && !containsAny(rule.Selectors, requestSelector(req.Query)) {
+		return false
+	}
+	if len(rule.Blocks) > 0 {
+		block := uint64(req.RecordedBlock)
+		inRange := false
+		for _, b := range rule.Blocks {
+			if (b.From == nil || *b.From <= block) && (b.To == nil || block <= *b.To) {
+				inRange = true
+				break
+			}
+		}
+		if !inRange {
+			return false
+		}
+	}
+	if len(rule.ErrorCodes) > 0 {
+		if req.Error == nil {
+			return false
+		}
+		found := false
+		for _, code := range rule.ErrorCodes {
+			if code == req.Error.Error.Code {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// containsAny returns true if the list contains any of the non-empty values.
+func containsAny(list []string, values ...string) bool {
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		for _, item := range list {
+			if item == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestSelector returns the lower-case function selector of the call data of a request, if any.
+func requestSelector(q *Body) string {
+	if len(q.Params) == 0 {
+		return ""
+	}
+	p, ok := q.Params[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for _, field := range []string{"data", "input"} {
+		if data, ok := p[field].(string); ok && len(data) >= 10 {
+			return strings.ToLower(data[:10])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeRuleTestCall returns an eth_call request to the given address recorded in the given block.
+func makeRuleTestCall(to string, data string, block int) *RequestAndResults {
+	return &RequestAndResults{
+		Query: &Body{
+			Method:     "eth_call",
+			MethodBase: "call",
+			Params:     []interface{}{map[string]interface{}{"to": to, "data": data}, "latest"},
+		},
+		RecordedBlock: block,
+	}
+}
+
+func writeRulesFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("cannot write rules file; %v", err)
+	}
+	return path
+}
+
+func TestRules_LoadYaml(t *testing.T) {
+	path := writeRulesFile(t, "rules.yaml", `
+rules:
+  - name: transfers
+    action: skip
+    methods: [eth_call]
+    selectors: ["0xA9059CBB"]
+    blocks:
+      - from: 10
+        to: 20
+`)
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("cannot load rules; %v", err)
+	}
+
+	if !rules.Skip(makeRuleTestCall("0x1", "0xa9059cbb0000", 15)) {
+		t.Error("transfer in block range must be skipped")
+	}
+	if rules.Skip(makeRuleTestCall("0x1", "0xa9059cbb0000", 21)) {
+		t.Error("transfer out of block range must not be skipped")
+	}
+	if rules.Skip(makeRuleTestCall("0x1", "0x095ea7b30000", 15)) {
+		t.Error("call with another selector must not be skipped")
+	}
+
+	hits, notIncluded := rules.Hits()
+	if got, want := len(hits), len(defaultRules)+1; got != want {
+		t.Fatalf("unexpected number of rules; got %v, want %v", got, want)
+	}
+	if got := hits[len(hits)-1]; got.Name != "transfers" || got.Hits != 1 {
+		t.Errorf("unexpected hits %+v", got)
+	}
+	if notIncluded != 0 {
+		t.Errorf("unexpected number of not included requests %v", notIncluded)
+	}
+}
+
+func TestRules_LoadJson(t *testing.T) {
+	path := writeRulesFile(t, "rules.json", `{"rules": [{"action": "skip", "methods": ["getBalance"], "errorCodes": [-32000]}]}`)
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("cannot load rules; %v", err)
+	}
+
+	req := &RequestAndResults{
+		Query: &Body{Method: "eth_getBalance", MethodBase: "getBalance", Params: []interface{}{"0x1", "latest"}},
+		Error: &ErrorResponse{Error: ErrorMessage{Code: -32000}},
+	}
+	if !rules.Skip(req) {
+		t.Error("request with matching error code must be skipped")
+	}
+	req.Error.Error.Code = 3
+	if rules.Skip(req) {
+		t.Error("request with another error code must not be skipped")
+	}
+	req.Error = nil
+	if rules.Skip(req) {
+		t.Error("request without error must not be skipped")
+	}
+
+	hits, _ := rules.Hits()
+	if got := hits[len(hits)-1].Name; got != "rule-1" {
+		t.Errorf("unexpected default rule name %v", got)
+	}
+}
+
+func TestRules_IncludeRulesSkipOtherRequests(t *testing.T) {
+	rules, err := NewRules([]*Rule{
+		{Name: "token", Action: IncludeRule, Addresses: []string{"0xAbC"}},
+		{Name: "approve", Action: SkipRule, Selectors: []string{"0x095ea7b3"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("cannot create rules; %v", err)
+	}
+
+	if rules.Skip(makeRuleTestCall("0xabc", "0xa9059cbb", 1)) {
+		t.Error("included request must not be skipped")
+	}
+	if !rules.Skip(makeRuleTestCall("0xabc", "0x095ea7b3", 1)) {
+		t.Error("included request matching skip rule must be skipped")
+	}
+	if !rules.Skip(makeRuleTestCall("0xdef", "0xa9059cbb", 1)) {
+		t.Error("request matching no include rule must be skipped")
+	}
+
+	if _, notIncluded := rules.Hits(); notIncluded != 1 {
+		t.Errorf("unexpected number of not included requests; got %v, want 1", notIncluded)
+	}
+}
+
+func TestRules_FalsyContractIsSkippedByDefault(t *testing.T) {
+	rules, err := LoadRules("")
+	if err != nil {
+		t.Fatalf("cannot create rules; %v", err)
+	}
+	if !rules.Skip(makeRuleTestCall("0xE0C38B2A8D09AAD53F1C67734B9A95E43D5981C0", "0x", 1)) {
+		t.Error("call to falsy contract must be skipped")
+	}
+	if rules.Skip(makeRuleTestCall("0x1", "0x", 1)) {
+		t.Error("call to other contract must not be skipped")
+	}
+}
+
+func TestRules_UnknownActionFails(t *testing.T) {
+	if _, err := NewRules([]*Rule{{Name: "bad", Action: "drop"}}, nil); err == nil {
+		t.Error("rule with unknown action must fail")
+	}
+}
+
+func TestRules_DefaultRulesCanBeDisabled(t *testing.T) {
+	path := writeRulesFile(t, "rules.yaml", `
+disableDefaults: [falsy-contract]
+rules:
+  - action: skip
+    methods: [getBalance]
+`)
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("cannot load rules; %v", err)
+	}
+	if rules.Skip(makeRuleTestCall(falsyContract, "0x", 1)) {
+		t.Error("call to falsy contract must not be skipped if the default rule is disabled")
+	}
+	hits, _ := rules.Hits()
+	if len(hits) != 1 || hits[0].Name != "rule-1" {
+		t.Errorf("unexpected rules %+v", hits)
+	}
+}
+
+func TestRules_DisablingUnknownDefaultRuleFails(t *testing.T) {
+	if _, err := NewRules(nil, []string{"unknown"}); err == nil {
+		t.Error("disabling unknown default rule must fail")
+	}
+}
+
+func TestRules_DefaultRulesAreNotModified(t *testing.T) {
+	name, address := defaultRules[0].Name, defaultRules[0].Addresses[0]
+	defaultRules[0].Addresses[0] = strings.ToUpper(address)
+	defer func() { defaultRules[0].Addresses[0] = address }()
+
+	rules, err := NewRules(nil, nil)
+	if err != nil {
+		t.Fatalf("cannot create rules; %v", err)
+	}
+	if !rules.Skip(makeRuleTestCall(address, "0x", 1)) {
+		t.Error("call to falsy contract must be skipped")
+	}
+	if defaultRules[0].Name != name || defaultRules[0].Addresses[0] != strings.ToUpper(address) {
+		t.Errorf("default rule was modified; %+v", defaultRules[0])
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/Fantom-foundation/lachesis-base/common/littleendian"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	parseErrorCode     = -32700
+	invalidRequestCode = -32600
+	methodNotFoundCode = -32601
+	invalidParamsCode  = -32602
+	executionErrorCode = -32000
+
+	// maxServerRequestSize is the largest body of an HTTP request accepted by the server.
+	maxServerRequestSize = 5 * 1024 * 1024
+)
+
+// blockParams is the number of params of served methods including the block number.
+var blockParams = map[string]int{
+	"getBalance":          2,
+	"getTransactionCount": 2,
+	"getCode":             2,
+	"getStorageAt":        3,
+	"call":                2,
+}
+
+// Server serves JSON-RPC requests over HTTP from the archive of a StateDB. Requests of
+// the eth and ftm namespaces are served for any block the archive holds.
+type Server struct {
+	db   state.StateDB
+	envs BlockEnvSource // timestamps of blocks for calls; nil if unknown
+	cfg  *utils.Config
+	log  logger.Logger
+}
+
+// NewServer creates a server for the archive of the given StateDB. The block environment
+// source provides the timestamps of blocks needed for executing calls.
+func NewServer(db state.StateDB, envs BlockEnvSource, cfg *utils.Config) *Server {
+	return &Server{
+		db:   db,
+		envs: envs,
+		cfg:  cfg,
+		log:  logger.NewLogger(cfg.LogLevel, "RPC-Server"),
+	}
+}
+
+// serverResponse is a JSON-RPC response of the server.
+type serverResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *ErrorMessage   `json:"error,omitempty"`
+}
+
+// ServeHTTP serves a single or a batch of JSON-RPC requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC requests must be sent by POST", http.StatusMethodNotAllowed)
+		return
+	}
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxServerRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var response interface{}
+	if trimmed := strings.TrimSpace(string(payload)); strings.HasPrefix(trimmed, "[") {
+		var batch []json.RawMessage
+		if err = json.Unmarshal(payload, &batch); err != nil {
+			response = newServerError(nil, parseErrorCode, err)
+		} else {
+			responses := make([]serverResponse, len(batch))
+			for i, req := range batch {
+				responses[i] = s.serve(req)
+			}
+			response = responses
+		}
+	} else {
+		response = s.serve(payload)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(response); err != nil {
+		s.log.Errorf("cannot send response; %v", err)
+	}
+}
+
+// serve decodes and executes a single request.
+func (s *Server) serve(payload []byte) serverResponse {
+	var body Body
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return newServerError(nil, parseErrorCode, err)
+	}
+	namespace, method, found := strings.Cut(body.Method, "_")
+	if !found || body.Version != "2.0" {
+		return newServerError(body.ID, invalidRequestCode, fmt.Errorf("invalid request"))
+	}
+	if namespace != "eth" && namespace != "ftm" {
+		return newServerError(body.ID, methodNotFoundCode, fmt.Errorf("the method %v does not exist/is not available", body.Method))
+	}
+	body.Namespace = namespace
+	body.MethodBase = method
+
+	res, code, err := s.execute(&body)
+	if err != nil {
+		s.log.Debugf("request %v failed; %v", body.Method, err)
+		return newServerError(body.ID, code, err)
+	}
+	return serverResponse{Version: "2.0", ID: body.ID, Result: res}
+}
+
+// execute executes a request on the archive and returns its result or an error with its code.
+func (s *Server) execute(body *Body) (res interface{}, code int, err error) {
+	height, empty, err := s.db.GetArchiveBlockHeight()
+	if err != nil {
+		return nil, executionErrorCode, fmt.Errorf("cannot get archive block height; %v", err)
+	}
+	if empty {
+		return nil, executionErrorCode, fmt.Errorf("archive is empty")
+	}
+	if body.MethodBase == "blockNumber" {
+		return hexutil.Uint64(height), 0, nil
+	}
+	if body.MethodBase == "getBlockByNumber" {
+		return s.getBlockByNumber(body, height)
+	}
+
+	n, ok := blockParams[body.MethodBase]
+	if !ok {
+		return nil, methodNotFoundCode, fmt.Errorf("the method %v does not exist/is not available", body.Method)
+	}
+	if len(body.Params) < n-1 || len(body.Params) > n {
+		return nil, invalidParamsCode, fmt.Errorf("expected %v params, got %v", n, len(body.Params))
+	}
+	block := height
+	if len(body.Params) == n {
+		if block, err = parseServerBlock(body.Params[n-1], height); err != nil {
+			return nil, invalidParamsCode, err
+		}
+		body.Params = body.Params[:n-1]
+	}
+	if block > height {
+		return nil, executionErrorCode, fmt.Errorf("block %v is not available in the archive (height %v)", block, height)
+	}
+
+	rec := &RequestAndResults{Query: body}
+	if body.MethodBase == "call" {
+		if s.envs == nil {
+			return nil, executionErrorCode, fmt.Errorf("timestamp of block %v is unknown; calls require a substate DB", block)
+		}
+		env, err := s.envs(block)
+		if err != nil {
+			return nil, executionErrorCode, fmt.Errorf("cannot get environment of block %v; %v", block, err)
+		}
+		rec.Timestamp = env.Timestamp
+	}
+
+	archive, err := s.db.GetArchiveState(block)
+	if err != nil {
+		return nil, executionErrorCode, fmt.Errorf("cannot get archive state of block %v; %v", block, err)
+	}
+	defer archive.Release()
+
+	// malformed params make the execution panic
+	defer func() {
+		if r := recover(); r != nil {
+			res, code, err = nil, invalidParamsCode, fmt.Errorf("invalid params; %v", r)
+		}
+	}()
+
+	result := Execute(block, rec, archive, nil, nil, nil, s.cfg)
+	if result == nil {
+		return nil, executionErrorCode, fmt.Errorf("request cannot be executed")
+	}
+	raw, err := result.GetRawResult()
+	if err != nil {
+		return nil, executionErrorCode, err
+	}
+	return encodeServerResult(body.MethodBase, raw), 0, nil
+}
+
+// serverBlock is the header of a block served by getBlockByNumber. The server knows only
+// the environment of blocks, hence the header holds no hashes and transactions.
+type serverBlock struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+	GasLimit  hexutil.Uint64 `json:"gasLimit"`
+	BaseFee   *hexutil.Big   `json:"baseFeePerGas,omitempty"`
+}
+
+// getBlockByNumber returns the header of the requested block from the block environment source.
+func (s *Server) getBlockByNumber(body *Body, height uint64) (interface{}, int, error) {
+	if len(body.Params) < 1 || len(body.Params) > 2 {
+		return nil, invalidParamsCode, fmt.Errorf("expected 2 params, got %v", len(body.Params))
+	}
+	block, err := parseServerBlock(body.Params[0], height)
+	if err != nil {
+		return nil, invalidParamsCode, err
+	}
+	if block > height {
+		return nil, executionErrorCode, fmt.Errorf("block %v is not available in the archive (height %v)", block, height)
+	}
+	if s.envs == nil {
+		return nil, executionErrorCode, fmt.Errorf("header of block %v is unknown; block headers require a substate DB", block)
+	}
+	env, err := s.envs(block)
+	if err != nil {
+		return nil, executionErrorCode, fmt.Errorf("cannot get environment of block %v; %v", block, err)
+	}
+	return serverBlock{
+		Number:    hexutil.Uint64(block),
+		Timestamp: hexutil.Uint64(env.Timestamp),
+		GasLimit:  hexutil.Uint64(env.GasLimit),
+		BaseFee:   (*hexutil.Big)(env.BaseFee),
+	}, 0, nil
+}
+
+// encodeServerResult encodes the raw result of Execute as the JSON-RPC result of the method.
+func encodeServerResult(method string, raw []byte) interface{} {
+	switch method {
+	case "getBalance":
+		return (*hexutil.Big)(new(big.Int).SetBytes(raw))
+	case "getTransactionCount":
+		return hexutil.Uint64(littleendian.BytesToUint64(raw))
+	default:
+		return hexutil.Bytes(raw)
+	}
+}
+
+// parseServerBlock parses the block number of a request.
+func parseServerBlock(param interface{}, height uint64) (uint64, error) {
+	str, ok := param.(string)
+	if !ok {
+		return 0, fmt.Errorf("block number %v is not a string", param)
+	}
+	switch str {
+	case "latest", "pending", "safe", "finalized":
+		return height, nil
+	case "earliest":
+		return 0, nil
+	}
+	return hexutil.DecodeUint64(str)
+}
+
+// newServerError returns a JSON-RPC error response.
+func newServerError(id json.RawMessage, code int, err error) serverResponse {
+	return serverResponse{
+		Version: "2.0",
+		ID:      id,
+		Error:   &ErrorMessage{Code: code, Message: err.Error()},
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/mock/gomock"
+)
+
+// postServerRequest sends a JSON-RPC request to the server and decodes the response into res.
+func postServerRequest(t *testing.T, server *Server, request string, res interface{}) {
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(request)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status %v", rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), res); err != nil {
+		t.Fatalf("cannot decode response %v; %v", rec.Body.String(), err)
+	}
+}
+
+func TestServer_ServesBalanceOfRequestedBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+	address := common.HexToAddress("0x1")
+
+	gomock.InOrder(
+		db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil),
+		db.EXPECT().GetArchiveState(uint64(5)).Return(archive, nil),
+		archive.EXPECT().GetBalance(address).Return(big.NewInt(255)),
+		archive.EXPECT().Release(),
+	)
+
+	var res serverResponse
+	server := NewServer(db, nil, &utils.Config{})
+	postServerRequest(t, server, `{"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000000001","0x5"]}`, &res)
+	if res.Error != nil {
+		t.Fatalf("unexpected error %v", res.Error)
+	}
+	if res.Result != "0xff" || string(res.ID) != "1" {
+		t.Errorf("unexpected response %v", res)
+	}
+}
+
+func TestServer_ServesBatchRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+	address := common.HexToAddress("0x1")
+
+	db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil).Times(3)
+	db.EXPECT().GetArchiveState(uint64(10)).Return(archive, nil)
+	archive.EXPECT().GetNonce(address).Return(uint64(3))
+	archive.EXPECT().Release()
+
+	var res []serverResponse
+	server := NewServer(db, nil, &utils.Config{})
+	postServerRequest(t, server, `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"},
+		{"jsonrpc":"2.0","id":2,"method":"ftm_getTransactionCount","params":["0x0000000000000000000000000000000000000001"]},
+		{"jsonrpc":"2.0","id":3,"method":"eth_getCode","params":["0x0000000000000000000000000000000000000001","0xb"]}
+	]`, &res)
+
+	if len(res) != 3 {
+		t.Fatalf("unexpected number of responses %v", len(res))
+	}
+	if res[0].Result != "0xa" {
+		t.Errorf("unexpected block number %v", res[0].Result)
+	}
+	if res[1].Result != "0x3" {
+		t.Errorf("unexpected nonce %v", res[1].Result)
+	}
+	if res[2].Error == nil || res[2].Error.Code != executionErrorCode {
+		t.Errorf("request of a block beyond the archive must fail; got %v", res[2])
+	}
+}
+
+func TestServer_RejectsUnknownMethods(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+
+	var res serverResponse
+	server := NewServer(db, nil, &utils.Config{})
+	postServerRequest(t, server, `{"jsonrpc":"2.0","id":1,"method":"debug_traceTransaction","params":[]}`, &res)
+	if res.Error == nil || res.Error.Code != methodNotFoundCode {
+		t.Errorf("unexpected response %v", res)
+	}
+}
+
+func TestServer_ServesBlockHeadersFromBlockEnvironments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil).Times(2)
+
+	envs := func(block uint64) (*BlockEnv, error) {
+		return &BlockEnv{GasLimit: 100, Timestamp: 1000 + block}, nil
+	}
+
+	var res []serverResponse
+	server := NewServer(db, envs, &utils.Config{})
+	postServerRequest(t, server, `[
+		{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["latest",false]},
+		{"jsonrpc":"2.0","id":2,"method":"eth_getBlockByNumber","params":["0xb",false]}
+	]`, &res)
+
+	if len(res) != 2 {
+		t.Fatalf("unexpected number of responses %v", len(res))
+	}
+	header, ok := res[0].Result.(map[string]interface{})
+	if !ok || header["number"] != "0xa" || header["timestamp"] != "0x3f2" || header["gasLimit"] != "0x64" {
+		t.Errorf("unexpected block header %v", res[0].Result)
+	}
+	if res[1].Error == nil || res[1].Error.Code != executionErrorCode {
+		t.Errorf("request of a block beyond the archive must fail; got %v", res[1])
+	}
+}
+
+func TestServer_BlockHeadersRequireBlockEnvironments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil)
+
+	var res serverResponse
+	server := NewServer(db, nil, &utils.Config{})
+	postServerRequest(t, server, `{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["latest",false]}`, &res)
+	if res.Error == nil || res.Error.Code != executionErrorCode {
+		t.Errorf("unexpected response %v", res)
+	}
+}
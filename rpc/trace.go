@@ -0,0 +1,322 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/Fantom-foundation/Aida/utils"
+	substate "github.com/Fantom-foundation/Substate"
+	"github.com/Fantom-foundation/go-opera/ethapi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// Tracers of debug requests which are replayed; requests of other tracers are not executed.
+const (
+	CallTracer     = "callTracer"
+	PrestateTracer = "prestateTracer"
+)
+
+// traceSearchDepth is the number of blocks searched for the transaction of a debug_traceTransaction request.
+const traceSearchDepth = 1000
+
+// CallFrame is a call in the result of the callTracer.
+type CallFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Calls   []CallFrame     `json:"calls,omitempty"`
+}
+
+// PrestateAccount is an account in the result of the prestateTracer.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   int64                       `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// traceConfig is the configuration of a debug request.
+type traceConfig struct {
+	Tracer         string               `json:"tracer"`
+	StateOverrides ethapi.StateOverride `json:"stateOverrides"`
+	BlockOverrides *BlockOverrides      `json:"blockOverrides"`
+}
+
+// TracerOf returns the tracer of a recorded debug request; it is empty if the request does not name one.
+func TracerOf(q *Body) string {
+	cfg, err := parseTraceConfig(q)
+	if err != nil {
+		return ""
+	}
+	return cfg.Tracer
+}
+
+// parseTraceConfig decodes the configuration following the call and the block of debug_traceCall
+// or the hash of debug_traceTransaction.
+func parseTraceConfig(q *Body) (*traceConfig, error) {
+	i := 1
+	if q.MethodBase == "traceCall" {
+		i = 2
+	}
+	cfg := new(traceConfig)
+	if len(q.Params) <= i || q.Params[i] == nil {
+		return cfg, nil
+	}
+	if err := remarshal(q.Params[i], cfg); err != nil {
+		return nil, fmt.Errorf("cannot decode trace config; %v", err)
+	}
+	return cfg, nil
+}
+
+// TracedTransaction is a recorded transaction together with the state it was executed on.
+type TracedTransaction struct {
+	Block    uint64
+	Args     ethapi.TransactionArgs
+	PreState ethapi.StateOverride // accounts accessed by the transaction before its execution
+	Env      BlockOverrides
+}
+
+// TransactionSource finds the transaction of a debug_traceTransaction request recorded in the given
+// block. The recorded top-level call of the transaction identifies it.
+type TransactionSource func(call *CallFrame, block uint64) (*TracedTransaction, error)
+
+// transactionIndexCacheSize is the number of blocks whose transactions are cached by a transaction index.
+const transactionIndexCacheSize = 100_000
+
+// BlockSubstates returns the substates of the transactions of a block by their index.
+type BlockSubstates func(block uint64) map[int]*substate.Substate
+
+// TransactionIndex is a local index of the transactions of blocks serving traceTransaction requests.
+// Only the top-level calls of transactions are cached; the substate of a found transaction is read
+// again. It is safe for concurrent use.
+type TransactionIndex struct {
+	substates BlockSubstates
+	mutex     sync.Mutex
+	blocks    map[uint64][]indexedTransaction // transactions of cached blocks in descending order
+	order     []uint64                        // cached blocks in insertion order for eviction
+}
+
+// indexedTransaction is the top-level call of a transaction in a transaction index.
+type indexedTransaction struct {
+	index int
+	msg   *substate.SubstateMessage // message without its call data
+	data  common.Hash               // hash of the call data
+}
+
+// NewTransactionIndex creates a transaction index reading the substates of blocks from the given source.
+func NewTransactionIndex(substates BlockSubstates) *TransactionIndex {
+	return &TransactionIndex{
+		substates: substates,
+		blocks:    map[uint64][]indexedTransaction{},
+	}
+}
+
+// NewSubstateTransactionIndex creates a transaction index reading the substate DB, which must be
+// opened beforehand.
+func NewSubstateTransactionIndex() *TransactionIndex {
+	return NewTransactionIndex(substate.GetBlockSubstates)
+}
+
+// blockTransactions returns the transactions of a block in descending order.
+func (i *TransactionIndex) blockTransactions(block uint64) []indexedTransaction {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if txs, ok := i.blocks[block]; ok {
+		return txs
+	}
+	substates := i.substates(block)
+	txs := make([]indexedTransaction, 0, len(substates))
+	for index, s := range substates {
+		if s.Message == nil || s.Env == nil {
+			continue
+		}
+		msg := *s.Message
+		msg.Data = nil
+		txs = append(txs, indexedTransaction{index: index, msg: &msg, data: crypto.Keccak256Hash(s.Message.Data)})
+	}
+	sort.Slice(txs, func(a, b int) bool { return txs[a].index > txs[b].index })
+	if len(i.order) >= transactionIndexCacheSize {
+		delete(i.blocks, i.order[0])
+		i.order = i.order[1:]
+	}
+	i.blocks[block] = txs
+	i.order = append(i.order, block)
+	return txs
+}
+
+// Find returns the latest transaction sending the given call recorded at most traceSearchDepth blocks
+// before the given block. It is a TransactionSource.
+func (i *TransactionIndex) Find(call *CallFrame, block uint64) (*TracedTransaction, error) {
+	data := crypto.Keccak256Hash(call.Input)
+	for b := block; b+traceSearchDepth > block; b-- {
+		for _, tx := range i.blockTransactions(b) {
+			if tx.data == data && sendsCall(tx.msg, call) {
+				return newTracedTransaction(i.substates(b)[tx.index]), nil
+			}
+		}
+		if b == 0 {
+			break
+		}
+	}
+	return nil, fmt.Errorf("transaction from %v not found in blocks %v-%v", call.From.Hex(), block-min(block, traceSearchDepth-1), block)
+}
+
+// sendsCall returns true if the message sends the given top-level call; the call data is not compared.
+func sendsCall(msg *substate.SubstateMessage, call *CallFrame) bool {
+	if msg.From != call.From {
+		return false
+	}
+	if (msg.To == nil) != (call.Type == "CREATE") {
+		return false
+	}
+	if msg.To != nil && (call.To == nil || *msg.To != *call.To) {
+		return false
+	}
+	value := new(big.Int)
+	if call.Value != nil {
+		value = call.Value.ToInt()
+	}
+	return msg.Value.Cmp(value) == 0
+}
+
+// newTracedTransaction creates the traced transaction of a substate.
+func newTracedTransaction(s *substate.Substate) *TracedTransaction {
+	msg := s.Message
+	gas := hexutil.Uint64(msg.Gas)
+	data := hexutil.Bytes(msg.Data)
+	args := ethapi.TransactionArgs{
+		From:  &msg.From,
+		To:    msg.To,
+		Gas:   &gas,
+		Value: (*hexutil.Big)(msg.Value),
+		Data:  &data,
+	}
+	if msg.GasFeeCap != nil && msg.GasTipCap != nil && s.Env.BaseFee != nil {
+		args.MaxFeePerGas = (*hexutil.Big)(msg.GasFeeCap)
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(msg.GasTipCap)
+	} else {
+		args.GasPrice = (*hexutil.Big)(msg.GasPrice)
+	}
+	if msg.AccessList != nil {
+		args.AccessList = &msg.AccessList
+	}
+
+	preState := make(ethapi.StateOverride, len(s.InputAlloc))
+	for addr, acc := range s.InputAlloc {
+		nonce := hexutil.Uint64(acc.Nonce)
+		code := hexutil.Bytes(acc.Code)
+		balance := (*hexutil.Big)(new(big.Int).Set(acc.Balance))
+		storage := make(map[common.Hash]common.Hash, len(acc.Storage))
+		for k, v := range acc.Storage {
+			storage[k] = v
+		}
+		preState[addr] = ethapi.OverrideAccount{Nonce: &nonce, Code: &code, Balance: &balance, StateDiff: &storage}
+	}
+
+	env := BlockOverrides{
+		Number:   (*hexutil.Big)(new(big.Int).SetUint64(s.Env.Number)),
+		Time:     (*hexutil.Uint64)(&s.Env.Timestamp),
+		GasLimit: (*hexutil.Uint64)(&s.Env.GasLimit),
+		Coinbase: &s.Env.Coinbase,
+		BaseFee:  (*hexutil.Big)(s.Env.BaseFee),
+	}
+	if s.Env.Difficulty != nil {
+		env.Difficulty = (*hexutil.Big)(s.Env.Difficulty)
+	}
+	return &TracedTransaction{Block: s.Env.Number, Args: args, PreState: preState, Env: env}
+}
+
+// executeTraceCall traces a recorded call with the tracer of the request.
+func executeTraceCall(block uint64, rec *RequestAndResults, archive state.NonCommittableStateDB, cfg *utils.Config) txcontext.Result {
+	tc, err := parseTraceConfig(rec.Query)
+	if err != nil {
+		return &result{err: err}
+	}
+	if !isReplayedTracer(tc.Tracer) {
+		return nil
+	}
+	params, ok := rec.Query.Params[0].(map[string]interface{})
+	if !ok {
+		return &result{err: fmt.Errorf("call is not an object")}
+	}
+	evm := newEvmExecutor(block, archive, cfg, params, rec.Timestamp)
+	evm.applyOverrides(tc.StateOverrides, tc.BlockOverrides)
+	return executeTrace(evm, tc.Tracer)
+}
+
+// executeTraceTransaction traces the recorded transaction over the archive with the state accessed
+// by the transaction replaced by its state before the transaction. The transaction is located by the top-level
+// call of the recorded callTracer result; results of other tracers do not identify the transaction.
+func executeTraceTransaction(block uint64, rec *RequestAndResults, archive state.NonCommittableStateDB, txs TransactionSource, cfg *utils.Config) txcontext.Result {
+	tc, err := parseTraceConfig(rec.Query)
+	if err != nil {
+		return &result{err: err}
+	}
+	if txs == nil || tc.Tracer != CallTracer || rec.Response == nil {
+		return nil
+	}
+	var call CallFrame
+	if err = json.Unmarshal(rec.Response.Result, &call); err != nil {
+		return nil
+	}
+	tx, err := txs(&call, block)
+	if err != nil {
+		return &result{err: fmt.Errorf("cannot find traced transaction; %v", err)}
+	}
+
+	evm := newTxEvmExecutor(tx.Block, archive, cfg, tx.Args, 0)
+	evm.applyOverrides(tx.PreState, &tx.Env)
+	return executeTrace(evm, tc.Tracer)
+}
+
+// isReplayedTracer returns true if requests of the tracer are replayed.
+func isReplayedTracer(tracer string) bool {
+	return tracer == CallTracer || tracer == PrestateTracer
+}
+
+// executeTrace executes the request in the EVM with the given tracer and returns the result of the tracer.
+func executeTrace(evm *EvmExecutor, tracer string) *result {
+	t, err := tracers.New(tracer, new(tracers.Context))
+	if err != nil {
+		return &result{err: fmt.Errorf("cannot create tracer %v; %v", tracer, err)}
+	}
+	evm.tracer = t
+	if _, err = evm.applyMessage(); err != nil {
+		return &result{err: fmt.Errorf("tracing failed: %v", err)}
+	}
+	res, err := t.GetResult()
+	if err != nil {
+		return &result{err: fmt.Errorf("tracing failed: %v", err)}
+	}
+	return &result{result: res}
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/utils"
+	substate "github.com/Fantom-foundation/Substate"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	traceCaller = common.HexToAddress("0xa0a")
+	traceCallee = common.HexToAddress("0xb0b")
+)
+
+const (
+	callCalleeCode = "0x60206000600060006000610b0b5af15060206000f3" // calls 0xb0b and returns its result
+	returnSlotCode = "0x60005460005260206000f3"                     // returns storage slot 0
+)
+
+// makeTraceCallRequest returns a debug_traceCall of the caller with the given tracer; the code of
+// the caller and the callee and the storage of the callee are given by state overrides.
+func makeTraceCallRequest(tracer string) *RequestAndResults {
+	overrides := map[string]interface{}{
+		traceCaller.Hex(): map[string]interface{}{"code": callCalleeCode},
+		traceCallee.Hex(): map[string]interface{}{
+			"code":      returnSlotCode,
+			"stateDiff": map[string]interface{}{common.Hash{}.Hex(): common.BigToHash(big.NewInt(42)).Hex()},
+		},
+	}
+	config := map[string]interface{}{"stateOverrides": overrides}
+	if tracer != "" {
+		config["tracer"] = tracer
+	}
+	return &RequestAndResults{
+		Query: &Body{
+			Namespace:  "debug",
+			MethodBase: "traceCall",
+			Method:     "debug_traceCall",
+			Params:     []interface{}{map[string]interface{}{"from": "0x0000000000000000000000000000000000000001", "to": traceCaller.Hex()}, "latest", config},
+		},
+		Timestamp: 1,
+	}
+}
+
+func TestExecute_TraceCallWithCallTracer(t *testing.T) {
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+	cfg := &utils.Config{ChainID: utils.MainnetChainID}
+
+	res, err := Execute(1, makeTraceCallRequest(CallTracer), archive, nil, nil, nil, cfg).GetRawResult()
+	if err != nil {
+		t.Fatalf("cannot trace call; %v", err)
+	}
+	var call CallFrame
+	if err = json.Unmarshal(res, &call); err != nil {
+		t.Fatalf("cannot decode call trace %s; %v", res, err)
+	}
+
+	if call.Type != "CALL" || call.To == nil || *call.To != traceCaller {
+		t.Errorf("unexpected top-level call %+v", call)
+	}
+	if len(call.Calls) != 1 || call.Calls[0].To == nil || *call.Calls[0].To != traceCallee {
+		t.Fatalf("unexpected inner calls %+v", call.Calls)
+	}
+	if got := new(big.Int).SetBytes(call.Calls[0].Output); got.Int64() != 42 {
+		t.Errorf("unexpected output of inner call %v", got)
+	}
+}
+
+func TestExecute_TraceCallWithPrestateTracer(t *testing.T) {
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+	cfg := &utils.Config{ChainID: utils.MainnetChainID}
+
+	res, err := Execute(1, makeTraceCallRequest(PrestateTracer), archive, nil, nil, nil, cfg).GetRawResult()
+	if err != nil {
+		t.Fatalf("cannot trace call; %v", err)
+	}
+	var accounts map[common.Address]PrestateAccount
+	if err = json.Unmarshal(res, &accounts); err != nil {
+		t.Fatalf("cannot decode prestate trace %s; %v", res, err)
+	}
+
+	callee, ok := accounts[traceCallee]
+	if !ok {
+		t.Fatalf("callee missing in prestate %s", res)
+	}
+	if got := callee.Storage[common.Hash{}]; got != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("unexpected storage of callee %v", got)
+	}
+	if _, ok = accounts[traceCaller]; !ok {
+		t.Errorf("caller missing in prestate %s", res)
+	}
+}
+
+func TestExecute_TraceCallWithoutReplayedTracerIsNotExecuted(t *testing.T) {
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+	cfg := &utils.Config{ChainID: utils.MainnetChainID}
+
+	if res := Execute(1, makeTraceCallRequest(""), archive, nil, nil, nil, cfg); res != nil {
+		t.Errorf("struct logger traces must not be executed")
+	}
+}
+
+// makeTraceTestSubstates returns a substate source with a transaction of the sender calling
+// the caller in block 5.
+func makeTraceTestSubstates(sender common.Address) func(uint64) map[int]*substate.Substate {
+	return func(block uint64) map[int]*substate.Substate {
+		if block != 5 {
+			return nil
+		}
+		callee := traceCallee
+		caller := traceCaller
+		code := hexutil.MustDecode(callCalleeCode)
+		calleeCode := hexutil.MustDecode(returnSlotCode)
+		return map[int]*substate.Substate{
+			0: {
+				InputAlloc: substate.SubstateAlloc{
+					sender: {Balance: big.NewInt(1e18), Storage: map[common.Hash]common.Hash{}},
+					caller: {Balance: big.NewInt(0), Code: code, Storage: map[common.Hash]common.Hash{}},
+					callee: {Balance: big.NewInt(0), Code: calleeCode, Storage: map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))}},
+				},
+				Env:     &substate.SubstateEnv{Number: 5, Timestamp: 100, GasLimit: 1_000_000, Difficulty: big.NewInt(1)},
+				Message: &substate.SubstateMessage{From: sender, To: &caller, Value: big.NewInt(0), Gas: 100_000, GasPrice: big.NewInt(0), Data: []byte{}},
+			},
+		}
+	}
+}
+
+func TestTransactionIndex_FindsTransactionOfCall(t *testing.T) {
+	sender := common.HexToAddress("0x5e")
+	index := NewTransactionIndex(makeTraceTestSubstates(sender))
+	call := &CallFrame{Type: "CALL", From: sender, To: &traceCaller, Value: nil, Input: []byte{}}
+
+	tx, err := index.Find(call, 10)
+	if err != nil {
+		t.Fatalf("cannot find transaction; %v", err)
+	}
+	if tx.Block != 5 || *tx.Args.From != sender || len(tx.PreState) != 3 {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+
+	other := common.HexToAddress("0x5f")
+	if _, err = index.Find(&CallFrame{Type: "CALL", From: other, To: &traceCaller}, 10); err == nil {
+		t.Error("transaction of another sender must not be found")
+	}
+	if _, err = index.Find(&CallFrame{Type: "CALL", From: sender, To: &traceCaller, Input: []byte{1}}, 10); err == nil {
+		t.Error("transaction with other call data must not be found")
+	}
+	if _, err = index.Find(call, 4); err == nil {
+		t.Error("transaction after the recorded block must not be found")
+	}
+}
+
+func TestTransactionIndex_ReadsSubstatesOfBlocksOnce(t *testing.T) {
+	sender := common.HexToAddress("0x5e")
+	substates := makeTraceTestSubstates(sender)
+	reads := map[uint64]int{}
+	index := NewTransactionIndex(func(block uint64) map[int]*substate.Substate {
+		reads[block]++
+		return substates(block)
+	})
+	call := &CallFrame{Type: "CALL", From: sender, To: &traceCaller, Input: []byte{}}
+
+	for i := 0; i < 3; i++ {
+		if _, err := index.Find(call, 10); err != nil {
+			t.Fatalf("cannot find transaction; %v", err)
+		}
+	}
+	// the block of the transaction is read again for its substate
+	for block := uint64(5); block <= 10; block++ {
+		want := 1
+		if block == 5 {
+			want = 4
+		}
+		if reads[block] != want {
+			t.Errorf("unexpected number of reads of block %v; got %v, want %v", block, reads[block], want)
+		}
+	}
+}
+
+func TestExecute_TraceTransactionReplaysRecordedPreState(t *testing.T) {
+	sender := common.HexToAddress("0x5e")
+	substates := makeTraceTestSubstates(sender)
+	txs := NewTransactionIndex(substates).Find
+	recorded, _ := json.Marshal(CallFrame{Type: "CALL", From: sender, To: &traceCaller, Input: []byte{}})
+	req := &RequestAndResults{
+		Query: &Body{
+			Namespace:  "debug",
+			MethodBase: "traceTransaction",
+			Method:     "debug_traceTransaction",
+			Params:     []interface{}{common.Hash{1}.Hex(), map[string]interface{}{"tracer": CallTracer}},
+		},
+		Response: &Response{Result: recorded},
+	}
+	archive := makeEstimateGasTestArchive(t, common.HexToAddress("0x1"))
+
+	res, err := Execute(10, req, archive, nil, nil, txs, &utils.Config{ChainID: utils.MainnetChainID}).GetRawResult()
+	if err != nil {
+		t.Fatalf("cannot trace transaction; %v", err)
+	}
+	var call CallFrame
+	if err = json.Unmarshal(res, &call); err != nil {
+		t.Fatalf("cannot decode call trace %s; %v", res, err)
+	}
+	if len(call.Calls) != 1 {
+		t.Fatalf("unexpected inner calls %+v", call.Calls)
+	}
+	// the storage of the callee is taken from the recorded pre-state
+	if got := new(big.Int).SetBytes(call.Output); got.Int64() != 7 {
+		t.Errorf("unexpected output %v", got)
+	}
+
+	if res := Execute(10, req, archive, nil, nil, nil, &utils.Config{ChainID: utils.MainnetChainID}); res != nil {
+		t.Error("traced transactions must not be executed without a transaction source")
+	}
+}
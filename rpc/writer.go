@@ -0,0 +1,113 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+)
+
+// Writer writes requests and their responses to a recording readable by NewFileReader.
+// It is safe for concurrent use.
+type Writer struct {
+	mutex   sync.Mutex
+	out     io.Writer
+	closers []io.Closer // closed in order on Close
+}
+
+// NewWriter creates a writer of a recording into the given output.
+func NewWriter(out io.Writer) *Writer {
+	return &Writer{out: out}
+}
+
+// NewFileWriter creates a writer of a recording file. The recording is gzipped if
+// the file has the .gz extension.
+func NewFileWriter(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".gz") {
+		zw := gzip.NewWriter(f)
+		return &Writer{out: zw, closers: []io.Closer{zw, f}}, nil
+	}
+	return &Writer{out: f, closers: []io.Closer{f}}, nil
+}
+
+// Write appends a request and its response or error to the recording. Requests of methods
+// which cannot be recorded are skipped. The block timestamp is expected in nanoseconds.
+func (w *Writer) Write(rec *RequestAndResults) error {
+	if !CanRecord(rec.Query.Namespace, rec.Query.MethodBase) {
+		return nil
+	}
+
+	hdr := new(Header)
+	if err := hdr.SetMethod(rec.Query.Namespace, rec.Query.MethodBase); err != nil {
+		return err
+	}
+	if err := hdr.SetQueryLength(len(rec.ParamsRaw)); err != nil {
+		return err
+	}
+
+	var response []byte
+	switch {
+	case rec.Error != nil:
+		hdr.SetError(rec.Error.Error.Code)
+		hdr.SetBlockID(rec.Error.BlockID)
+		hdr.SetBlockTimestamp(rec.Error.Timestamp)
+	case rec.Response != nil:
+		response = rec.Response.Result
+		hdr.SetResponseLength(len(response))
+		hdr.SetBlockID(rec.Response.BlockID)
+		hdr.SetBlockTimestamp(rec.Response.Timestamp)
+	default:
+		return fmt.Errorf("request %v has neither response nor error", rec.Query.Method)
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if _, err := hdr.WriteTo(w.out); err != nil {
+		return fmt.Errorf("cannot write header; %v", err)
+	}
+	if _, err := w.out.Write(rec.ParamsRaw); err != nil {
+		return fmt.Errorf("cannot write query; %v", err)
+	}
+	if _, err := w.out.Write(response); err != nil {
+		return fmt.Errorf("cannot write response; %v", err)
+	}
+	return nil
+}
+
+// Close flushes the recording and releases the underlying file.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, c := range w.closers {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	w.closers = nil
+	return nil
+}
@@ -48,6 +48,14 @@ func (r *RecorderProxy) write(op operation.Operation) {
 	operation.WriteOp(r.ctx, op)
 }
 
+// writeResult writes the result of the last read operation to file if the
+// trace is recorded for validation.
+func (r *RecorderProxy) writeResult() {
+	if r.ctx.Validate {
+		r.write(operation.NewResult(r.ctx.Result()))
+	}
+}
+
 // CreateAccount creates a new account.
 func (r *RecorderProxy) CreateAccount(addr common.Address) {
 	contract := r.ctx.EncodeContract(addr)
@@ -74,6 +82,8 @@ func (r *RecorderProxy) GetBalance(addr common.Address) *big.Int {
 	contract := r.ctx.EncodeContract(addr)
 	r.write(operation.NewGetBalance(contract))
 	balance := r.db.GetBalance(addr)
+	r.ctx.SetBalanceResult(balance)
+	r.writeResult()
 	return balance
 }
 
@@ -82,6 +92,8 @@ func (r *RecorderProxy) GetNonce(addr common.Address) uint64 {
 	contract := r.ctx.EncodeContract(addr)
 	r.write(operation.NewGetNonce(contract))
 	nonce := r.db.GetNonce(addr)
+	r.ctx.SetUint64Result(nonce)
+	r.writeResult()
 	return nonce
 }
 
@@ -103,6 +115,8 @@ func (r *RecorderProxy) GetCodeHash(addr common.Address) common.Hash {
 	}
 
 	hash := r.db.GetCodeHash(addr)
+	r.ctx.SetHashResult(hash)
+	r.writeResult()
 	return hash
 }
 
@@ -111,6 +125,8 @@ func (r *RecorderProxy) GetCode(addr common.Address) []byte {
 	contract := r.ctx.EncodeContract(addr)
 	r.write(operation.NewGetCode(contract))
 	code := r.db.GetCode(addr)
+	r.ctx.SetBytesResult(code)
+	r.writeResult()
 	return code
 }
 
@@ -126,6 +142,8 @@ func (r *RecorderProxy) GetCodeSize(addr common.Address) int {
 	contract := r.ctx.EncodeContract(addr)
 	r.write(operation.NewGetCodeSize(contract))
 	size := r.db.GetCodeSize(addr)
+	r.ctx.SetUint64Result(uint64(size))
+	r.writeResult()
 	return size
 }
 
@@ -156,6 +174,8 @@ func (r *RecorderProxy) GetCommittedState(addr common.Address, key common.Hash)
 		r.write(operation.NewGetCommittedState(contract, key))
 	}
 	value := r.db.GetCommittedState(addr, key)
+	r.ctx.SetHashResult(value)
+	r.writeResult()
 	return value
 }
 
@@ -178,6 +198,8 @@ func (r *RecorderProxy) GetState(addr common.Address, key common.Hash) common.Ha
 	}
 	r.write(op)
 	value := r.db.GetState(addr, key)
+	r.ctx.SetHashResult(value)
+	r.writeResult()
 	return value
 }
 
@@ -201,6 +223,8 @@ func (r *RecorderProxy) Suicide(addr common.Address) bool {
 	contract := r.ctx.EncodeContract(addr)
 	r.write(operation.NewSuicide(contract))
 	ok := r.db.Suicide(addr)
+	r.ctx.SetBoolResult(ok)
+	r.writeResult()
 	return ok
 }
 
@@ -215,7 +239,10 @@ func (r *RecorderProxy) HasSuicided(addr common.Address) bool {
 func (r *RecorderProxy) Exist(addr common.Address) bool {
 	contract := r.ctx.EncodeContract(addr)
 	r.write(operation.NewExist(contract))
-	return r.db.Exist(addr)
+	exist := r.db.Exist(addr)
+	r.ctx.SetBoolResult(exist)
+	r.writeResult()
+	return exist
 }
 
 // Empty checks whether the contract is either non-existent
@@ -0,0 +1,259 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic/exponential"
+	"github.com/Fantom-foundation/Aida/stochastic/generator"
+	"github.com/Fantom-foundation/Aida/stochastic/stationary"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ArchiveQueryLength is the number of read operations per archive query.
+var ArchiveQueryLength = 100
+
+// archiveReadOps are the operations that archive readers may issue.
+var archiveReadOps = map[int]bool{
+	EmptyID:             true,
+	ExistID:             true,
+	GetBalanceID:        true,
+	GetCodeHashID:       true,
+	GetCodeID:           true,
+	GetCodeSizeID:       true,
+	GetCommittedStateID: true,
+	GetNonceID:          true,
+	GetStateID:          true,
+	HasSuicidedID:       true,
+}
+
+// archiveReaders runs concurrent readers that query historic blocks of the archive
+// while the stochastic replay modifies the live state. Each query opens the archive
+// state of a block whose age is sampled from a truncated exponential distribution
+// (or a uniform distribution if lambda is zero), and issues read operations sampled
+// from the stationary distribution of the read operations of the simulation model.
+type archiveReaders struct {
+	db       state.StateDB
+	model    *EstimationModelJSON
+	readOps  []string      // opcodes of read operations
+	readCdf  []float64     // cumulative probabilities of read operations
+	lambda   float64       // lambda parameter of the age distribution
+	maxAge   uint64        // maximal age of queried blocks
+	interval time.Duration // delay between two queries of a reader
+	log      logger.Logger
+
+	// reader control
+	finished utils.Event
+	done     sync.WaitGroup
+
+	// first error of readers
+	err      error
+	errMutex sync.Mutex
+
+	// counters for throughput reporting
+	queries    atomic.Uint64
+	operations atomic.Uint64
+}
+
+// newArchiveReaders creates archive readers for a simulation model. The query rate
+// is the total number of queries per second of all readers.
+func newArchiveReaders(db state.StateDB, e *EstimationModelJSON, queryRate int, maxAge int, lambda float64, log logger.Logger) (*archiveReaders, error) {
+	if queryRate <= 0 {
+		return nil, fmt.Errorf("archive query rate must be positive")
+	}
+	if maxAge < 0 || lambda < 0 {
+		return nil, fmt.Errorf("maximal query age and age lambda must not be negative")
+	}
+	dist, err := stationary.ComputeDistribution(e.StochasticMatrix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute stationary distribution; %v", err)
+	}
+	a := &archiveReaders{
+		db:       db,
+		model:    e,
+		lambda:   lambda,
+		maxAge:   uint64(maxAge),
+		interval: time.Second / time.Duration(queryRate),
+		log:      log,
+		finished: utils.MakeEvent(),
+	}
+	sum := 0.0
+	for i, opc := range e.Operations {
+		op, _, _, _ := DecodeOpcode(opc)
+		if archiveReadOps[op] && dist[i] > 0 {
+			sum += dist[i]
+			a.readOps = append(a.readOps, opc)
+			a.readCdf = append(a.readCdf, sum)
+		}
+	}
+	if len(a.readOps) == 0 {
+		return nil, fmt.Errorf("simulation model has no read operations for archive queries")
+	}
+	return a, nil
+}
+
+// start spawns the given number of readers. Reader i uses seed+i+1 as its random seed
+// so that the sampled queries are reproducible for the same archive block heights.
+func (a *archiveReaders) start(numReaders int, seed int64) {
+	// each reader keeps the per-reader share of the total query rate
+	a.interval *= time.Duration(numReaders)
+	a.done.Add(numReaders)
+	for i := 0; i < numReaders; i++ {
+		go a.run(rand.New(rand.NewSource(seed + int64(i) + 1)))
+	}
+}
+
+// stop signals the readers to finish and waits for them. It returns the first error of the readers.
+func (a *archiveReaders) stop() error {
+	a.finished.Signal()
+	a.done.Wait()
+	a.errMutex.Lock()
+	defer a.errMutex.Unlock()
+	return a.err
+}
+
+// run issues archive queries until the readers are stopped or a query fails.
+func (a *archiveReaders) run(rg *rand.Rand) {
+	defer a.done.Done()
+	contracts := generator.NewRandomAccess(rg, a.model.Contracts.NumKeys, a.model.Contracts.Lambda, a.model.Contracts.QueueDistribution)
+	keys := generator.NewRandomAccess(rg, a.model.Keys.NumKeys, a.model.Keys.Lambda, a.model.Keys.QueueDistribution)
+	if contracts == nil || keys == nil {
+		a.fail(fmt.Errorf("too few contracts or keys for archive queries"))
+		return
+	}
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-a.finished.Wait():
+			return
+		case <-ticker.C:
+		}
+		if err := a.query(rg, contracts, keys); err != nil {
+			a.fail(err)
+			return
+		}
+	}
+}
+
+// fail records the first error of readers.
+func (a *archiveReaders) fail(err error) {
+	a.errMutex.Lock()
+	defer a.errMutex.Unlock()
+	if a.err == nil {
+		a.err = err
+	}
+}
+
+// sampleAge draws the age of a queried block that does not exceed the given bound.
+func (a *archiveReaders) sampleAge(rg *rand.Rand, bound uint64) uint64 {
+	if bound > a.maxAge {
+		bound = a.maxAge
+	}
+	n := int64(bound) + 1
+	if a.lambda == 0 {
+		return uint64(rg.Int63n(n))
+	}
+	age := exponential.DiscreteSample(rg, a.lambda, n)
+	if age >= n {
+		age = n - 1
+	}
+	return uint64(age)
+}
+
+// sampleOp draws a read operation with its argument classes.
+func (a *archiveReaders) sampleOp(rg *rand.Rand) (int, int, int) {
+	u := rg.Float64() * a.readCdf[len(a.readCdf)-1]
+	i := sort.SearchFloat64s(a.readCdf, u)
+	if i >= len(a.readOps) {
+		i = len(a.readOps) - 1
+	}
+	op, addrCl, keyCl, _ := DecodeOpcode(a.readOps[i])
+	return op, addrCl, keyCl
+}
+
+// query issues read operations against the archive state of a sampled block.
+func (a *archiveReaders) query(rg *rand.Rand, contracts *generator.RandomAccess, keys *generator.RandomAccess) error {
+	height, empty, err := a.db.GetArchiveBlockHeight()
+	if err != nil {
+		return fmt.Errorf("failed to obtain archive block height; %v", err)
+	}
+	if empty {
+		return nil
+	}
+	block := height - a.sampleAge(rg, height)
+	archive, err := a.db.GetArchiveState(block)
+	if err != nil {
+		return fmt.Errorf("failed to obtain archive state of block %v; %v", block, err)
+	}
+	defer archive.Release()
+	if err := archive.BeginTransaction(0); err != nil {
+		return fmt.Errorf("cannot begin archive transaction of block %v; %v", block, err)
+	}
+	for i := 0; i < ArchiveQueryLength; i++ {
+		op, addrCl, keyCl := a.sampleOp(rg)
+		addrIdx := contracts.NextIndex(addrCl)
+		keyIdx := keys.NextIndex(keyCl)
+		if addrIdx < 0 || (keyCl != statistics.NoArgID && keyIdx < 0) {
+			// no recent contract or key is available yet
+			continue
+		}
+		addr := toAddress(addrIdx)
+		var key common.Hash
+		if keyCl != statistics.NoArgID {
+			key = toHash(keyIdx)
+		}
+		switch op {
+		case EmptyID:
+			archive.Empty(addr)
+		case ExistID:
+			archive.Exist(addr)
+		case GetBalanceID:
+			archive.GetBalance(addr)
+		case GetCodeHashID:
+			archive.GetCodeHash(addr)
+		case GetCodeID:
+			archive.GetCode(addr)
+		case GetCodeSizeID:
+			archive.GetCodeSize(addr)
+		case GetCommittedStateID:
+			archive.GetCommittedState(addr, key)
+		case GetNonceID:
+			archive.GetNonce(addr)
+		case GetStateID:
+			archive.GetState(addr, key)
+		case HasSuicidedID:
+			archive.HasSuicided(addr)
+		}
+		a.operations.Add(1)
+	}
+	a.queries.Add(1)
+	if err := archive.EndTransaction(); err != nil {
+		return fmt.Errorf("cannot end archive transaction of block %v; %v", block, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic/generator"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	"go.uber.org/mock/gomock"
+)
+
+// newTestAccess creates an access generator for estimated access statistics.
+func newTestAccess(rg *rand.Rand, s EstimationStatsJSON) *generator.RandomAccess {
+	return generator.NewRandomAccess(rg, s.NumKeys, s.Lambda, s.QueueDistribution)
+}
+
+// newArchiveTestModel creates a simulation model that alternates between a read and a write operation.
+func newArchiveTestModel() *EstimationModelJSON {
+	return &EstimationModelJSON{
+		Operations: []string{
+			EncodeOpcode(GetStateID, statistics.RandomValueID, statistics.RandomValueID, statistics.NoArgID),
+			EncodeOpcode(SetNonceID, statistics.RandomValueID, statistics.NoArgID, statistics.NoArgID),
+		},
+		StochasticMatrix: [][]float64{{0, 1}, {1, 0}},
+		Contracts:        EstimationStatsJSON{NumKeys: 10 * statistics.QueueLen, Lambda: 5, QueueDistribution: make([]float64, statistics.QueueLen)},
+		Keys:             EstimationStatsJSON{NumKeys: 10 * statistics.QueueLen, Lambda: 5, QueueDistribution: make([]float64, statistics.QueueLen)},
+	}
+}
+
+// TestArchiveReaders_SampleOnlyReadOperations checks that readers only issue read operations.
+func TestArchiveReaders_SampleOnlyReadOperations(t *testing.T) {
+	a, err := newArchiveReaders(nil, newArchiveTestModel(), 10, 5, 0, logger.NewLogger("INFO", "Archive Test"))
+	if err != nil {
+		t.Fatalf("failed to create archive readers; %v", err)
+	}
+	rg := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if op, _, _ := a.sampleOp(rg); op != GetStateID {
+			t.Fatalf("unexpected operation %v", opText[op])
+		}
+		if age := a.sampleAge(rg, 3); age > 3 {
+			t.Fatalf("age %v exceeds block height", age)
+		}
+		if age := a.sampleAge(rg, 1000); age > 5 {
+			t.Fatalf("age %v exceeds maximal query age", age)
+		}
+	}
+
+	writeOnly := newArchiveTestModel()
+	writeOnly.Operations[0] = EncodeOpcode(SetStateID, statistics.RandomValueID, statistics.RandomValueID, statistics.RandomValueID)
+	if _, err := newArchiveReaders(nil, writeOnly, 10, 5, 0, nil); err == nil {
+		t.Errorf("expected an error for a model without read operations")
+	}
+}
+
+// TestArchiveReaders_QueryHistoricBlock checks that a query reads from a historic archive state.
+func TestArchiveReaders_QueryHistoricBlock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	archive := state.NewMockNonCommittableStateDB(ctrl)
+
+	a, err := newArchiveReaders(db, newArchiveTestModel(), 10, 2, 1.0, logger.NewLogger("INFO", "Archive Test"))
+	if err != nil {
+		t.Fatalf("failed to create archive readers; %v", err)
+	}
+	db.EXPECT().GetArchiveBlockHeight().Return(uint64(10), false, nil)
+	db.EXPECT().GetArchiveState(gomock.Any()).DoAndReturn(func(block uint64) (state.NonCommittableStateDB, error) {
+		if block < 8 || block > 10 {
+			t.Errorf("unexpected block %v", block)
+		}
+		return archive, nil
+	})
+	gomock.InOrder(
+		archive.EXPECT().BeginTransaction(uint32(0)),
+		archive.EXPECT().GetState(gomock.Any(), gomock.Any()).Times(ArchiveQueryLength),
+		archive.EXPECT().EndTransaction(),
+		archive.EXPECT().Release(),
+	)
+
+	rg := rand.New(rand.NewSource(1))
+	m := newArchiveTestModel()
+	contracts, keys := newTestAccess(rg, m.Contracts), newTestAccess(rg, m.Keys)
+	if err := a.query(rg, contracts, keys); err != nil {
+		t.Fatalf("query failed; %v", err)
+	}
+	if a.queries.Load() != 1 || a.operations.Load() != uint64(ArchiveQueryLength) {
+		t.Errorf("unexpected counters: %v queries, %v operations", a.queries.Load(), a.operations.Load())
+	}
+}
+
+// TestArchiveReaders_StopReportsError checks that errors of readers are reported when stopping them.
+func TestArchiveReaders_StopReportsError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+	db.EXPECT().GetArchiveBlockHeight().Return(uint64(0), false, errors.New("archive failure")).MinTimes(1)
+
+	a, err := newArchiveReaders(db, newArchiveTestModel(), 1000, 2, 0, logger.NewLogger("INFO", "Archive Test"))
+	if err != nil {
+		t.Fatalf("failed to create archive readers; %v", err)
+	}
+	a.start(2, 42)
+	for failed := false; !failed; time.Sleep(time.Millisecond) {
+		a.errMutex.Lock()
+		failed = a.err != nil
+		a.errMutex.Unlock()
+	}
+	if err := a.stop(); err == nil {
+		t.Errorf("expected an error of archive readers")
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/Fantom-foundation/Aida/stochastic/exponential"
+	"github.com/Fantom-foundation/Aida/stochastic/stationary"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+)
+
+// divergenceEpsilon is the probability assumed for operations that a model never
+// produces when computing the Kullback-Leibler divergence.
+const divergenceEpsilon = 1e-9
+
+// ModelSummary contains the parameters of an events or a simulation file that are compared.
+type ModelSummary struct {
+	FileId           string       // either "events" or "simulation"
+	Operations       []string     // opcodes of the stochastic matrix
+	StochasticMatrix [][]float64  // first-order stochastic matrix
+	ContractLambda   float64      // lambda of contract-address accesses
+	KeyLambda        float64      // lambda of storage-key accesses
+	ValueLambda      float64      // lambda of storage-value accesses
+	SnapshotLambda   float64      // lambda of snapshot deltas
+	SnapshotEcdf     [][2]float64 // eCDF of snapshot deltas (events files only)
+}
+
+// ReadModelSummary reads an events or a simulation file in JSON format.
+func ReadModelSummary(filename string) (*ModelSummary, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading file %v; %v", filename, err)
+	}
+	var header struct {
+		FileId string `json:"FileId"`
+	}
+	if err := json.Unmarshal(contents, &header); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling JSON; %v", err)
+	}
+	switch header.FileId {
+	case "events":
+		var d EventRegistryJSON
+		if err := json.Unmarshal(contents, &d); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal event registry; %v", err)
+		}
+		return NewEventsSummary(&d)
+	case "simulation":
+		var e EstimationModelJSON
+		if err := json.Unmarshal(contents, &e); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal simulation; %v", err)
+		}
+		return NewSimulationSummary(&e), nil
+	default:
+		return nil, fmt.Errorf("file %v is neither an events nor a simulation file", filename)
+	}
+}
+
+// NewEventsSummary summarizes an event registry by estimating its lambda parameters.
+func NewEventsSummary(d *EventRegistryJSON) (*ModelSummary, error) {
+	s := &ModelSummary{
+		FileId:           d.FileId,
+		Operations:       d.Operations,
+		StochasticMatrix: d.StochasticMatrix,
+		SnapshotEcdf:     d.SnapshotEcdf,
+	}
+	var err error
+	if s.ContractLambda, err = exponential.ApproximateLambda(d.Contracts.Counting.ECdf); err != nil {
+		return nil, fmt.Errorf("failed to approximate lambda of contracts; %v", err)
+	}
+	if s.KeyLambda, err = exponential.ApproximateLambda(d.Keys.Counting.ECdf); err != nil {
+		return nil, fmt.Errorf("failed to approximate lambda of keys; %v", err)
+	}
+	if s.ValueLambda, err = exponential.ApproximateLambda(d.Values.Counting.ECdf); err != nil {
+		return nil, fmt.Errorf("failed to approximate lambda of values; %v", err)
+	}
+	if s.SnapshotLambda, err = exponential.ApproximateLambda(d.SnapshotEcdf); err != nil {
+		return nil, fmt.Errorf("failed to approximate lambda of snapshot deltas; %v", err)
+	}
+	return s, nil
+}
+
+// NewSimulationSummary summarizes a simulation file.
+func NewSimulationSummary(e *EstimationModelJSON) *ModelSummary {
+	return &ModelSummary{
+		FileId:           e.FileId,
+		Operations:       e.Operations,
+		StochasticMatrix: e.StochasticMatrix,
+		ContractLambda:   e.Contracts.Lambda,
+		KeyLambda:        e.Keys.Lambda,
+		ValueLambda:      e.Values.Lambda,
+		SnapshotLambda:   e.SnapshotLambda,
+	}
+}
+
+// ComparisonReport contains the distances between two stochastic models A and B.
+type ComparisonReport struct {
+	FileA string `json:"fileA"`
+	FileB string `json:"fileB"`
+
+	// divergences of the operation frequencies (without argument classes) in bits
+	OperationKL float64 `json:"operationKLDivergence"` // Kullback-Leibler divergence KL(A||B)
+	OperationJS float64 `json:"operationJSDivergence"` // Jensen-Shannon divergence
+
+	// total variation distance of the stationary distributions of argument-encoded operations
+	StationaryDistance float64 `json:"stationaryDistance"`
+
+	Operations []FrequencyComparison `json:"operations"`
+
+	Contracts LambdaComparison `json:"contractStats"`
+	Keys      LambdaComparison `json:"keyStats"`
+	Values    LambdaComparison `json:"valueStats"`
+	Snapshot  LambdaComparison `json:"snapshotStats"`
+
+	// Kolmogorov-Smirnov distance of the snapshot-delta eCDFs (only if both are events files)
+	SnapshotKS *float64 `json:"snapshotKSDistance,omitempty"`
+}
+
+// FrequencyComparison contains the frequencies of an operation in both models.
+type FrequencyComparison struct {
+	Operation  string  `json:"operation"`
+	A          float64 `json:"a"`
+	B          float64 `json:"b"`
+	Difference float64 `json:"difference"`
+}
+
+// LambdaComparison contains the lambda parameters of an exponential distribution in both models.
+type LambdaComparison struct {
+	A          float64 `json:"a"`
+	B          float64 `json:"b"`
+	Difference float64 `json:"difference"`
+}
+
+// newLambdaComparison compares two lambda parameters.
+func newLambdaComparison(a float64, b float64) LambdaComparison {
+	return LambdaComparison{A: a, B: b, Difference: b - a}
+}
+
+// Compare computes the distances between two stochastic models.
+func Compare(fileA string, a *ModelSummary, fileB string, b *ModelSummary) (*ComparisonReport, error) {
+	stationaryA, err := stationary.ComputeDistribution(a.StochasticMatrix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute stationary distribution of %v; %v", fileA, err)
+	}
+	stationaryB, err := stationary.ComputeDistribution(b.StochasticMatrix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute stationary distribution of %v; %v", fileB, err)
+	}
+
+	// total variation distance over the union of argument-encoded operations
+	argOpA := map[string]float64{}
+	for i, opc := range a.Operations {
+		argOpA[opc] = stationaryA[i]
+	}
+	argOpB := map[string]float64{}
+	for i, opc := range b.Operations {
+		argOpB[opc] = stationaryB[i]
+	}
+	distance := 0.0
+	for opc, p := range argOpA {
+		distance += math.Abs(p - argOpB[opc])
+	}
+	for opc, q := range argOpB {
+		if _, ok := argOpA[opc]; !ok {
+			distance += q
+		}
+	}
+
+	// aggregate operation frequencies without argument classes
+	var freqA, freqB [NumOps]float64
+	for opc, p := range argOpA {
+		op, _, _, _ := DecodeOpcode(opc)
+		freqA[op] += p
+	}
+	for opc, q := range argOpB {
+		op, _, _, _ := DecodeOpcode(opc)
+		freqB[op] += q
+	}
+
+	report := &ComparisonReport{
+		FileA:              fileA,
+		FileB:              fileB,
+		OperationKL:        klDivergence(freqA[:], freqB[:], divergenceEpsilon),
+		OperationJS:        jsDivergence(freqA[:], freqB[:]),
+		StationaryDistance: distance / 2,
+		Contracts:          newLambdaComparison(a.ContractLambda, b.ContractLambda),
+		Keys:               newLambdaComparison(a.KeyLambda, b.KeyLambda),
+		Values:             newLambdaComparison(a.ValueLambda, b.ValueLambda),
+		Snapshot:           newLambdaComparison(a.SnapshotLambda, b.SnapshotLambda),
+	}
+	for op := 0; op < NumOps; op++ {
+		if freqA[op] == 0 && freqB[op] == 0 {
+			continue
+		}
+		report.Operations = append(report.Operations, FrequencyComparison{
+			Operation:  opText[op],
+			A:          freqA[op],
+			B:          freqB[op],
+			Difference: freqB[op] - freqA[op],
+		})
+	}
+	sort.SliceStable(report.Operations, func(i, j int) bool {
+		return math.Abs(report.Operations[i].Difference) > math.Abs(report.Operations[j].Difference)
+	})
+	if a.SnapshotEcdf != nil && b.SnapshotEcdf != nil {
+		ks := ksDistance(a.SnapshotEcdf, b.SnapshotEcdf)
+		report.SnapshotKS = &ks
+	}
+	return report, nil
+}
+
+// klDivergence computes the Kullback-Leibler divergence KL(p||q) in bits. Probabilities
+// of q are floored by epsilon so that the divergence stays finite.
+func klDivergence(p []float64, q []float64, epsilon float64) float64 {
+	sum := 0.0
+	for i := range p {
+		if p[i] <= 0.0 {
+			continue
+		}
+		sum += p[i] * math.Log2(p[i]/math.Max(q[i], epsilon))
+	}
+	return sum
+}
+
+// jsDivergence computes the Jensen-Shannon divergence of p and q in bits (between 0 and 1).
+func jsDivergence(p []float64, q []float64) float64 {
+	m := make([]float64, len(p))
+	for i := range p {
+		m[i] = (p[i] + q[i]) / 2
+	}
+	return (klDivergence(p, m, 0) + klDivergence(q, m, 0)) / 2
+}
+
+// ksDistance computes the Kolmogorov-Smirnov distance of two piecewise linear eCDFs.
+func ksDistance(a [][2]float64, b [][2]float64) float64 {
+	distance := 0.0
+	for _, points := range [][][2]float64{a, b} {
+		for _, point := range points {
+			if d := math.Abs(statistics.EvaluateECdf(a, point[0]) - statistics.EvaluateECdf(b, point[0])); d > distance {
+				distance = d
+			}
+		}
+	}
+	return distance
+}
+
+// WriteReport writes the comparison as human-readable tables.
+func (r *ComparisonReport) WriteReport(out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "A:\t%v\t\n", r.FileA)
+	fmt.Fprintf(tw, "B:\t%v\t\n", r.FileB)
+	fmt.Fprintf(tw, "operation KL divergence (bits):\t%.6f\t\n", r.OperationKL)
+	fmt.Fprintf(tw, "operation JS divergence (bits):\t%.6f\t\n", r.OperationJS)
+	fmt.Fprintf(tw, "stationary distance:\t%.6f\t\n", r.StationaryDistance)
+	if r.SnapshotKS != nil {
+		fmt.Fprintf(tw, "snapshot KS distance:\t%.6f\t\n", *r.SnapshotKS)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+
+	tw = tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "lambda\tA\tB\tdifference\t")
+	for _, row := range []struct {
+		name string
+		c    LambdaComparison
+	}{{"contracts", r.Contracts}, {"keys", r.Keys}, {"values", r.Values}, {"snapshots", r.Snapshot}} {
+		fmt.Fprintf(tw, "%v\t%.6f\t%.6f\t%+.6f\t\n", row.name, row.c.A, row.c.B, row.c.Difference)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+
+	tw = tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "operation\tA\tB\tdifference\t")
+	for _, op := range r.Operations {
+		fmt.Fprintf(tw, "%v\t%.6f\t%.6f\t%+.6f\t\n", op.Operation, op.A, op.B, op.Difference)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the comparison report in JSON format.
+func (r *ComparisonReport) WriteJSON(filename string) error {
+	f, fErr := os.Create(filename)
+	if fErr != nil {
+		return fmt.Errorf("cannot open JSON file; %v", fErr)
+	}
+	defer f.Close()
+	jOut, jErr := json.MarshalIndent(r, "", "    ")
+	if jErr != nil {
+		return fmt.Errorf("failed to convert JSON file; %v", jErr)
+	}
+	_, pErr := fmt.Fprintln(f, string(jOut))
+	if pErr != nil {
+		return fmt.Errorf("failed to convert JSON file; %v", pErr)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// newTestSummary creates a model summary of a two-state Markov chain.
+func newTestSummary(p float64, lambda float64) *ModelSummary {
+	return &ModelSummary{
+		FileId:           "simulation",
+		Operations:       []string{"BB", "EB"},
+		StochasticMatrix: [][]float64{{1 - p, p}, {1.0, 0.0}},
+		ContractLambda:   lambda,
+		KeyLambda:        lambda,
+		ValueLambda:      lambda,
+		SnapshotLambda:   lambda,
+	}
+}
+
+// TestCompare_IdenticalModelsHaveZeroDistance checks that a model does not differ from itself.
+func TestCompare_IdenticalModelsHaveZeroDistance(t *testing.T) {
+	report, err := Compare("a", newTestSummary(0.5, 2.0), "b", newTestSummary(0.5, 2.0))
+	if err != nil {
+		t.Fatalf("failed to compare; %v", err)
+	}
+	if math.Abs(report.OperationKL) > 1e-9 || math.Abs(report.OperationJS) > 1e-9 || math.Abs(report.StationaryDistance) > 1e-9 {
+		t.Errorf("unexpected distances %v %v %v", report.OperationKL, report.OperationJS, report.StationaryDistance)
+	}
+	if report.Contracts.Difference != 0 || report.Snapshot.Difference != 0 {
+		t.Errorf("unexpected lambda differences")
+	}
+	if report.SnapshotKS != nil {
+		t.Errorf("snapshot KS distance must not be computed for simulation files")
+	}
+}
+
+// TestCompare_DifferentModels checks distances of two differing models.
+func TestCompare_DifferentModels(t *testing.T) {
+	// stationary distributions are (2/3, 1/3) and (1/2, 1/2)
+	report, err := Compare("a", newTestSummary(0.5, 2.0), "b", newTestSummary(1.0, 3.0))
+	if err != nil {
+		t.Fatalf("failed to compare; %v", err)
+	}
+	if math.Abs(report.StationaryDistance-1.0/6.0) > 1e-6 {
+		t.Errorf("unexpected stationary distance %v", report.StationaryDistance)
+	}
+	if report.OperationKL <= 0 || report.OperationJS <= 0 || report.OperationJS > 1 {
+		t.Errorf("unexpected divergences %v %v", report.OperationKL, report.OperationJS)
+	}
+	if report.Keys.Difference != 1.0 {
+		t.Errorf("unexpected lambda difference %v", report.Keys.Difference)
+	}
+	if len(report.Operations) != 2 {
+		t.Fatalf("unexpected number of operations %v", len(report.Operations))
+	}
+
+	filename := filepath.Join(t.TempDir(), "report.json")
+	if err := report.WriteJSON(filename); err != nil {
+		t.Fatalf("failed to write report; %v", err)
+	}
+}
+
+// TestCompare_DivergenceOfDisjointDistributions checks the bounds of the divergences.
+func TestCompare_DivergenceOfDisjointDistributions(t *testing.T) {
+	p := []float64{1.0, 0.0}
+	q := []float64{0.0, 1.0}
+	if js := jsDivergence(p, q); math.Abs(js-1.0) > 1e-9 {
+		t.Errorf("JS divergence of disjoint distributions must be one bit; got %v", js)
+	}
+	if kl := klDivergence(p, q, divergenceEpsilon); math.IsInf(kl, 0) || kl <= 0 {
+		t.Errorf("KL divergence must be finite and positive; got %v", kl)
+	}
+}
+
+// TestCompare_KSDistance checks the Kolmogorov-Smirnov distance of piecewise linear eCDFs.
+func TestCompare_KSDistance(t *testing.T) {
+	a := [][2]float64{{0.0, 0.0}, {0.5, 1.0}, {1.0, 1.0}}
+	b := [][2]float64{{0.0, 0.0}, {1.0, 1.0}}
+	if d := ksDistance(a, b); math.Abs(d-0.5) > 1e-9 {
+		t.Errorf("unexpected KS distance %v", d)
+	}
+	if d := ksDistance(a, a); d != 0 {
+		t.Errorf("unexpected KS distance %v", d)
+	}
+}
@@ -38,6 +38,20 @@ type EstimationModelJSON struct {
 	Values    EstimationStatsJSON `json:"valueStats"`
 
 	SnapshotLambda float64 `json:"snapshotLambda"`
+
+	ValueClasses ValueModelJSON `json:"valueClasses"`
+
+	Order    int                `json:"order"`
+	Contexts []ContextModelJSON `json:"contexts,omitempty"`
+
+	Phases []PhaseModelJSON `json:"phases,omitempty"`
+}
+
+// ContextModelJSON is the estimated successor distribution of a higher-order context.
+type ContextModelJSON struct {
+	Context       []string  `json:"context"`
+	Successors    []string  `json:"successors"`
+	Probabilities []float64 `json:"probabilities"`
 }
 
 // NewEstimationModelJSON creates a new estimation model.
@@ -68,7 +82,36 @@ func NewEstimationModelJSON(d *EventRegistryJSON) EstimationModelJSON {
 		Keys:             NewEstimationStats(&d.Keys),
 		Values:           NewEstimationStats(&d.Values),
 		SnapshotLambda:   snapshotLambda,
+		ValueClasses:     NewValueModel(&d.ValueClasses),
+		Order:            d.Order,
+		Contexts:         EstimateContexts(d.Contexts),
+	}
+}
+
+// EstimateContexts normalizes the transition frequencies of higher-order contexts to probabilities.
+func EstimateContexts(contexts []ContextJSON) []ContextModelJSON {
+	var models []ContextModelJSON
+	for _, c := range contexts {
+		total := uint64(0)
+		for _, freq := range c.Frequencies {
+			total += freq
+		}
+		if total == 0 {
+			continue
+		}
+		m := ContextModelJSON{
+			Context:       make([]string, len(c.Context)),
+			Successors:    make([]string, len(c.Successors)),
+			Probabilities: make([]float64, len(c.Frequencies)),
+		}
+		copy(m.Context, c.Context)
+		copy(m.Successors, c.Successors)
+		for i, freq := range c.Frequencies {
+			m.Probabilities[i] = float64(freq) / float64(total)
+		}
+		models = append(models, m)
 	}
+	return models
 }
 
 // ReadSimulation reads the simulation file in JSON format (generated by the estimator).
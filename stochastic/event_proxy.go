@@ -56,6 +56,7 @@ func (p *EventProxy) CreateAccount(address common.Address) {
 func (p *EventProxy) SubBalance(address common.Address, amount *big.Int) {
 	// register event
 	p.registry.RegisterAddressOp(SubBalanceID, &address)
+	p.registry.RegisterBalance(amount)
 
 	// call real StateDB
 	p.db.SubBalance(address, amount)
@@ -65,6 +66,7 @@ func (p *EventProxy) SubBalance(address common.Address, amount *big.Int) {
 func (p *EventProxy) AddBalance(address common.Address, amount *big.Int) {
 	// register event
 	p.registry.RegisterAddressOp(AddBalanceID, &address)
+	p.registry.RegisterBalance(amount)
 
 	// call real StateDB
 	p.db.AddBalance(address, amount)
@@ -92,6 +94,7 @@ func (p *EventProxy) GetNonce(address common.Address) uint64 {
 func (p *EventProxy) SetNonce(address common.Address, nonce uint64) {
 	// register event
 	p.registry.RegisterAddressOp(SetNonceID, &address)
+	p.registry.RegisterNonce(p.db.GetNonce(address), nonce)
 
 	// call real StateDB
 	p.db.SetNonce(address, nonce)
@@ -119,6 +122,7 @@ func (p *EventProxy) GetCode(address common.Address) []byte {
 func (p *EventProxy) SetCode(address common.Address, code []byte) {
 	// register event
 	p.registry.RegisterAddressOp(SetCodeID, &address)
+	p.registry.RegisterCodeSize(len(code))
 
 	// call real StateDB
 	p.db.SetCode(address, code)
@@ -135,18 +139,27 @@ func (p *EventProxy) GetCodeSize(address common.Address) int {
 
 // AddRefund adds gas to the refund counter.
 func (p *EventProxy) AddRefund(gas uint64) {
+	// register event
+	p.registry.RegisterOp(AddRefundID)
+
 	// call real StateDB
 	p.db.AddRefund(gas)
 }
 
 // SubRefund subtracts gas to the refund counter.
 func (p *EventProxy) SubRefund(gas uint64) {
+	// register event
+	p.registry.RegisterOp(SubRefundID)
+
 	// call real StateDB
 	p.db.SubRefund(gas)
 }
 
 // GetRefund returns the current value of the refund counter.
 func (p *EventProxy) GetRefund() uint64 {
+	// register event
+	p.registry.RegisterOp(GetRefundID)
+
 	// call real StateDB
 	return p.db.GetRefund()
 }
@@ -173,6 +186,7 @@ func (p *EventProxy) GetState(address common.Address, key common.Hash) common.Ha
 func (p *EventProxy) SetState(address common.Address, key common.Hash, value common.Hash) {
 	// register event
 	p.registry.RegisterValueOp(SetStateID, &address, &key, &value)
+	p.registry.RegisterStorageValue(value)
 
 	// call real StateDB
 	p.db.SetState(address, key, value)
@@ -217,30 +231,45 @@ func (p *EventProxy) Empty(address common.Address) bool {
 
 // PrepareAccessList handles the preparatory steps for executing a state transition.
 func (p *EventProxy) PrepareAccessList(render common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	// register event
+	p.registry.RegisterAddressOp(PrepareAccessListID, &render)
+
 	// call real StateDB
 	p.db.PrepareAccessList(render, dest, precompiles, txAccesses)
 }
 
 // AddAddressToAccessList adds an address to the access list.
 func (p *EventProxy) AddAddressToAccessList(address common.Address) {
+	// register event
+	p.registry.RegisterAddressOp(AddAddressToAccessListID, &address)
+
 	// call real StateDB
 	p.db.AddAddressToAccessList(address)
 }
 
 // AddressInAccessList checks whether an address is in the access list.
 func (p *EventProxy) AddressInAccessList(address common.Address) bool {
+	// register event
+	p.registry.RegisterAddressOp(AddressInAccessListID, &address)
+
 	// call real StateDB
 	return p.db.AddressInAccessList(address)
 }
 
 // SlotInAccessList checks whether the (address, slot)-tuple is in the access list.
 func (p *EventProxy) SlotInAccessList(address common.Address, slot common.Hash) (bool, bool) {
+	// register event
+	p.registry.RegisterKeyOp(SlotInAccessListID, &address, &slot)
+
 	// call real StateDB
 	return p.db.SlotInAccessList(address, slot)
 }
 
 // AddSlotToAccessList adds the given (address, slot)-tuple to the access list
 func (p *EventProxy) AddSlotToAccessList(address common.Address, slot common.Hash) {
+	// register event
+	p.registry.RegisterKeyOp(AddSlotToAccessListID, &address, &slot)
+
 	// call real StateDB
 	p.db.AddSlotToAccessList(address, slot)
 }
@@ -282,6 +311,9 @@ func (p *EventProxy) Snapshot() int {
 
 // AddLog adds a log entry.
 func (p *EventProxy) AddLog(log *types.Log) {
+	// register event
+	p.registry.RegisterAddressOp(AddLogID, &log.Address)
+
 	// call real StateDB
 	p.db.AddLog(log)
 }
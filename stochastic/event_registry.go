@@ -21,7 +21,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"os"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/paulmach/orb"
@@ -36,7 +38,18 @@ type EventRegistry struct {
 	argOpFreq [numArgOps]uint64
 
 	// Transition frequencies between two subsequent argument-encoded operations
-	transitFreq [numArgOps][numArgOps]uint64
+	// (rows are sparse since most argument-encoded operations are never observed)
+	transitFreq [numArgOps]map[int]uint64
+
+	// Order of the Markov chain, i.e., the maximal context length
+	order int
+
+	// Sparse transition frequencies for contexts of length 2 up to order
+	// (the key is the encoded context of argument-encoded operations)
+	contextFreq map[string]map[int]uint64
+
+	// Most recent argument-encoded operations (at most order many)
+	history []int
 
 	// Contract-address access statistics
 	contracts statistics.Access[common.Address]
@@ -52,17 +65,44 @@ type EventRegistry struct {
 
 	// Snapshot deltas
 	snapshotFreq map[int]uint64
+
+	// Value-class frequencies of balances, nonce increments, code sizes and storage values
+	valueClasses ValueClassesJSON
+
+	// Segments of consecutive blocks with their own transition frequencies
+	// (a segment length of zero disables segmentation)
+	segmentLength  int
+	segmentBlocks  int
+	segmentFull    bool
+	segmentTransit map[int]map[int]uint64
+	segments       []SegmentJSON
 }
 
-// NewEventRegistry creates a new event registry.
+// NewEventRegistry creates a new event registry for a first-order Markov chain.
 func NewEventRegistry() EventRegistry {
-	return EventRegistry{
+	return NewEventRegistryWithOrder(1)
+}
+
+// NewEventRegistryWithOrder creates a new event registry that additionally counts
+// transitions conditioned on the last k argument-encoded operations for k up to order.
+func NewEventRegistryWithOrder(order int) EventRegistry {
+	if order < 1 {
+		log.Fatalf("invalid order of Markov chain")
+	}
+	r := EventRegistry{
 		prevArgOp:    numArgOps,
+		order:        order,
+		contextFreq:  map[string]map[int]uint64{},
 		contracts:    statistics.NewAccess[common.Address](),
 		keys:         statistics.NewAccess[common.Hash](),
 		values:       statistics.NewAccess[common.Hash](),
 		snapshotFreq: map[int]uint64{},
+		valueClasses: NewValueClassesJSON(),
+	}
+	for i := range r.transitFreq {
+		r.transitFreq[i] = map[int]uint64{}
 	}
+	return r
 }
 
 // RegisterOp registers an operation with no simulation arguments
@@ -156,7 +196,27 @@ func (r *EventRegistry) updateFreq(op int, addr int, key int, value int) {
 	if r.prevArgOp < numArgOps {
 		r.transitFreq[r.prevArgOp][argOp] = r.transitFreq[r.prevArgOp][argOp] + 1
 	}
+
+	// count transitions of the current segment
+	if r.segmentLength > 0 {
+		r.updateSegment(op, argOp)
+	}
 	r.prevArgOp = argOp
+
+	// count transitions of higher-order contexts
+	if r.order > 1 {
+		for l := 2; l <= len(r.history); l++ {
+			ctx := encodeContext(r.history[len(r.history)-l:])
+			if _, ok := r.contextFreq[ctx]; !ok {
+				r.contextFreq[ctx] = map[int]uint64{}
+			}
+			r.contextFreq[ctx][argOp]++
+		}
+		r.history = append(r.history, argOp)
+		if len(r.history) > r.order {
+			r.history = r.history[1:]
+		}
+	}
 }
 
 // RegisterSnapshotDelta counts the delta of a snapshot. The delta is
@@ -168,28 +228,45 @@ func (r *EventRegistry) RegisterSnapshotDelta(delta int) {
 	r.snapshotFreq[delta]++
 }
 
-// WriteJSON writes an event registry in JSON format.
-func (r *EventRegistry) WriteJSON(filename string) error {
-	f, fErr := os.Create(filename)
-	if fErr != nil {
-		return fmt.Errorf("cannot open JSON file; %v", fErr)
-	}
-	defer f.Close()
-	jOut, jErr := json.MarshalIndent(r.NewEventRegistryJSON(), "", "    ")
-	if jErr != nil {
-		return fmt.Errorf("failed to convert JSON file; %v", jErr)
+// RegisterBalance counts the value class of a balance amount.
+func (r *EventRegistry) RegisterBalance(amount *big.Int) {
+	r.valueClasses.Balances[balanceClass(amount)]++
+}
+
+// RegisterNonce counts the value class of a nonce increment. Nonces that
+// do not increase are counted as an increment of zero.
+func (r *EventRegistry) RegisterNonce(previous uint64, nonce uint64) {
+	increment := uint64(0)
+	if nonce > previous {
+		increment = nonce - previous
 	}
-	_, pErr := fmt.Fprintln(f, string(jOut))
-	if pErr != nil {
-		return fmt.Errorf("failed to convert JSON file; %v", pErr)
+	r.valueClasses.Nonces[nonceClass(increment)]++
+}
+
+// RegisterCodeSize counts the value class of a code size.
+func (r *EventRegistry) RegisterCodeSize(size int) {
+	r.valueClasses.CodeSizes[codeSizeClass(size)]++
+}
+
+// RegisterStorageValue counts the value class of a non-zero storage value.
+// Zero values are already modelled by the argument class of the operation.
+func (r *EventRegistry) RegisterStorageValue(value common.Hash) {
+	if value != (common.Hash{}) {
+		r.valueClasses.StorageValues[valueClass(value)]++
 	}
-	return nil
+}
+
+// WriteJSON writes an event registry in JSON format.
+func (r *EventRegistry) WriteJSON(filename string) error {
+	d := r.NewEventRegistryJSON()
+	return d.WriteJSON(filename)
 }
 
 // EventRegistryJSON is the JSON struct for an event registry.
 type EventRegistryJSON struct {
 	FileId           string      `json:"FileId"`           // file identification
 	Operations       []string    `json:"operations"`       // name of operations with argument classes
+	Frequencies      []uint64    `json:"frequencies"`      // observed frequencies of operations
 	StochasticMatrix [][]float64 `json:"stochasticMatrix"` // observed stochastic matrix
 
 	// access statistics for contracts, keys, and values
@@ -198,18 +275,38 @@ type EventRegistryJSON struct {
 	Values    statistics.AccessJSON `json:"valueSats"`
 
 	// snapshot delta frequencies
-	SnapshotEcdf [][2]float64 `json:"snapshotEcdf"`
+	SnapshotEcdf [][2]float64   `json:"snapshotEcdf"`
+	SnapshotFreq map[int]uint64 `json:"snapshotFreq"`
+
+	// value-class frequencies of balances, nonce increments, code sizes and storage values
+	ValueClasses ValueClassesJSON `json:"valueClasses"`
+
+	// transition frequencies of consecutive segments of blocks
+	Segments []SegmentJSON `json:"segments,omitempty"`
+
+	// order of the Markov chain and transition frequencies of higher-order contexts
+	Order    int           `json:"order"`
+	Contexts []ContextJSON `json:"contexts,omitempty"`
+}
+
+// ContextJSON is the JSON struct for the transition frequencies of a higher-order context.
+type ContextJSON struct {
+	Context     []string `json:"context"`     // operations of the context (oldest first)
+	Successors  []string `json:"successors"`  // observed successor operations
+	Frequencies []uint64 `json:"frequencies"` // transition frequencies of the successors
 }
 
 // NewEventRegistry produces the JSON output for an event registry.
 func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 	// generate labels for observable operations
 	label := []string{}
+	frequencies := []uint64{}
 	for argop := 0; argop < numArgOps; argop++ {
 		if r.argOpFreq[argop] > 0 {
 			// decode argument-encoded operation
 			op, addr, key, value := DecodeArgOp(argop)
 			label = append(label, EncodeOpcode(op, addr, key, value))
+			frequencies = append(frequencies, r.argOpFreq[argop])
 		}
 	}
 
@@ -220,8 +317,8 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 			row := []float64{}
 			// find row total of row (i.e. state i)
 			total := uint64(0)
-			for j := 0; j < numArgOps; j++ {
-				total += r.transitFreq[i][j]
+			for _, freq := range r.transitFreq[i] {
+				total += freq
 			}
 			// normalize row
 			for j := 0; j < numArgOps; j++ {
@@ -233,10 +330,58 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 		}
 	}
 
-	// Compute ECDF of snapshot deltas
+	// Copy snapshot delta frequencies
+	snapshotFreq := make(map[int]uint64, len(r.snapshotFreq))
+	for delta, freq := range r.snapshotFreq {
+		snapshotFreq[delta] = freq
+	}
+
+	// Collect transition frequencies of higher-order contexts in a deterministic order
+	var contexts []ContextJSON
+	ctxKeys := make([]string, 0, len(r.contextFreq))
+	for ctx := range r.contextFreq {
+		ctxKeys = append(ctxKeys, ctx)
+	}
+	sort.Strings(ctxKeys)
+	for _, ctx := range ctxKeys {
+		successors := make([]int, 0, len(r.contextFreq[ctx]))
+		for argop := range r.contextFreq[ctx] {
+			successors = append(successors, argop)
+		}
+		sort.Ints(successors)
+		c := ContextJSON{}
+		for _, argop := range decodeContext(ctx) {
+			c.Context = append(c.Context, argOpcode(argop))
+		}
+		for _, argop := range successors {
+			c.Successors = append(c.Successors, argOpcode(argop))
+			c.Frequencies = append(c.Frequencies, r.contextFreq[ctx][argop])
+		}
+		contexts = append(contexts, c)
+	}
+
+	return EventRegistryJSON{
+		FileId:           "events",
+		Operations:       label,
+		Frequencies:      frequencies,
+		StochasticMatrix: A,
+		Contracts:        r.contracts.NewAccessJSON(),
+		Keys:             r.keys.NewAccessJSON(),
+		Values:           r.values.NewAccessJSON(),
+		SnapshotEcdf:     snapshotEcdf(r.snapshotFreq),
+		SnapshotFreq:     snapshotFreq,
+		ValueClasses:     r.valueClasses.copy(),
+		Segments:         r.segmentsJSON(),
+		Order:            r.order,
+		Contexts:         contexts,
+	}
+}
+
+// snapshotEcdf computes the simplified ECDF of snapshot deltas.
+func snapshotEcdf(snapshotFreq map[int]uint64) [][2]float64 {
 	totalFreq := uint64(0)
 	maxDelta := 0
-	for delta, freq := range r.snapshotFreq {
+	for delta, freq := range snapshotFreq {
 		totalFreq += freq
 		if maxDelta < delta {
 			maxDelta = delta
@@ -246,7 +391,7 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 	var simplified orb.LineString
 
 	// if no data-points, nothing to plot
-	if len(r.snapshotFreq) > 0 {
+	if len(snapshotFreq) > 0 {
 
 		// construct full eCdf as LineString
 		ls := orb.LineString{}
@@ -265,8 +410,11 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 			// Implement Kahan's summation to avoid errors
 			// for accumulated probabilities (they might be very small)
 			// https://en.wikipedia.org/wiki/Kahan_summation_algorithm
-			f := float64(r.snapshotFreq[delta]) / float64(totalFreq)
-			x := float64(delta) / float64(maxDelta)
+			f := float64(snapshotFreq[delta]) / float64(totalFreq)
+			x := 1.0
+			if maxDelta > 0 {
+				x = float64(delta) / float64(maxDelta)
+			}
 
 			yP := f - cP
 			tP := sumP + yP
@@ -291,16 +439,7 @@ func (r *EventRegistry) NewEventRegistryJSON() EventRegistryJSON {
 	for i := range simplified {
 		eCdf[i] = [2]float64(simplified[i])
 	}
-
-	return EventRegistryJSON{
-		FileId:           "events",
-		Operations:       label,
-		StochasticMatrix: A,
-		Contracts:        r.contracts.NewAccessJSON(),
-		Keys:             r.keys.NewAccessJSON(),
-		Values:           r.values.NewAccessJSON(),
-		SnapshotEcdf:     eCdf,
-	}
+	return eCdf
 }
 
 // ReadEventsJSON reads event file in JSON format.
@@ -17,6 +17,7 @@
 package stochastic
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/Fantom-foundation/Aida/stochastic/statistics"
@@ -208,3 +209,42 @@ func TestEventRegistryZeroOperation(t *testing.T) {
 		t.Fatalf("operation/transit frequency diverges")
 	}
 }
+
+// TestEventRegistryHigherOrderContexts checks that transitions of higher-order contexts are counted.
+func TestEventRegistryHigherOrderContexts(t *testing.T) {
+	r := NewEventRegistryWithOrder(3)
+	for _, op := range []int{BeginBlockID, BeginTransactionID, EndTransactionID, BeginTransactionID, EndTransactionID, EndBlockID} {
+		r.RegisterOp(op)
+	}
+
+	events := r.NewEventRegistryJSON()
+	if events.Order != 3 {
+		t.Fatalf("unexpected order %v", events.Order)
+	}
+	freq := map[string]uint64{}
+	for _, c := range events.Contexts {
+		if len(c.Successors) != len(c.Frequencies) {
+			t.Fatalf("mismatching successors and frequencies")
+		}
+		for i, successor := range c.Successors {
+			freq[fmt.Sprintf("%v->%v", c.Context, successor)] = c.Frequencies[i]
+		}
+	}
+	want := map[string]uint64{
+		"[BB BT]->ET":    1,
+		"[BT ET]->BT":    1,
+		"[ET BT]->ET":    1,
+		"[BT ET]->EB":    1,
+		"[BB BT ET]->BT": 1,
+		"[BT ET BT]->ET": 1,
+		"[ET BT ET]->EB": 1,
+	}
+	if len(freq) != len(want) {
+		t.Fatalf("unexpected contexts; got %v, want %v", freq, want)
+	}
+	for k, v := range want {
+		if freq[k] != v {
+			t.Errorf("unexpected frequency of %v; got %v, want %v", k, freq[k], v)
+		}
+	}
+}
@@ -0,0 +1,503 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// FuzzOperation is a StateDB operation with concrete arguments observed in a fuzzing run.
+type FuzzOperation struct {
+	Op      string          `json:"op"`                // mnemonic of the operation
+	Address *common.Address `json:"address,omitempty"` // contract address
+	Key     *common.Hash    `json:"key,omitempty"`     // storage key
+	Value   *common.Hash    `json:"value,omitempty"`   // storage value
+	Amount  *hexutil.Big    `json:"amount,omitempty"`  // balance amount
+	Code    hexutil.Bytes   `json:"code,omitempty"`    // contract code
+	Number  uint64          `json:"number,omitempty"`  // sync-period, block or transaction number, nonce, gas, or snapshot delta
+}
+
+// String returns a human-readable representation of the operation.
+func (o *FuzzOperation) String() string {
+	s := opText[opId[o.Op]]
+	if o.Address != nil {
+		s += fmt.Sprintf(" %v", o.Address.Hex())
+	}
+	if o.Key != nil {
+		s += fmt.Sprintf(" %v", o.Key.Hex())
+	}
+	if o.Value != nil {
+		s += fmt.Sprintf(" %v", o.Value.Hex())
+	}
+	if o.Amount != nil {
+		s += fmt.Sprintf(" %v", o.Amount.ToInt())
+	}
+	if o.Code != nil {
+		s += fmt.Sprintf(" code-size: %v", len(o.Code))
+	}
+	switch opId[o.Op] {
+	case BeginSyncPeriodID, BeginBlockID, BeginTransactionID, SetNonceID, AddRefundID, SubRefundID, RevertToSnapshotID:
+		s += fmt.Sprintf(" %v", o.Number)
+	}
+	return s
+}
+
+// fuzzRecorder is a StateDB proxy recording the operations issued by the stochastic
+// replay with their concrete arguments. Snapshot reverts are recorded by their delta
+// on the snapshot stack so that they remain valid when operations are removed.
+type fuzzRecorder struct {
+	state.StateDB
+	ops       []FuzzOperation
+	snapshots []int // active snapshot ids of the current transaction
+}
+
+// newFuzzRecorder creates a recording proxy for a StateDB.
+func newFuzzRecorder(db state.StateDB) *fuzzRecorder {
+	return &fuzzRecorder{StateDB: db}
+}
+
+func (r *fuzzRecorder) record(op int, o FuzzOperation) {
+	o.Op = OpMnemo(op)
+	r.ops = append(r.ops, o)
+}
+
+func (r *fuzzRecorder) CreateAccount(addr common.Address) {
+	r.record(CreateAccountID, FuzzOperation{Address: &addr})
+	r.StateDB.CreateAccount(addr)
+}
+
+func (r *fuzzRecorder) Exist(addr common.Address) bool {
+	r.record(ExistID, FuzzOperation{Address: &addr})
+	return r.StateDB.Exist(addr)
+}
+
+func (r *fuzzRecorder) Empty(addr common.Address) bool {
+	r.record(EmptyID, FuzzOperation{Address: &addr})
+	return r.StateDB.Empty(addr)
+}
+
+func (r *fuzzRecorder) Suicide(addr common.Address) bool {
+	r.record(SuicideID, FuzzOperation{Address: &addr})
+	return r.StateDB.Suicide(addr)
+}
+
+func (r *fuzzRecorder) HasSuicided(addr common.Address) bool {
+	r.record(HasSuicidedID, FuzzOperation{Address: &addr})
+	return r.StateDB.HasSuicided(addr)
+}
+
+func (r *fuzzRecorder) GetBalance(addr common.Address) *big.Int {
+	r.record(GetBalanceID, FuzzOperation{Address: &addr})
+	return r.StateDB.GetBalance(addr)
+}
+
+func (r *fuzzRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.record(AddBalanceID, FuzzOperation{Address: &addr, Amount: (*hexutil.Big)(new(big.Int).Set(amount))})
+	r.StateDB.AddBalance(addr, amount)
+}
+
+func (r *fuzzRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	r.record(SubBalanceID, FuzzOperation{Address: &addr, Amount: (*hexutil.Big)(new(big.Int).Set(amount))})
+	r.StateDB.SubBalance(addr, amount)
+}
+
+func (r *fuzzRecorder) GetNonce(addr common.Address) uint64 {
+	r.record(GetNonceID, FuzzOperation{Address: &addr})
+	return r.StateDB.GetNonce(addr)
+}
+
+func (r *fuzzRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.record(SetNonceID, FuzzOperation{Address: &addr, Number: nonce})
+	r.StateDB.SetNonce(addr, nonce)
+}
+
+func (r *fuzzRecorder) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	r.record(GetCommittedStateID, FuzzOperation{Address: &addr, Key: &key})
+	return r.StateDB.GetCommittedState(addr, key)
+}
+
+func (r *fuzzRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	r.record(GetStateID, FuzzOperation{Address: &addr, Key: &key})
+	return r.StateDB.GetState(addr, key)
+}
+
+func (r *fuzzRecorder) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	r.record(SetStateID, FuzzOperation{Address: &addr, Key: &key, Value: &value})
+	r.StateDB.SetState(addr, key, value)
+}
+
+func (r *fuzzRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.record(GetCodeHashID, FuzzOperation{Address: &addr})
+	return r.StateDB.GetCodeHash(addr)
+}
+
+func (r *fuzzRecorder) GetCode(addr common.Address) []byte {
+	r.record(GetCodeID, FuzzOperation{Address: &addr})
+	return r.StateDB.GetCode(addr)
+}
+
+func (r *fuzzRecorder) SetCode(addr common.Address, code []byte) {
+	r.record(SetCodeID, FuzzOperation{Address: &addr, Code: common.CopyBytes(code)})
+	r.StateDB.SetCode(addr, code)
+}
+
+func (r *fuzzRecorder) GetCodeSize(addr common.Address) int {
+	r.record(GetCodeSizeID, FuzzOperation{Address: &addr})
+	return r.StateDB.GetCodeSize(addr)
+}
+
+func (r *fuzzRecorder) AddRefund(gas uint64) {
+	r.record(AddRefundID, FuzzOperation{Number: gas})
+	r.StateDB.AddRefund(gas)
+}
+
+func (r *fuzzRecorder) SubRefund(gas uint64) {
+	r.record(SubRefundID, FuzzOperation{Number: gas})
+	r.StateDB.SubRefund(gas)
+}
+
+func (r *fuzzRecorder) GetRefund() uint64 {
+	r.record(GetRefundID, FuzzOperation{})
+	return r.StateDB.GetRefund()
+}
+
+func (r *fuzzRecorder) PrepareAccessList(sender common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	r.record(PrepareAccessListID, FuzzOperation{Address: &sender})
+	r.StateDB.PrepareAccessList(sender, dest, precompiles, txAccesses)
+}
+
+func (r *fuzzRecorder) AddressInAccessList(addr common.Address) bool {
+	r.record(AddressInAccessListID, FuzzOperation{Address: &addr})
+	return r.StateDB.AddressInAccessList(addr)
+}
+
+func (r *fuzzRecorder) SlotInAccessList(addr common.Address, slot common.Hash) (bool, bool) {
+	r.record(SlotInAccessListID, FuzzOperation{Address: &addr, Key: &slot})
+	return r.StateDB.SlotInAccessList(addr, slot)
+}
+
+func (r *fuzzRecorder) AddAddressToAccessList(addr common.Address) {
+	r.record(AddAddressToAccessListID, FuzzOperation{Address: &addr})
+	r.StateDB.AddAddressToAccessList(addr)
+}
+
+func (r *fuzzRecorder) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	r.record(AddSlotToAccessListID, FuzzOperation{Address: &addr, Key: &slot})
+	r.StateDB.AddSlotToAccessList(addr, slot)
+}
+
+func (r *fuzzRecorder) AddLog(l *types.Log) {
+	r.record(AddLogID, FuzzOperation{Address: &l.Address})
+	r.StateDB.AddLog(l)
+}
+
+func (r *fuzzRecorder) Snapshot() int {
+	r.record(SnapshotID, FuzzOperation{})
+	id := r.StateDB.Snapshot()
+	r.snapshots = append(r.snapshots, id)
+	return id
+}
+
+func (r *fuzzRecorder) RevertToSnapshot(id int) {
+	delta := 0
+	for i := len(r.snapshots) - 1; i >= 0; i-- {
+		if r.snapshots[i] == id {
+			delta = len(r.snapshots) - 1 - i
+			r.snapshots = r.snapshots[:i]
+			break
+		}
+	}
+	r.record(RevertToSnapshotID, FuzzOperation{Number: uint64(delta)})
+	r.StateDB.RevertToSnapshot(id)
+}
+
+func (r *fuzzRecorder) BeginTransaction(tx uint32) error {
+	r.record(BeginTransactionID, FuzzOperation{Number: uint64(tx)})
+	r.snapshots = r.snapshots[:0]
+	return r.StateDB.BeginTransaction(tx)
+}
+
+func (r *fuzzRecorder) EndTransaction() error {
+	r.record(EndTransactionID, FuzzOperation{})
+	return r.StateDB.EndTransaction()
+}
+
+func (r *fuzzRecorder) BeginBlock(block uint64) error {
+	r.record(BeginBlockID, FuzzOperation{Number: block})
+	return r.StateDB.BeginBlock(block)
+}
+
+func (r *fuzzRecorder) EndBlock() error {
+	r.record(EndBlockID, FuzzOperation{})
+	return r.StateDB.EndBlock()
+}
+
+func (r *fuzzRecorder) BeginSyncPeriod(number uint64) {
+	r.record(BeginSyncPeriodID, FuzzOperation{Number: number})
+	r.StateDB.BeginSyncPeriod(number)
+}
+
+func (r *fuzzRecorder) EndSyncPeriod() {
+	r.record(EndSyncPeriodID, FuzzOperation{})
+	r.StateDB.EndSyncPeriod()
+}
+
+// fuzzExecutor executes recorded operations on a StateDB.
+type fuzzExecutor struct {
+	db        state.StateDB
+	snapshots []int // active snapshot ids of the current transaction
+}
+
+// execute executes a recorded operation. Balances and refunds are only decreased
+// by at most their current values, and reverts to snapshots that do not exist
+// (because operations were removed) are skipped.
+func (x *fuzzExecutor) execute(o *FuzzOperation) error {
+	db := x.db
+	addr := common.Address{}
+	if o.Address != nil {
+		addr = *o.Address
+	}
+	key := common.Hash{}
+	if o.Key != nil {
+		key = *o.Key
+	}
+	op, ok := opId[o.Op]
+	if !ok {
+		return fmt.Errorf("unknown operation %v", o.Op)
+	}
+	switch op {
+	case AddAddressToAccessListID:
+		db.AddAddressToAccessList(addr)
+	case AddBalanceID:
+		db.AddBalance(addr, o.Amount.ToInt())
+	case AddLogID:
+		db.AddLog(&types.Log{Address: addr})
+	case AddRefundID:
+		db.AddRefund(o.Number)
+	case AddSlotToAccessListID:
+		db.AddSlotToAccessList(addr, key)
+	case AddressInAccessListID:
+		db.AddressInAccessList(addr)
+	case BeginBlockID:
+		return db.BeginBlock(o.Number)
+	case BeginSyncPeriodID:
+		db.BeginSyncPeriod(o.Number)
+	case BeginTransactionID:
+		x.snapshots = x.snapshots[:0]
+		return db.BeginTransaction(uint32(o.Number))
+	case CreateAccountID:
+		db.CreateAccount(addr)
+	case EmptyID:
+		db.Empty(addr)
+	case EndBlockID:
+		return db.EndBlock()
+	case EndSyncPeriodID:
+		db.EndSyncPeriod()
+	case EndTransactionID:
+		return db.EndTransaction()
+	case ExistID:
+		db.Exist(addr)
+	case GetBalanceID:
+		db.GetBalance(addr)
+	case GetCodeHashID:
+		db.GetCodeHash(addr)
+	case GetCodeID:
+		db.GetCode(addr)
+	case GetCodeSizeID:
+		db.GetCodeSize(addr)
+	case GetCommittedStateID:
+		db.GetCommittedState(addr, key)
+	case GetNonceID:
+		db.GetNonce(addr)
+	case GetRefundID:
+		db.GetRefund()
+	case GetStateID:
+		db.GetState(addr, key)
+	case HasSuicidedID:
+		db.HasSuicided(addr)
+	case PrepareAccessListID:
+		db.PrepareAccessList(addr, nil, []common.Address{}, types.AccessList{})
+	case RevertToSnapshotID:
+		delta := int(o.Number)
+		if delta < len(x.snapshots) {
+			idx := len(x.snapshots) - 1 - delta
+			db.RevertToSnapshot(x.snapshots[idx])
+			x.snapshots = x.snapshots[:idx]
+		}
+	case SetCodeID:
+		db.SetCode(addr, o.Code)
+	case SetNonceID:
+		db.SetNonce(addr, o.Number)
+	case SetStateID:
+		value := common.Hash{}
+		if o.Value != nil {
+			value = *o.Value
+		}
+		db.SetState(addr, key, value)
+	case SlotInAccessListID:
+		db.SlotInAccessList(addr, key)
+	case SnapshotID:
+		x.snapshots = append(x.snapshots, db.Snapshot())
+	case SubBalanceID:
+		amount := o.Amount.ToInt()
+		if balance := x.reference().GetBalance(addr); amount.Cmp(balance) > 0 {
+			amount = balance
+		}
+		db.SubBalance(addr, amount)
+	case SubRefundID:
+		gas := o.Number
+		if refund := x.reference().GetRefund(); gas > refund {
+			gas = refund
+		}
+		db.SubRefund(gas)
+	case SuicideID:
+		db.Suicide(addr)
+	}
+	return nil
+}
+
+// reference returns the shadow DB if present for reading values without cross-checking.
+func (x *fuzzExecutor) reference() state.StateDB {
+	if shadow := x.db.GetShadowDB(); shadow != nil {
+		return shadow
+	}
+	return x.db
+}
+
+// FuzzFailure describes the failure of a fuzzing run.
+type FuzzFailure struct {
+	Panic   bool   `json:"panic"`   // true if a StateDB panicked, false if the StateDBs diverged
+	Message string `json:"message"` // panic or error message
+	Index   int    `json:"index"`   // index of the failing operation
+}
+
+// sameKind returns true if both failures are panics or both are divergences.
+func (f *FuzzFailure) sameKind(other *FuzzFailure) bool {
+	return other != nil && f.Panic == other.Panic
+}
+
+// ExecuteFuzzOperations executes recorded operations on a StateDB and returns the
+// first failure, i.e., a panic or an error reported by the StateDB (e.g. a divergence
+// between the primary and the shadow DB), or nil if all operations succeeded.
+func ExecuteFuzzOperations(db state.StateDB, ops []FuzzOperation) (failure *FuzzFailure) {
+	x := &fuzzExecutor{db: db}
+	i := 0
+	defer func() {
+		if r := recover(); r != nil {
+			failure = &FuzzFailure{Panic: true, Message: fmt.Sprint(r), Index: i}
+		}
+	}()
+	for ; i < len(ops); i++ {
+		err := x.execute(&ops[i])
+		if err == nil {
+			err = db.Error()
+		}
+		if err != nil {
+			return &FuzzFailure{Message: err.Error(), Index: i}
+		}
+	}
+	return nil
+}
+
+// FuzzArtifactJSON is a replayable fuzzing failure with the operations that trigger it.
+type FuzzArtifactJSON struct {
+	FileId     string          `json:"FileId"`
+	Seed       int64           `json:"seed"`       // random seed of the failing run
+	Blocks     int             `json:"blocks"`     // number of simulated blocks of the failing run
+	Recorded   int             `json:"recorded"`   // number of recorded operations before shrinking
+	Failure    FuzzFailure     `json:"failure"`    // failure of the shrunk operations
+	Operations []FuzzOperation `json:"operations"` // shrunk operations
+}
+
+// ReadFuzzArtifact reads a fuzzing artifact in JSON format.
+func ReadFuzzArtifact(filename string) (*FuzzArtifactJSON, error) {
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading fuzzing artifact; %v", err)
+	}
+	var artifact FuzzArtifactJSON
+	if err := json.Unmarshal(contents, &artifact); err != nil {
+		return nil, fmt.Errorf("failed unmarshalling JSON; %v", err)
+	}
+	if artifact.FileId != "fuzz" {
+		return nil, fmt.Errorf("file %v is not a fuzzing artifact", filename)
+	}
+	return &artifact, nil
+}
+
+// WriteJSON writes a fuzzing artifact in JSON format.
+func (a *FuzzArtifactJSON) WriteJSON(filename string) error {
+	f, fErr := os.Create(filename)
+	if fErr != nil {
+		return fmt.Errorf("cannot open JSON file; %v", fErr)
+	}
+	defer f.Close()
+	jOut, jErr := json.MarshalIndent(a, "", "    ")
+	if jErr != nil {
+		return fmt.Errorf("failed to convert JSON file; %v", jErr)
+	}
+	if _, pErr := fmt.Fprintln(f, string(jOut)); pErr != nil {
+		return fmt.Errorf("failed to convert JSON file; %v", pErr)
+	}
+	return nil
+}
+
+// WriteReport writes a human-readable report of a fuzzing artifact.
+func (a *FuzzArtifactJSON) WriteReport(out io.Writer) error {
+	kind := "divergence"
+	if a.Failure.Panic {
+		kind = "panic"
+	}
+	blocks, txs := countFuzzStructure(a.Operations)
+	if _, err := fmt.Fprintf(out, "seed: %v\nsimulated blocks: %v\nfailure (%v): %v\nrecorded operations: %v\nshrunk operations: %v (%v blocks, %v transactions)\n\n",
+		a.Seed, a.Blocks, kind, a.Failure.Message, a.Recorded, len(a.Operations), blocks, txs); err != nil {
+		return err
+	}
+	for i := range a.Operations {
+		marker := " "
+		if i == a.Failure.Index {
+			marker = ">"
+		}
+		if _, err := fmt.Fprintf(out, "%v %5d %v\n", marker, i, a.Operations[i].String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countFuzzStructure counts the blocks and transactions of recorded operations.
+func countFuzzStructure(ops []FuzzOperation) (int, int) {
+	blocks, txs := 0, 0
+	for i := range ops {
+		switch opId[ops[i].Op] {
+		case BeginBlockID:
+			blocks++
+		case BeginTransactionID:
+			txs++
+		}
+	}
+	return blocks, txs
+}
@@ -0,0 +1,232 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+)
+
+// FuzzShrinkAttempts is the maximal number of executions for shrinking a failure.
+var FuzzShrinkAttempts = 1000
+
+// StateDBFactory creates a fresh StateDB and a function that closes and removes it.
+type StateDBFactory func() (state.StateDB, func(), error)
+
+// Fuzz runs the stochastic replay of nBlocks blocks for consecutive random seeds
+// starting with the configured seed until a StateDB panics or reports an error (e.g.
+// a divergence between the primary and the shadow DB). At most numSeeds seeds are
+// run (unlimited if zero). The operations of the failing run are shrunk to fewer
+// sync-periods, blocks, transactions and operations that still produce a failure of
+// the same kind. Fuzz returns nil if no failure was found.
+func Fuzz(e *EstimationModelJSON, nBlocks int, numSeeds int, cfg *utils.Config, makeDb StateDBFactory, log logger.Logger) (*FuzzArtifactJSON, error) {
+	// validate the simulation model before running seeds
+	if _, err := NewMarkovChain(e.Operations, e.StochasticMatrix, e.Order, e.Contexts); err != nil {
+		return nil, err
+	}
+	if _, err := newPhaseSchedule(e); err != nil {
+		return nil, err
+	}
+
+	for i := 0; numSeeds == 0 || i < numSeeds; i++ {
+		seed := cfg.RandomSeed + int64(i)
+		log.Infof("Run seed %v", seed)
+		ops, failure, err := runFuzzSeed(e, nBlocks, seed, cfg, makeDb)
+		if err != nil {
+			return nil, err
+		}
+		if failure == nil {
+			continue
+		}
+		log.Noticef("Seed %v failed after %v operations: %v", seed, len(ops), failure.Message)
+		artifact := &FuzzArtifactJSON{
+			FileId:     "fuzz",
+			Seed:       seed,
+			Blocks:     nBlocks,
+			Recorded:   len(ops),
+			Failure:    *failure,
+			Operations: ops,
+		}
+
+		// reproduce the failure with the recorded operations and shrink them
+		s := &fuzzShrinker{makeDb: makeDb, target: failure}
+		if !s.fails(ops) {
+			log.Warning("Failure cannot be reproduced with the recorded operations; skip shrinking")
+			return artifact, nil
+		}
+		artifact.Operations = s.shrink(s.ops)
+		artifact.Failure = *s.failure
+		log.Noticef("Shrunk failure to %v operations in %v executions", len(artifact.Operations), s.attempts)
+		return artifact, nil
+	}
+	return nil, nil
+}
+
+// runFuzzSeed runs the stochastic replay for a seed and returns the recorded operations
+// up to the failing operation and the failure, or no failure if the run succeeded.
+func runFuzzSeed(e *EstimationModelJSON, nBlocks int, seed int64, cfg *utils.Config, makeDb StateDBFactory) (ops []FuzzOperation, failure *FuzzFailure, err error) {
+	db, release, err := makeDb()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer safeRelease(release)
+	rec := newFuzzRecorder(db)
+	runCfg := *cfg
+	runCfg.RandomSeed = seed
+	runCfg.ContinueOnFailure = false
+	runCfg.ArchiveQueryRate = 0
+	defer func() {
+		if r := recover(); r != nil {
+			failure = &FuzzFailure{Panic: true, Message: fmt.Sprint(r), Index: len(rec.ops) - 1}
+			ops = rec.ops
+		}
+	}()
+	if runErr := RunStochasticReplay(rec, e, nBlocks, &runCfg, logger.NewLogger("ERROR", "Stochastic Fuzz")); runErr != nil {
+		return rec.ops, &FuzzFailure{Message: runErr.Error(), Index: len(rec.ops) - 1}, nil
+	}
+	return rec.ops, nil, nil
+}
+
+// safeRelease releases a StateDB ignoring panics of a StateDB in an inconsistent state.
+func safeRelease(release func()) {
+	defer func() {
+		recover()
+	}()
+	release()
+}
+
+// fuzzShrinker removes operations of a failing run while a failure of the same kind persists.
+type fuzzShrinker struct {
+	makeDb   StateDBFactory
+	target   *FuzzFailure    // original failure
+	ops      []FuzzOperation // smallest failing operations found so far
+	failure  *FuzzFailure    // failure of the smallest failing operations
+	attempts int             // number of executions
+}
+
+// fails executes operations on a fresh StateDB and returns true if a failure of the
+// same kind as the original failure occurs. The operations after the failing operation
+// are dropped from the smallest failing operations.
+func (s *fuzzShrinker) fails(ops []FuzzOperation) bool {
+	if s.attempts >= FuzzShrinkAttempts {
+		return false
+	}
+	s.attempts++
+	db, release, err := s.makeDb()
+	if err != nil {
+		return false
+	}
+	defer safeRelease(release)
+	failure := ExecuteFuzzOperations(db, ops)
+	if !s.target.sameKind(failure) {
+		return false
+	}
+	s.ops = ops[:failure.Index+1]
+	s.failure = failure
+	return true
+}
+
+// shrink removes sync-periods, then blocks, then transactions and then single operations.
+func (s *fuzzShrinker) shrink(ops []FuzzOperation) []FuzzOperation {
+	ops = s.removeUnits(ops, fuzzSpans(BeginSyncPeriodID, EndSyncPeriodID))
+	ops = s.removeUnits(ops, fuzzSpans(BeginBlockID, EndBlockID))
+	ops = s.removeUnits(ops, fuzzSpans(BeginTransactionID, EndTransactionID))
+	ops = s.removeUnits(ops, fuzzOperationSpans)
+	return ops
+}
+
+// removeUnits removes spans of operations by delta debugging: the spans are split into
+// chunks, and a chunk is removed if the remaining operations still fail. The granularity
+// is refined until single spans cannot be removed anymore.
+func (s *fuzzShrinker) removeUnits(ops []FuzzOperation, spans func([]FuzzOperation) [][2]int) []FuzzOperation {
+	n := 2
+	for {
+		units := spans(ops)
+		if len(units) == 0 {
+			return ops
+		}
+		n = min(n, len(units))
+		chunk := (len(units) + n - 1) / n
+		removed := false
+		for start := 0; start < len(units); start += chunk {
+			if s.fails(withoutSpans(ops, units[start:min(start+chunk, len(units))])) {
+				ops = s.ops
+				n = max(n-1, 2)
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			if n >= len(units) || s.attempts >= FuzzShrinkAttempts {
+				return ops
+			}
+			n = min(2*n, len(units))
+		}
+	}
+}
+
+// fuzzSpans returns a function computing the spans from begin to end operations.
+// A span without end operation extends to the last operation.
+func fuzzSpans(begin int, end int) func([]FuzzOperation) [][2]int {
+	return func(ops []FuzzOperation) [][2]int {
+		spans := [][2]int{}
+		start := -1
+		for i := range ops {
+			switch opId[ops[i].Op] {
+			case begin:
+				start = i
+			case end:
+				if start != -1 {
+					spans = append(spans, [2]int{start, i + 1})
+					start = -1
+				}
+			}
+		}
+		if start != -1 {
+			spans = append(spans, [2]int{start, len(ops)})
+		}
+		return spans
+	}
+}
+
+// fuzzOperationSpans returns the spans of single operations that do not begin or end
+// sync-periods, blocks or transactions.
+func fuzzOperationSpans(ops []FuzzOperation) [][2]int {
+	spans := [][2]int{}
+	for i := range ops {
+		switch opId[ops[i].Op] {
+		case BeginSyncPeriodID, EndSyncPeriodID, BeginBlockID, EndBlockID, BeginTransactionID, EndTransactionID:
+			continue
+		}
+		spans = append(spans, [2]int{i, i + 1})
+	}
+	return spans
+}
+
+// withoutSpans returns the operations without the operations of the given spans.
+func withoutSpans(ops []FuzzOperation, spans [][2]int) []FuzzOperation {
+	result := make([]FuzzOperation, 0, len(ops))
+	next := 0
+	for _, span := range spans {
+		result = append(result, ops[next:span[0]]...)
+		next = span[1]
+	}
+	return append(result, ops[next:]...)
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newFuzzTestModel creates a uniform simulation model for fuzzing tests.
+func newFuzzTestModel() (*utils.Config, *EstimationModelJSON) {
+	cfg := &utils.Config{
+		ContractNumber:    1000,
+		KeysNumber:        1000,
+		ValuesNumber:      1000,
+		SnapshotDepth:     100,
+		BlockLength:       3,
+		SyncPeriodLength:  10,
+		TransactionLength: 10,
+		BalanceRange:      100000,
+		NonceRange:        1000000,
+		RandomSeed:        7,
+	}
+	events := GenerateUniformRegistry(cfg, logger.NewLogger("ERROR", "Fuzz Test")).NewEventRegistryJSON()
+	e := NewEstimationModelJSON(&events)
+	return cfg, &e
+}
+
+// faultyStateDB is a StateDB reporting an error after each SetCode operation.
+type faultyStateDB struct {
+	state.StateDB
+	err error
+}
+
+func (db *faultyStateDB) SetCode(addr common.Address, code []byte) {
+	db.err = fmt.Errorf("injected failure")
+	db.StateDB.SetCode(addr, code)
+}
+
+func (db *faultyStateDB) Error() error {
+	err := db.err
+	db.err = nil
+	return err
+}
+
+// makeFuzzTestDb creates in-memory StateDBs, optionally with injected failures.
+func makeFuzzTestDb(faulty bool) StateDBFactory {
+	return func() (state.StateDB, func(), error) {
+		db, err := state.MakeEmptyGethInMemoryStateDB("")
+		if err != nil {
+			return nil, nil, err
+		}
+		if faulty {
+			db = &faultyStateDB{StateDB: db}
+		}
+		return db, func() {}, nil
+	}
+}
+
+// TestFuzz_RecordedOperationsAreReplayable checks that recorded operations can be executed again.
+func TestFuzz_RecordedOperationsAreReplayable(t *testing.T) {
+	cfg, e := newFuzzTestModel()
+	ops, failure, err := runFuzzSeed(e, 3, cfg.RandomSeed, cfg, makeFuzzTestDb(false))
+	if err != nil {
+		t.Fatalf("failed to run seed; %v", err)
+	}
+	if failure != nil {
+		t.Fatalf("unexpected failure: %v", failure.Message)
+	}
+	if blocks, _ := countFuzzStructure(ops); blocks != 4 {
+		t.Errorf("unexpected number of blocks including priming: %v", blocks)
+	}
+	db, _, _ := makeFuzzTestDb(false)()
+	if failure := ExecuteFuzzOperations(db, ops); failure != nil {
+		t.Errorf("recorded operations failed: %v", failure.Message)
+	}
+}
+
+// TestFuzz_ShrinksFailure checks that a failure is found and shrunk to its failing operation.
+func TestFuzz_ShrinksFailure(t *testing.T) {
+	cfg, e := newFuzzTestModel()
+	artifact, err := Fuzz(e, 5, 1, cfg, makeFuzzTestDb(true), logger.NewLogger("ERROR", "Fuzz Test"))
+	if err != nil {
+		t.Fatalf("fuzzing failed; %v", err)
+	}
+	if artifact == nil {
+		t.Fatalf("failure was not found")
+	}
+	if artifact.Failure.Panic || artifact.Failure.Message != "injected failure" {
+		t.Errorf("unexpected failure: %v", artifact.Failure)
+	}
+	last := artifact.Operations[len(artifact.Operations)-1]
+	if last.Op != OpMnemo(SetCodeID) || artifact.Failure.Index != len(artifact.Operations)-1 {
+		t.Errorf("shrunk operations do not end with failing operation: %v", last.String())
+	}
+	if blocks, txs := countFuzzStructure(artifact.Operations); blocks != 1 || txs != 1 || len(artifact.Operations) > 5 {
+		t.Errorf("operations are not shrunk: %v operations, %v blocks, %v transactions", len(artifact.Operations), blocks, txs)
+	}
+	if artifact.Recorded <= len(artifact.Operations) {
+		t.Errorf("unexpected number of recorded operations %v", artifact.Recorded)
+	}
+
+	// the report lists the shrunk operations and marks the failing operation
+	var report bytes.Buffer
+	if err := artifact.WriteReport(&report); err != nil {
+		t.Fatalf("failed to write report; %v", err)
+	}
+	if !bytes.Contains(report.Bytes(), []byte("> ")) || !bytes.Contains(report.Bytes(), []byte("SetCode")) {
+		t.Errorf("unexpected report:\n%v", report.String())
+	}
+}
+
+// TestFuzz_ExecuteReportsPanics checks that panics of a StateDB are reported as failures.
+func TestFuzz_ExecuteReportsPanics(t *testing.T) {
+	db, _, _ := makeFuzzTestDb(false)()
+	ops := []FuzzOperation{{Op: OpMnemo(BeginTransactionID)}, {Op: "??"}}
+	if failure := ExecuteFuzzOperations(db, ops); failure == nil || failure.Index != 1 {
+		t.Errorf("unknown operation was not reported: %v", failure)
+	}
+	ops = []FuzzOperation{{Op: OpMnemo(AddBalanceID)}}
+	if failure := ExecuteFuzzOperations(db, ops); failure == nil || !failure.Panic {
+		t.Errorf("panic was not reported: %v", failure)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// minProbability is the probability assumed for a transition that a model has never observed.
+const minProbability = 1e-9
+
+// encodeContext encodes a sequence of argument-encoded operations as a map key.
+func encodeContext(ctx []int) string {
+	parts := make([]string, len(ctx))
+	for i, x := range ctx {
+		parts[i] = strconv.Itoa(x)
+	}
+	return strings.Join(parts, ",")
+}
+
+// decodeContext decodes a map key into a sequence of argument-encoded operations.
+func decodeContext(key string) []int {
+	parts := strings.Split(key, ",")
+	ctx := make([]int, len(parts))
+	for i, part := range parts {
+		x, err := strconv.Atoi(part)
+		if err != nil {
+			panic("invalid context encoding")
+		}
+		ctx[i] = x
+	}
+	return ctx
+}
+
+// argOpcode returns the opcode of an argument-encoded operation.
+func argOpcode(argop int) string {
+	op, addr, key, value := DecodeArgOp(argop)
+	return EncodeOpcode(op, addr, key, value)
+}
+
+// successorDistribution is the distribution of successor states for a context.
+type successorDistribution struct {
+	successors    []int     // indexes of successor states
+	probabilities []float64 // probabilities of successor states
+}
+
+// MarkovChain is a Markov chain of order k over the operations of a stochastic model.
+// States are indexes into the operation list. A context without observed successors
+// falls back to the longest shorter context and eventually to the stochastic matrix.
+type MarkovChain struct {
+	order      int                              // maximal context length
+	operations []string                         // opcodes of states
+	A          [][]float64                      // first-order stochastic matrix
+	contexts   map[string]successorDistribution // distributions of higher-order contexts
+}
+
+// NewMarkovChain creates a Markov chain of the given order from a stochastic matrix
+// and the successor distributions of higher-order contexts.
+func NewMarkovChain(operations []string, A [][]float64, order int, contexts []ContextModelJSON) (*MarkovChain, error) {
+	if order < 1 {
+		order = 1
+	}
+	index := map[string]int{}
+	for i, opc := range operations {
+		index[opc] = i
+	}
+	lookup := func(opcodes []string) ([]int, error) {
+		states := make([]int, len(opcodes))
+		for i, opc := range opcodes {
+			state, ok := index[opc]
+			if !ok {
+				return nil, fmt.Errorf("operation %v of context is not a state of the stochastic matrix", opc)
+			}
+			states[i] = state
+		}
+		return states, nil
+	}
+	m := &MarkovChain{
+		order:      order,
+		operations: operations,
+		A:          A,
+		contexts:   map[string]successorDistribution{},
+	}
+	for _, c := range contexts {
+		if len(c.Context) < 2 || len(c.Context) > order {
+			return nil, fmt.Errorf("context length %v exceeds range [2,%v]", len(c.Context), order)
+		}
+		if len(c.Successors) != len(c.Probabilities) {
+			return nil, fmt.Errorf("mismatching number of successors and probabilities")
+		}
+		ctx, err := lookup(c.Context)
+		if err != nil {
+			return nil, err
+		}
+		successors, err := lookup(c.Successors)
+		if err != nil {
+			return nil, err
+		}
+		probabilities := make([]float64, len(c.Probabilities))
+		copy(probabilities, c.Probabilities)
+		m.contexts[encodeContext(ctx)] = successorDistribution{
+			successors:    successors,
+			probabilities: probabilities,
+		}
+	}
+	return m, nil
+}
+
+// Order returns the order of the Markov chain.
+func (m *MarkovChain) Order() int {
+	return m.order
+}
+
+// WithOrder returns a view of the Markov chain whose contexts are limited to the given order.
+func (m *MarkovChain) WithOrder(order int) *MarkovChain {
+	if order < 1 {
+		order = 1
+	}
+	if order > m.order {
+		order = m.order
+	}
+	return &MarkovChain{
+		order:      order,
+		operations: m.operations,
+		A:          m.A,
+		contexts:   m.contexts,
+	}
+}
+
+// distribution finds the successor distribution of the longest known context
+// of the history. It returns nil if only the first-order transition applies.
+func (m *MarkovChain) distribution(history []int) *successorDistribution {
+	l := len(history)
+	if l > m.order {
+		l = m.order
+	}
+	for ; l >= 2; l-- {
+		if d, ok := m.contexts[encodeContext(history[len(history)-l:])]; ok {
+			return &d
+		}
+	}
+	return nil
+}
+
+// Next samples the next state for a history of states whose last element is the current state.
+func (m *MarkovChain) Next(rg *rand.Rand, history []int) int {
+	if d := m.distribution(history); d != nil {
+		if k := nextState(rg, [][]float64{d.probabilities}, 0); k != -1 {
+			return d.successors[k]
+		}
+	}
+	return nextState(rg, m.A, history[len(history)-1])
+}
+
+// Probability returns the probability of transiting to the next state after a history of states.
+func (m *MarkovChain) Probability(history []int, next int) float64 {
+	if d := m.distribution(history); d != nil {
+		for i, successor := range d.successors {
+			if successor == next {
+				return d.probabilities[i]
+			}
+		}
+		return 0.0
+	}
+	return m.A[history[len(history)-1]][next]
+}
+
+// PredictionScore measures how well a Markov chain predicts the transitions of a recording.
+type PredictionScore struct {
+	Order         int     // order of the evaluated Markov chain
+	Transitions   uint64  // number of evaluated transitions
+	Unseen        uint64  // number of transitions never observed by the Markov chain
+	LogLikelihood float64 // average log-likelihood per transition
+	Perplexity    float64 // perplexity of the transitions
+}
+
+// Evaluate computes the prediction score of the Markov chain for a held-out recording.
+// The held-out recording must have been recorded with an order of at least two; its
+// longest contexts cover all transitions with a complete history.
+func (m *MarkovChain) Evaluate(heldOut *EventRegistryJSON) (PredictionScore, error) {
+	score := PredictionScore{Order: m.order}
+	if heldOut.Order < 2 {
+		return score, fmt.Errorf("held-out recording has no higher-order contexts")
+	}
+	index := map[string]int{}
+	for i, opc := range m.operations {
+		index[opc] = i
+	}
+	sum := 0.0
+	for _, c := range heldOut.Contexts {
+		if len(c.Context) != heldOut.Order {
+			continue
+		}
+		// keep the longest suffix of the context that is known to the model
+		// (the suffix is empty if the current state is unknown)
+		history := []int{}
+		for _, opc := range c.Context {
+			state, ok := index[opc]
+			if !ok {
+				history = history[:0]
+				continue
+			}
+			history = append(history, state)
+		}
+		for i, opc := range c.Successors {
+			freq := c.Frequencies[i]
+			p := 0.0
+			if next, ok := index[opc]; ok && len(history) > 0 {
+				p = m.Probability(history, next)
+			}
+			if p <= 0.0 {
+				p = minProbability
+				score.Unseen += freq
+			}
+			sum += float64(freq) * math.Log(p)
+			score.Transitions += freq
+		}
+	}
+	if score.Transitions == 0 {
+		return score, fmt.Errorf("held-out recording has no transitions")
+	}
+	score.LogLikelihood = sum / float64(score.Transitions)
+	score.Perplexity = math.Exp(-score.LogLikelihood)
+	return score, nil
+}
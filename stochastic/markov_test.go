@@ -0,0 +1,104 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestMarkovChain_NextUsesLongestContext checks that a known context determines the successor
+// and that unknown contexts fall back to the stochastic matrix.
+func TestMarkovChain_NextUsesLongestContext(t *testing.T) {
+	operations := []string{"BB", "BT", "ET"}
+	A := [][]float64{
+		{0.0, 1.0, 0.0},
+		{0.0, 0.0, 1.0},
+		{0.5, 0.5, 0.0},
+	}
+	contexts := []ContextModelJSON{
+		{Context: []string{"BT", "ET"}, Successors: []string{"BB"}, Probabilities: []float64{1.0}},
+	}
+	chain, err := NewMarkovChain(operations, A, 2, contexts)
+	if err != nil {
+		t.Fatalf("failed to create Markov chain; %v", err)
+	}
+
+	rg := rand.New(rand.NewSource(999))
+	for i := 0; i < 100; i++ {
+		if next := chain.Next(rg, []int{1, 2}); next != 0 {
+			t.Fatalf("context was not used; got %v", next)
+		}
+	}
+	counts := [3]int{}
+	for i := 0; i < 1000; i++ {
+		counts[chain.Next(rg, []int{0, 2})]++
+	}
+	if counts[0] == 0 || counts[1] == 0 || counts[2] != 0 {
+		t.Fatalf("unknown context did not fall back to stochastic matrix; got %v", counts)
+	}
+	if p := chain.WithOrder(1).Probability([]int{1, 2}, 0); p != 0.5 {
+		t.Fatalf("first-order view used higher-order context; got %v", p)
+	}
+}
+
+// TestMarkovChain_NewFailsForUnknownOperation checks that contexts must refer to states of the matrix.
+func TestMarkovChain_NewFailsForUnknownOperation(t *testing.T) {
+	contexts := []ContextModelJSON{
+		{Context: []string{"BB", "SU"}, Successors: []string{"BB"}, Probabilities: []float64{1.0}},
+	}
+	if _, err := NewMarkovChain([]string{"BB"}, [][]float64{{1.0}}, 2, contexts); err == nil {
+		t.Fatalf("expected an error for an unknown operation")
+	}
+}
+
+// TestMarkovChain_EvaluateHigherOrderPredictsBetter checks that an order-2 chain
+// predicts a recording better than a first-order chain when the successor
+// depends on the last two operations.
+func TestMarkovChain_EvaluateHigherOrderPredictsBetter(t *testing.T) {
+	r := NewEventRegistryWithOrder(2)
+	for i := 0; i < 100; i++ {
+		r.RegisterOp(BeginBlockID)
+		r.RegisterOp(BeginTransactionID)
+		r.RegisterOp(BeginBlockID)
+		r.RegisterOp(EndTransactionID)
+	}
+	events := r.NewEventRegistryJSON()
+
+	chain, err := NewMarkovChain(events.Operations, events.StochasticMatrix, events.Order, EstimateContexts(events.Contexts))
+	if err != nil {
+		t.Fatalf("failed to create Markov chain; %v", err)
+	}
+	first, err := chain.WithOrder(1).Evaluate(&events)
+	if err != nil {
+		t.Fatalf("failed to evaluate first-order chain; %v", err)
+	}
+	second, err := chain.Evaluate(&events)
+	if err != nil {
+		t.Fatalf("failed to evaluate second-order chain; %v", err)
+	}
+	if second.Transitions != first.Transitions || second.Transitions != 398 {
+		t.Fatalf("unexpected number of transitions; %v and %v", first.Transitions, second.Transitions)
+	}
+	if math.Abs(second.Perplexity-1.0) > 1e-9 {
+		t.Errorf("second-order chain should predict perfectly; perplexity %v", second.Perplexity)
+	}
+	if first.Perplexity <= second.Perplexity {
+		t.Errorf("first-order chain should predict worse; perplexity %v vs %v", first.Perplexity, second.Perplexity)
+	}
+}
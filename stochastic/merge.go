@@ -0,0 +1,227 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+)
+
+// MergeEvents merges the event registries of several recordings into a single registry.
+// The frequencies of each recording are scaled by its weight, i.e., a weight of two
+// counts a recording twice. Access statistics are merged assuming that the recordings
+// access disjoint contracts, keys and values. Higher-order contexts are kept up to the
+// smallest order of all recordings. Segments are concatenated in the order of the recordings.
+func MergeEvents(events []*EventRegistryJSON, weights []float64) (*EventRegistryJSON, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no event registries to merge")
+	}
+	if len(events) != len(weights) {
+		return nil, fmt.Errorf("number of weights (%v) does not match number of event registries (%v)", len(weights), len(events))
+	}
+	order := math.MaxInt
+	for i, d := range events {
+		if weights[i] <= 0 {
+			return nil, fmt.Errorf("weight %v of event registry %v must be positive", weights[i], i)
+		}
+		if len(d.Frequencies) != len(d.Operations) || len(d.StochasticMatrix) != len(d.Operations) {
+			return nil, fmt.Errorf("event registry %v has no operation frequencies; it must be recorded again", i)
+		}
+		if d.Order < order {
+			order = d.Order
+		}
+	}
+
+	// collect weighted operation frequencies and transition frequencies
+	opFreq := map[string]float64{}
+	transitFreq := map[string]map[string]float64{}
+	snapshotFreq := map[int]float64{}
+	contextFreq := map[string]map[string]float64{}
+	contextOps := map[string][]string{}
+	for i, d := range events {
+		w := weights[i]
+		for row, from := range d.Operations {
+			freq := w * float64(d.Frequencies[row])
+			opFreq[from] += freq
+			if _, ok := transitFreq[from]; !ok {
+				transitFreq[from] = map[string]float64{}
+			}
+			for col, to := range d.Operations {
+				if p := d.StochasticMatrix[row][col]; p > 0 {
+					transitFreq[from][to] += freq * p
+				}
+			}
+		}
+		for delta, freq := range d.SnapshotFreq {
+			snapshotFreq[delta] += w * float64(freq)
+		}
+		for _, c := range d.Contexts {
+			if len(c.Context) > order {
+				continue
+			}
+			key := fmt.Sprint(c.Context)
+			if _, ok := contextFreq[key]; !ok {
+				contextFreq[key] = map[string]float64{}
+				contextOps[key] = c.Context
+			}
+			for j, successor := range c.Successors {
+				contextFreq[key][successor] += w * float64(c.Frequencies[j])
+			}
+		}
+	}
+
+	// order operations by their argument encoding
+	label := make([]string, 0, len(opFreq))
+	for opc := range opFreq {
+		label = append(label, opc)
+	}
+	sortOpcodes(label)
+
+	merged := &EventRegistryJSON{
+		FileId:       "events",
+		Operations:   label,
+		Frequencies:  make([]uint64, len(label)),
+		SnapshotFreq: map[int]uint64{},
+		ValueClasses: NewValueClassesJSON(),
+		Order:        max(order, 1),
+	}
+	for i, from := range label {
+		merged.Frequencies[i] = uint64(math.Round(opFreq[from]))
+		total := 0.0
+		for _, freq := range transitFreq[from] {
+			total += freq
+		}
+		row := make([]float64, len(label))
+		if total > 0 {
+			for j, to := range label {
+				row[j] = transitFreq[from][to] / total
+			}
+		}
+		merged.StochasticMatrix = append(merged.StochasticMatrix, row)
+	}
+
+	// merge access statistics
+	contracts := make([]statistics.AccessJSON, len(events))
+	keys := make([]statistics.AccessJSON, len(events))
+	values := make([]statistics.AccessJSON, len(events))
+	for i, d := range events {
+		contracts[i] = d.Contracts
+		keys[i] = d.Keys
+		values[i] = d.Values
+	}
+	merged.Contracts = statistics.MergeAccessJSON(contracts, weights)
+	merged.Keys = statistics.MergeAccessJSON(keys, weights)
+	merged.Values = statistics.MergeAccessJSON(values, weights)
+
+	// merge snapshot deltas
+	for delta, freq := range snapshotFreq {
+		if f := uint64(math.Round(freq)); f > 0 {
+			merged.SnapshotFreq[delta] = f
+		}
+	}
+	merged.SnapshotEcdf = snapshotEcdf(merged.SnapshotFreq)
+
+	// merge value classes
+	for i, d := range events {
+		merged.ValueClasses.add(&d.ValueClasses, weights[i])
+	}
+
+	// concatenate segments of recordings in the given order
+	for i, d := range events {
+		for _, segment := range d.Segments {
+			merged.Segments = append(merged.Segments, scaleSegment(segment, weights[i]))
+		}
+	}
+
+	// merge higher-order contexts
+	ctxKeys := make([]string, 0, len(contextFreq))
+	for key := range contextFreq {
+		ctxKeys = append(ctxKeys, key)
+	}
+	sort.Strings(ctxKeys)
+	for _, key := range ctxKeys {
+		successors := make([]string, 0, len(contextFreq[key]))
+		for successor := range contextFreq[key] {
+			successors = append(successors, successor)
+		}
+		sortOpcodes(successors)
+		c := ContextJSON{Context: contextOps[key]}
+		for _, successor := range successors {
+			if f := uint64(math.Round(contextFreq[key][successor])); f > 0 {
+				c.Successors = append(c.Successors, successor)
+				c.Frequencies = append(c.Frequencies, f)
+			}
+		}
+		if len(c.Successors) > 0 {
+			merged.Contexts = append(merged.Contexts, c)
+		}
+	}
+
+	return merged, nil
+}
+
+// scaleSegment scales the transition frequencies of a segment by a weight.
+func scaleSegment(segment SegmentJSON, weight float64) SegmentJSON {
+	scaled := SegmentJSON{Blocks: segment.Blocks, Transitions: make([]ContextJSON, 0, len(segment.Transitions))}
+	for _, c := range segment.Transitions {
+		t := ContextJSON{Context: c.Context}
+		for j, successor := range c.Successors {
+			if f := uint64(math.Round(weight * float64(c.Frequencies[j]))); f > 0 {
+				t.Successors = append(t.Successors, successor)
+				t.Frequencies = append(t.Frequencies, f)
+			}
+		}
+		if len(t.Successors) > 0 {
+			scaled.Transitions = append(scaled.Transitions, t)
+		}
+	}
+	return scaled
+}
+
+// sortOpcodes sorts opcodes by their argument encoding.
+func sortOpcodes(opcodes []string) {
+	argop := func(opc string) int {
+		op, addr, key, value := DecodeOpcode(opc)
+		return EncodeArgOp(op, addr, key, value)
+	}
+	sort.Slice(opcodes, func(i, j int) bool {
+		return argop(opcodes[i]) < argop(opcodes[j])
+	})
+}
+
+// WriteJSON writes an event registry in JSON format.
+func (d *EventRegistryJSON) WriteJSON(filename string) error {
+	f, fErr := os.Create(filename)
+	if fErr != nil {
+		return fmt.Errorf("cannot open JSON file; %v", fErr)
+	}
+	defer f.Close()
+	jOut, jErr := json.MarshalIndent(d, "", "    ")
+	if jErr != nil {
+		return fmt.Errorf("failed to convert JSON file; %v", jErr)
+	}
+	_, pErr := fmt.Fprintln(f, string(jOut))
+	if pErr != nil {
+		return fmt.Errorf("failed to convert JSON file; %v", pErr)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// recordTestEvents records a block with a single transaction that executes the given operations.
+func recordTestEvents(ops ...int) *EventRegistryJSON {
+	r := NewEventRegistryWithOrder(2)
+	addr := common.HexToAddress("0x1")
+	r.RegisterOp(BeginBlockID)
+	r.RegisterOp(BeginTransactionID)
+	for _, op := range ops {
+		r.RegisterAddressOp(op, &addr)
+	}
+	r.RegisterOp(SnapshotID)
+	r.RegisterSnapshotDelta(0)
+	r.RegisterOp(EndTransactionID)
+	r.RegisterOp(EndBlockID)
+	d := r.NewEventRegistryJSON()
+	return &d
+}
+
+// probability returns the transition probability between two opcodes of an event registry.
+func probability(t *testing.T, d *EventRegistryJSON, from string, to string) float64 {
+	i := find(d.Operations, from)
+	j := find(d.Operations, to)
+	if i == -1 || j == -1 {
+		t.Fatalf("operations %v or %v are not in the registry", from, to)
+	}
+	return d.StochasticMatrix[i][j]
+}
+
+// TestMergeEvents_WeightsScaleTransitions checks that the transitions of each recording are weighted.
+func TestMergeEvents_WeightsScaleTransitions(t *testing.T) {
+	a := recordTestEvents(GetBalanceID)
+	b := recordTestEvents(GetNonceID)
+
+	merged, err := MergeEvents([]*EventRegistryJSON{a, b}, []float64{1, 3})
+	if err != nil {
+		t.Fatalf("failed to merge; %v", err)
+	}
+	if got := probability(t, merged, "BT", "GBn"); got != 0.25 {
+		t.Errorf("unexpected probability BT->GBn %v", got)
+	}
+	if got := probability(t, merged, "BT", "GNn"); got != 0.75 {
+		t.Errorf("unexpected probability BT->GNn %v", got)
+	}
+	if got := probability(t, merged, "GBn", "SN"); got != 1.0 {
+		t.Errorf("unexpected probability GBn->SN %v", got)
+	}
+	if merged.Frequencies[find(merged.Operations, "BT")] != 4 {
+		t.Errorf("unexpected frequency of BT %v", merged.Frequencies[find(merged.Operations, "BT")])
+	}
+	if merged.SnapshotFreq[0] != 4 {
+		t.Errorf("unexpected snapshot frequency %v", merged.SnapshotFreq[0])
+	}
+	if merged.Order != 2 || len(merged.Contexts) == 0 {
+		t.Errorf("higher-order contexts were not merged")
+	}
+
+	filename := filepath.Join(t.TempDir(), "events.json")
+	if err := merged.WriteJSON(filename); err != nil {
+		t.Fatalf("failed to write merged events; %v", err)
+	}
+	if _, err := ReadEvents(filename); err != nil {
+		t.Fatalf("failed to read merged events; %v", err)
+	}
+}
+
+// TestMergeEvents_RejectsInvalidInput checks the validation of weights and registries.
+func TestMergeEvents_RejectsInvalidInput(t *testing.T) {
+	a := recordTestEvents(GetBalanceID)
+	if _, err := MergeEvents([]*EventRegistryJSON{a, a}, []float64{1}); err == nil {
+		t.Errorf("expected an error for missing weights")
+	}
+	if _, err := MergeEvents([]*EventRegistryJSON{a, a}, []float64{1, 0}); err == nil {
+		t.Errorf("expected an error for a non-positive weight")
+	}
+	old := *a
+	old.Frequencies = nil
+	if _, err := MergeEvents([]*EventRegistryJSON{a, &old}, []float64{1, 1}); err == nil {
+		t.Errorf("expected an error for a registry without frequencies")
+	}
+}
+
+// TestMergeEvents_WeightsValueClasses checks that value classes are merged with weights.
+func TestMergeEvents_WeightsValueClasses(t *testing.T) {
+	a := recordTestEvents(GetBalanceID)
+	a.ValueClasses.Balances[3] = 2
+	b := recordTestEvents(GetBalanceID)
+	b.ValueClasses.Balances[3] = 1
+	b.ValueClasses.Balances[8] = 4
+
+	merged, err := MergeEvents([]*EventRegistryJSON{a, b}, []float64{1, 2})
+	if err != nil {
+		t.Fatalf("failed to merge; %v", err)
+	}
+	if got := merged.ValueClasses.Balances; got[3] != 4 || got[8] != 8 {
+		t.Errorf("unexpected merged balance classes: %v", got)
+	}
+}
@@ -25,7 +25,12 @@ import (
 
 // IDs of StateDB Operations
 const (
-	AddBalanceID = iota
+	AddAddressToAccessListID = iota
+	AddBalanceID
+	AddLogID
+	AddRefundID
+	AddSlotToAccessListID
+	AddressInAccessListID
 	BeginBlockID
 	BeginSyncPeriodID
 	BeginTransactionID
@@ -41,14 +46,18 @@ const (
 	GetCodeSizeID
 	GetCommittedStateID
 	GetNonceID
+	GetRefundID
 	GetStateID
 	HasSuicidedID
+	PrepareAccessListID
 	RevertToSnapshotID
 	SetCodeID
 	SetNonceID
 	SetStateID
+	SlotInAccessListID
 	SnapshotID
 	SubBalanceID
+	SubRefundID
 	SuicideID
 
 	NumOps
@@ -59,94 +68,126 @@ const numArgOps = NumOps * statistics.NumClasses * statistics.NumClasses * stati
 
 // opText translates IDs to operation's text
 var opText = map[int]string{
-	AddBalanceID:        "AddBalance",
-	BeginBlockID:        "BeginBlock",
-	BeginSyncPeriodID:   "BeginSyncPeriod",
-	BeginTransactionID:  "BeginTransaction",
-	CreateAccountID:     "CreateAccount",
-	EmptyID:             "Empty",
-	EndBlockID:          "EndBlock",
-	EndSyncPeriodID:     "EndSyncPeriod",
-	EndTransactionID:    "EndTransaction",
-	ExistID:             "Exist",
-	GetBalanceID:        "GetBalance",
-	GetCodeHashID:       "GetCodeHash",
-	GetCodeID:           "GetCode",
-	GetCodeSizeID:       "GetCodeSize",
-	GetCommittedStateID: "GetCommittedState",
-	GetNonceID:          "GetNonce",
-	GetStateID:          "GetState",
-	HasSuicidedID:       "HasSuicided",
-	RevertToSnapshotID:  "RevertToSnapshot",
-	SetCodeID:           "SetCode",
-	SetNonceID:          "SetNonce",
-	SetStateID:          "SetState",
-	SnapshotID:          "Snapshot",
-	SubBalanceID:        "SubBalance",
-	SuicideID:           "Suicide",
+	AddAddressToAccessListID: "AddAddressToAccessList",
+	AddBalanceID:             "AddBalance",
+	AddLogID:                 "AddLog",
+	AddRefundID:              "AddRefund",
+	AddSlotToAccessListID:    "AddSlotToAccessList",
+	AddressInAccessListID:    "AddressInAccessList",
+	BeginBlockID:             "BeginBlock",
+	BeginSyncPeriodID:        "BeginSyncPeriod",
+	BeginTransactionID:       "BeginTransaction",
+	CreateAccountID:          "CreateAccount",
+	EmptyID:                  "Empty",
+	EndBlockID:               "EndBlock",
+	EndSyncPeriodID:          "EndSyncPeriod",
+	EndTransactionID:         "EndTransaction",
+	ExistID:                  "Exist",
+	GetBalanceID:             "GetBalance",
+	GetCodeHashID:            "GetCodeHash",
+	GetCodeID:                "GetCode",
+	GetCodeSizeID:            "GetCodeSize",
+	GetCommittedStateID:      "GetCommittedState",
+	GetNonceID:               "GetNonce",
+	GetRefundID:              "GetRefund",
+	GetStateID:               "GetState",
+	HasSuicidedID:            "HasSuicided",
+	PrepareAccessListID:      "PrepareAccessList",
+	RevertToSnapshotID:       "RevertToSnapshot",
+	SetCodeID:                "SetCode",
+	SetNonceID:               "SetNonce",
+	SetStateID:               "SetState",
+	SlotInAccessListID:       "SlotInAccessList",
+	SnapshotID:               "Snapshot",
+	SubBalanceID:             "SubBalance",
+	SubRefundID:              "SubRefund",
+	SuicideID:                "Suicide",
 }
 
 // opMnemo is a mnemonics table for operations.
 var opMnemo = map[int]string{
-	AddBalanceID:        "AB",
-	BeginBlockID:        "BB",
-	BeginSyncPeriodID:   "BS",
-	BeginTransactionID:  "BT",
-	CreateAccountID:     "CA",
-	EmptyID:             "EM",
-	EndBlockID:          "EB",
-	EndSyncPeriodID:     "ES",
-	EndTransactionID:    "ET",
-	ExistID:             "EX",
-	GetBalanceID:        "GB",
-	GetCodeHashID:       "GH",
-	GetCodeID:           "GC",
-	GetCodeSizeID:       "GZ",
-	GetCommittedStateID: "GM",
-	GetNonceID:          "GN",
-	GetStateID:          "GS",
-	HasSuicidedID:       "HS",
-	RevertToSnapshotID:  "RS",
-	SetCodeID:           "SC",
-	SetNonceID:          "SO",
-	SetStateID:          "SS",
-	SnapshotID:          "SN",
-	SubBalanceID:        "SB",
-	SuicideID:           "SU",
+	AddAddressToAccessListID: "AA",
+	AddBalanceID:             "AB",
+	AddLogID:                 "AL",
+	AddRefundID:              "AR",
+	AddSlotToAccessListID:    "AS",
+	AddressInAccessListID:    "AI",
+	BeginBlockID:             "BB",
+	BeginSyncPeriodID:        "BS",
+	BeginTransactionID:       "BT",
+	CreateAccountID:          "CA",
+	EmptyID:                  "EM",
+	EndBlockID:               "EB",
+	EndSyncPeriodID:          "ES",
+	EndTransactionID:         "ET",
+	ExistID:                  "EX",
+	GetBalanceID:             "GB",
+	GetCodeHashID:            "GH",
+	GetCodeID:                "GC",
+	GetCodeSizeID:            "GZ",
+	GetCommittedStateID:      "GM",
+	GetNonceID:               "GN",
+	GetRefundID:              "GR",
+	GetStateID:               "GS",
+	HasSuicidedID:            "HS",
+	PrepareAccessListID:      "PA",
+	RevertToSnapshotID:       "RS",
+	SetCodeID:                "SC",
+	SetNonceID:               "SO",
+	SetStateID:               "SS",
+	SlotInAccessListID:       "SI",
+	SnapshotID:               "SN",
+	SubBalanceID:             "SB",
+	SubRefundID:              "SR",
+	SuicideID:                "SU",
 }
 
 // opNumArgs is an argument number table for operations.
 var opNumArgs = map[int]int{
-	AddBalanceID:        1,
-	BeginBlockID:        0,
-	BeginSyncPeriodID:   0,
-	BeginTransactionID:  0,
-	CreateAccountID:     1,
-	EmptyID:             1,
-	EndBlockID:          0,
-	EndSyncPeriodID:     0,
-	EndTransactionID:    0,
-	ExistID:             1,
-	GetBalanceID:        1,
-	GetCodeHashID:       1,
-	GetCodeID:           1,
-	GetCodeSizeID:       1,
-	GetCommittedStateID: 2,
-	GetNonceID:          1,
-	GetStateID:          2,
-	HasSuicidedID:       1,
-	RevertToSnapshotID:  0,
-	SetCodeID:           1,
-	SetNonceID:          1,
-	SetStateID:          3,
-	SnapshotID:          0,
-	SubBalanceID:        1,
-	SuicideID:           1,
+	AddAddressToAccessListID: 1,
+	AddBalanceID:             1,
+	AddLogID:                 1,
+	AddRefundID:              0,
+	AddSlotToAccessListID:    2,
+	AddressInAccessListID:    1,
+	BeginBlockID:             0,
+	BeginSyncPeriodID:        0,
+	BeginTransactionID:       0,
+	CreateAccountID:          1,
+	EmptyID:                  1,
+	EndBlockID:               0,
+	EndSyncPeriodID:          0,
+	EndTransactionID:         0,
+	ExistID:                  1,
+	GetBalanceID:             1,
+	GetCodeHashID:            1,
+	GetCodeID:                1,
+	GetCodeSizeID:            1,
+	GetCommittedStateID:      2,
+	GetNonceID:               1,
+	GetRefundID:              0,
+	GetStateID:               2,
+	HasSuicidedID:            1,
+	PrepareAccessListID:      1,
+	RevertToSnapshotID:       0,
+	SetCodeID:                1,
+	SetNonceID:               1,
+	SetStateID:               3,
+	SlotInAccessListID:       2,
+	SnapshotID:               0,
+	SubBalanceID:             1,
+	SubRefundID:              0,
+	SuicideID:                1,
 }
 
 // opId is an operation ID table.
 var opId = map[string]int{
+	"AA": AddAddressToAccessListID,
 	"AB": AddBalanceID,
+	"AL": AddLogID,
+	"AR": AddRefundID,
+	"AS": AddSlotToAccessListID,
+	"AI": AddressInAccessListID,
 	"BB": BeginBlockID,
 	"BS": BeginSyncPeriodID,
 	"BT": BeginTransactionID,
@@ -162,14 +203,18 @@ var opId = map[string]int{
 	"GZ": GetCodeSizeID,
 	"GM": GetCommittedStateID,
 	"GN": GetNonceID,
+	"GR": GetRefundID,
 	"GS": GetStateID,
 	"HS": HasSuicidedID,
+	"PA": PrepareAccessListID,
 	"RS": RevertToSnapshotID,
 	"SC": SetCodeID,
 	"SO": SetNonceID,
 	"SN": SnapshotID,
 	"SB": SubBalanceID,
+	"SR": SubRefundID,
 	"SS": SetStateID,
+	"SI": SlotInAccessListID,
 	"SU": SuicideID,
 }
 
@@ -200,6 +245,14 @@ func OpMnemo(op int) string {
 	return opMnemo[op]
 }
 
+// OpText returns the name of an operation.
+func OpText(op int) string {
+	if op < 0 || op >= NumOps {
+		panic("opcode is out of range")
+	}
+	return opText[op]
+}
+
 // checkArgOp checks whether op/argument combination is valid.
 func checkArgOp(op int, contract int, key int, value int) bool {
 	if op < 0 || op >= NumOps {
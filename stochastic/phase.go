@@ -0,0 +1,290 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// SegmentJSON is the JSON struct for the transition frequencies of a segment of consecutive blocks.
+type SegmentJSON struct {
+	Blocks      int           `json:"blocks"`      // number of blocks of the segment
+	Transitions []ContextJSON `json:"transitions"` // transition frequencies grouped by source operation
+}
+
+// EnableSegments splits the recording into segments of the given number of blocks.
+// Each segment counts its own transition frequencies so that the estimator can
+// detect phases of the workload.
+func (r *EventRegistry) EnableSegments(length int) {
+	if length < 1 {
+		return
+	}
+	r.segmentLength = length
+	r.segmentTransit = map[int]map[int]uint64{}
+}
+
+// updateSegment counts a transition of the current segment. A segment is closed
+// after the transition that leaves its last end-block operation.
+func (r *EventRegistry) updateSegment(op int, argOp int) {
+	if r.prevArgOp < numArgOps {
+		row, ok := r.segmentTransit[r.prevArgOp]
+		if !ok {
+			row = map[int]uint64{}
+			r.segmentTransit[r.prevArgOp] = row
+		}
+		row[argOp]++
+	}
+	if r.segmentFull {
+		r.segments = append(r.segments, newSegmentJSON(r.segmentBlocks, r.segmentTransit))
+		r.segmentBlocks = 0
+		r.segmentFull = false
+		r.segmentTransit = map[int]map[int]uint64{}
+	}
+	if op == EndBlockID {
+		r.segmentBlocks++
+		if r.segmentBlocks >= r.segmentLength {
+			r.segmentFull = true
+		}
+	}
+}
+
+// segmentsJSON returns the closed segments and the current segment if it is not empty.
+func (r *EventRegistry) segmentsJSON() []SegmentJSON {
+	if r.segmentLength == 0 {
+		return nil
+	}
+	segments := make([]SegmentJSON, len(r.segments), len(r.segments)+1)
+	copy(segments, r.segments)
+	if len(r.segmentTransit) > 0 {
+		segments = append(segments, newSegmentJSON(r.segmentBlocks, r.segmentTransit))
+	}
+	return segments
+}
+
+// newSegmentJSON produces the JSON output of a segment in a deterministic order.
+func newSegmentJSON(blocks int, transit map[int]map[int]uint64) SegmentJSON {
+	sources := make([]int, 0, len(transit))
+	for argop := range transit {
+		sources = append(sources, argop)
+	}
+	sort.Ints(sources)
+	s := SegmentJSON{Blocks: blocks, Transitions: make([]ContextJSON, 0, len(sources))}
+	for _, from := range sources {
+		successors := make([]int, 0, len(transit[from]))
+		for argop := range transit[from] {
+			successors = append(successors, argop)
+		}
+		sort.Ints(successors)
+		c := ContextJSON{Context: []string{argOpcode(from)}}
+		for _, to := range successors {
+			c.Successors = append(c.Successors, argOpcode(to))
+			c.Frequencies = append(c.Frequencies, transit[from][to])
+		}
+		s.Transitions = append(s.Transitions, c)
+	}
+	return s
+}
+
+// PhaseModelJSON is the estimated stochastic matrix of a phase of the workload.
+// The matrix ranges over the operations of the simulation model.
+type PhaseModelJSON struct {
+	Blocks           int         `json:"blocks"`           // duration of the phase in blocks
+	StochasticMatrix [][]float64 `json:"stochasticMatrix"` // stochastic matrix of the phase
+}
+
+// phase accumulates the transition frequencies of consecutive segments.
+type phase struct {
+	blocks  int
+	transit [][]float64 // transition frequencies between operations
+	opFreq  []float64   // frequencies of source operations
+}
+
+// newPhase creates an empty phase for n operations.
+func newPhase(n int) *phase {
+	p := &phase{transit: make([][]float64, n), opFreq: make([]float64, n)}
+	for i := range p.transit {
+		p.transit[i] = make([]float64, n)
+	}
+	return p
+}
+
+// add adds the transition frequencies of another phase.
+func (p *phase) add(other *phase) {
+	p.blocks += other.blocks
+	for i := range p.transit {
+		p.opFreq[i] += other.opFreq[i]
+		for j := range p.transit[i] {
+			p.transit[i][j] += other.transit[i][j]
+		}
+	}
+}
+
+// distribution returns the normalized frequencies of operations of a phase.
+func (p *phase) distribution() []float64 {
+	total := 0.0
+	for _, f := range p.opFreq {
+		total += f
+	}
+	dist := make([]float64, len(p.opFreq))
+	if total > 0 {
+		for i, f := range p.opFreq {
+			dist[i] = f / total
+		}
+	}
+	return dist
+}
+
+// EstimatePhases partitions the segments of a recording into phases and estimates
+// a stochastic matrix for each phase. A new phase starts when the current phase has
+// reached phaseLength blocks (fixed windows; zero disables the limit), or when the
+// Jensen-Shannon divergence between the operation frequencies of the next segment and
+// the current phase exceeds threshold (change-point detection; zero disables the
+// detection). Rows of operations that a phase never leaves are taken from the
+// stochastic matrix of the whole recording.
+func EstimatePhases(d *EventRegistryJSON, phaseLength int, threshold float64) ([]PhaseModelJSON, error) {
+	if len(d.Segments) == 0 {
+		return nil, fmt.Errorf("events file has no segments; record it with --segment-length")
+	}
+	if phaseLength < 0 || threshold < 0 {
+		return nil, fmt.Errorf("phase length and change-point threshold must not be negative")
+	}
+	n := len(d.Operations)
+	index := make(map[string]int, n)
+	for i, opc := range d.Operations {
+		index[opc] = i
+	}
+
+	// convert segments to phases over the operations of the recording
+	segments := make([]*phase, len(d.Segments))
+	for k, s := range d.Segments {
+		segments[k] = newPhase(n)
+		segments[k].blocks = s.Blocks
+		for _, c := range s.Transitions {
+			if len(c.Context) != 1 || len(c.Successors) != len(c.Frequencies) {
+				return nil, fmt.Errorf("malformed transitions of segment %v", k)
+			}
+			i, ok := index[c.Context[0]]
+			if !ok {
+				return nil, fmt.Errorf("operation %v of segment %v is not a recorded operation", c.Context[0], k)
+			}
+			for l, successor := range c.Successors {
+				j, ok := index[successor]
+				if !ok {
+					return nil, fmt.Errorf("operation %v of segment %v is not a recorded operation", successor, k)
+				}
+				segments[k].transit[i][j] += float64(c.Frequencies[l])
+				segments[k].opFreq[i] += float64(c.Frequencies[l])
+			}
+		}
+	}
+
+	// group consecutive segments into phases
+	phases := []*phase{}
+	var current *phase
+	for _, s := range segments {
+		if current != nil {
+			full := phaseLength > 0 && current.blocks >= phaseLength
+			shift := threshold > 0 && jsDivergence(current.distribution(), s.distribution()) > threshold
+			if full || shift {
+				phases = append(phases, current)
+				current = nil
+			}
+		}
+		if current == nil {
+			current = newPhase(n)
+		}
+		current.add(s)
+	}
+	phases = append(phases, current)
+
+	// normalize transition frequencies of phases
+	models := make([]PhaseModelJSON, 0, len(phases))
+	for _, p := range phases {
+		if p.blocks == 0 {
+			continue
+		}
+		A := make([][]float64, n)
+		for i := range A {
+			A[i] = make([]float64, n)
+			if p.opFreq[i] == 0 {
+				if i < len(d.StochasticMatrix) {
+					for j, p := range d.StochasticMatrix[i] {
+						if !math.IsNaN(p) {
+							A[i][j] = p
+						}
+					}
+				}
+				continue
+			}
+			for j := range A[i] {
+				A[i][j] = p.transit[i][j] / p.opFreq[i]
+			}
+		}
+		models = append(models, PhaseModelJSON{Blocks: p.blocks, StochasticMatrix: A})
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("segments of events file contain no blocks")
+	}
+	return models, nil
+}
+
+// phaseSchedule switches between the Markov chains of phases at phase boundaries.
+// After the last phase, the schedule restarts with the first phase.
+type phaseSchedule struct {
+	chains []*MarkovChain // first-order Markov chains of phases
+	blocks []int          // durations of phases in blocks
+	phase  int            // current phase
+	block  int            // number of completed blocks in current phase
+}
+
+// newPhaseSchedule creates a phase schedule for the phases of a simulation model.
+func newPhaseSchedule(e *EstimationModelJSON) (*phaseSchedule, error) {
+	s := &phaseSchedule{}
+	for k, p := range e.Phases {
+		if p.Blocks < 1 {
+			return nil, fmt.Errorf("phase %v has no blocks", k)
+		}
+		if len(p.StochasticMatrix) != len(e.Operations) {
+			return nil, fmt.Errorf("stochastic matrix of phase %v does not match operations", k)
+		}
+		chain, err := NewMarkovChain(e.Operations, p.StochasticMatrix, 1, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.chains = append(s.chains, chain)
+		s.blocks = append(s.blocks, p.Blocks)
+	}
+	return s, nil
+}
+
+// chain returns the Markov chain of the current phase.
+func (s *phaseSchedule) chain() *MarkovChain {
+	return s.chains[s.phase]
+}
+
+// endBlock completes a block and reports whether a new phase has started.
+func (s *phaseSchedule) endBlock() bool {
+	s.block++
+	if s.block < s.blocks[s.phase] {
+		return false
+	}
+	s.block = 0
+	s.phase = (s.phase + 1) % len(s.chains)
+	return true
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/logger"
+	"go.uber.org/mock/gomock"
+)
+
+// recordPhasedEvents records blocks with a single transaction each; the transactions
+// of the first blocks execute firstOp and the transactions of the remaining blocks secondOp
+// (both without arguments).
+func recordPhasedEvents(segmentLength int, firstBlocks int, firstOp int, secondBlocks int, secondOp int) *EventRegistryJSON {
+	r := NewEventRegistry()
+	r.EnableSegments(segmentLength)
+	r.RegisterOp(BeginSyncPeriodID)
+	for b := 0; b < firstBlocks+secondBlocks; b++ {
+		op := firstOp
+		if b >= firstBlocks {
+			op = secondOp
+		}
+		r.RegisterOp(BeginBlockID)
+		r.RegisterOp(BeginTransactionID)
+		r.RegisterOp(op)
+		r.RegisterOp(EndTransactionID)
+		r.RegisterOp(EndBlockID)
+	}
+	r.RegisterOp(EndSyncPeriodID)
+	d := r.NewEventRegistryJSON()
+	return &d
+}
+
+// TestEventRegistrySegments checks that transitions are counted per segment of blocks.
+func TestEventRegistrySegments(t *testing.T) {
+	d := recordPhasedEvents(2, 5, AddRefundID, 0, AddRefundID)
+	if len(d.Segments) != 3 {
+		t.Fatalf("unexpected number of segments: %v", len(d.Segments))
+	}
+	for i, blocks := range []int{2, 2, 1} {
+		if d.Segments[i].Blocks != blocks {
+			t.Errorf("unexpected number of blocks of segment %v: %v", i, d.Segments[i].Blocks)
+		}
+	}
+	// the transition leaving the last end-block operation belongs to the segment
+	endBlock := EncodeOpcode(EndBlockID, 0, 0, 0)
+	found := false
+	for _, c := range d.Segments[0].Transitions {
+		if c.Context[0] == endBlock {
+			found = len(c.Frequencies) == 1 && c.Frequencies[0] == 2
+		}
+	}
+	if !found {
+		t.Errorf("transitions leaving end-block operations are not counted in first segment")
+	}
+	if r := NewEventRegistry(); len(r.NewEventRegistryJSON().Segments) != 0 {
+		t.Errorf("segments must be disabled by default")
+	}
+}
+
+// TestEstimatePhases_DetectsChangePoint checks that a shift of the workload starts a new phase.
+func TestEstimatePhases_DetectsChangePoint(t *testing.T) {
+	d := recordPhasedEvents(1, 4, AddRefundID, 4, GetRefundID)
+	phases, err := EstimatePhases(d, 0, 0.1)
+	if err != nil {
+		t.Fatalf("failed to estimate phases; %v", err)
+	}
+	if len(phases) != 2 || phases[0].Blocks != 4 || phases[1].Blocks != 4 {
+		t.Fatalf("unexpected phases: %v", phases)
+	}
+	beginTx := find(d.Operations, EncodeOpcode(BeginTransactionID, 0, 0, 0))
+	addRefund := find(d.Operations, EncodeOpcode(AddRefundID, 0, 0, 0))
+	getRefund := find(d.Operations, EncodeOpcode(GetRefundID, 0, 0, 0))
+	if got := phases[0].StochasticMatrix[beginTx][addRefund]; got != 1.0 {
+		t.Errorf("unexpected probability of first phase: %v", got)
+	}
+	if got := phases[1].StochasticMatrix[beginTx][getRefund]; got != 1.0 {
+		t.Errorf("unexpected probability of second phase: %v", got)
+	}
+
+	// rows of operations that are not left in a phase fall back to the whole recording
+	if got := phases[0].StochasticMatrix[getRefund]; got[find(d.Operations, EncodeOpcode(EndTransactionID, 0, 0, 0))] != 1.0 {
+		t.Errorf("unexpected fallback row: %v", got)
+	}
+}
+
+// TestEstimatePhases_FixedWindows checks that phases are limited by the phase length.
+func TestEstimatePhases_FixedWindows(t *testing.T) {
+	d := recordPhasedEvents(1, 4, AddRefundID, 4, GetRefundID)
+	phases, err := EstimatePhases(d, 3, 0)
+	if err != nil {
+		t.Fatalf("failed to estimate phases; %v", err)
+	}
+	if len(phases) != 3 || phases[0].Blocks != 3 || phases[1].Blocks != 3 || phases[2].Blocks != 2 {
+		t.Fatalf("unexpected phases: %v", phases)
+	}
+	if _, err := EstimatePhases(recordTestEvents(GetBalanceID), 3, 0); err == nil {
+		t.Errorf("expected an error for events without segments")
+	}
+}
+
+// TestPhaseSchedule_SwitchesChains checks that the schedule cycles through the phases.
+func TestPhaseSchedule_SwitchesChains(t *testing.T) {
+	d := recordPhasedEvents(1, 2, AddRefundID, 1, GetRefundID)
+	e := EstimationModelJSON{Operations: d.Operations}
+	var err error
+	e.Phases, err = EstimatePhases(d, 0, 0.1)
+	if err != nil {
+		t.Fatalf("failed to estimate phases; %v", err)
+	}
+	s, err := newPhaseSchedule(&e)
+	if err != nil {
+		t.Fatalf("failed to create schedule; %v", err)
+	}
+	expected := []bool{false, true, true, false, true}
+	for i, switched := range expected {
+		if got := s.endBlock(); got != switched {
+			t.Errorf("unexpected phase switch after block %v: %v", i, got)
+		}
+	}
+	if s.phase != 1 {
+		t.Errorf("unexpected phase %v", s.phase)
+	}
+}
+
+func TestNewReplayChain_WarnsIfPhasesIgnoreOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	log := logger.NewMockLogger(ctrl)
+
+	d := recordPhasedEvents(1, 2, AddRefundID, 1, GetRefundID)
+	e := EstimationModelJSON{Operations: d.Operations, Order: 2}
+	var err error
+	e.Phases, err = EstimatePhases(d, 0, 0.1)
+	if err != nil {
+		t.Fatalf("failed to estimate phases; %v", err)
+	}
+
+	log.EXPECT().Warningf(gomock.Any(), 2)
+	log.EXPECT().Noticef(gomock.Any(), len(e.Phases))
+	chain, schedule, err := newReplayChain(&e, e.Operations, nil, log)
+	if err != nil {
+		t.Fatalf("failed to create chain; %v", err)
+	}
+	if schedule == nil || chain.Order() != 1 {
+		t.Errorf("phases must be replayed with first-order chains; got order %v", chain.Order())
+	}
+}
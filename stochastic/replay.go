@@ -30,6 +30,7 @@ import (
 	"github.com/Fantom-foundation/Aida/stochastic/statistics"
 	"github.com/Fantom-foundation/Aida/utils"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 // Parameterisable simulation constants
@@ -40,8 +41,9 @@ var (
 
 // Simulation constants
 const (
-	MaxCodeSize  = 24576 // fixed upper limit by EIP-170
-	FinaliseFlag = true  // flag for Finalise() StateDB operation
+	MaxCodeSize  = 24576  // fixed upper limit by EIP-170
+	FinaliseFlag = true   // flag for Finalise() StateDB operation
+	RefundRange  = 100000 // refund range for generating randomized gas refunds
 )
 
 // stochasticState keeps the execution state for the stochastic simulation
@@ -51,6 +53,7 @@ type stochasticState struct {
 	keys           *generator.RandomAccess   // index access generator for keys
 	values         *generator.RandomAccess   // index access generator for values
 	snapshotLambda float64                   // lambda parameter for snapshot delta distribution
+	valueSampler   valueSampler              // sampler for balances, nonces, code sizes and storage values
 	totalTx        uint64                    // total number of transactions
 	txNum          uint32                    // current transaction number
 	blockNum       uint64                    // current block number
@@ -99,6 +102,7 @@ func createState(cfg *utils.Config, e *EstimationModelJSON, db state.StateDB, rg
 
 	// setup state
 	ss := NewStochasticState(rg, db, contracts, keys, values, e.SnapshotLambda, log)
+	ss.valueSampler = newValueSampler(&e.ValueClasses)
 
 	// create accounts in StateDB
 	ss.prime()
@@ -118,6 +122,30 @@ func getStochasticMatrix(e *EstimationModelJSON) ([]string, [][]float64, int) {
 	return operations, A, state
 }
 
+// newReplayChain constructs the Markov chain of the model's order. If the model has phases,
+// the replay switches between the first-order Markov chains of the phases, which are returned
+// with their schedule.
+func newReplayChain(e *EstimationModelJSON, operations []string, A [][]float64, log logger.Logger) (*MarkovChain, *phaseSchedule, error) {
+	if len(e.Phases) == 0 {
+		chain, err := NewMarkovChain(operations, A, e.Order, e.Contexts)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Noticef("Markov chain of order %v", chain.Order())
+		return chain, nil, nil
+	}
+
+	schedule, err := newPhaseSchedule(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.Order > 1 {
+		log.Warningf("Phases are replayed with first-order Markov chains; order %v of the model is ignored", e.Order)
+	}
+	log.Noticef("%v phases of the workload", len(e.Phases))
+	return schedule.chain(), schedule, nil
+}
+
 // retrieve operations and stochastic matrix from simulation object
 
 // RunStochasticReplay runs the stochastic simulation for StateDB operations.
@@ -149,6 +177,25 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 	// get stochastic matrix
 	operations, A, state := getStochasticMatrix(e)
 
+	// construct Markov chain of the model
+	chain, schedule, err := newReplayChain(e, operations, A, log)
+	if err != nil {
+		return err
+	}
+	history := []int{state}
+
+	// spawn concurrent archive readers if archive queries are enabled
+	var readers *archiveReaders
+	if cfg.ArchiveMode && cfg.ArchiveQueryRate > 0 {
+		readers, err = newArchiveReaders(db, e, cfg.ArchiveQueryRate, cfg.ArchiveMaxQueryAge, cfg.ArchiveQueryAgeLambda, log)
+		if err != nil {
+			return err
+		}
+		numReaders := max(cfg.Workers, 1)
+		log.Noticef("Start %v archive readers with %v queries/s", numReaders, cfg.ArchiveQueryRate)
+		readers.start(numReaders, cfg.RandomSeed)
+	}
+
 	// progress message setup
 	var (
 		start    time.Time
@@ -187,6 +234,10 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 			if block >= nBlocks {
 				break
 			}
+			if schedule != nil && schedule.endBlock() {
+				chain = schedule.chain()
+				log.Infof("Start of phase %v at block %v", schedule.phase, ss.blockNum)
+			}
 			// if current block is greater or equal to debug block, enable debug.
 			if cfg.Debug && !ss.traceDebug && ss.blockNum >= cfg.DebugFrom {
 				ss.enableDebug()
@@ -214,7 +265,23 @@ func RunStochasticReplay(db state.StateDB, e *EstimationModelJSON, nBlocks int,
 		}
 
 		// transit to next state in Markovian process
-		state = nextState(rg, A, state)
+		state = chain.Next(rg, history)
+		history = append(history, state)
+		if len(history) > chain.Order() {
+			history = history[1:]
+		}
+	}
+
+	// stop archive readers
+	if readers != nil {
+		if err := readers.stop(); err != nil {
+			errCount++
+			if runErr == nil {
+				runErr = fmt.Errorf("error: stochastic replay failed.")
+			}
+			runErr = fmt.Errorf("%v\n\tArchive reader: %v", runErr, err)
+		}
+		log.Noticef("Archive queries: %v, archive operations: %v", readers.queries.Load(), readers.operations.Load())
 	}
 
 	// print progress summary
@@ -269,7 +336,7 @@ func (ss *stochasticState) prime() {
 	for i := int64(0); i <= numInitialAccounts; i++ {
 		addr := toAddress(i)
 		db.CreateAccount(addr)
-		db.AddBalance(addr, big.NewInt(ss.rg.Int63n(BalanceRange)))
+		db.AddBalance(addr, ss.valueSampler.balance(ss.rg))
 		pt.PrintProgress()
 	}
 	ss.log.Notice("Finalizing...")
@@ -307,7 +374,7 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 		key = toHash(keyIdx)
 	}
 	if valueCl != statistics.NoArgID {
-		value = toHash(valueIdx)
+		value = ss.valueSampler.storageValue(valueIdx)
 	}
 
 	// print opcode and its arguments
@@ -328,12 +395,31 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 	}
 
 	switch op {
+	case AddAddressToAccessListID:
+		db.AddAddressToAccessList(addr)
+
 	case AddBalanceID:
-		value := rg.Int63n(BalanceRange)
+		value := ss.valueSampler.balance(rg)
 		if ss.traceDebug {
 			ss.log.Infof("value: %v", value)
 		}
-		db.AddBalance(addr, big.NewInt(value))
+		db.AddBalance(addr, value)
+
+	case AddLogID:
+		db.AddLog(&types.Log{Address: addr})
+
+	case AddRefundID:
+		gas := uint64(rg.Int63n(RefundRange))
+		if ss.traceDebug {
+			ss.log.Infof(" gas: %v", gas)
+		}
+		db.AddRefund(gas)
+
+	case AddSlotToAccessListID:
+		db.AddSlotToAccessList(addr, key)
+
+	case AddressInAccessListID:
+		db.AddressInAccessList(addr)
 
 	case BeginBlockID:
 		if ss.traceDebug {
@@ -398,12 +484,18 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 	case GetNonceID:
 		db.GetNonce(addr)
 
+	case GetRefundID:
+		db.GetRefund()
+
 	case GetStateID:
 		db.GetState(addr, key)
 
 	case HasSuicidedID:
 		db.HasSuicided(addr)
 
+	case PrepareAccessListID:
+		db.PrepareAccessList(addr, nil, []common.Address{}, types.AccessList{})
+
 	case RevertToSnapshotID:
 		snapshotNum := len(ss.snapshot)
 		if snapshotNum > 0 {
@@ -421,7 +513,7 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 		}
 
 	case SetCodeID:
-		sz := rg.Intn(MaxCodeSize-1) + 1
+		sz := ss.valueSampler.codeSize(rg)
 		if ss.traceDebug {
 			ss.log.Infof(" code-size: %v", sz)
 		}
@@ -433,12 +525,28 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 		db.SetCode(addr, code)
 
 	case SetNonceID:
-		value := uint64(rg.Intn(NonceRange))
+		// the current nonce is only read if nonce increments are modelled so that
+		// the workload does not contain reads which were not sampled
+		var nonce uint64
+		if ss.valueSampler.nonces != nil {
+			if shadowDB := db.GetShadowDB(); shadowDB == nil {
+				nonce = db.GetNonce(addr)
+			} else {
+				nonce = shadowDB.GetNonce(addr)
+			}
+		}
+		value := ss.valueSampler.nonce(rg, nonce)
+		if ss.traceDebug {
+			ss.log.Infof(" nonce: %v", value)
+		}
 		db.SetNonce(addr, value)
 
 	case SetStateID:
 		db.SetState(addr, key, value)
 
+	case SlotInAccessListID:
+		db.SlotInAccessList(addr, key)
+
 	case SnapshotID:
 		id := db.Snapshot()
 		if ss.traceDebug {
@@ -448,20 +556,40 @@ func (ss *stochasticState) execute(op int, addrCl int, keyCl int, valueCl int) {
 
 	case SubBalanceID:
 		shadowDB := db.GetShadowDB()
-		var balance int64
+		var balance *big.Int
 		if shadowDB == nil {
-			balance = db.GetBalance(addr).Int64()
+			balance = db.GetBalance(addr)
 		} else {
-			balance = shadowDB.GetBalance(addr).Int64()
+			balance = shadowDB.GetBalance(addr)
 		}
-		if balance > 0 {
+		if balance.Sign() > 0 {
 			// get a delta that does not exceed current balance
 			// in the current snapshot
-			value := rg.Int63n(balance)
+			value := ss.valueSampler.balance(rg)
+			if value.Cmp(balance) >= 0 {
+				value = new(big.Int).Rand(rg, balance)
+			}
 			if ss.traceDebug {
 				ss.log.Infof(" value: %v", value)
 			}
-			db.SubBalance(addr, big.NewInt(value))
+			db.SubBalance(addr, value)
+		}
+
+	case SubRefundID:
+		shadowDB := db.GetShadowDB()
+		var refund uint64
+		if shadowDB == nil {
+			refund = db.GetRefund()
+		} else {
+			refund = shadowDB.GetRefund()
+		}
+		if refund > 0 {
+			// get a delta that does not exceed the current refund
+			gas := uint64(rg.Int63n(int64(refund)))
+			if ss.traceDebug {
+				ss.log.Infof(" gas: %v", gas)
+			}
+			db.SubRefund(gas)
 		}
 
 	case SuicideID:
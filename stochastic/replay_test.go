@@ -20,8 +20,18 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"path/filepath"
+	"slices"
 	"testing"
 
+	"github.com/Fantom-foundation/Aida/logger"
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/stochastic/generator"
+	"github.com/Fantom-foundation/Aida/stochastic/statistics"
+	"github.com/Fantom-foundation/Aida/tracer"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"go.uber.org/mock/gomock"
 	"gonum.org/v1/gonum/stat/distuv"
 )
 
@@ -204,3 +214,139 @@ func TestInitialState(t *testing.T) {
 		t.Fatalf("Should not find first state")
 	}
 }
+
+// TestStochasticState_ExecuteRefundLogAndAccessListOps checks whether refund, log
+// and access-list operations are executed on the StateDB without violating its
+// invariants, e.g., the refund counter must never drop below zero.
+func TestStochasticState_ExecuteRefundLogAndAccessListOps(t *testing.T) {
+	db, err := state.MakeEmptyGethInMemoryStateDB("")
+	if err != nil {
+		t.Fatalf("failed to create StateDB; %v", err)
+	}
+
+	rg := rand.New(rand.NewSource(999))
+	qpdf := make([]float64, statistics.QueueLen)
+	n := int64(10 * statistics.QueueLen)
+	contracts := generator.NewIndirectAccess(generator.NewRandomAccess(rg, n, 0.1, qpdf))
+	keys := generator.NewRandomAccess(rg, n, 0.1, qpdf)
+	values := generator.NewRandomAccess(rg, n, 0.1, qpdf)
+	ss := NewStochasticState(rg, db, contracts, keys, values, 0.1, logger.NewLogger("INFO", "Stochastic Test"))
+
+	ss.execute(BeginSyncPeriodID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+	ss.execute(BeginBlockID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+	ss.execute(BeginTransactionID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+	ss.execute(PrepareAccessListID, statistics.RandomValueID, statistics.NoArgID, statistics.NoArgID)
+	for i := 0; i < 100; i++ {
+		ss.execute(AddAddressToAccessListID, statistics.RandomValueID, statistics.NoArgID, statistics.NoArgID)
+		ss.execute(AddressInAccessListID, statistics.PreviousValueID, statistics.NoArgID, statistics.NoArgID)
+		ss.execute(AddSlotToAccessListID, statistics.PreviousValueID, statistics.RandomValueID, statistics.NoArgID)
+		ss.execute(SlotInAccessListID, statistics.PreviousValueID, statistics.PreviousValueID, statistics.NoArgID)
+		ss.execute(AddLogID, statistics.RandomValueID, statistics.NoArgID, statistics.NoArgID)
+		ss.execute(SubRefundID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+		ss.execute(AddRefundID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+		ss.execute(SubRefundID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+		ss.execute(GetRefundID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+	}
+	if refund := db.GetRefund(); refund >= 100*RefundRange {
+		t.Errorf("unexpected refund counter %v", refund)
+	}
+	ss.execute(EndTransactionID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+	ss.execute(EndBlockID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+	ss.execute(EndSyncPeriodID, statistics.NoArgID, statistics.NoArgID, statistics.NoArgID)
+
+	if err := db.Error(); err != nil {
+		t.Fatalf("unexpected StateDB error; %v", err)
+	}
+}
+
+// TestStochasticState_SetNonceReadsNonceOnlyIfModelled checks that setting a nonce
+// reads the current nonce only if nonce increments are modelled.
+func TestStochasticState_SetNonceReadsNonceOnlyIfModelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := state.NewMockStateDB(ctrl)
+
+	rg := rand.New(rand.NewSource(999))
+	qpdf := make([]float64, statistics.QueueLen)
+	n := int64(10 * statistics.QueueLen)
+	contracts := generator.NewIndirectAccess(generator.NewRandomAccess(rg, n, 0.1, qpdf))
+	keys := generator.NewRandomAccess(rg, n, 0.1, qpdf)
+	values := generator.NewRandomAccess(rg, n, 0.1, qpdf)
+	ss := NewStochasticState(rg, db, contracts, keys, values, 0.1, logger.NewLogger("INFO", "Stochastic Test"))
+
+	// without a nonce model, nonces are drawn uniformly
+	db.EXPECT().SetNonce(gomock.Any(), gomock.Any())
+	ss.execute(SetNonceID, statistics.RandomValueID, statistics.NoArgID, statistics.NoArgID)
+
+	// with a nonce model, the nonce is incremented
+	ss.valueSampler = newValueSampler(&ValueModelJSON{Nonces: ClassDistributionJSON{Classes: []int{1}, Probabilities: []float64{1}}})
+	gomock.InOrder(
+		db.EXPECT().GetShadowDB().Return(nil),
+		db.EXPECT().GetNonce(gomock.Any()).Return(uint64(5)),
+		db.EXPECT().SetNonce(gomock.Any(), uint64(6)),
+	)
+	ss.execute(SetNonceID, statistics.PreviousValueID, statistics.NoArgID, statistics.NoArgID)
+}
+
+// TestRunStochasticReplay_RecordsReplayableTrace checks that the operations of a stochastic
+// replay can be recorded in a storage trace and replayed with validated results.
+func TestRunStochasticReplay_RecordsReplayableTrace(t *testing.T) {
+	cfg, e := newFuzzTestModel()
+	traceFile := filepath.Join(t.TempDir(), "trace.dat")
+
+	// record the stochastic replay
+	rCtx, err := context.NewRecord(traceFile, 0)
+	if err != nil {
+		t.Fatalf("failed to create record context; %v", err)
+	}
+	rCtx.Validate = true
+	db, err := state.MakeEmptyGethInMemoryStateDB("")
+	if err != nil {
+		t.Fatalf("failed to create StateDB; %v", err)
+	}
+	if err := RunStochasticReplay(NewTracedStateDB(db, rCtx), e, 3, cfg, logger.NewLogger("ERROR", "Trace Test")); err != nil {
+		t.Fatalf("stochastic replay failed; %v", err)
+	}
+	rCtx.Close()
+
+	// replay the trace on a fresh StateDB
+	db, err = state.MakeEmptyGethInMemoryStateDB("")
+	if err != nil {
+		t.Fatalf("failed to create StateDB; %v", err)
+	}
+	replayCtx := context.NewReplay()
+	replayCtx.Validate = true
+	iter := tracer.NewTraceIterator([]string{traceFile}, 0)
+	defer iter.Release()
+	blocks := 0
+	existenceChecks := 0
+	for iter.Next() {
+		op := iter.Value()
+		switch op.GetId() {
+		case operation.BeginBlockID:
+			blocks++
+		case operation.HasSuicidedID, operation.EmptyID:
+			existenceChecks++
+		}
+		operation.Execute(op, db, replayCtx)
+		if err := replayCtx.Mismatch(); err != nil {
+			t.Fatalf("replayed trace diverges in block %v; %v", blocks-1, err)
+		}
+	}
+	// the priming block and the simulated blocks are recorded
+	if blocks != 4 {
+		t.Errorf("unexpected number of blocks in trace: %v", blocks)
+	}
+	if existenceChecks == 0 {
+		t.Errorf("HasSuicided and Empty operations are not recorded in trace")
+	}
+}
+
+// TestUntracedOperations_ReportsOperationsMissingInTrace checks that the refund,
+// access-list and log operations of a model are reported as untraced.
+func TestUntracedOperations_ReportsOperationsMissingInTrace(t *testing.T) {
+	e := &EstimationModelJSON{Operations: []string{"BS", "AR", "GR", "EXr", "ALr", "AR"}}
+	ops := UntracedOperations(e)
+	if want := []string{"AddLog", "AddRefund", "GetRefund"}; !slices.Equal(ops, want) {
+		t.Errorf("unexpected untraced operations: got %v, want %v", ops, want)
+	}
+}
@@ -83,3 +83,14 @@ func (a *Access[T]) Classify(data T) int {
 func (a *Access[T]) NewAccessJSON() AccessJSON {
 	return AccessJSON{a.cstats.NewCountingJSON(), a.qstats.NewQueuingJSON()}
 }
+
+// MergeAccessJSON merges weighted access statistics.
+func MergeAccessJSON(stats []AccessJSON, weights []float64) AccessJSON {
+	counting := make([]CountingJSON, len(stats))
+	queuing := make([]QueuingJSON, len(stats))
+	for i := range stats {
+		counting[i] = stats[i].Counting
+		queuing[i] = stats[i].Queuing
+	}
+	return AccessJSON{MergeCountingJSON(counting, weights), MergeQueuingJSON(queuing, weights)}
+}
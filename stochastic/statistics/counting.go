@@ -17,6 +17,7 @@
 package statistics
 
 import (
+	"math"
 	"sort"
 
 	"github.com/paulmach/orb"
@@ -30,8 +31,9 @@ type Counting[T comparable] struct {
 
 // JSON output for a Counting object
 type CountingJSON struct {
-	NumKeys int64        `json:"n"`    // Number of data entries
-	ECdf    [][2]float64 `json:"ecdf"` // Empirical cumulative distribution function
+	NumKeys int64        `json:"n"`     // Number of data entries
+	Total   uint64       `json:"total"` // Total frequency of all data entries
+	ECdf    [][2]float64 `json:"ecdf"`  // Empirical cumulative distribution function
 }
 
 // NewCounting creates a new counting statistics.
@@ -120,6 +122,7 @@ func (s *Counting[T]) produceJSON(numPoints int) CountingJSON {
 
 	return CountingJSON{
 		NumKeys: int64(numKeys),
+		Total:   totalFreq,
 		ECdf:    ECdf,
 	}
 }
@@ -128,3 +131,85 @@ func (s *Counting[T]) produceJSON(numPoints int) CountingJSON {
 func (s *Counting[T]) NewCountingJSON() CountingJSON {
 	return s.produceJSON(NumDistributionPoints)
 }
+
+// mergeBuckets is the number of buckets per counting statistics used for merging.
+const mergeBuckets = 1000
+
+// EvaluateECdf evaluates a piecewise linear ECDF given by its points at x.
+func EvaluateECdf(points [][2]float64, x float64) float64 {
+	if len(points) == 0 || x <= points[0][0] {
+		return 0.0
+	}
+	for i := 1; i < len(points); i++ {
+		if x <= points[i][0] {
+			x0, y0 := points[i-1][0], points[i-1][1]
+			x1, y1 := points[i][0], points[i][1]
+			if x1 == x0 {
+				return y1
+			}
+			return y0 + (y1-y0)*(x-x0)/(x1-x0)
+		}
+	}
+	return 1.0
+}
+
+// MergeCountingJSON merges weighted counting statistics assuming disjoint data entries.
+// Each ECDF is split into buckets of data entries with equal frequency; the buckets of
+// all statistics are re-sorted by their descending frequency per data entry, and the
+// frequencies are scaled by the weights.
+func MergeCountingJSON(stats []CountingJSON, weights []float64) CountingJSON {
+	type bucket struct {
+		keys float64 // number of data entries in bucket
+		freq float64 // total frequency of bucket
+	}
+	buckets := []bucket{}
+	numKeys := int64(0)
+	total := 0.0
+	for i, s := range stats {
+		if s.NumKeys == 0 || s.Total == 0 {
+			continue
+		}
+		numKeys += s.NumKeys
+		total += weights[i] * float64(s.Total)
+		n := int64(mergeBuckets)
+		if s.NumKeys < n {
+			n = s.NumKeys
+		}
+		for b := int64(0); b < n; b++ {
+			x0 := float64(b) / float64(n)
+			x1 := float64(b+1) / float64(n)
+			p := EvaluateECdf(s.ECdf, x1) - EvaluateECdf(s.ECdf, x0)
+			buckets = append(buckets, bucket{
+				keys: float64(s.NumKeys) / float64(n),
+				freq: weights[i] * float64(s.Total) * p,
+			})
+		}
+	}
+	if numKeys == 0 || total == 0 {
+		return CountingJSON{NumKeys: numKeys, ECdf: [][2]float64{}}
+	}
+	sort.SliceStable(buckets, func(i, j int) bool {
+		return buckets[i].freq/buckets[i].keys > buckets[j].freq/buckets[j].keys
+	})
+
+	// construct merged eCdf and simplify it
+	ls := orb.LineString{orb.Point{0.0, 0.0}}
+	keys, freq := 0.0, 0.0
+	for _, b := range buckets {
+		keys += b.keys
+		freq += b.freq
+		ls = append(ls, orb.Point{keys / float64(numKeys), freq / total})
+	}
+	ls = append(ls, orb.Point{1.0, 1.0})
+	simplifier := simplify.VisvalingamKeep(NumDistributionPoints)
+	simplified := simplifier.Simplify(ls).(orb.LineString)
+	ECdf := make([][2]float64, len(simplified))
+	for i := range simplified {
+		ECdf[i] = [2]float64(simplified[i])
+	}
+	return CountingJSON{
+		NumKeys: numKeys,
+		Total:   uint64(math.Round(total)),
+		ECdf:    ECdf,
+	}
+}
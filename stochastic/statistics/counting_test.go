@@ -71,7 +71,7 @@ func TestCountingSimple4(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Marshalling failed to produce distribution")
 	}
-	expected := `{"n":0,"ecdf":[]}`
+	expected := `{"n":0,"total":0,"ecdf":[]}`
 	if string(jOut) != expected {
 		t.Fatalf("case 0: produced wrong JSON output (%v)", string(jOut))
 	}
@@ -88,7 +88,7 @@ func TestCountingSimple4(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Marshalling failed to produce distribution")
 	}
-	expected = `{"n":10,"ecdf":[[0,0],[0.15,0.3333333333333333],[0.95,1],[1,1]]}`
+	expected = `{"n":10,"total":12,"ecdf":[[0,0],[0.15,0.3333333333333333],[0.95,1],[1,1]]}`
 	if string(jOut) != expected {
 		t.Fatalf("case 1: produced wrong JSON output (%v)", string(jOut))
 	}
@@ -99,8 +99,37 @@ func TestCountingSimple4(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Marshalling failed to produce distribution")
 	}
-	expected = `{"n":10,"ecdf":[[0,0],[0.05,0.16666666666666666],[0.15,0.3333333333333333],[0.25,0.41666666666666663],[0.35,0.5],[0.45,0.5833333333333333],[0.55,0.6666666666666666],[0.65,0.75],[0.75,0.8333333333333333],[0.85,0.9166666666666666],[0.95,1],[1,1]]}`
+	expected = `{"n":10,"total":12,"ecdf":[[0,0],[0.05,0.16666666666666666],[0.15,0.3333333333333333],[0.25,0.41666666666666663],[0.35,0.5],[0.45,0.5833333333333333],[0.55,0.6666666666666666],[0.65,0.75],[0.75,0.8333333333333333],[0.85,0.9166666666666666],[0.95,1],[1,1]]}`
 	if string(jOut) != expected {
 		t.Fatalf("case 2: produced wrong JSON output (%v)", string(jOut))
 	}
 }
+
+// TestCountingMerge checks merging of counting statistics with disjoint data entries.
+func TestCountingMerge(t *testing.T) {
+	a := NewCounting[int]()
+	b := NewCounting[int]()
+	for i := 0; i < 10; i++ {
+		a.Place(i)
+		b.Place(100 + i)
+		b.Place(100 + i)
+	}
+
+	merged := MergeCountingJSON([]CountingJSON{a.NewCountingJSON(), b.NewCountingJSON()}, []float64{1, 1})
+	if merged.NumKeys != 20 {
+		t.Fatalf("unexpected number of keys %v", merged.NumKeys)
+	}
+	if merged.Total != 30 {
+		t.Fatalf("unexpected total frequency %v", merged.Total)
+	}
+	// the ten entries of b are accessed twice as often and account for 2/3 of all accesses
+	if y := EvaluateECdf(merged.ECdf, 0.5); y < 0.6 || y > 0.7 {
+		t.Fatalf("unexpected cumulative frequency %v at 0.5", y)
+	}
+
+	// a weight of two for a makes all entries equally frequent
+	merged = MergeCountingJSON([]CountingJSON{a.NewCountingJSON(), b.NewCountingJSON()}, []float64{2, 1})
+	if y := EvaluateECdf(merged.ECdf, 0.5); y < 0.4 || y > 0.6 {
+		t.Fatalf("unexpected cumulative frequency %v at 0.5", y)
+	}
+}
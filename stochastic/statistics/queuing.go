@@ -16,6 +16,8 @@
 
 package statistics
 
+import "math"
+
 // Queuing data structure for a generic FIFO queue.
 type Queuing[T comparable] struct {
 	// queue structure
@@ -32,6 +34,9 @@ type Queuing[T comparable] struct {
 type QueuingJSON struct {
 	// probability of a position in the queue
 	Distribution []float64 `json:"distribution"`
+
+	// total number of successful finds
+	Total uint64 `json:"total"`
 }
 
 // NewQueuing creates a new queue.
@@ -110,5 +115,28 @@ func (q *Queuing[T]) NewQueuingJSON() QueuingJSON {
 	// populate new index probabilities
 	return QueuingJSON{
 		Distribution: dist,
+		Total:        total,
+	}
+}
+
+// MergeQueuingJSON merges weighted queuing statistics.
+func MergeQueuingJSON(stats []QueuingJSON, weights []float64) QueuingJSON {
+	dist := make([]float64, QueueLen)
+	total := 0.0
+	for i, s := range stats {
+		w := weights[i] * float64(s.Total)
+		for j := 0; j < QueueLen && j < len(s.Distribution); j++ {
+			dist[j] += w * s.Distribution[j]
+		}
+		total += w
+	}
+	if total > 0 {
+		for j := range dist {
+			dist[j] /= total
+		}
+	}
+	return QueuingJSON{
+		Distribution: dist,
+		Total:        uint64(math.Round(total)),
 	}
 }
@@ -133,8 +133,24 @@ func TestQueuingJSON(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Marshalling failed to produce distribution")
 	}
-	expected := `{"distribution":[0.25125628140703515,0.25041876046901174,0.24958123953098826,0.24874371859296482,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]}`
+	expected := `{"distribution":[0.25125628140703515,0.25041876046901174,0.24958123953098826,0.24874371859296482,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0],"total":1194}`
 	if string(jOut) != expected {
 		t.Fatalf("produced wrong JSON output %v", string(jOut))
 	}
 }
+
+// TestQueuingMerge checks merging of weighted queuing statistics.
+func TestQueuingMerge(t *testing.T) {
+	a := QueuingJSON{Distribution: make([]float64, QueueLen), Total: 10}
+	b := QueuingJSON{Distribution: make([]float64, QueueLen), Total: 10}
+	a.Distribution[0] = 1.0
+	b.Distribution[1] = 1.0
+
+	merged := MergeQueuingJSON([]QueuingJSON{a, b}, []float64{1, 3})
+	if merged.Total != 40 {
+		t.Fatalf("unexpected total %v", merged.Total)
+	}
+	if merged.Distribution[0] != 0.25 || merged.Distribution[1] != 0.75 {
+		t.Fatalf("unexpected distribution %v", merged.Distribution[:2])
+	}
+}
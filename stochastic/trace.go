@@ -0,0 +1,106 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"sort"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/state/proxy"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// untracedOps are the operations of the simulation which have no counterpart in the
+// storage trace format.
+var untracedOps = map[int]bool{
+	AddAddressToAccessListID: true,
+	AddLogID:                 true,
+	AddRefundID:              true,
+	AddSlotToAccessListID:    true,
+	AddressInAccessListID:    true,
+	GetRefundID:              true,
+	PrepareAccessListID:      true,
+	SlotInAccessListID:       true,
+	SubRefundID:              true,
+}
+
+// UntracedOperations returns the names of the operations of the simulation model
+// which are not recorded in a storage trace.
+func UntracedOperations(e *EstimationModelJSON) []string {
+	var ops []string
+	seen := map[int]bool{}
+	for _, opc := range e.Operations {
+		op, _, _, _ := DecodeOpcode(opc)
+		if untracedOps[op] && !seen[op] {
+			seen[op] = true
+			ops = append(ops, OpText(op))
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+// tracedStateDB records the operations of a stochastic replay in a storage trace.
+// In addition to the recorder proxy, it records HasSuicided and Empty operations.
+// Archive queries are not traced and served by the StateDB under test.
+type tracedStateDB struct {
+	state.StateDB
+	db  state.StateDB
+	ctx *context.Record
+}
+
+// NewTracedStateDB creates a StateDB recording the operations of a stochastic replay
+// in the given record context.
+func NewTracedStateDB(db state.StateDB, ctx *context.Record) state.StateDB {
+	return tracedStateDB{proxy.NewRecorderProxy(db, ctx), db, ctx}
+}
+
+// HasSuicided records the HasSuicided operation and checks whether a contract has been suicided.
+func (t tracedStateDB) HasSuicided(addr common.Address) bool {
+	operation.WriteOp(t.ctx, operation.NewHasSuicided(t.ctx.EncodeContract(addr)))
+	hasSuicided := t.db.HasSuicided(addr)
+	t.writeBoolResult(hasSuicided)
+	return hasSuicided
+}
+
+// Empty records the Empty operation and checks whether the contract is empty.
+func (t tracedStateDB) Empty(addr common.Address) bool {
+	operation.WriteOp(t.ctx, operation.NewEmpty(t.ctx.EncodeContract(addr)))
+	empty := t.db.Empty(addr)
+	t.writeBoolResult(empty)
+	return empty
+}
+
+// writeBoolResult records the result of a read operation if the trace is recorded for validation.
+func (t tracedStateDB) writeBoolResult(value bool) {
+	t.ctx.SetBoolResult(value)
+	if t.ctx.Validate {
+		operation.WriteOp(t.ctx, operation.NewResult(t.ctx.Result()))
+	}
+}
+
+// GetArchiveState returns the archive state of the StateDB under test.
+func (t tracedStateDB) GetArchiveState(block uint64) (state.NonCommittableStateDB, error) {
+	return t.db.GetArchiveState(block)
+}
+
+// GetArchiveBlockHeight returns the archive block height of the StateDB under test.
+func (t tracedStateDB) GetArchiveBlockHeight() (uint64, bool, error) {
+	return t.db.GetArchiveBlockHeight()
+}
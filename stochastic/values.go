@@ -0,0 +1,293 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+	"math/rand"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Values of StateDB operations are modelled by classes: balances, nonce increments
+// and code sizes are classified by their bit length, and storage values by their
+// number of significant bytes. Within a class, values are sampled uniformly.
+
+// balanceClass returns the class of a balance amount.
+func balanceClass(amount *big.Int) int {
+	return amount.BitLen()
+}
+
+// nonceClass returns the class of a nonce increment.
+func nonceClass(increment uint64) int {
+	return bits.Len64(increment)
+}
+
+// codeSizeClass returns the class of a code size.
+func codeSizeClass(size int) int {
+	return bits.Len(uint(size))
+}
+
+// valueClass returns the class of a storage value, i.e., its number of significant bytes.
+func valueClass(value common.Hash) int {
+	for i, b := range value {
+		if b != 0 {
+			return len(value) - i
+		}
+	}
+	return 0
+}
+
+// ValueClassesJSON contains the frequencies of value classes.
+type ValueClassesJSON struct {
+	Balances      map[int]uint64 `json:"balances"`      // bit lengths of balance amounts
+	Nonces        map[int]uint64 `json:"nonces"`        // bit lengths of nonce increments
+	CodeSizes     map[int]uint64 `json:"codeSizes"`     // bit lengths of code sizes
+	StorageValues map[int]uint64 `json:"storageValues"` // significant bytes of storage values
+}
+
+// NewValueClassesJSON creates empty value-class frequencies.
+func NewValueClassesJSON() ValueClassesJSON {
+	return ValueClassesJSON{
+		Balances:      map[int]uint64{},
+		Nonces:        map[int]uint64{},
+		CodeSizes:     map[int]uint64{},
+		StorageValues: map[int]uint64{},
+	}
+}
+
+// copy returns a deep copy of value-class frequencies.
+func (v *ValueClassesJSON) copy() ValueClassesJSON {
+	c := NewValueClassesJSON()
+	c.add(v, 1.0)
+	return c
+}
+
+// add adds weighted value-class frequencies of another recording.
+func (v *ValueClassesJSON) add(other *ValueClassesJSON, weight float64) {
+	addFreq := func(dst, src map[int]uint64) {
+		for class, freq := range src {
+			if f := uint64(math.Round(weight * float64(freq))); f > 0 {
+				dst[class] += f
+			}
+		}
+	}
+	addFreq(v.Balances, other.Balances)
+	addFreq(v.Nonces, other.Nonces)
+	addFreq(v.CodeSizes, other.CodeSizes)
+	addFreq(v.StorageValues, other.StorageValues)
+}
+
+// ClassDistributionJSON is the estimated probability distribution of value classes.
+type ClassDistributionJSON struct {
+	Classes       []int     `json:"classes"`
+	Probabilities []float64 `json:"probabilities"`
+}
+
+// NewClassDistribution estimates the distribution of value classes from their frequencies.
+func NewClassDistribution(freq map[int]uint64) ClassDistributionJSON {
+	d := ClassDistributionJSON{Classes: []int{}, Probabilities: []float64{}}
+	total := uint64(0)
+	for class, f := range freq {
+		if f > 0 {
+			d.Classes = append(d.Classes, class)
+			total += f
+		}
+	}
+	sort.Ints(d.Classes)
+	for _, class := range d.Classes {
+		d.Probabilities = append(d.Probabilities, float64(freq[class])/float64(total))
+	}
+	return d
+}
+
+// ValueModelJSON contains the estimated distributions of value classes.
+type ValueModelJSON struct {
+	Balances      ClassDistributionJSON `json:"balances"`
+	Nonces        ClassDistributionJSON `json:"nonces"`
+	CodeSizes     ClassDistributionJSON `json:"codeSizes"`
+	StorageValues ClassDistributionJSON `json:"storageValues"`
+}
+
+// NewValueModel estimates the distributions of value classes from their frequencies.
+func NewValueModel(v *ValueClassesJSON) ValueModelJSON {
+	return ValueModelJSON{
+		Balances:      NewClassDistribution(v.Balances),
+		Nonces:        NewClassDistribution(v.Nonces),
+		CodeSizes:     NewClassDistribution(v.CodeSizes),
+		StorageValues: NewClassDistribution(v.StorageValues),
+	}
+}
+
+// classSampler samples value classes from an estimated distribution.
+type classSampler struct {
+	classes []int     // value classes
+	cdf     []float64 // cumulative probabilities of value classes
+}
+
+// newClassSampler creates a sampler for a class distribution. It returns nil if the
+// distribution is empty, i.e., if the model has not observed any value of the kind.
+func newClassSampler(d ClassDistributionJSON) *classSampler {
+	if len(d.Classes) == 0 || len(d.Classes) != len(d.Probabilities) {
+		return nil
+	}
+	s := &classSampler{classes: d.Classes, cdf: make([]float64, len(d.Probabilities))}
+	sum := 0.0
+	for i, p := range d.Probabilities {
+		sum += p
+		s.cdf[i] = sum
+	}
+	return s
+}
+
+// quantile returns the class for a cumulative probability u in [0,1).
+func (s *classSampler) quantile(u float64) int {
+	u *= s.cdf[len(s.cdf)-1]
+	i := sort.SearchFloat64s(s.cdf, u)
+	if i >= len(s.classes) {
+		i = len(s.classes) - 1
+	}
+	return s.classes[i]
+}
+
+// sample draws a class.
+func (s *classSampler) sample(rg *rand.Rand) int {
+	return s.quantile(rg.Float64())
+}
+
+// sampleBigMagnitude draws a value uniformly from a bit-length class.
+func sampleBigMagnitude(rg *rand.Rand, class int) *big.Int {
+	if class <= 0 {
+		return new(big.Int)
+	}
+	low := new(big.Int).Lsh(big.NewInt(1), uint(class-1))
+	return low.Add(low, new(big.Int).Rand(rg, low))
+}
+
+// sampleMagnitude draws a value uniformly from a bit-length class of at most 63 bits.
+func sampleMagnitude(rg *rand.Rand, class int) uint64 {
+	if class <= 0 {
+		return 0
+	}
+	if class > 63 {
+		class = 63
+	}
+	low := int64(1) << (class - 1)
+	return uint64(low + rg.Int63n(low))
+}
+
+// splitMix64 is a bijective mixing function for deriving pseudo-random values from indexes.
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// toShapedHash converts a non-zero value index to a storage value whose number of
+// significant bytes follows the class distribution. The same index always produces
+// the same value.
+func toShapedHash(idx int64, s *classSampler) common.Hash {
+	if idx == 0 {
+		return common.Hash{}
+	}
+	x := splitMix64(uint64(idx))
+	u := float64(x>>11) / float64(uint64(1)<<53)
+	length := s.quantile(u)
+	if length < 1 {
+		length = 1
+	}
+	if length > common.HashLength {
+		length = common.HashLength
+	}
+	var h common.Hash
+	for i := common.HashLength - length; i < common.HashLength; i++ {
+		x = splitMix64(x)
+		h[i] = byte(x)
+	}
+	if h[common.HashLength-length] == 0 {
+		h[common.HashLength-length] = 1
+	}
+	return h
+}
+
+// valueSampler samples balances, nonce increments, code sizes and storage values
+// from estimated distributions of value classes. A sampler of a kind is nil if no
+// value of that kind was observed (e.g. simulation files without value classes);
+// in this case the replay falls back to uniformly distributed values.
+type valueSampler struct {
+	balances      *classSampler
+	nonces        *classSampler
+	codeSizes     *classSampler
+	storageValues *classSampler
+}
+
+// newValueSampler creates a value sampler for the estimated value classes.
+func newValueSampler(m *ValueModelJSON) valueSampler {
+	return valueSampler{
+		balances:      newClassSampler(m.Balances),
+		nonces:        newClassSampler(m.Nonces),
+		codeSizes:     newClassSampler(m.CodeSizes),
+		storageValues: newClassSampler(m.StorageValues),
+	}
+}
+
+// balance draws a balance amount.
+func (v *valueSampler) balance(rg *rand.Rand) *big.Int {
+	if v.balances == nil {
+		return big.NewInt(rg.Int63n(BalanceRange))
+	}
+	return sampleBigMagnitude(rg, v.balances.sample(rg))
+}
+
+// nonce draws the next nonce of an account with the current nonce.
+func (v *valueSampler) nonce(rg *rand.Rand, current uint64) uint64 {
+	if v.nonces == nil {
+		return uint64(rg.Intn(NonceRange))
+	}
+	increment := sampleMagnitude(rg, v.nonces.sample(rg))
+	if current+increment < current {
+		return math.MaxUint64
+	}
+	return current + increment
+}
+
+// codeSize draws a code size between 1 and MaxCodeSize.
+func (v *valueSampler) codeSize(rg *rand.Rand) int {
+	if v.codeSizes == nil {
+		return rg.Intn(MaxCodeSize-1) + 1
+	}
+	size := sampleMagnitude(rg, v.codeSizes.sample(rg))
+	if size < 1 {
+		return 1
+	}
+	if size > MaxCodeSize {
+		return MaxCodeSize
+	}
+	return int(size)
+}
+
+// storageValue converts a value index to a storage value.
+func (v *valueSampler) storageValue(idx int64) common.Hash {
+	if v.storageValues == nil {
+		return toHash(idx)
+	}
+	return toShapedHash(idx, v.storageValues)
+}
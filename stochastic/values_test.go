@@ -0,0 +1,135 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package stochastic
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestValueClasses checks the classification of values.
+func TestValueClasses(t *testing.T) {
+	if got := balanceClass(big.NewInt(0)); got != 0 {
+		t.Errorf("unexpected class of zero balance: %v", got)
+	}
+	if got := balanceClass(big.NewInt(1000)); got != 10 {
+		t.Errorf("unexpected balance class: %v", got)
+	}
+	if got := nonceClass(1); got != 1 {
+		t.Errorf("unexpected nonce class: %v", got)
+	}
+	if got := codeSizeClass(MaxCodeSize); got != 15 {
+		t.Errorf("unexpected code-size class: %v", got)
+	}
+	if got := valueClass(common.Hash{}); got != 0 {
+		t.Errorf("unexpected class of zero value: %v", got)
+	}
+	if got := valueClass(common.BigToHash(big.NewInt(0x1ff))); got != 2 {
+		t.Errorf("unexpected value class: %v", got)
+	}
+	if got := valueClass(common.HexToHash("0xff00000000000000000000000000000000000000000000000000000000000000")); got != 32 {
+		t.Errorf("unexpected value class of full value: %v", got)
+	}
+}
+
+// TestEventRegistryValueClasses checks that value classes are registered and exported.
+func TestEventRegistryValueClasses(t *testing.T) {
+	r := NewEventRegistry()
+	r.RegisterBalance(big.NewInt(5))
+	r.RegisterBalance(big.NewInt(6))
+	r.RegisterNonce(3, 4)
+	r.RegisterNonce(4, 4)
+	r.RegisterCodeSize(100)
+	r.RegisterStorageValue(common.Hash{})
+	r.RegisterStorageValue(common.BigToHash(big.NewInt(1)))
+
+	d := r.NewEventRegistryJSON()
+	v := d.ValueClasses
+	if v.Balances[3] != 2 || len(v.Balances) != 1 {
+		t.Errorf("unexpected balance classes: %v", v.Balances)
+	}
+	if v.Nonces[0] != 1 || v.Nonces[1] != 1 {
+		t.Errorf("unexpected nonce classes: %v", v.Nonces)
+	}
+	if v.CodeSizes[7] != 1 {
+		t.Errorf("unexpected code-size classes: %v", v.CodeSizes)
+	}
+	if v.StorageValues[1] != 1 || len(v.StorageValues) != 1 {
+		t.Errorf("unexpected storage-value classes: %v", v.StorageValues)
+	}
+
+	m := NewValueModel(&v)
+	if len(m.Nonces.Classes) != 2 || m.Nonces.Probabilities[0] != 0.5 {
+		t.Errorf("unexpected nonce distribution: %v", m.Nonces)
+	}
+}
+
+// TestValueSampler_SamplesWithinClasses checks that sampled values belong to observed classes.
+func TestValueSampler_SamplesWithinClasses(t *testing.T) {
+	v := ValueClassesJSON{
+		Balances:      map[int]uint64{64: 1, 80: 3},
+		Nonces:        map[int]uint64{1: 1},
+		CodeSizes:     map[int]uint64{10: 1},
+		StorageValues: map[int]uint64{1: 1, 32: 1},
+	}
+	m := NewValueModel(&v)
+	s := newValueSampler(&m)
+	rg := rand.New(rand.NewSource(42))
+	lengths := map[int]int{}
+	for i := 0; i < 1000; i++ {
+		if c := balanceClass(s.balance(rg)); c != 64 && c != 80 {
+			t.Fatalf("balance of class %v sampled", c)
+		}
+		if n := s.nonce(rg, 7); n != 8 {
+			t.Fatalf("unexpected nonce %v", n)
+		}
+		if c := codeSizeClass(s.codeSize(rg)); c != 10 {
+			t.Fatalf("code size of class %v sampled", c)
+		}
+		value := s.storageValue(int64(i + 1))
+		lengths[valueClass(value)]++
+		if value != s.storageValue(int64(i+1)) {
+			t.Fatalf("storage values are not deterministic")
+		}
+	}
+	if len(lengths) != 2 || lengths[1] == 0 || lengths[32] == 0 {
+		t.Errorf("unexpected storage-value shapes: %v", lengths)
+	}
+	if s.storageValue(0) != (common.Hash{}) {
+		t.Errorf("zero value index must produce the zero value")
+	}
+}
+
+// TestValueSampler_FallsBackToUniformValues checks the sampling of models without value classes.
+func TestValueSampler_FallsBackToUniformValues(t *testing.T) {
+	s := newValueSampler(&ValueModelJSON{})
+	rg := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		if b := s.balance(rg); b.Cmp(big.NewInt(BalanceRange)) >= 0 {
+			t.Fatalf("balance %v out of range", b)
+		}
+		if sz := s.codeSize(rg); sz < 1 || sz >= MaxCodeSize {
+			t.Fatalf("code size %v out of range", sz)
+		}
+	}
+	if s.storageValue(5) != toHash(5) {
+		t.Errorf("unexpected storage value")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package visualizer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Fantom-foundation/Aida/stochastic"
+)
+
+// preReportHtml is the preamble of a single-file report.
+const preReportHtml = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="utf-8">
+    <title>Aida: Stochastic Estimator</title>
+</head>
+<body>
+`
+
+// postReportHtml is the postamble of a single-file report.
+const postReportHtml = `</body>
+</html>
+`
+
+// ExportStatic produces a data model for the recorded events and renders all views
+// as static HTML. If output ends with ".html", the views are embedded into a single
+// report file; otherwise, an index page and a page per view are written to the output
+// directory. If a held-out recording is provided, the Markov chains are evaluated on it.
+func ExportStatic(eventRegistry *stochastic.EventRegistryJSON, heldOut *stochastic.EventRegistryJSON, output string) error {
+	if err := populate(eventRegistry, heldOut); err != nil {
+		return err
+	}
+	views := staticPages(heldOut != nil)
+	if strings.HasSuffix(strings.ToLower(output), ".html") {
+		return exportReport(views, output)
+	}
+	return exportDirectory(views, output)
+}
+
+// staticPages returns the pages to be exported; the prediction is only exported
+// if a held-out recording is provided.
+func staticPages(prediction bool) []page {
+	views := make([]page, 0, len(pages))
+	for _, p := range pages {
+		if p.ref == predictionRef && !prediction {
+			continue
+		}
+		views = append(views, p)
+	}
+	return views
+}
+
+// exportDirectory writes an index page and a page per view to a directory.
+func exportDirectory(views []page, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create output directory; %v", err)
+	}
+	var buf bytes.Buffer
+	if err := renderMain(&buf, views, func(ref string) string { return ref + ".html" }); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("cannot write index page; %v", err)
+	}
+	for _, p := range views {
+		buf.Reset()
+		if err := p.render(&buf, true); err != nil {
+			return fmt.Errorf("cannot render %v; %v", p.title, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, p.ref+".html"), buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("cannot write %v; %v", p.title, err)
+		}
+	}
+	return nil
+}
+
+// exportReport writes a single report file embedding each view in an inline frame.
+func exportReport(views []page, filename string) error {
+	var buf bytes.Buffer
+	buf.WriteString(preReportHtml)
+	renderMenu(&buf, views)
+	var view bytes.Buffer
+	for _, p := range views {
+		view.Reset()
+		if err := p.render(&view, true); err != nil {
+			return fmt.Errorf("cannot render %v; %v", p.title, err)
+		}
+		fmt.Fprintf(&buf, "<h2 id=\"%v\">%v</h2>\n", p.ref, p.title)
+		fmt.Fprintf(&buf, "<iframe srcdoc=\"%v\" style=\"width: 100%%; height: 1400px; border: none;\"></iframe>\n", html.EscapeString(view.String()))
+	}
+	buf.WriteString(postReportHtml)
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("cannot write report; %v", err)
+	}
+	return nil
+}
+
+// renderMenu renders the menu of a single-file report linking to its sections.
+func renderMenu(buf *bytes.Buffer, views []page) {
+	buf.WriteString("<h1>Aida: Stochastic Estimator</h1>\n<ul>\n")
+	for _, p := range views {
+		fmt.Fprintf(buf, "<li> <h3> <a href=\"#%v\"> %v </a> </h3> </li>\n", p.ref, p.title)
+	}
+	buf.WriteString("</ul>\n")
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package visualizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/stochastic"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// newTestEvents creates an event registry of a short recording.
+func newTestEvents() *stochastic.EventRegistryJSON {
+	r := stochastic.NewEventRegistryWithOrder(2)
+	for i := 0; i < 20; i++ {
+		addr := common.Address{byte(i % 7)}
+		key := common.Hash{byte(i % 5)}
+		value := common.Hash{byte(i % 3)}
+		r.RegisterOp(stochastic.BeginSyncPeriodID)
+		r.RegisterOp(stochastic.BeginBlockID)
+		r.RegisterOp(stochastic.BeginTransactionID)
+		r.RegisterAddressOp(stochastic.GetBalanceID, &addr)
+		r.RegisterValueOp(stochastic.SetStateID, &addr, &key, &value)
+		r.RegisterKeyOp(stochastic.GetStateID, &addr, &key)
+		r.RegisterOp(stochastic.SnapshotID)
+		r.RegisterSnapshotDelta(i % 2)
+		r.RegisterOp(stochastic.RevertToSnapshotID)
+		r.RegisterOp(stochastic.EndTransactionID)
+		r.RegisterOp(stochastic.EndBlockID)
+		r.RegisterOp(stochastic.EndSyncPeriodID)
+	}
+	events := r.NewEventRegistryJSON()
+	return &events
+}
+
+// TestExportStatic_WritesDirectory checks that an index page and a page per view are exported.
+func TestExportStatic_WritesDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "report")
+	if err := ExportStatic(newTestEvents(), nil, dir); err != nil {
+		t.Fatalf("failed to export views; %v", err)
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil {
+		t.Fatalf("failed to read index page; %v", err)
+	}
+	for _, p := range pages {
+		_, err := os.Stat(filepath.Join(dir, p.ref+".html"))
+		linked := strings.Contains(string(index), p.ref+".html")
+		if p.ref == predictionRef {
+			if err == nil || linked {
+				t.Errorf("prediction must not be exported without held-out recording")
+			}
+			continue
+		}
+		if err != nil || !linked {
+			t.Errorf("view %v is not exported", p.title)
+		}
+	}
+
+	// graphs are embedded as SVG images without scripts
+	graph, err := os.ReadFile(filepath.Join(dir, simplifiedMarkovRef+".html"))
+	if err != nil {
+		t.Fatalf("failed to read graph page; %v", err)
+	}
+	if !strings.Contains(string(graph), "<svg") || strings.Contains(string(graph), "<script") {
+		t.Errorf("graph is not embedded as SVG image")
+	}
+
+	// charts are embedded as SVG images as well, hence no page loads scripts from the network
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list exported pages; %v", err)
+	}
+	for _, file := range files {
+		page, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			t.Fatalf("failed to read page %v; %v", file.Name(), err)
+		}
+		if strings.Contains(string(page), "<script src=\"http") {
+			t.Errorf("page %v loads a remote script", file.Name())
+		}
+		if file.Name() != "index.html" && !strings.Contains(string(page), "<svg") {
+			t.Errorf("page %v does not embed an SVG image", file.Name())
+		}
+	}
+}
+
+// TestExportStatic_WritesSingleReport checks that all views are embedded into a single report.
+func TestExportStatic_WritesSingleReport(t *testing.T) {
+	events := newTestEvents()
+	file := filepath.Join(t.TempDir(), "report.html")
+	if err := ExportStatic(events, events, file); err != nil {
+		t.Fatalf("failed to export views; %v", err)
+	}
+	report, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("failed to read report; %v", err)
+	}
+	for _, p := range pages {
+		if !strings.Contains(string(report), "id=\""+p.ref+"\"") {
+			t.Errorf("view %v is not embedded", p.title)
+		}
+	}
+	if got := strings.Count(string(report), "<iframe srcdoc="); got != len(pages) {
+		t.Errorf("unexpected number of embedded views: %v", got)
+	}
+	if strings.Contains(string(report), "<script src=\"http") || strings.Contains(string(report), "&lt;script src=&#34;http") {
+		t.Errorf("report loads a remote script")
+	}
+}
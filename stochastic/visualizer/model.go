@@ -40,6 +40,7 @@ type EventData struct {
 	OperationLabel      []string                                      // operation labels for stochastic matrix
 	StochasticMatrix    [][]float64                                   // stochastic Matrix
 	SimplifiedMatrix    [stochastic.NumOps][stochastic.NumOps]float64 // simplified stochastic matrix
+	Prediction          []stochastic.PredictionScore                  // prediction scores of Markov chains for a held-out recording
 }
 
 // AccessData contains the statistical data for access statistics that is used for visualization.
@@ -180,6 +181,24 @@ func (e *EventData) PopulateEventData(d *stochastic.EventRegistryJSON) {
 	}
 }
 
+// PopulatePrediction evaluates the Markov chains of order 1 up to the recorded
+// order on a held-out recording.
+func (e *EventData) PopulatePrediction(d *stochastic.EventRegistryJSON, heldOut *stochastic.EventRegistryJSON) error {
+	chain, err := stochastic.NewMarkovChain(d.Operations, d.StochasticMatrix, d.Order, stochastic.EstimateContexts(d.Contexts))
+	if err != nil {
+		return err
+	}
+	e.Prediction = []stochastic.PredictionScore{}
+	for order := 1; order <= chain.Order(); order++ {
+		score, err := chain.WithOrder(order).Evaluate(heldOut)
+		if err != nil {
+			return err
+		}
+		e.Prediction = append(e.Prediction, score)
+	}
+	return nil
+}
+
 // PopulateAccess populates access stats model
 func (a *AccessData) PopulateAccess(d *statistics.AccessJSON) {
 	a.ECdf = make([][2]float64, len(d.Counting.ECdf))
@@ -0,0 +1,221 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package visualizer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// chartKind selects how the series of a static chart are drawn.
+type chartKind int
+
+const (
+	lineChart chartKind = iota
+	scatterChart
+	barChart
+)
+
+// chartSeries is a named data series of a static chart. The points of bar charts
+// are pairs of category index and value.
+type chartSeries struct {
+	name   string
+	points [][2]float64
+}
+
+// staticChart is a chart rendered as SVG image so that static pages do not depend
+// on scripts loaded from the network.
+type staticChart struct {
+	title      string
+	subtitle   string
+	kind       chartKind
+	categories []string // category labels of bar charts
+	horizontal bool     // true if the bars of a bar chart are horizontal
+	series     []chartSeries
+}
+
+// colors of the chart elements matching the chalk theme of the interactive charts
+const (
+	chartBackground = "#293441"
+	chartForeground = "#eeeeee"
+	chartGrid       = "#4a5568"
+)
+
+// chartColors are the colors of consecutive series.
+var chartColors = []string{"#fc97af", "#87f7cf", "#f7f494", "#72ccff", "#f7c5a0", "#d4a4eb"}
+
+// chartTicks is the number of intervals of a value axis.
+const chartTicks = 5
+
+// preChartHtml is the preamble for an HTML page embedding charts as SVG images.
+const preChartHtml = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>TITLE</title>
+</head>
+
+<body style="background-color: ` + chartBackground + `;">
+`
+
+// postChartHtml is the postamble for an HTML page embedding charts as SVG images.
+const postChartHtml = `
+</body>
+</html>
+`
+
+// renderStaticCharts renders the charts as a self-contained HTML document.
+func renderStaticCharts(w io.Writer, charts []*staticChart) error {
+	title := "Aida: Stochastic Estimator"
+	if len(charts) > 0 {
+		title = charts[0].title
+	}
+	var buf bytes.Buffer
+	buf.WriteString(strings.Replace(preChartHtml, "TITLE", html.EscapeString(title), -1))
+	for _, c := range charts {
+		c.render(&buf)
+	}
+	buf.WriteString(postChartHtml)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// formatTick formats a value of an axis.
+func formatTick(v float64) string {
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// render writes the chart as SVG image.
+func (c *staticChart) render(buf *bytes.Buffer) {
+	width, height := 1000.0, 500.0
+	left, right, top, bottom := 80.0, 30.0, 80.0, 50.0
+	if c.kind == barChart {
+		if c.horizontal {
+			left = 240
+			height = top + bottom + 20*float64(len(c.categories))
+		} else {
+			bottom = 180
+		}
+	}
+	plotWidth, plotHeight := width-left-right, height-top-bottom
+
+	// ranges of the axes; values start at zero
+	xMin, xMax, yMax := math.Inf(1), math.Inf(-1), 0.0
+	for _, s := range c.series {
+		for _, p := range s.points {
+			xMin, xMax, yMax = math.Min(xMin, p[0]), math.Max(xMax, p[0]), math.Max(yMax, p[1])
+		}
+	}
+	if c.kind == barChart || xMin > xMax {
+		xMin, xMax = 0, 1
+	}
+	if xMax == xMin {
+		xMax = xMin + 1
+	}
+	if yMax <= 0 {
+		yMax = 1
+	}
+	px := func(x float64) float64 { return left + (x-xMin)/(xMax-xMin)*plotWidth }
+	py := func(y float64) float64 { return top + plotHeight - y/yMax*plotHeight }
+
+	fmt.Fprintf(buf, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height)
+	fmt.Fprintf(buf, "<rect width=\"100%%\" height=\"100%%\" fill=\"%v\"/>\n", chartBackground)
+	fmt.Fprintf(buf, "<text x=\"%v\" y=\"24\" font-size=\"18\" font-weight=\"bold\" fill=\"%v\">%v</text>\n", left, chartForeground, html.EscapeString(c.title))
+	if c.subtitle != "" {
+		fmt.Fprintf(buf, "<text x=\"%v\" y=\"44\" fill=\"%v\">%v</text>\n", left, chartForeground, html.EscapeString(c.subtitle))
+	}
+	for i, s := range c.series {
+		x := left + float64(i)*200
+		fmt.Fprintf(buf, "<rect x=\"%v\" y=\"54\" width=\"20\" height=\"10\" fill=\"%v\"/>\n", x, chartColors[i%len(chartColors)])
+		fmt.Fprintf(buf, "<text x=\"%v\" y=\"64\" fill=\"%v\">%v</text>\n", x+26, chartForeground, html.EscapeString(s.name))
+	}
+
+	// value axis and grid
+	for i := 0; i <= chartTicks; i++ {
+		v := yMax * float64(i) / chartTicks
+		if c.kind == barChart && c.horizontal {
+			x := left + float64(i)/chartTicks*plotWidth
+			fmt.Fprintf(buf, "<line x1=\"%.1f\" y1=\"%v\" x2=\"%.1f\" y2=\"%v\" stroke=\"%v\"/>\n", x, top, x, top+plotHeight, chartGrid)
+			fmt.Fprintf(buf, "<text x=\"%.1f\" y=\"%v\" text-anchor=\"middle\" fill=\"%v\">%v</text>\n", x, top+plotHeight+16, chartForeground, formatTick(v))
+			continue
+		}
+		y := py(v)
+		fmt.Fprintf(buf, "<line x1=\"%v\" y1=\"%.1f\" x2=\"%v\" y2=\"%.1f\" stroke=\"%v\"/>\n", left, y, left+plotWidth, y, chartGrid)
+		fmt.Fprintf(buf, "<text x=\"%v\" y=\"%.1f\" text-anchor=\"end\" fill=\"%v\">%v</text>\n", left-6, y+4, chartForeground, formatTick(v))
+	}
+
+	switch c.kind {
+	case lineChart, scatterChart:
+		for i := 0; i <= chartTicks; i++ {
+			v := xMin + (xMax-xMin)*float64(i)/chartTicks
+			fmt.Fprintf(buf, "<text x=\"%.1f\" y=\"%v\" text-anchor=\"middle\" fill=\"%v\">%v</text>\n", px(v), top+plotHeight+16, chartForeground, formatTick(v))
+		}
+		for i, s := range c.series {
+			color := chartColors[i%len(chartColors)]
+			if c.kind == scatterChart {
+				for _, p := range s.points {
+					fmt.Fprintf(buf, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"2.5\" fill=\"%v\"/>\n", px(p[0]), py(p[1]), color)
+				}
+				continue
+			}
+			points := make([]string, len(s.points))
+			for j, p := range s.points {
+				points[j] = fmt.Sprintf("%.1f,%.1f", px(p[0]), py(p[1]))
+			}
+			fmt.Fprintf(buf, "<polyline points=\"%v\" fill=\"none\" stroke=\"%v\" stroke-width=\"2\"/>\n", strings.Join(points, " "), color)
+		}
+
+	case barChart:
+		n := math.Max(float64(len(c.categories)), 1)
+		numSeries := math.Max(float64(len(c.series)), 1)
+		for i, label := range c.categories {
+			if c.horizontal {
+				band := plotHeight / n
+				fmt.Fprintf(buf, "<text x=\"%v\" y=\"%.1f\" text-anchor=\"end\" fill=\"%v\">%v</text>\n", left-6, top+(float64(i)+0.5)*band+4, chartForeground, html.EscapeString(label))
+				continue
+			}
+			band := plotWidth / n
+			x, y := left+(float64(i)+0.5)*band, top+plotHeight+12
+			fmt.Fprintf(buf, "<text x=\"%.1f\" y=\"%v\" text-anchor=\"end\" transform=\"rotate(-60 %.1f %v)\" fill=\"%v\">%v</text>\n", x, y, x, y, chartForeground, html.EscapeString(label))
+		}
+		for i, s := range c.series {
+			color := chartColors[i%len(chartColors)]
+			for _, p := range s.points {
+				if c.horizontal {
+					band := plotHeight / n
+					thickness := band * 0.8 / numSeries
+					y := top + p[0]*band + band*0.1 + float64(i)*thickness
+					fmt.Fprintf(buf, "<rect x=\"%v\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%v\"/>\n", left, y, p[1]/yMax*plotWidth, thickness, color)
+					continue
+				}
+				band := plotWidth / n
+				thickness := band * 0.8 / numSeries
+				x := left + p[0]*band + band*0.1 + float64(i)*thickness
+				fmt.Fprintf(buf, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%v\"/>\n", x, py(p[1]), thickness, p[1]/yMax*plotHeight, color)
+			}
+		}
+	}
+	fmt.Fprintf(buf, "<line x1=\"%v\" y1=\"%v\" x2=\"%v\" y2=\"%v\" stroke=\"%v\"/>\n", left, top+plotHeight, left+plotWidth, top+plotHeight, chartForeground)
+	fmt.Fprintf(buf, "<line x1=\"%v\" y1=\"%v\" x2=\"%v\" y2=\"%v\" stroke=\"%v\"/>\n", left, top, left, top+plotHeight, chartForeground)
+	buf.WriteString("</svg>\n")
+}
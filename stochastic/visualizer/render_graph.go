@@ -18,6 +18,8 @@ package visualizer
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/goccy/go-graphviz"
@@ -65,3 +67,55 @@ func renderDotGraph(title string, g *graphviz.Graphviz, graph *cgraph.Graph) (st
 	}
 	return preamble + buf.String() + postamble, nil
 }
+
+// preSvgHtml is the preamble for an HTML page embedding an SVG image of a dot graph.
+const preSvgHtml = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>TITLE</title>
+</head>
+
+<body>
+    <h1>TITLE</h1>
+    <div id="graph">
+`
+
+// postSvgHtml is the postamble for an HTML page embedding an SVG image of a dot graph.
+const postSvgHtml = `
+    </div>
+</body>
+</html>
+`
+
+// renderSvgGraph renders a dotgraph as a self-contained HTML document whose
+// graph layout is computed by graphviz instead of a script.
+func renderSvgGraph(title string, g *graphviz.Graphviz, graph *cgraph.Graph) (string, error) {
+	preamble := strings.Replace(preSvgHtml, "TITLE", title, -1)
+	postamble := strings.Replace(postSvgHtml, "TITLE", title, -1)
+	var buf bytes.Buffer
+	if err := g.Render(graph, graphviz.SVG, &buf); err != nil {
+		return "", err
+	}
+	// drop XML declaration and doctype of the SVG document
+	svg := buf.String()
+	if i := strings.Index(svg, "<svg"); i > 0 {
+		svg = svg[i:]
+	}
+	return preamble + svg + postamble, nil
+}
+
+// renderGraph writes a dotgraph as HTML document; static documents embed the graph as SVG image.
+func renderGraph(w io.Writer, title string, g *graphviz.Graphviz, graph *cgraph.Graph, static bool) error {
+	render := renderDotGraph
+	if static {
+		render = renderSvgGraph
+	}
+	txt, err := render(title, g, graph)
+	if err != nil {
+		return fmt.Errorf("cannot render %v; %v", title, err)
+	}
+	_, err = fmt.Fprint(w, txt)
+	return err
+}
@@ -18,6 +18,7 @@ package visualizer
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/Fantom-foundation/Aida/stochastic"
@@ -37,35 +38,69 @@ const operationRef = "operation-stats"
 const txoperationRef = "tx-operation-stats"
 const simplifiedMarkovRef = "simplified-markov-stats"
 const markovRef = "markov-stats"
+const predictionRef = "prediction-stats"
 
-// MainHtml is the index page.
-const MainHtml = `
+// page is a view of the visualizer.
+type page struct {
+	ref    string                               // HTML reference of the page
+	title  string                               // title of the page in the main menu
+	render func(w io.Writer, static bool) error // renders the page; static pages render graphs without scripts
+}
+
+// pages lists the views of the visualizer in the order of the main menu.
+var pages = []page{
+	{countingRef, "Counting Statistics", chartPage(renderCounting, countingCharts)},
+	{queuingRef, "Queuing Statistics", chartPage(renderQueuing, queuingCharts)},
+	{snapshotRef, "Snapshot Statistics", chartPage(renderSnapshotStats, snapshotCharts)},
+	{txoperationRef, "Transactional Operation Statistics", chartPage(renderTransactionalOperationStats, transactionalOperationCharts)},
+	{operationRef, "Operation Statistics", chartPage(renderOperationStats, operationCharts)},
+	{simplifiedMarkovRef, "Simplified Markov Chain", renderSimplifiedMarkovChain},
+	{markovRef, "Markov Chain", renderMarkovChain},
+	{predictionRef, "Prediction of Held-Out Recording", chartPage(renderPrediction, predictionCharts)},
+}
+
+// chartPage adapts a chart renderer to a page renderer. Interactive pages are rendered
+// by echarts, which is loaded from the network; static pages embed the charts as SVG images.
+func chartPage(render func(w io.Writer) error, charts func() []*staticChart) func(io.Writer, bool) error {
+	return func(w io.Writer, static bool) error {
+		if static {
+			return renderStaticCharts(w, charts())
+		}
+		return render(w)
+	}
+}
+
+// preMainHtml is the preamble of the main menu.
+const preMainHtml = `
 <!DOCTYPE html>
 <html lang="en">
   <head>
     <meta charset="utf-8">
     <title>Aida: Stochastic Estimator</title>
-    <link rel="stylesheet" href="style.css">
-    <script src="script.js"></script>
   </head>
   <body>
     <h1>Aida: Stochastic Estimator</h1>
     <ul>
-    <li> <h3> <a href="/` + countingRef + `"> Counting Statistics </a> </h3> </li>
-    <li> <h3> <a href="/` + queuingRef + `"> Queuing Statistics </a> </h3> </li>
-    <li> <h3> <a href="/` + snapshotRef + `"> Snapshot Statistics </a> </h3> </li>
-    <li> <h3> <a href="/` + txoperationRef + `"> Transactional Operation Statistics  </a> </h3> </li>
-    <li> <h3> <a href="/` + operationRef + `"> Operation Statistics  </a> </h3> </li>
-    <li> <h3> <a href="/` + simplifiedMarkovRef + `"> Simplified Markov Chain </a> </h3> </li>
-    <li> <h3> <a href="/` + markovRef + `"> Markov Chain </a> </h3> </li>
-    </ul>
+`
+
+// postMainHtml is the postamble of the main menu.
+const postMainHtml = `    </ul>
 </body>
 </html>
 `
 
-// renderMain renders the main menu.
-func renderMain(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, MainHtml)
+// renderMain renders the main menu with links to the given pages.
+func renderMain(w io.Writer, menu []page, link func(ref string) string) error {
+	if _, err := fmt.Fprint(w, preMainHtml); err != nil {
+		return err
+	}
+	for _, p := range menu {
+		if _, err := fmt.Fprintf(w, "    <li> <h3> <a href=\"%v\"> %v </a> </h3> </li>\n", link(p.ref), p.title); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprint(w, postMainHtml)
+	return err
 }
 
 // convertCountingData converts CDF points to chart points.
@@ -106,7 +141,7 @@ func newCountingChart(title string, subtitle string, lambda float64, ecdf [][2]f
 }
 
 // renderCounting renders counting statistics.
-func renderCounting(w http.ResponseWriter, r *http.Request) {
+func renderCounting(w io.Writer) error {
 	events := GetEventsData()
 	contracts := newCountingChart("Counting Statistics", "for Contract-Addresses",
 		events.Contracts.Lambda,
@@ -124,11 +159,27 @@ func renderCounting(w http.ResponseWriter, r *http.Request) {
 	// TODO: Set HTML title via GlobalOption
 	page := components.NewPage()
 	page.AddCharts(contracts, keys, values)
-	page.Render(w)
+	return page.Render(w)
+}
+
+// countingCharts returns the static charts of the counting statistics.
+func countingCharts() []*staticChart {
+	events := GetEventsData()
+	newChart := func(subtitle string, data *AccessData) *staticChart {
+		return &staticChart{title: "Counting Statistics", subtitle: subtitle, kind: lineChart, series: []chartSeries{
+			{"eCDF", data.ECdf},
+			{fmt.Sprintf("CDF, λ=%v", data.Lambda), data.Cdf},
+		}}
+	}
+	return []*staticChart{
+		newChart("for Contract-Addresses", &events.Contracts),
+		newChart("for Storage-Keys", &events.Keys),
+		newChart("for Storage-Values", &events.Values),
+	}
 }
 
 // renderSnapshotStast renders a line chart for a snapshot statistics
-func renderSnapshotStats(w http.ResponseWriter, r *http.Request) {
+func renderSnapshotStats(w io.Writer) error {
 	chart := charts.NewLine()
 	chart.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
 		Theme: types.ThemeChalk,
@@ -153,7 +204,16 @@ func renderSnapshotStats(w http.ResponseWriter, r *http.Request) {
 	events := GetEventsData()
 	sLambda := fmt.Sprintf("%v", events.Snapshot.Lambda)
 	chart.AddSeries("eCDF", convertCountingData(events.Snapshot.ECdf)).AddSeries("CDF, λ="+sLambda, convertCountingData(events.Snapshot.Cdf))
-	chart.Render(w)
+	return chart.Render(w)
+}
+
+// snapshotCharts returns the static chart of the snapshot statistics.
+func snapshotCharts() []*staticChart {
+	events := GetEventsData()
+	return []*staticChart{{title: "Snapshot Statistics", subtitle: "Delta Distribution", kind: lineChart, series: []chartSeries{
+		{"eCDF", events.Snapshot.ECdf},
+		{fmt.Sprintf("CDF, λ=%v", events.Snapshot.Lambda), events.Snapshot.Cdf},
+	}}}
 }
 
 // convertQueuingData rendering plot data for the queuing statistics.
@@ -166,7 +226,7 @@ func convertQueuingData(data []float64) []opts.ScatterData {
 }
 
 // renderQueuing renders a queuing statistics.
-func renderQueuing(w http.ResponseWriter, r *http.Request) {
+func renderQueuing(w io.Writer) error {
 	events := GetEventsData()
 	scatter := charts.NewScatter()
 	scatter.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
@@ -191,7 +251,26 @@ func renderQueuing(w http.ResponseWriter, r *http.Request) {
 			Subtitle: "for contract-addresses, storage-keys, and storage-values",
 		}))
 	scatter.AddSeries("Contract", convertQueuingData(events.Contracts.QPdf)).AddSeries("Keys", convertQueuingData(events.Keys.QPdf)).AddSeries("Values", convertQueuingData(events.Values.QPdf))
-	scatter.Render(w)
+	return scatter.Render(w)
+}
+
+// queuingPoints converts queuing probabilities to chart points.
+func queuingPoints(data []float64) [][2]float64 {
+	points := make([][2]float64, len(data))
+	for x, p := range data {
+		points[x] = [2]float64{float64(x), p}
+	}
+	return points
+}
+
+// queuingCharts returns the static chart of the queuing statistics.
+func queuingCharts() []*staticChart {
+	events := GetEventsData()
+	return []*staticChart{{title: "Queuing Probabilities", subtitle: "for contract-addresses, storage-keys, and storage-values", kind: scatterChart, series: []chartSeries{
+		{"Contract", queuingPoints(events.Contracts.QPdf)},
+		{"Keys", queuingPoints(events.Keys.QPdf)},
+		{"Values", queuingPoints(events.Values.QPdf)},
+	}}}
 }
 
 // convertOperationData produces the data series for the sationary distribution.
@@ -213,7 +292,7 @@ func convertOperationLabel(data []OpData) []string {
 }
 
 // renderOperationStats renders the stationary distribution.
-func renderOperationStats(w http.ResponseWriter, r *http.Request) {
+func renderOperationStats(w io.Writer) error {
 	events := GetEventsData()
 	bar := charts.NewBar()
 	bar.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
@@ -239,11 +318,29 @@ func renderOperationStats(w http.ResponseWriter, r *http.Request) {
 		}))
 	bar.SetXAxis(convertOperationLabel(events.Stationary)).AddSeries("Stationary Distribution", convertOperationData(events.Stationary))
 	bar.XYReversal()
-	bar.Render(w)
+	return bar.Render(w)
+}
+
+// operationPoints converts operation data to points of a bar chart.
+func operationPoints(data []OpData) [][2]float64 {
+	points := make([][2]float64, len(data))
+	for i, d := range data {
+		points[i] = [2]float64{float64(i), d.value}
+	}
+	return points
+}
+
+// operationCharts returns the static chart of the stationary distribution.
+func operationCharts() []*staticChart {
+	events := GetEventsData()
+	return []*staticChart{{title: "StateDB Operations", kind: barChart, horizontal: true,
+		categories: convertOperationLabel(events.Stationary),
+		series:     []chartSeries{{"Stationary Distribution", operationPoints(events.Stationary)}},
+	}}
 }
 
 // renderTransactionalOperationStats renders the average number of operations per transaction.
-func renderTransactionalOperationStats(w http.ResponseWriter, r *http.Request) {
+func renderTransactionalOperationStats(w io.Writer) error {
 	events := GetEventsData()
 	title := fmt.Sprintf("Average %.1f Tx/Bl; %.1f Bl/Ep", events.TxPerBlock, events.BlocksPerSyncPeriod)
 	bar := charts.NewBar()
@@ -269,11 +366,20 @@ func renderTransactionalOperationStats(w http.ResponseWriter, r *http.Request) {
 			Title: title,
 		}))
 	bar.SetXAxis(convertOperationLabel(events.TxOperation)).AddSeries("Ops/Tx", convertOperationData(events.TxOperation))
-	bar.Render(w)
+	return bar.Render(w)
+}
+
+// transactionalOperationCharts returns the static chart of the average number of operations per transaction.
+func transactionalOperationCharts() []*staticChart {
+	events := GetEventsData()
+	return []*staticChart{{title: fmt.Sprintf("Average %.1f Tx/Bl; %.1f Bl/Ep", events.TxPerBlock, events.BlocksPerSyncPeriod), kind: barChart,
+		categories: convertOperationLabel(events.TxOperation),
+		series:     []chartSeries{{"Ops/Tx", operationPoints(events.TxOperation)}},
+	}}
 }
 
 // renderSimplifiedMarkovChain renders a reduced markov chain whose nodes have no argument classes.
-func renderSimplifiedMarkovChain(w http.ResponseWriter, r *http.Request) {
+func renderSimplifiedMarkovChain(w io.Writer, static bool) error {
 	events := GetEventsData()
 	g := graphviz.New()
 	graph, _ := g.Graph()
@@ -308,12 +414,11 @@ func renderSimplifiedMarkovChain(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	txt, _ := renderDotGraph("StateDB Simplified Markov-Chain", g, graph)
-	fmt.Fprint(w, txt)
+	return renderGraph(w, "StateDB Simplified Markov-Chain", g, graph, static)
 }
 
 // renderMarkovChain renders a markov chain.
-func renderMarkovChain(w http.ResponseWriter, r *http.Request) {
+func renderMarkovChain(w io.Writer, static bool) error {
 	events := GetEventsData()
 	g := graphviz.New()
 	graph, _ := g.Graph()
@@ -349,26 +454,100 @@ func renderMarkovChain(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
-	txt, _ := renderDotGraph("StateDB Markov-Chain", g, graph)
-	fmt.Fprint(w, txt)
+	return renderGraph(w, "StateDB Markov-Chain", g, graph, static)
 }
 
-// FireUpWeb produces a data model for the recorded events and
-// visualizes with a local web-server.
-func FireUpWeb(eventRegistry *stochastic.EventRegistryJSON, addr string) {
+// renderPrediction renders the perplexity of Markov chains of increasing order for a held-out recording.
+func renderPrediction(w io.Writer) error {
+	events := GetEventsData()
+	if events.Prediction == nil {
+		_, err := fmt.Fprint(w, "No held-out recording was provided.")
+		return err
+	}
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(charts.WithInitializationOpts(opts.Initialization{
+		Theme:     types.ThemeChalk,
+		PageTitle: "Prediction of Held-Out Recording",
+	}),
+		charts.WithToolboxOpts(opts.Toolbox{
+			Show: true,
+			Feature: &opts.ToolBoxFeature{
+				SaveAsImage: &opts.ToolBoxFeatureSaveAsImage{
+					Show:  true,
+					Title: "Save",
+				},
+			},
+		}),
+		charts.WithLegendOpts(opts.Legend{Show: true}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Prediction of Held-Out Recording",
+			Subtitle: "lower perplexity is better",
+		}))
+	labels := []string{}
+	perplexity := []opts.BarData{}
+	unseen := []opts.BarData{}
+	for _, score := range events.Prediction {
+		labels = append(labels, fmt.Sprintf("order %v", score.Order))
+		perplexity = append(perplexity, opts.BarData{Value: score.Perplexity})
+		unseen = append(unseen, opts.BarData{Value: float64(score.Unseen) / float64(score.Transitions)})
+	}
+	bar.SetXAxis(labels).
+		AddSeries("Perplexity", perplexity).
+		AddSeries("Unseen Transitions", unseen)
+	return bar.Render(w)
+}
 
-	// create data model (as a singleton) for visualization
+// predictionCharts returns the static chart of the prediction scores.
+func predictionCharts() []*staticChart {
+	events := GetEventsData()
+	chart := &staticChart{title: "Prediction of Held-Out Recording", subtitle: "lower perplexity is better", kind: barChart}
+	var perplexity, unseen [][2]float64
+	for i, score := range events.Prediction {
+		chart.categories = append(chart.categories, fmt.Sprintf("order %v", score.Order))
+		perplexity = append(perplexity, [2]float64{float64(i), score.Perplexity})
+		unseen = append(unseen, [2]float64{float64(i), float64(score.Unseen) / float64(score.Transitions)})
+	}
+	chart.series = []chartSeries{{"Perplexity", perplexity}, {"Unseen Transitions", unseen}}
+	return []*staticChart{chart}
+}
+
+// populate produces the data model (as a singleton) for the recorded events. If a
+// held-out recording is provided, the Markov chains are evaluated on it.
+func populate(eventRegistry *stochastic.EventRegistryJSON, heldOut *stochastic.EventRegistryJSON) error {
 	eventModel := GetEventsData()
 	eventModel.PopulateEventData(eventRegistry)
+	eventModel.Prediction = nil
+	if heldOut != nil {
+		if err := eventModel.PopulatePrediction(eventRegistry, heldOut); err != nil {
+			return fmt.Errorf("cannot evaluate held-out recording; %v", err)
+		}
+	}
+	return nil
+}
+
+// handler serves a page.
+func handler(render func(io.Writer, bool) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := render(w, false); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// FireUpWeb produces a data model for the recorded events and
+// visualizes with a local web-server. If a held-out recording is
+// provided, the Markov chains are evaluated on it.
+func FireUpWeb(eventRegistry *stochastic.EventRegistryJSON, heldOut *stochastic.EventRegistryJSON, addr string) error {
+	if err := populate(eventRegistry, heldOut); err != nil {
+		return err
+	}
 
 	// create web server
-	http.HandleFunc("/", renderMain)
-	http.HandleFunc("/"+countingRef, renderCounting)
-	http.HandleFunc("/"+queuingRef, renderQueuing)
-	http.HandleFunc("/"+snapshotRef, renderSnapshotStats)
-	http.HandleFunc("/"+operationRef, renderOperationStats)
-	http.HandleFunc("/"+txoperationRef, renderTransactionalOperationStats)
-	http.HandleFunc("/"+simplifiedMarkovRef, renderSimplifiedMarkovChain)
-	http.HandleFunc("/"+markovRef, renderMarkovChain)
-	http.ListenAndServe(":"+addr, nil)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		renderMain(w, pages, func(ref string) string { return "/" + ref })
+	})
+	for _, p := range pages {
+		http.HandleFunc("/"+p.ref, handler(p.render))
+	}
+	return http.ListenAndServe(":"+addr, nil)
 }
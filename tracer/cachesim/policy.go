@@ -0,0 +1,284 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package cachesim
+
+import (
+	"container/heap"
+	"container/list"
+	"fmt"
+)
+
+// Cache simulates the replacement policy of a cache with a fixed number of entries.
+type Cache[K comparable] interface {
+	// Access looks up a key, inserts it on a miss, and returns true on a hit.
+	Access(key K) bool
+}
+
+// Policies lists the names of all supported replacement policies.
+var Policies = []string{"lru", "lfu", "arc"}
+
+// NewCache creates a cache of the given size using the named replacement policy.
+func NewCache[K comparable](policy string, size int) (Cache[K], error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid cache size %v", size)
+	}
+	switch policy {
+	case "lru":
+		return NewLRU[K](size), nil
+	case "lfu":
+		return NewLFU[K](size), nil
+	case "arc":
+		return NewARC[K](size), nil
+	}
+	return nil, fmt.Errorf("unknown cache policy %v", policy)
+}
+
+////////////////////////////////////////////////////////////////
+// Recency list
+////////////////////////////////////////////////////////////////
+
+// recencyList is a set of keys ordered from most to least recently used.
+type recencyList[K comparable] struct {
+	order *list.List
+	index map[K]*list.Element
+}
+
+func newRecencyList[K comparable]() *recencyList[K] {
+	return &recencyList[K]{order: list.New(), index: make(map[K]*list.Element)}
+}
+
+// Len returns the number of keys in the list.
+func (l *recencyList[K]) Len() int {
+	return len(l.index)
+}
+
+// Contains returns true if the key is in the list.
+func (l *recencyList[K]) Contains(key K) bool {
+	_, ok := l.index[key]
+	return ok
+}
+
+// PushFront adds a key as most recently used key.
+func (l *recencyList[K]) PushFront(key K) {
+	l.index[key] = l.order.PushFront(key)
+}
+
+// MoveToFront marks a key in the list as most recently used.
+func (l *recencyList[K]) MoveToFront(key K) {
+	l.order.MoveToFront(l.index[key])
+}
+
+// Remove deletes a key from the list and returns true if it was present.
+func (l *recencyList[K]) Remove(key K) bool {
+	elem, ok := l.index[key]
+	if ok {
+		l.order.Remove(elem)
+		delete(l.index, key)
+	}
+	return ok
+}
+
+// PopBack removes and returns the least recently used key.
+func (l *recencyList[K]) PopBack() K {
+	elem := l.order.Back()
+	key := elem.Value.(K)
+	l.order.Remove(elem)
+	delete(l.index, key)
+	return key
+}
+
+////////////////////////////////////////////////////////////////
+// LRU
+////////////////////////////////////////////////////////////////
+
+// LRU evicts the least recently used key.
+type LRU[K comparable] struct {
+	size    int
+	entries *recencyList[K]
+}
+
+// NewLRU creates a new LRU cache.
+func NewLRU[K comparable](size int) *LRU[K] {
+	return &LRU[K]{size: size, entries: newRecencyList[K]()}
+}
+
+// Access looks up a key and returns true on a hit.
+func (c *LRU[K]) Access(key K) bool {
+	if c.entries.Contains(key) {
+		c.entries.MoveToFront(key)
+		return true
+	}
+	if c.entries.Len() >= c.size {
+		c.entries.PopBack()
+	}
+	c.entries.PushFront(key)
+	return false
+}
+
+////////////////////////////////////////////////////////////////
+// LFU
+////////////////////////////////////////////////////////////////
+
+// lfuEntry is a cached key with its access frequency.
+type lfuEntry[K comparable] struct {
+	key   K
+	freq  uint64
+	tick  uint64 // time of last access, breaks ties in favour of recent keys
+	index int    // position in heap
+}
+
+// lfuHeap orders entries by frequency and time of last access.
+type lfuHeap[K comparable] []*lfuEntry[K]
+
+func (h lfuHeap[K]) Len() int { return len(h) }
+func (h lfuHeap[K]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+func (h lfuHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+func (h *lfuHeap[K]) Push(x any) {
+	e := x.(*lfuEntry[K])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+func (h *lfuHeap[K]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// LFU evicts the least frequently used key; ties are broken by recency.
+type LFU[K comparable] struct {
+	size    int
+	tick    uint64
+	heap    lfuHeap[K]
+	entries map[K]*lfuEntry[K]
+}
+
+// NewLFU creates a new LFU cache.
+func NewLFU[K comparable](size int) *LFU[K] {
+	return &LFU[K]{size: size, entries: make(map[K]*lfuEntry[K])}
+}
+
+// Access looks up a key and returns true on a hit.
+func (c *LFU[K]) Access(key K) bool {
+	c.tick++
+	if e, ok := c.entries[key]; ok {
+		e.freq++
+		e.tick = c.tick
+		heap.Fix(&c.heap, e.index)
+		return true
+	}
+	if len(c.entries) >= c.size {
+		e := heap.Pop(&c.heap).(*lfuEntry[K])
+		delete(c.entries, e.key)
+	}
+	e := &lfuEntry[K]{key: key, freq: 1, tick: c.tick}
+	heap.Push(&c.heap, e)
+	c.entries[key] = e
+	return false
+}
+
+////////////////////////////////////////////////////////////////
+// ARC
+////////////////////////////////////////////////////////////////
+
+// ARC is the adaptive replacement cache of Megiddo and Modha. It balances
+// between recency (t1) and frequency (t2) using the ghost lists b1 and b2 of
+// recently evicted keys.
+type ARC[K comparable] struct {
+	size           int
+	p              int // target size of t1
+	t1, t2, b1, b2 *recencyList[K]
+}
+
+// NewARC creates a new ARC cache.
+func NewARC[K comparable](size int) *ARC[K] {
+	return &ARC[K]{
+		size: size,
+		t1:   newRecencyList[K](),
+		t2:   newRecencyList[K](),
+		b1:   newRecencyList[K](),
+		b2:   newRecencyList[K](),
+	}
+}
+
+// Access looks up a key and returns true on a hit.
+func (c *ARC[K]) Access(key K) bool {
+	// cache hit
+	if c.t1.Remove(key) {
+		c.t2.PushFront(key)
+		return true
+	}
+	if c.t2.Contains(key) {
+		c.t2.MoveToFront(key)
+		return true
+	}
+
+	// ghost hit in b1: favour recency
+	if c.b1.Contains(key) {
+		c.p = min(c.size, c.p+max(c.b2.Len()/c.b1.Len(), 1))
+		c.replace(false)
+		c.b1.Remove(key)
+		c.t2.PushFront(key)
+		return false
+	}
+
+	// ghost hit in b2: favour frequency
+	if c.b2.Contains(key) {
+		c.p = max(0, c.p-max(c.b1.Len()/c.b2.Len(), 1))
+		c.replace(true)
+		c.b2.Remove(key)
+		c.t2.PushFront(key)
+		return false
+	}
+
+	// complete miss
+	l1 := c.t1.Len() + c.b1.Len()
+	total := l1 + c.t2.Len() + c.b2.Len()
+	if l1 == c.size {
+		if c.t1.Len() < c.size {
+			c.b1.PopBack()
+			c.replace(false)
+		} else {
+			c.t1.PopBack()
+		}
+	} else if total >= c.size {
+		if total == 2*c.size {
+			c.b2.PopBack()
+		}
+		c.replace(false)
+	}
+	c.t1.PushFront(key)
+	return false
+}
+
+// replace evicts a key from t1 or t2 into the respective ghost list.
+func (c *ARC[K]) replace(inB2 bool) {
+	if c.t1.Len() > 0 && (c.t1.Len() > c.p || (inB2 && c.t1.Len() == c.p)) {
+		c.b1.PushFront(c.t1.PopBack())
+	} else if c.t2.Len() > 0 {
+		c.b2.PushFront(c.t2.PopBack())
+	}
+}
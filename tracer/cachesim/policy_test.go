@@ -0,0 +1,84 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package cachesim
+
+import "testing"
+
+// checkAccesses accesses the keys in order and compares the hits with the expected hits.
+func checkAccesses(t *testing.T, c Cache[string], keys []string, hits []bool) {
+	t.Helper()
+	for i, key := range keys {
+		if got, want := c.Access(key), hits[i]; got != want {
+			t.Errorf("unexpected result of access %d (%v); got %v, want %v", i, key, got, want)
+		}
+	}
+}
+
+func TestLRU_EvictsLeastRecentlyUsedKey(t *testing.T) {
+	checkAccesses(t, NewLRU[string](2),
+		[]string{"a", "b", "a", "c", "a", "b", "a", "c"},
+		[]bool{false, false, true, false, true, false, true, false},
+	)
+}
+
+func TestLFU_EvictsLeastFrequentlyUsedKey(t *testing.T) {
+	checkAccesses(t, NewLFU[string](2),
+		[]string{"a", "a", "b", "c", "a", "b", "c"},
+		[]bool{false, true, false, false, true, false, false},
+	)
+}
+
+func TestARC_FrequentKeySurvivesScan(t *testing.T) {
+	keys := []string{"a", "a", "x1", "x2", "x3", "x4", "x5", "a"}
+
+	checkAccesses(t, NewARC[string](2), keys,
+		[]bool{false, true, false, false, false, false, false, true},
+	)
+
+	// LRU loses the frequent key during the scan
+	checkAccesses(t, NewLRU[string](2), keys,
+		[]bool{false, true, false, false, false, false, false, false},
+	)
+}
+
+func TestARC_SizeIsNotExceeded(t *testing.T) {
+	c := NewARC[int](4)
+	for i := 0; i < 1000; i++ {
+		c.Access(i % 7)
+		c.Access(i % 3)
+		if got := c.t1.Len() + c.t2.Len(); got > 4 {
+			t.Fatalf("cache exceeds its size; got %v entries", got)
+		}
+		if got := c.t1.Len() + c.t2.Len() + c.b1.Len() + c.b2.Len(); got > 8 {
+			t.Fatalf("directory exceeds twice the cache size; got %v entries", got)
+		}
+	}
+}
+
+func TestNewCache_RejectsInvalidParameters(t *testing.T) {
+	if _, err := NewCache[int]("fifo", 10); err == nil {
+		t.Errorf("unknown policy must be rejected")
+	}
+	if _, err := NewCache[int]("lru", 0); err == nil {
+		t.Errorf("empty cache must be rejected")
+	}
+	for _, policy := range Policies {
+		if _, err := NewCache[int](policy, 10); err != nil {
+			t.Errorf("cannot create %v cache; %v", policy, err)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package cachesim
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Stream identifies the access stream of a simulated cache.
+type Stream int
+
+const (
+	AddressStream Stream = iota // accounts accessed by any operation
+	KeyStream                   // storage slots accessed by storage operations
+	CodeStream                  // contract codes accessed by code operations
+	NumStreams
+)
+
+// streamLabels contains the names of the access streams.
+var streamLabels = [NumStreams]string{"address", "key", "code"}
+
+// String returns the name of the stream.
+func (s Stream) String() string {
+	return streamLabels[s]
+}
+
+// Slot identifies a storage slot of an account.
+type Slot struct {
+	Address common.Address
+	Key     common.Hash
+}
+
+// counter counts the accesses and hits of a simulated cache.
+type counter struct {
+	accesses uint64
+	hits     uint64
+}
+
+// HitRate returns the ratio of hits to accesses.
+func (c counter) HitRate() float64 {
+	if c.accesses == 0 {
+		return 0
+	}
+	return float64(c.hits) / float64(c.accesses)
+}
+
+// window contains the counters of a block range; counters are indexed by
+// stream, operation, policy and size.
+type window struct {
+	first, last uint64
+	counters    []counter
+}
+
+// Simulator feeds the accounts, storage slots and codes accessed by the
+// operations of a storage trace into caches of several policies and sizes.
+type Simulator struct {
+	ctx      *context.Replay // decodes contracts and keys of operations
+	policies []string
+	sizes    []int
+	first    uint64 // first block of the simulated range
+	length   uint64 // number of blocks per window; a single window if 0
+	block    uint64 // current block
+
+	addresses [][]Cache[common.Address] // caches indexed by policy and size
+	keys      [][]Cache[Slot]
+	codes     [][]Cache[common.Address]
+
+	windows []*window
+}
+
+// NewSimulator creates a simulator of the given cache policies and sizes. Hit
+// rates are collected for windows of the given number of blocks starting at
+// the first block, or for the whole trace if the length is 0.
+func NewSimulator(policies []string, sizes []int, first, length uint64) (*Simulator, error) {
+	s := &Simulator{
+		ctx:      context.NewReplay(),
+		policies: policies,
+		sizes:    sizes,
+		first:    first,
+		length:   length,
+		block:    first,
+	}
+	for _, policy := range policies {
+		var addresses []Cache[common.Address]
+		var keys []Cache[Slot]
+		var codes []Cache[common.Address]
+		for _, size := range sizes {
+			a, err := NewCache[common.Address](policy, size)
+			if err != nil {
+				return nil, err
+			}
+			k, _ := NewCache[Slot](policy, size)
+			c, _ := NewCache[common.Address](policy, size)
+			addresses = append(addresses, a)
+			keys = append(keys, k)
+			codes = append(codes, c)
+		}
+		s.addresses = append(s.addresses, addresses)
+		s.keys = append(s.keys, keys)
+		s.codes = append(s.codes, codes)
+	}
+	return s, nil
+}
+
+// Process simulates the cache accesses of an operation.
+func (s *Simulator) Process(op operation.Operation) {
+	id := op.GetId()
+	switch op := op.(type) {
+	case *operation.BeginBlock:
+		s.block = op.BlockNumber
+
+	// account operations
+	case *operation.CreateAccount:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.AddBalance:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.SubBalance:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.GetBalance:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.GetNonce:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.SetNonce:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.Exist:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.Empty:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.Suicide:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.HasSuicided:
+		s.accessAddress(id, s.ctx.DecodeContract(op.Contract))
+
+	// code operations
+	case *operation.GetCode:
+		s.accessCode(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.GetCodeHash:
+		s.accessCode(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.GetCodeHashLc:
+		s.accessCode(id, s.ctx.PrevContract())
+	case *operation.GetCodeSize:
+		s.accessCode(id, s.ctx.DecodeContract(op.Contract))
+	case *operation.SetCode:
+		s.accessCode(id, s.ctx.DecodeContract(op.Contract))
+
+	// storage operations
+	case *operation.GetState:
+		s.accessSlot(id, s.ctx.DecodeContract(op.Contract), s.ctx.DecodeKey(op.Key))
+	case *operation.GetStateLc:
+		s.accessSlot(id, s.ctx.PrevContract(), s.ctx.DecodeKey(op.Key))
+	case *operation.GetStateLccs:
+		s.accessSlot(id, s.ctx.PrevContract(), s.ctx.DecodeKeyCache(int(op.StoragePosition)))
+	case *operation.GetStateLcls:
+		s.accessSlot(id, s.ctx.PrevContract(), s.ctx.DecodeKeyCache(0))
+	case *operation.GetCommittedState:
+		s.accessSlot(id, s.ctx.DecodeContract(op.Contract), s.ctx.DecodeKey(op.Key))
+	case *operation.GetCommittedStateLcls:
+		s.accessSlot(id, s.ctx.PrevContract(), s.ctx.DecodeKeyCache(0))
+	case *operation.SetState:
+		s.accessSlot(id, s.ctx.DecodeContract(op.Contract), s.ctx.DecodeKey(op.Key))
+	case *operation.SetStateLcls:
+		s.accessSlot(id, s.ctx.PrevContract(), s.ctx.DecodeKeyCache(0))
+	}
+}
+
+// accessAddress simulates an access of an account.
+func (s *Simulator) accessAddress(id byte, addr common.Address) {
+	w := s.window()
+	for p := range s.policies {
+		for i := range s.sizes {
+			w.count(s, AddressStream, id, p, i, s.addresses[p][i].Access(addr))
+		}
+	}
+}
+
+// accessCode simulates an access of an account and its code.
+func (s *Simulator) accessCode(id byte, addr common.Address) {
+	s.accessAddress(id, addr)
+	w := s.window()
+	for p := range s.policies {
+		for i := range s.sizes {
+			w.count(s, CodeStream, id, p, i, s.codes[p][i].Access(addr))
+		}
+	}
+}
+
+// accessSlot simulates an access of an account and one of its storage slots.
+func (s *Simulator) accessSlot(id byte, addr common.Address, key common.Hash) {
+	s.accessAddress(id, addr)
+	w := s.window()
+	slot := Slot{addr, key}
+	for p := range s.policies {
+		for i := range s.sizes {
+			w.count(s, KeyStream, id, p, i, s.keys[p][i].Access(slot))
+		}
+	}
+}
+
+// window returns the counters of the current block.
+func (s *Simulator) window() *window {
+	first, last := s.first, s.block
+	if s.length > 0 && s.block >= s.first {
+		first = s.first + (s.block-s.first)/s.length*s.length
+		last = first + s.length - 1
+	}
+	if n := len(s.windows); n > 0 && s.windows[n-1].first == first {
+		w := s.windows[n-1]
+		if s.length == 0 {
+			w.last = last
+		}
+		return w
+	}
+	w := &window{
+		first:    first,
+		last:     last,
+		counters: make([]counter, int(NumStreams)*int(operation.NumOperations)*len(s.policies)*len(s.sizes)),
+	}
+	s.windows = append(s.windows, w)
+	return w
+}
+
+// index returns the position of a counter in a window.
+func (s *Simulator) index(stream Stream, id byte, policy, size int) int {
+	return ((int(stream)*int(operation.NumOperations)+int(id))*len(s.policies)+policy)*len(s.sizes) + size
+}
+
+// count records an access in the window.
+func (w *window) count(s *Simulator, stream Stream, id byte, policy, size int, hit bool) {
+	c := &w.counters[s.index(stream, id, policy, size)]
+	c.accesses++
+	if hit {
+		c.hits++
+	}
+}
+
+// total sums the counters of all operations, or of a single operation if id is not negative.
+func (s *Simulator) total(w *window, stream Stream, id int, policy, size int) counter {
+	var res counter
+	for op := 0; op < int(operation.NumOperations); op++ {
+		if id >= 0 && op != id {
+			continue
+		}
+		c := w.counters[s.index(stream, byte(op), policy, size)]
+		res.accesses += c.accesses
+		res.hits += c.hits
+	}
+	return res
+}
+
+// merged returns a window summing the counters of all windows.
+func (s *Simulator) merged() *window {
+	res := &window{counters: make([]counter, int(NumStreams)*int(operation.NumOperations)*len(s.policies)*len(s.sizes))}
+	for i, w := range s.windows {
+		if i == 0 {
+			res.first = w.first
+		}
+		res.last = w.last
+		for j, c := range w.counters {
+			res.counters[j].accesses += c.accesses
+			res.counters[j].hits += c.hits
+		}
+	}
+	return res
+}
+
+// HitRate returns the hit rate of a stream over the whole trace for the given policy and size index.
+func (s *Simulator) HitRate(stream Stream, policy, size int) float64 {
+	return s.total(s.merged(), stream, -1, policy, size).HitRate()
+}
+
+// WriteReport prints hit-rate curves of all streams for the whole trace, for
+// each operation type, and for each window.
+func (s *Simulator) WriteReport(out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	header := "stream\toperation\tpolicy\taccesses"
+	for _, size := range s.sizes {
+		header += fmt.Sprintf("\t%d", size)
+	}
+	fmt.Fprintln(tw, header+"\t")
+
+	all := s.merged()
+	for stream := Stream(0); stream < NumStreams; stream++ {
+		s.writeRows(tw, all, stream, -1, "all")
+		for id := 0; id < int(operation.NumOperations); id++ {
+			s.writeRows(tw, all, stream, id, operation.GetLabel(byte(id)))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if s.length == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	tw = tabwriter.NewWriter(out, 0, 8, 2, ' ', tabwriter.AlignRight)
+	header = "blocks\tstream\tpolicy\taccesses"
+	for _, size := range s.sizes {
+		header += fmt.Sprintf("\t%d", size)
+	}
+	fmt.Fprintln(tw, header+"\t")
+	for _, w := range s.windows {
+		for stream := Stream(0); stream < NumStreams; stream++ {
+			for p, policy := range s.policies {
+				accesses := s.total(w, stream, -1, p, 0).accesses
+				if accesses == 0 {
+					continue
+				}
+				row := fmt.Sprintf("%d-%d\t%v\t%v\t%d", w.first, w.last, stream, policy, accesses)
+				for i := range s.sizes {
+					row += fmt.Sprintf("\t%.2f%%", 100*s.total(w, stream, -1, p, i).HitRate())
+				}
+				fmt.Fprintln(tw, row+"\t")
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// writeRows prints the hit rates of all policies for a stream and operation.
+func (s *Simulator) writeRows(out io.Writer, w *window, stream Stream, id int, label string) {
+	for p, policy := range s.policies {
+		accesses := s.total(w, stream, id, p, 0).accesses
+		if accesses == 0 {
+			return
+		}
+		row := fmt.Sprintf("%v\t%v\t%v\t%d", stream, label, policy, accesses)
+		for i := range s.sizes {
+			row += fmt.Sprintf("\t%.2f%%", 100*s.total(w, stream, id, p, i).HitRate())
+		}
+		fmt.Fprintln(out, row+"\t")
+	}
+}
+
+// WriteCsv writes the counters of all windows, streams, operations, policies and sizes.
+func (s *Simulator) WriteCsv(out io.Writer) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"first", "last", "stream", "operation", "policy", "size", "accesses", "hits", "hit_rate"}); err != nil {
+		return err
+	}
+	for _, win := range s.windows {
+		for stream := Stream(0); stream < NumStreams; stream++ {
+			for id := 0; id < int(operation.NumOperations); id++ {
+				for p, policy := range s.policies {
+					for i, size := range s.sizes {
+						c := win.counters[s.index(stream, byte(id), p, i)]
+						if c.accesses == 0 {
+							continue
+						}
+						record := []string{
+							strconv.FormatUint(win.first, 10),
+							strconv.FormatUint(win.last, 10),
+							stream.String(),
+							operation.GetLabel(byte(id)),
+							policy,
+							strconv.Itoa(size),
+							strconv.FormatUint(c.accesses, 10),
+							strconv.FormatUint(c.hits, 10),
+							strconv.FormatFloat(c.HitRate(), 'f', 6, 64),
+						}
+						if err := w.Write(record); err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package cachesim
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSimulator_LastContractAndKeyCacheAreDecoded(t *testing.T) {
+	s, err := NewSimulator([]string{"lru"}, []int{1, 4}, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot create simulator; %v", err)
+	}
+
+	a := common.Address{1}
+	b := common.Address{2}
+	key := common.Hash{1}
+
+	for _, op := range []operation.Operation{
+		operation.NewBeginBlock(0),
+		operation.NewGetState(a, key),
+		operation.NewGetStateLcls(),   // a, key
+		operation.NewGetBalance(b),    // b
+		operation.NewGetState(a, key), // a, key
+		operation.NewGetCodeHashLc(),  // a
+	} {
+		s.Process(op)
+	}
+
+	// addresses: a, a, b, a, a
+	if got, want := s.HitRate(AddressStream, 0, 0), 2.0/5; got != want {
+		t.Errorf("unexpected address hit rate of size 1; got %v, want %v", got, want)
+	}
+	if got, want := s.HitRate(AddressStream, 0, 1), 3.0/5; got != want {
+		t.Errorf("unexpected address hit rate of size 4; got %v, want %v", got, want)
+	}
+	// slots: (a,key) three times
+	if got, want := s.HitRate(KeyStream, 0, 0), 2.0/3; got != want {
+		t.Errorf("unexpected key hit rate; got %v, want %v", got, want)
+	}
+	// codes: a once
+	if got, want := s.HitRate(CodeStream, 0, 0), 0.0; got != want {
+		t.Errorf("unexpected code hit rate; got %v, want %v", got, want)
+	}
+}
+
+func TestSimulator_ReportContainsOperationsAndWindows(t *testing.T) {
+	s, err := NewSimulator(Policies, []int{2}, 10, 2)
+	if err != nil {
+		t.Fatalf("cannot create simulator; %v", err)
+	}
+
+	addr := common.Address{1}
+	for block := uint64(10); block < 14; block++ {
+		s.Process(operation.NewBeginBlock(block))
+		s.Process(operation.NewSetState(addr, common.Hash{byte(block)}, common.Hash{}))
+		s.Process(operation.NewGetCode(addr))
+	}
+
+	if got, want := len(s.windows), 2; got != want {
+		t.Fatalf("unexpected number of windows; got %v, want %v", got, want)
+	}
+
+	var report bytes.Buffer
+	if err := s.WriteReport(&report); err != nil {
+		t.Fatalf("cannot write report; %v", err)
+	}
+	for _, want := range []string{"SetState", "GetCode", "arc", "10-11", "12-13"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report does not contain %v\n%v", want, report.String())
+		}
+	}
+
+	var csv bytes.Buffer
+	if err := s.WriteCsv(&csv); err != nil {
+		t.Fatalf("cannot write csv; %v", err)
+	}
+	if !strings.Contains(csv.String(), "12,13,key,SetState,lfu,2,2,0,0.000000") {
+		t.Errorf("csv does not contain key accesses of second window\n%v", csv.String())
+	}
+}
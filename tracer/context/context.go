@@ -18,9 +18,11 @@ package context
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"log"
+	"math/big"
 	"os"
 
 	"github.com/Fantom-foundation/Aida/profile"
@@ -36,6 +38,8 @@ const (
 type Context struct {
 	prevContract common.Address // previously used contract
 	keyCache     *KeyCache      // key cache
+	Validate     bool           // if true, results of read operations are recorded/validated
+	result       []byte         // encoded result of the last read operation
 }
 
 // Record is the recording environment/facade
@@ -53,6 +57,7 @@ type Replay struct {
 	snapshot *SnapshotIndex // snapshot translation table for replay
 	Profile  bool           // if true collect stats
 	Stats    *profile.Stats // stats object
+	mismatch error          // first mismatch between a recorded and a replayed result
 }
 
 // NewReplay creates a new replay context.
@@ -164,6 +169,70 @@ func (ctx *Context) ReadKeyCache(sPos int) common.Hash {
 	return key
 }
 
+////////////////////////////////////////////////////////////////
+// Result methods
+////////////////////////////////////////////////////////////////
+
+// SetHashResult stores a hash returned by a read operation.
+func (ctx *Context) SetHashResult(value common.Hash) {
+	if ctx.Validate {
+		ctx.result = append(ctx.result[:0], value[:]...)
+	}
+}
+
+// SetBoolResult stores a boolean returned by a read operation.
+func (ctx *Context) SetBoolResult(value bool) {
+	if ctx.Validate {
+		var b byte
+		if value {
+			b = 1
+		}
+		ctx.result = append(ctx.result[:0], b)
+	}
+}
+
+// SetUint64Result stores an integer returned by a read operation.
+func (ctx *Context) SetUint64Result(value uint64) {
+	if ctx.Validate {
+		ctx.result = binary.LittleEndian.AppendUint64(ctx.result[:0], value)
+	}
+}
+
+// SetBalanceResult stores a balance returned by a read operation.
+func (ctx *Context) SetBalanceResult(value *big.Int) {
+	if ctx.Validate {
+		ctx.result = append(ctx.result[:0], value.Bytes()...)
+	}
+}
+
+// SetBytesResult stores a byte slice returned by a read operation.
+func (ctx *Context) SetBytesResult(value []byte) {
+	if ctx.Validate {
+		ctx.result = append(ctx.result[:0], value...)
+	}
+}
+
+// Result returns the encoded result of the last read operation.
+func (ctx *Context) Result() []byte {
+	return ctx.result
+}
+
+// ValidateResult compares a recorded result with the result of the last
+// replayed read operation. The first mismatch is kept and returned by Mismatch.
+func (ctx *Replay) ValidateResult(expected []byte) {
+	if !ctx.Validate || ctx.mismatch != nil {
+		return
+	}
+	if !bytes.Equal(expected, ctx.result) {
+		ctx.mismatch = fmt.Errorf("unexpected result; got 0x%x, want 0x%x", ctx.result, expected)
+	}
+}
+
+// Mismatch returns the first mismatch found by ValidateResult, or nil.
+func (ctx *Replay) Mismatch() error {
+	return ctx.mismatch
+}
+
 ////////////////////////////////////////////////////////////////
 // Snapshot methods
 ////////////////////////////////////////////////////////////////
@@ -60,8 +60,10 @@ func (op *Empty) Write(f io.Writer) error {
 func (op *Empty) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.Empty(contract)
-	return time.Since(start)
+	value := db.Empty(contract)
+	elapsed := time.Since(start)
+	ctx.SetBoolResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the Empty operation.
@@ -60,8 +60,10 @@ func (op *Exist) Write(f io.Writer) error {
 func (op *Exist) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.Exist(contract)
-	return time.Since(start)
+	value := db.Exist(contract)
+	elapsed := time.Since(start)
+	ctx.SetBoolResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the exist operation.
@@ -60,8 +60,10 @@ func (op *GetBalance) Write(f io.Writer) error {
 func (op *GetBalance) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.GetBalance(contract)
-	return time.Since(start)
+	value := db.GetBalance(contract)
+	elapsed := time.Since(start)
+	ctx.SetBalanceResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-balance operation.
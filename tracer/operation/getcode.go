@@ -60,8 +60,10 @@ func (op *GetCode) Write(f io.Writer) error {
 func (op *GetCode) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.GetCode(contract)
-	return time.Since(start)
+	value := db.GetCode(contract)
+	elapsed := time.Since(start)
+	ctx.SetBytesResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-code operation.
@@ -60,8 +60,10 @@ func (op *GetCodeHash) Write(f io.Writer) error {
 func (op *GetCodeHash) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.GetCodeHash(contract)
-	return time.Since(start)
+	value := db.GetCodeHash(contract)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-code-hash operation.
@@ -59,8 +59,10 @@ func (op *GetCodeHashLc) Write(f io.Writer) error {
 func (op *GetCodeHashLc) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.PrevContract()
 	start := time.Now()
-	db.GetCodeHash(contract)
-	return time.Since(start)
+	value := db.GetCodeHash(contract)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-code-hash-lc operation.
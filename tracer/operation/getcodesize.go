@@ -60,8 +60,10 @@ func (op *GetCodeSize) Write(f io.Writer) error {
 func (op *GetCodeSize) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.GetCodeSize(contract)
-	return time.Since(start)
+	value := db.GetCodeSize(contract)
+	elapsed := time.Since(start)
+	ctx.SetUint64Result(uint64(value))
+	return elapsed
 }
 
 // Debug prints a debug message for get-code-size.
@@ -62,8 +62,10 @@ func (op *GetCommittedState) Execute(db state.StateDB, ctx *context.Replay) time
 	contract := ctx.DecodeContract(op.Contract)
 	storage := ctx.DecodeKey(op.Key)
 	start := time.Now()
-	db.GetCommittedState(contract, storage)
-	return time.Since(start)
+	value := db.GetCommittedState(contract, storage)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints debug message for the get-committed-state operation.
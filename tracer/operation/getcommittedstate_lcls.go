@@ -61,8 +61,10 @@ func (op *GetCommittedStateLcls) Execute(db state.StateDB, ctx *context.Replay)
 	contract := ctx.PrevContract()
 	storage := ctx.DecodeKeyCache(0)
 	start := time.Now()
-	db.GetCommittedState(contract, storage)
-	return time.Since(start)
+	value := db.GetCommittedState(contract, storage)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-committed-state-lcls operation.
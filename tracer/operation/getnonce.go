@@ -60,8 +60,10 @@ func (op *GetNonce) Write(f io.Writer) error {
 func (op *GetNonce) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.GetNonce(contract)
-	return time.Since(start)
+	value := db.GetNonce(contract)
+	elapsed := time.Since(start)
+	ctx.SetUint64Result(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-nonce operation.
@@ -62,8 +62,10 @@ func (op *GetState) Execute(db state.StateDB, ctx *context.Replay) time.Duration
 	contract := ctx.DecodeContract(op.Contract)
 	storage := ctx.DecodeKey(op.Key)
 	start := time.Now()
-	db.GetState(contract, storage)
-	return time.Since(start)
+	value := db.GetState(contract, storage)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-state operation.
@@ -66,8 +66,10 @@ func (op *GetStateLc) Execute(db state.StateDB, ctx *context.Replay) time.Durati
 	contract := ctx.PrevContract()
 	storage := ctx.DecodeKey(op.Key)
 	start := time.Now()
-	db.GetState(contract, storage)
-	return time.Since(start)
+	value := db.GetState(contract, storage)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-state-lc operation.
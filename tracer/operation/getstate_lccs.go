@@ -70,8 +70,10 @@ func (op *GetStateLccs) Execute(db state.StateDB, ctx *context.Replay) time.Dura
 	contract := ctx.PrevContract()
 	storage := ctx.DecodeKeyCache(int(op.StoragePosition))
 	start := time.Now()
-	db.GetState(contract, storage)
-	return time.Since(start)
+	value := db.GetState(contract, storage)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-state-lccs operation.
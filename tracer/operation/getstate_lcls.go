@@ -60,8 +60,10 @@ func (op *GetStateLcls) Execute(db state.StateDB, ctx *context.Replay) time.Dura
 	contract := ctx.PrevContract()
 	storage := ctx.DecodeKeyCache(0)
 	start := time.Now()
-	db.GetState(contract, storage)
-	return time.Since(start)
+	value := db.GetState(contract, storage)
+	elapsed := time.Since(start)
+	ctx.SetHashResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the get-state-lcls operation.
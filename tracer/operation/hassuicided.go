@@ -59,8 +59,10 @@ func (op *HasSuicided) Write(f io.Writer) error {
 func (op *HasSuicided) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.HasSuicided(contract)
-	return time.Since(start)
+	value := db.HasSuicided(contract)
+	elapsed := time.Since(start)
+	ctx.SetBoolResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the HasSuicided operation.
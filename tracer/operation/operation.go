@@ -80,6 +80,8 @@ const (
 	PrepareID
 	SubRefundID
 
+	ResultID
+
 	// WARNING: New IDs should be added here. Any change in the order of the
 	// IDs above invalidates persisted data -- in particular storage traces.
 
@@ -145,6 +147,8 @@ var opDict = map[byte]OperationDictionary{
 	PrepareID:                {label: "Prepare", readfunc: ReadPanic},
 	SlotInAccessListID:       {label: "SlotInAccessList", readfunc: ReadPanic},
 	SubRefundID:              {label: "SubRefund", readfunc: ReadPanic},
+
+	ResultID: {label: "Result", readfunc: ReadResult},
 }
 
 // GetLabel retrieves a label of a state operation.
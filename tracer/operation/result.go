@@ -0,0 +1,81 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package operation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Fantom-foundation/Aida/state"
+
+	"github.com/Fantom-foundation/Aida/tracer/context"
+)
+
+// Result data structure
+type Result struct {
+	Value []byte // encoded result of the preceding read operation
+}
+
+// GetId returns the result operation identifier.
+func (op *Result) GetId() byte {
+	return ResultID
+}
+
+// NewResult creates a new result operation.
+func NewResult(value []byte) *Result {
+	return &Result{Value: value}
+}
+
+// ReadResult reads a result operation from a file.
+func ReadResult(f io.Reader) (Operation, error) {
+	data := new(Result)
+	var length uint32
+	if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
+		return nil, fmt.Errorf("Cannot read result length. Error: %v", err)
+	}
+	data.Value = make([]byte, length)
+	if err := binary.Read(f, binary.LittleEndian, data.Value); err != nil {
+		return nil, fmt.Errorf("Cannot read result. Error: %v", err)
+	}
+	return data, nil
+}
+
+// Write the result operation to a file.
+func (op *Result) Write(f io.Writer) error {
+	var length = uint32(len(op.Value))
+	if err := binary.Write(f, binary.LittleEndian, &length); err != nil {
+		return fmt.Errorf("Cannot write result length. Error: %v", err)
+	}
+	if err := binary.Write(f, binary.LittleEndian, op.Value); err != nil {
+		return fmt.Errorf("Cannot write result. Error: %v", err)
+	}
+	return nil
+}
+
+// Execute the result operation by comparing the recorded result with the
+// result of the preceding read operation.
+func (op *Result) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
+	ctx.ValidateResult(op.Value)
+	return time.Duration(0)
+}
+
+// Debug prints a debug message for the result operation.
+func (op *Result) Debug(ctx *context.Context) {
+	fmt.Printf("0x%x", op.Value)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package operation
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func initResult(t *testing.T, value []byte) (*context.Replay, *Result) {
+	// create context context
+	ctx := context.NewReplay()
+	ctx.Validate = true
+
+	// create new operation
+	op := NewResult(value)
+	if op == nil {
+		t.Fatalf("failed to create operation")
+	}
+	// check id
+	if op.GetId() != ResultID {
+		t.Fatalf("wrong ID returned")
+	}
+
+	return ctx, op
+}
+
+// TestResultReadWrite writes a new Result object into a buffer, reads from it,
+// and checks equality.
+func TestResultReadWrite(t *testing.T) {
+	_, op1 := initResult(t, []byte{1, 2, 3})
+	testOperationReadWrite(t, op1, ReadResult)
+}
+
+// TestResultDebug creates a new Result object and checks its Debug message.
+func TestResultDebug(t *testing.T) {
+	ctx, op := initResult(t, []byte{1, 2, 3})
+	testOperationDebug(t, ctx, op, fmt.Sprintf("0x%x", []byte{1, 2, 3}))
+}
+
+// TestResultExecuteMatch checks that a matching result of the preceding read
+// operation is accepted.
+func TestResultExecuteMatch(t *testing.T) {
+	ctx, op := initResult(t, binary.LittleEndian.AppendUint64(nil, 0))
+
+	mock := NewMockStateDB()
+	NewGetNonce(common.HexToAddress("0x1")).Execute(mock, ctx)
+	op.Execute(mock, ctx)
+
+	if err := ctx.Mismatch(); err != nil {
+		t.Errorf("unexpected mismatch; %v", err)
+	}
+}
+
+// TestResultExecuteMismatch checks that a different result of the preceding
+// read operation is reported.
+func TestResultExecuteMismatch(t *testing.T) {
+	ctx, op := initResult(t, common.HexToHash("0x1").Bytes())
+
+	mock := NewMockStateDB()
+	NewGetState(common.HexToAddress("0x1"), common.HexToHash("0x2")).Execute(mock, ctx)
+	op.Execute(mock, ctx)
+
+	if ctx.Mismatch() == nil {
+		t.Errorf("mismatch of results is not reported")
+	}
+}
+
+// TestResultExecuteWithoutValidation checks that results are ignored if validation is disabled.
+func TestResultExecuteWithoutValidation(t *testing.T) {
+	ctx, op := initResult(t, common.HexToHash("0x1").Bytes())
+	ctx.Validate = false
+
+	mock := NewMockStateDB()
+	NewGetState(common.HexToAddress("0x1"), common.HexToHash("0x2")).Execute(mock, ctx)
+	op.Execute(mock, ctx)
+
+	if err := ctx.Mismatch(); err != nil {
+		t.Errorf("unexpected mismatch; %v", err)
+	}
+}
@@ -60,8 +60,10 @@ func (op *Suicide) Write(f io.Writer) error {
 func (op *Suicide) Execute(db state.StateDB, ctx *context.Replay) time.Duration {
 	contract := ctx.DecodeContract(op.Contract)
 	start := time.Now()
-	db.Suicide(contract)
-	return time.Since(start)
+	value := db.Suicide(contract)
+	elapsed := time.Since(start)
+	ctx.SetBoolResult(value)
+	return elapsed
 }
 
 // Debug prints a debug message for the suicide operation.
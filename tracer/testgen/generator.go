@@ -0,0 +1,380 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package testgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// account is the state of an account observed the first time the
+// account was touched by the trace segment.
+type account struct {
+	addr    common.Address
+	exists  bool
+	balance *big.Int
+	nonce   uint64
+	code    []byte
+	slots   []slot
+}
+
+// slot is the value of a storage slot observed the first time the
+// slot was touched by the trace segment.
+type slot struct {
+	key   common.Hash
+	value common.Hash
+}
+
+// statement is a single line of the generated test body.
+type statement struct {
+	text     string       // rendered source code of the statement
+	snapshot *snapshotVar // set if the statement creates a snapshot
+}
+
+// snapshotVar is a variable holding a snapshot id in the generated test.
+type snapshotVar struct {
+	name string // name of the variable
+	used bool   // true if the variable is referenced by a revert
+}
+
+// Generator collects the StateDB calls of a trace segment including their
+// results and renders them as a self-contained Go test. The pre-state read
+// by the segment is captured as well so that the test can prime an empty
+// StateDB before replaying the calls.
+type Generator struct {
+	pkg      string                                  // package name of the generated file
+	name     string                                  // name suffix of the generated functions
+	block    uint64                                  // first block of the segment
+	accounts []*account                              // touched accounts in order of first access
+	index    map[common.Address]*account             // index of touched accounts
+	slots    map[common.Address]map[common.Hash]bool // touched storage slots
+	body     []statement                             // statements of the replayed segment
+	snapshot map[int]*snapshotVar                    // snapshot ids of the reference DB
+	numOps   int                                     // number of recorded operations
+	inBlock  bool                                    // true if a block is open
+	inTx     bool                                    // true if a transaction is open
+	began    bool                                    // true if the segment started with a block
+	useBig   bool                                    // true if hexutil is required
+	useBytes bool                                    // true if bytes is required
+}
+
+// NewGenerator creates a generator for a test named by the given suffix
+// (e.g. Block123_Tx4) for a segment starting at the given block.
+func NewGenerator(pkg string, name string, block uint64) *Generator {
+	return &Generator{
+		pkg:      pkg,
+		name:     name,
+		block:    block,
+		index:    make(map[common.Address]*account),
+		slots:    make(map[common.Address]map[common.Hash]bool),
+		snapshot: make(map[int]*snapshotVar),
+	}
+}
+
+// NumOperations returns the number of StateDB operations recorded so far.
+func (g *Generator) NumOperations() int {
+	return g.numOps
+}
+
+// isTouched returns true if the account has been already captured.
+func (g *Generator) isTouched(addr common.Address) bool {
+	_, ok := g.index[addr]
+	return ok
+}
+
+// addAccount captures the state of an account before its first access.
+func (g *Generator) addAccount(addr common.Address, exists bool, balance *big.Int, nonce uint64, code []byte) {
+	acc := &account{
+		addr:    addr,
+		exists:  exists,
+		balance: new(big.Int),
+		nonce:   nonce,
+		code:    common.CopyBytes(code),
+	}
+	if balance != nil {
+		acc.balance.Set(balance)
+	}
+	g.accounts = append(g.accounts, acc)
+	g.index[addr] = acc
+	g.slots[addr] = make(map[common.Hash]bool)
+}
+
+// isSlotTouched returns true if the storage slot has been already captured.
+func (g *Generator) isSlotTouched(addr common.Address, key common.Hash) bool {
+	return g.slots[addr][key]
+}
+
+// addSlot captures the value of a storage slot before its first access.
+// The account of the slot must have been captured before.
+func (g *Generator) addSlot(addr common.Address, key common.Hash, value common.Hash) {
+	acc := g.index[addr]
+	acc.slots = append(acc.slots, slot{key: key, value: value})
+	g.slots[addr][key] = true
+}
+
+// call records a StateDB call without a result.
+func (g *Generator) call(format string, args ...any) {
+	g.numOps++
+	g.beginImplicitBlock()
+	g.body = append(g.body, statement{text: "db." + fmt.Sprintf(format, args...)})
+}
+
+// callWithError records a StateDB call returning an error.
+func (g *Generator) callWithError(label string, format string, args ...any) {
+	g.numOps++
+	g.body = append(g.body, statement{text: fmt.Sprintf("if err := db.%s; err != nil {\nt.Fatalf(\"operation %d: %s failed; %%v\", err)\n}",
+		fmt.Sprintf(format, args...), g.numOps, label)})
+}
+
+// check records a StateDB call and an assertion of its result. The
+// comparison must be an expression over the variables got and want
+// which is true if both differ.
+func (g *Generator) check(label string, call string, want string, differs string) {
+	g.numOps++
+	g.beginImplicitBlock()
+	g.body = append(g.body, statement{text: fmt.Sprintf("if got, want := db.%s, %s; %s {\nt.Errorf(\"operation %d: unexpected result of %s; got %%v, want %%v\", got, want)\n}",
+		call, want, differs, g.numOps, label)})
+}
+
+// checkHash records a StateDB call returning a hash.
+func (g *Generator) checkHash(label string, call string, want common.Hash) {
+	g.check(label, call, hashLit(want), "got != want")
+}
+
+// checkBool records a StateDB call returning a boolean.
+func (g *Generator) checkBool(label string, call string, want bool) {
+	g.check(label, call, fmt.Sprint(want), "got != want")
+}
+
+// checkUint64 records a StateDB call returning an uint64 value.
+func (g *Generator) checkUint64(label string, call string, want uint64) {
+	g.check(label, call, fmt.Sprintf("uint64(%d)", want), "got != want")
+}
+
+// checkInt records a StateDB call returning an int value.
+func (g *Generator) checkInt(label string, call string, want int) {
+	g.check(label, call, fmt.Sprint(want), "got != want")
+}
+
+// checkBig records a StateDB call returning a big integer.
+func (g *Generator) checkBig(label string, call string, want *big.Int) {
+	g.check(label, call, g.bigLit(want), "got.Cmp(want) != 0")
+}
+
+// checkBytes records a StateDB call returning a byte slice.
+func (g *Generator) checkBytes(label string, call string, want []byte) {
+	g.useBytes = true
+	g.check(label, call, bytesLit(want), "!bytes.Equal(got, want)")
+}
+
+// control records a call managing sync-periods which may appear outside of blocks.
+func (g *Generator) control(format string, args ...any) {
+	g.numOps++
+	g.body = append(g.body, statement{text: "db." + fmt.Sprintf(format, args...)})
+}
+
+// beginBlock records the beginning of a block.
+func (g *Generator) beginBlock(number uint64) {
+	g.began = true
+	g.inBlock = true
+	g.callWithError("BeginBlock", "BeginBlock(%d)", number)
+}
+
+// endBlock records the end of a block.
+func (g *Generator) endBlock() {
+	g.inBlock = false
+	g.callWithError("EndBlock", "EndBlock()")
+}
+
+// beginTransaction records the beginning of a transaction.
+func (g *Generator) beginTransaction(number uint32) {
+	g.beginImplicitBlock()
+	g.inTx = true
+	g.callWithError("BeginTransaction", "BeginTransaction(%d)", number)
+}
+
+// endTransaction records the end of a transaction.
+func (g *Generator) endTransaction() {
+	g.inTx = false
+	g.callWithError("EndTransaction", "EndTransaction()")
+}
+
+// beginImplicitBlock opens the block of the segment if the segment does not
+// start with a BeginBlock operation (e.g. for a single transaction).
+func (g *Generator) beginImplicitBlock() {
+	if g.inBlock || g.began {
+		return
+	}
+	g.began = true
+	g.inBlock = true
+	g.body = append(g.body, statement{text: fmt.Sprintf("if err := db.BeginBlock(%d); err != nil {\nt.Fatalf(\"cannot begin block; %%v\", err)\n}", g.block)})
+}
+
+// snapshot records the creation of a snapshot with the given id as returned
+// by the reference StateDB.
+func (g *Generator) snapshotCreated(id int) {
+	g.numOps++
+	g.beginImplicitBlock()
+	v := &snapshotVar{name: fmt.Sprintf("snapshot%d", len(g.snapshot))}
+	g.snapshot[id] = v
+	g.body = append(g.body, statement{text: "db.Snapshot()", snapshot: v})
+}
+
+// revertToSnapshot records a revert to the snapshot with the given id as
+// returned by the reference StateDB.
+func (g *Generator) revertToSnapshot(id int) {
+	v, ok := g.snapshot[id]
+	if !ok {
+		g.call("RevertToSnapshot(%d)", id)
+		return
+	}
+	v.used = true
+	g.call("RevertToSnapshot(%s)", v.name)
+}
+
+// Write renders the generated test into the given writer.
+func (g *Generator) Write(w io.Writer) error {
+	var b bytes.Buffer
+
+	// balances of the pre-state are rendered as big integers too
+	for _, acc := range g.accounts {
+		if acc.exists && acc.balance.Sign() != 0 {
+			g.useBig = true
+		}
+	}
+
+	fmt.Fprintf(&b, "// Code generated by aida-sdb generate-test. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", g.pkg)
+	fmt.Fprintf(&b, "import (\n")
+	if g.useBytes {
+		fmt.Fprintf(&b, "\"bytes\"\n")
+	}
+	fmt.Fprintf(&b, "\"testing\"\n\n")
+	fmt.Fprintf(&b, "\"github.com/Fantom-foundation/Aida/state\"\n")
+	fmt.Fprintf(&b, "\"github.com/ethereum/go-ethereum/common\"\n")
+	if g.useBig {
+		fmt.Fprintf(&b, "\"github.com/ethereum/go-ethereum/common/hexutil\"\n")
+	}
+	fmt.Fprintf(&b, ")\n\n")
+
+	// test entry point
+	fmt.Fprintf(&b, "// TestTrace_%s replays a recorded storage trace segment on a geth StateDB.\n", g.name)
+	fmt.Fprintf(&b, "func TestTrace_%s(t *testing.T) {\n", g.name)
+	fmt.Fprintf(&b, "db, err := state.MakeGethStateDB(t.TempDir(), \"\", common.Hash{}, false, nil)\n")
+	fmt.Fprintf(&b, "if err != nil {\nt.Fatalf(\"cannot create state DB; %%v\", err)\n}\n")
+	fmt.Fprintf(&b, "defer func() {\nif err := db.Close(); err != nil {\nt.Fatalf(\"cannot close state DB; %%v\", err)\n}\n}()\n")
+	fmt.Fprintf(&b, "PrimeTrace_%s(t, db)\n", g.name)
+	fmt.Fprintf(&b, "RunTrace_%s(t, db)\n", g.name)
+	fmt.Fprintf(&b, "}\n\n")
+
+	g.writePrime(&b)
+	g.writeRun(&b)
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("cannot format generated test; %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// writePrime renders the function loading the pre-state of the segment.
+func (g *Generator) writePrime(b *bytes.Buffer) {
+	primeBlock := uint64(0)
+	if g.block > 0 {
+		primeBlock = g.block - 1
+	}
+	fmt.Fprintf(b, "// PrimeTrace_%s loads the state read by the trace segment into the given StateDB.\n", g.name)
+	fmt.Fprintf(b, "func PrimeTrace_%s(t *testing.T, db state.StateDB) {\n", g.name)
+	fmt.Fprintf(b, "load, err := db.StartBulkLoad(%d)\n", primeBlock)
+	fmt.Fprintf(b, "if err != nil {\nt.Fatalf(\"cannot start bulk load; %%v\", err)\n}\n")
+	for _, acc := range g.accounts {
+		if !acc.exists {
+			continue
+		}
+		addr := addrLit(acc.addr)
+		fmt.Fprintf(b, "load.CreateAccount(%s)\n", addr)
+		if acc.balance.Sign() != 0 {
+			fmt.Fprintf(b, "load.SetBalance(%s, %s)\n", addr, g.bigLit(acc.balance))
+		}
+		if acc.nonce != 0 {
+			fmt.Fprintf(b, "load.SetNonce(%s, %d)\n", addr, acc.nonce)
+		}
+		if len(acc.code) > 0 {
+			fmt.Fprintf(b, "load.SetCode(%s, %s)\n", addr, bytesLit(acc.code))
+		}
+		for _, s := range acc.slots {
+			if s.value == (common.Hash{}) {
+				continue
+			}
+			fmt.Fprintf(b, "load.SetState(%s, %s, %s)\n", addr, hashLit(s.key), hashLit(s.value))
+		}
+	}
+	fmt.Fprintf(b, "if err := load.Close(); err != nil {\nt.Fatalf(\"cannot close bulk load; %%v\", err)\n}\n")
+	fmt.Fprintf(b, "}\n\n")
+}
+
+// writeRun renders the function replaying the recorded operations.
+func (g *Generator) writeRun(b *bytes.Buffer) {
+	fmt.Fprintf(b, "// RunTrace_%s replays the trace segment on the given StateDB and checks the results of all reads.\n", g.name)
+	fmt.Fprintf(b, "func RunTrace_%s(t *testing.T, db state.StateDB) {\n", g.name)
+	for _, s := range g.body {
+		if s.snapshot != nil && s.snapshot.used {
+			fmt.Fprintf(b, "%s := %s\n", s.snapshot.name, s.text)
+			continue
+		}
+		fmt.Fprintf(b, "%s\n", s.text)
+	}
+	// close a transaction and block left open by the segment
+	if g.inTx {
+		fmt.Fprintf(b, "if err := db.EndTransaction(); err != nil {\nt.Fatalf(\"cannot end transaction; %%v\", err)\n}\n")
+	}
+	if g.inBlock {
+		fmt.Fprintf(b, "if err := db.EndBlock(); err != nil {\nt.Fatalf(\"cannot end block; %%v\", err)\n}\n")
+	}
+	fmt.Fprintf(b, "}\n")
+}
+
+// addrLit returns the source code of an address literal.
+func addrLit(addr common.Address) string {
+	return fmt.Sprintf("common.HexToAddress(%q)", addr.Hex())
+}
+
+// hashLit returns the source code of a hash literal.
+func hashLit(hash common.Hash) string {
+	return fmt.Sprintf("common.HexToHash(%q)", hash.Hex())
+}
+
+// bigLit returns the source code of a big integer literal.
+func (g *Generator) bigLit(value *big.Int) string {
+	g.useBig = true
+	if value == nil {
+		value = new(big.Int)
+	}
+	return fmt.Sprintf("hexutil.MustDecodeBig(%q)", hexutil.EncodeBig(value))
+}
+
+// bytesLit returns the source code of a byte slice literal.
+func bytesLit(value []byte) string {
+	return fmt.Sprintf("common.FromHex(%q)", hexutil.Encode(value))
+}
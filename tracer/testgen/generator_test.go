@@ -0,0 +1,163 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package testgen
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/tracer/context"
+	"github.com/Fantom-foundation/Aida/tracer/operation"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// generate replays the given operations on an in-memory StateDB through
+// a generator proxy and returns the rendered test.
+func generate(t *testing.T, ops []operation.Operation) string {
+	db, err := state.MakeEmptyGethInMemoryStateDB("")
+	if err != nil {
+		t.Fatalf("cannot create state DB; %v", err)
+	}
+	gen := NewGenerator("state_test", "Block10_Tx2", 10)
+	proxy := NewGeneratorProxy(db, gen)
+	ctx := context.NewReplay()
+	for _, op := range ops {
+		operation.Execute(op, proxy, ctx)
+	}
+	if got, want := gen.NumOperations(), len(ops); got != want {
+		t.Errorf("unexpected number of recorded operations; got %v, want %v", got, want)
+	}
+
+	var b bytes.Buffer
+	if err := gen.Write(&b); err != nil {
+		t.Fatalf("cannot write generated test; %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "generated_test.go", b.Bytes(), 0); err != nil {
+		t.Fatalf("generated test is not valid Go code; %v\n%s", err, b.String())
+	}
+	runGenerated(t, b.Bytes())
+	return b.String()
+}
+
+// runGenerated vets and runs the generated test in a temporary package of this module,
+// hence the generated code must compile and its assertions must hold on a fresh StateDB.
+func runGenerated(t *testing.T, src []byte) {
+	if testing.Short() {
+		return
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skipf("go tool is not available; %v", err)
+	}
+	// the package must be inside the module to import Aida packages; the underscore
+	// prefix keeps it out of ./... patterns if the test is interrupted
+	dir, err := os.MkdirTemp(".", "_generated")
+	if err != nil {
+		t.Fatalf("cannot create package directory; %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err = os.WriteFile(filepath.Join(dir, "generated_test.go"), src, 0600); err != nil {
+		t.Fatalf("cannot write generated test; %v", err)
+	}
+
+	pkg := "./" + filepath.Base(dir)
+	for _, args := range [][]string{{"vet", pkg}, {"test", "-count=1", pkg}} {
+		if out, err := exec.Command(goTool, args...).CombinedOutput(); err != nil {
+			t.Fatalf("go %v of generated test failed; %v\n%s\n%s", args[0], err, out, src)
+		}
+	}
+}
+
+// TestGenerator_RecordsCallsAndResults checks that writes are replayed and reads are asserted.
+func TestGenerator_RecordsCallsAndResults(t *testing.T) {
+	addr := common.HexToAddress("0x1")
+	key := common.HexToHash("0x2")
+	value := common.HexToHash("0x3")
+
+	src := generate(t, []operation.Operation{
+		operation.NewBeginTransaction(2),
+		operation.NewCreateAccount(addr),
+		operation.NewAddBalance(addr, big.NewInt(1000)),
+		operation.NewSetState(addr, key, value),
+		operation.NewGetState(addr, key),
+		operation.NewGetBalance(addr),
+		operation.NewExist(addr),
+		operation.NewEndTransaction(),
+	})
+
+	expected := []string{
+		"package state_test",
+		"func TestTrace_Block10_Tx2(t *testing.T)",
+		"func PrimeTrace_Block10_Tx2(t *testing.T, db state.StateDB)",
+		"func RunTrace_Block10_Tx2(t *testing.T, db state.StateDB)",
+		"db.BeginBlock(10)",
+		"db.BeginTransaction(2)",
+		"db.CreateAccount(common.HexToAddress(\"0x0000000000000000000000000000000000000001\"))",
+		"db.AddBalance(common.HexToAddress(\"0x0000000000000000000000000000000000000001\"), hexutil.MustDecodeBig(\"0x3e8\"))",
+		"got, want := db.GetState(common.HexToAddress(\"0x0000000000000000000000000000000000000001\"), common.HexToHash(\"0x0000000000000000000000000000000000000000000000000000000000000002\")), common.HexToHash(\"0x0000000000000000000000000000000000000000000000000000000000000003\")",
+		"got, want := db.GetBalance(common.HexToAddress(\"0x0000000000000000000000000000000000000001\")), hexutil.MustDecodeBig(\"0x3e8\")",
+		"got, want := db.Exist(common.HexToAddress(\"0x0000000000000000000000000000000000000001\")), true",
+		"db.EndTransaction()",
+		"db.EndBlock()",
+	}
+	for _, want := range expected {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated test does not contain %q\n%s", want, src)
+		}
+	}
+
+	// the account did not exist before the segment, hence nothing is primed
+	if strings.Contains(src, "load.CreateAccount") {
+		t.Errorf("unexpected priming of a new account\n%s", src)
+	}
+}
+
+// TestGenerator_SnapshotVariables checks that snapshot ids are bound to variables if reverted.
+func TestGenerator_SnapshotVariables(t *testing.T) {
+	addr := common.HexToAddress("0x1")
+
+	src := generate(t, []operation.Operation{
+		operation.NewBeginTransaction(0),
+		operation.NewSnapshot(0),
+		operation.NewSnapshot(1),
+		operation.NewSetNonce(addr, 5),
+		operation.NewRevertToSnapshot(1),
+		operation.NewGetNonce(addr),
+		operation.NewEndTransaction(),
+	})
+
+	if !strings.Contains(src, "snapshot1 := db.Snapshot()") {
+		t.Errorf("reverted snapshot is not bound to a variable\n%s", src)
+	}
+	if strings.Contains(src, "snapshot0 :=") {
+		t.Errorf("unused snapshot must not be bound to a variable\n%s", src)
+	}
+	if !strings.Contains(src, "db.RevertToSnapshot(snapshot1)") {
+		t.Errorf("revert does not refer to snapshot variable\n%s", src)
+	}
+	if !strings.Contains(src, "db.GetNonce(common.HexToAddress(\"0x0000000000000000000000000000000000000001\")), uint64(0)") {
+		t.Errorf("nonce is not checked after revert\n%s", src)
+	}
+}
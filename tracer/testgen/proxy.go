@@ -0,0 +1,363 @@
+// Copyright 2024 Fantom Foundation
+// This file is part of Aida Testing Infrastructure for Sonic
+//
+// Aida is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Aida is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Aida. If not, see <http://www.gnu.org/licenses/>.
+
+package testgen
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Fantom-foundation/Aida/state"
+	"github.com/Fantom-foundation/Aida/txcontext"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// GeneratorProxy data structure for capturing StateDB operations of
+// a replayed trace segment together with the results of the reference
+// StateDB and forwarding them to a test generator.
+type GeneratorProxy struct {
+	db  state.StateDB // reference state db
+	gen *Generator    // generator collecting the operations
+}
+
+// NewGeneratorProxy creates a new StateDB proxy.
+func NewGeneratorProxy(db state.StateDB, gen *Generator) *GeneratorProxy {
+	return &GeneratorProxy{
+		db:  db,
+		gen: gen,
+	}
+}
+
+// touch captures the state of an account before it is accessed first.
+func (p *GeneratorProxy) touch(addr common.Address) {
+	if p.gen.isTouched(addr) {
+		return
+	}
+	exists := p.db.Exist(addr)
+	if !exists {
+		p.gen.addAccount(addr, false, nil, 0, nil)
+		return
+	}
+	p.gen.addAccount(addr, true, p.db.GetBalance(addr), p.db.GetNonce(addr), p.db.GetCode(addr))
+}
+
+// touchSlot captures the value of a storage slot before it is accessed first.
+func (p *GeneratorProxy) touchSlot(addr common.Address, key common.Hash) {
+	p.touch(addr)
+	if p.gen.isSlotTouched(addr, key) {
+		return
+	}
+	p.gen.addSlot(addr, key, p.db.GetState(addr, key))
+}
+
+// CreateAccount creates a new account.
+func (p *GeneratorProxy) CreateAccount(addr common.Address) {
+	p.touch(addr)
+	p.db.CreateAccount(addr)
+	p.gen.call("CreateAccount(%s)", addrLit(addr))
+}
+
+// SubBalance subtracts amount from a contract address.
+func (p *GeneratorProxy) SubBalance(addr common.Address, amount *big.Int) {
+	p.touch(addr)
+	p.db.SubBalance(addr, amount)
+	p.gen.call("SubBalance(%s, %s)", addrLit(addr), p.gen.bigLit(amount))
+}
+
+// AddBalance adds amount to a contract address.
+func (p *GeneratorProxy) AddBalance(addr common.Address, amount *big.Int) {
+	p.touch(addr)
+	p.db.AddBalance(addr, amount)
+	p.gen.call("AddBalance(%s, %s)", addrLit(addr), p.gen.bigLit(amount))
+}
+
+// GetBalance retrieves the amount of a contract address.
+func (p *GeneratorProxy) GetBalance(addr common.Address) *big.Int {
+	p.touch(addr)
+	balance := p.db.GetBalance(addr)
+	p.gen.checkBig("GetBalance", fmt.Sprintf("GetBalance(%s)", addrLit(addr)), balance)
+	return balance
+}
+
+// GetNonce retrieves the nonce of a contract address.
+func (p *GeneratorProxy) GetNonce(addr common.Address) uint64 {
+	p.touch(addr)
+	nonce := p.db.GetNonce(addr)
+	p.gen.checkUint64("GetNonce", fmt.Sprintf("GetNonce(%s)", addrLit(addr)), nonce)
+	return nonce
+}
+
+// SetNonce sets the nonce of a contract address.
+func (p *GeneratorProxy) SetNonce(addr common.Address, nonce uint64) {
+	p.touch(addr)
+	p.db.SetNonce(addr, nonce)
+	p.gen.call("SetNonce(%s, %d)", addrLit(addr), nonce)
+}
+
+// GetCodeHash returns the hash of the EVM bytecode.
+func (p *GeneratorProxy) GetCodeHash(addr common.Address) common.Hash {
+	p.touch(addr)
+	hash := p.db.GetCodeHash(addr)
+	p.gen.checkHash("GetCodeHash", fmt.Sprintf("GetCodeHash(%s)", addrLit(addr)), hash)
+	return hash
+}
+
+// GetCode returns the EVM bytecode of a contract.
+func (p *GeneratorProxy) GetCode(addr common.Address) []byte {
+	p.touch(addr)
+	code := p.db.GetCode(addr)
+	p.gen.checkBytes("GetCode", fmt.Sprintf("GetCode(%s)", addrLit(addr)), code)
+	return code
+}
+
+// SetCode sets the EVM bytecode of a contract.
+func (p *GeneratorProxy) SetCode(addr common.Address, code []byte) {
+	p.touch(addr)
+	p.db.SetCode(addr, code)
+	p.gen.call("SetCode(%s, %s)", addrLit(addr), bytesLit(code))
+}
+
+// GetCodeSize returns the EVM bytecode's size.
+func (p *GeneratorProxy) GetCodeSize(addr common.Address) int {
+	p.touch(addr)
+	size := p.db.GetCodeSize(addr)
+	p.gen.checkInt("GetCodeSize", fmt.Sprintf("GetCodeSize(%s)", addrLit(addr)), size)
+	return size
+}
+
+// AddRefund adds gas to the refund counter.
+func (p *GeneratorProxy) AddRefund(gas uint64) {
+	p.db.AddRefund(gas)
+}
+
+// SubRefund subtracts gas to the refund counter.
+func (p *GeneratorProxy) SubRefund(gas uint64) {
+	p.db.SubRefund(gas)
+}
+
+// GetRefund returns the current value of the refund counter.
+func (p *GeneratorProxy) GetRefund() uint64 {
+	return p.db.GetRefund()
+}
+
+// GetCommittedState retrieves a value that is already committed.
+func (p *GeneratorProxy) GetCommittedState(addr common.Address, key common.Hash) common.Hash {
+	p.touchSlot(addr, key)
+	value := p.db.GetCommittedState(addr, key)
+	p.gen.checkHash("GetCommittedState", fmt.Sprintf("GetCommittedState(%s, %s)", addrLit(addr), hashLit(key)), value)
+	return value
+}
+
+// GetState retrieves a value from the StateDB.
+func (p *GeneratorProxy) GetState(addr common.Address, key common.Hash) common.Hash {
+	p.touchSlot(addr, key)
+	value := p.db.GetState(addr, key)
+	p.gen.checkHash("GetState", fmt.Sprintf("GetState(%s, %s)", addrLit(addr), hashLit(key)), value)
+	return value
+}
+
+// SetState sets a value in the StateDB.
+func (p *GeneratorProxy) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	p.touchSlot(addr, key)
+	p.db.SetState(addr, key, value)
+	p.gen.call("SetState(%s, %s, %s)", addrLit(addr), hashLit(key), hashLit(value))
+}
+
+// Suicide marks the given account as suicided. This clears the account balance.
+// The account is still available until the state is committed;
+// return a non-nil account after Suicide.
+func (p *GeneratorProxy) Suicide(addr common.Address) bool {
+	p.touch(addr)
+	ok := p.db.Suicide(addr)
+	p.gen.checkBool("Suicide", fmt.Sprintf("Suicide(%s)", addrLit(addr)), ok)
+	return ok
+}
+
+// HasSuicided checks whether a contract has been suicided.
+func (p *GeneratorProxy) HasSuicided(addr common.Address) bool {
+	p.touch(addr)
+	hasSuicided := p.db.HasSuicided(addr)
+	p.gen.checkBool("HasSuicided", fmt.Sprintf("HasSuicided(%s)", addrLit(addr)), hasSuicided)
+	return hasSuicided
+}
+
+// Exist checks whether the contract exists in the StateDB.
+// Notably this also returns true for suicided accounts.
+func (p *GeneratorProxy) Exist(addr common.Address) bool {
+	p.touch(addr)
+	exists := p.db.Exist(addr)
+	p.gen.checkBool("Exist", fmt.Sprintf("Exist(%s)", addrLit(addr)), exists)
+	return exists
+}
+
+// Empty checks whether the contract is either non-existent
+// or empty according to the EIP161 specification (balance = nonce = code = 0).
+func (p *GeneratorProxy) Empty(addr common.Address) bool {
+	p.touch(addr)
+	empty := p.db.Empty(addr)
+	p.gen.checkBool("Empty", fmt.Sprintf("Empty(%s)", addrLit(addr)), empty)
+	return empty
+}
+
+// PrepareAccessList handles the preparatory steps for executing a state transition.
+func (p *GeneratorProxy) PrepareAccessList(sender common.Address, dest *common.Address, precompiles []common.Address, txAccesses types.AccessList) {
+	p.db.PrepareAccessList(sender, dest, precompiles, txAccesses)
+}
+
+// AddAddressToAccessList adds an address to the access list.
+func (p *GeneratorProxy) AddAddressToAccessList(addr common.Address) {
+	p.db.AddAddressToAccessList(addr)
+}
+
+// AddressInAccessList checks whether an address is in the access list.
+func (p *GeneratorProxy) AddressInAccessList(addr common.Address) bool {
+	return p.db.AddressInAccessList(addr)
+}
+
+// SlotInAccessList checks whether the (address, slot)-tuple is in the access list.
+func (p *GeneratorProxy) SlotInAccessList(addr common.Address, slot common.Hash) (bool, bool) {
+	return p.db.SlotInAccessList(addr, slot)
+}
+
+// AddSlotToAccessList adds the given (address, slot)-tuple to the access list.
+func (p *GeneratorProxy) AddSlotToAccessList(addr common.Address, slot common.Hash) {
+	p.db.AddSlotToAccessList(addr, slot)
+}
+
+// RevertToSnapshot reverts all state changes from a given revision.
+func (p *GeneratorProxy) RevertToSnapshot(snapshot int) {
+	p.db.RevertToSnapshot(snapshot)
+	p.gen.revertToSnapshot(snapshot)
+}
+
+// Snapshot returns an identifier for the current revision of the state.
+func (p *GeneratorProxy) Snapshot() int {
+	snapshot := p.db.Snapshot()
+	p.gen.snapshotCreated(snapshot)
+	return snapshot
+}
+
+// AddLog adds a log entry.
+func (p *GeneratorProxy) AddLog(log *types.Log) {
+	p.db.AddLog(log)
+}
+
+// GetLogs retrieves log entries.
+func (p *GeneratorProxy) GetLogs(hash common.Hash, blockHash common.Hash) []*types.Log {
+	return p.db.GetLogs(hash, blockHash)
+}
+
+// AddPreimage adds a SHA3 preimage.
+func (p *GeneratorProxy) AddPreimage(hash common.Hash, image []byte) {
+	p.db.AddPreimage(hash, image)
+}
+
+// ForEachStorage performs a function over all storage locations in a contract.
+func (p *GeneratorProxy) ForEachStorage(addr common.Address, fn func(common.Hash, common.Hash) bool) error {
+	return p.db.ForEachStorage(addr, fn)
+}
+
+// Prepare sets the current transaction hash and index.
+func (p *GeneratorProxy) Prepare(thash common.Hash, ti int) {
+	p.db.Prepare(thash, ti)
+}
+
+// Finalise the state in StateDB.
+func (p *GeneratorProxy) Finalise(deleteEmptyObjects bool) {
+	p.db.Finalise(deleteEmptyObjects)
+	p.gen.call("Finalise(%v)", deleteEmptyObjects)
+}
+
+// IntermediateRoot computes the current hash of the StateDB.
+func (p *GeneratorProxy) IntermediateRoot(deleteEmptyObjects bool) common.Hash {
+	return p.db.IntermediateRoot(deleteEmptyObjects)
+}
+
+func (p *GeneratorProxy) Commit(deleteEmptyObjects bool) (common.Hash, error) {
+	return p.db.Commit(deleteEmptyObjects)
+}
+
+func (p *GeneratorProxy) GetHash() (common.Hash, error) {
+	return p.db.GetHash()
+}
+
+func (p *GeneratorProxy) Error() error {
+	return p.db.Error()
+}
+
+// GetSubstatePostAlloc gets substate post allocation.
+func (p *GeneratorProxy) GetSubstatePostAlloc() txcontext.WorldState {
+	return p.db.GetSubstatePostAlloc()
+}
+
+func (p *GeneratorProxy) PrepareSubstate(substate txcontext.WorldState, block uint64) {
+	p.db.PrepareSubstate(substate, block)
+}
+
+func (p *GeneratorProxy) BeginTransaction(number uint32) error {
+	p.gen.beginTransaction(number)
+	return p.db.BeginTransaction(number)
+}
+
+func (p *GeneratorProxy) EndTransaction() error {
+	p.gen.endTransaction()
+	return p.db.EndTransaction()
+}
+
+func (p *GeneratorProxy) BeginBlock(number uint64) error {
+	p.gen.beginBlock(number)
+	return p.db.BeginBlock(number)
+}
+
+func (p *GeneratorProxy) EndBlock() error {
+	p.gen.endBlock()
+	return p.db.EndBlock()
+}
+
+func (p *GeneratorProxy) BeginSyncPeriod(number uint64) {
+	p.gen.control("BeginSyncPeriod(%d)", number)
+	p.db.BeginSyncPeriod(number)
+}
+
+func (p *GeneratorProxy) EndSyncPeriod() {
+	p.gen.control("EndSyncPeriod()")
+	p.db.EndSyncPeriod()
+}
+
+func (p *GeneratorProxy) GetArchiveState(block uint64) (state.NonCommittableStateDB, error) {
+	return p.db.GetArchiveState(block)
+}
+
+func (p *GeneratorProxy) GetArchiveBlockHeight() (uint64, bool, error) {
+	return p.db.GetArchiveBlockHeight()
+}
+
+func (p *GeneratorProxy) Close() error {
+	return p.db.Close()
+}
+
+func (p *GeneratorProxy) StartBulkLoad(block uint64) (state.BulkLoad, error) {
+	return p.db.StartBulkLoad(block)
+}
+
+func (p *GeneratorProxy) GetMemoryUsage() *state.MemoryUsage {
+	return p.db.GetMemoryUsage()
+}
+
+func (p *GeneratorProxy) GetShadowDB() state.StateDB {
+	return p.db.GetShadowDB()
+}
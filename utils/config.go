@@ -135,6 +135,7 @@ type Config struct {
 	AidaDb                 string         // directory to profiling database containing substate, update, delete accounts data
 	ArchiveMaxQueryAge     int            // the maximum age for archive queries (in blocks)
 	ArchiveMode            bool           // enable archive mode
+	ArchiveQueryAgeLambda  float64        // lambda parameter of the exponential distribution of archive query ages (0 for uniform ages)
 	ArchiveQueryRate       int            // the queries per second send to the archive
 	ArchiveVariant         string         // selects the implementation variant of the archive
 	ArgPath                string         // path to file or directory given as argument
@@ -144,6 +145,9 @@ type Config struct {
 	CPUProfile             string         // pprof cpu profile output file name
 	CPUProfilePerInterval  bool           // a different CPU profile is taken per 100k block interval
 	Cache                  int            // Cache for StateDb or Priming
+	CachePolicies          string         // comma-separated list of simulated cache policies
+	CacheSizes             string         // comma-separated list of simulated cache sizes
+	CacheWindow            uint64         // number of blocks per range reported by the cache simulation
 	CarmenSchema           int            // the current DB schema ID to use in Carmen
 	CarmenStateCacheSize   int            // the number of values cached in the Carmen StateDB (0 for default value)
 	CarmenNodeCacheSize    int            // the size of the in-memory cache to be used by a Carmen LiveDB in byte (0 for default value)
@@ -164,14 +168,17 @@ type Config struct {
 	DeletionDb             string         // directory of deleted account database
 	DiagnosticServer       int64          // if not zero, the port used for hosting a HTTP server for performance diagnostics
 	ErrorLogging           string         // if defined, error logging to file is enabled
+	EstimateGasTolerance   float64        // relative tolerance of replayed gas estimations
 	Genesis                string         // genesis file
 	IncludeStorage         bool           // represents a flag for contract storage inclusion in an operation
 	IsExistingStateDb      bool           // this is true if we are using an existing StateDb
 	KeepDb                 bool           // set to true if db is kept after run
 	KeysNumber             int64          // number of keys to generate
 	LogLevel               string         // level of the logging of the app action
+	MarkovOrder            int            // order of the Markov chain of the stochastic model
 	MaxNumErrors           int            // maximum number of errors when ContinueOnFailure is enabled
 	MaxNumTransactions     int            // the maximum number of processed transactions
+	MaxReportSamples       int            // maximum number of reported mismatches per group (0 for all)
 	MemoryBreakdown        bool           // enable printing of memory breakdown
 	MemoryProfile          string         // capture the memory heap profile into the file
 	MicroProfiling         bool           // enable micro-profiling of EVM
@@ -197,9 +204,13 @@ type Config struct {
 	RandomSeed             int64          // set random seed for stochastic testing
 	RegisterRun            string         // register run to the provided connection string
 	RpcRecordingPath       string         // path to source file (or dir with files) with recorded RPC requests
+	RpcReport              string         // path to the JSON report of RPC mismatches
+	RpcRules               string         // path to the rules file selecting validated RPC requests
+	SegmentLength          int            // number of blocks per segment of a stochastic recording (0 disables segments)
 	ShadowDb               bool           // defines we want to open an existing db as shadow
 	ShadowImpl             string         // implementation of the shadow DB to use, empty if disabled
 	ShadowVariant          string         // database variant of the shadow DB to be used
+	ShardLength            uint64         // number of blocks per shard in parallel trace replay (0 disables sharding)
 	SkipMetadata           bool           // skip metadata insert/getting into AidaDb
 	SkipPriming            bool           // skip priming of the state DB
 	SkipStateHashScrapping bool           // if enabled, then state-hashes are not loaded from rpc
@@ -211,9 +222,12 @@ type Config struct {
 	SyncPeriodLength       uint64         // length of a sync-period in number of blocks
 	TargetDb               string         // represents the path of a target DB
 	TargetEpoch            uint64         // represents the ID of target epoch to be reached by autogen patch generator
+	TestPackage            string         // package name of generated tests
 	Trace                  bool           // trace flag
 	TraceDirectory         string         // name of trace directory
 	TraceFile              string         // name of trace file
+	TraceTransaction       int            // transaction selected from the storage trace (negative for all)
+	TraceValidation        bool           // records and validates results of read operations in storage traces
 	TrackProgress          bool           // enables track progress logging
 	TransactionLength      uint64         // determines indirectly the length of a transaction
 	UpdateBufferSize       uint64         // cache size in Bytes
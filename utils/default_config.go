@@ -32,6 +32,7 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		AidaDb:                 getFlagValue(ctx, AidaDbFlag).(string),
 		ArchiveMaxQueryAge:     getFlagValue(ctx, ArchiveMaxQueryAgeFlag).(int),
 		ArchiveMode:            getFlagValue(ctx, ArchiveModeFlag).(bool),
+		ArchiveQueryAgeLambda:  getFlagValue(ctx, ArchiveQueryAgeLambdaFlag).(float64),
 		ArchiveQueryRate:       getFlagValue(ctx, ArchiveQueryRateFlag).(int),
 		ArchiveVariant:         getFlagValue(ctx, ArchiveVariantFlag).(string),
 		BalanceRange:           getFlagValue(ctx, BalanceRangeFlag).(int64),
@@ -40,6 +41,9 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		CPUProfile:             getFlagValue(ctx, CpuProfileFlag).(string),
 		CPUProfilePerInterval:  getFlagValue(ctx, CpuProfilePerIntervalFlag).(bool),
 		Cache:                  getFlagValue(ctx, CacheFlag).(int),
+		CachePolicies:          getFlagValue(ctx, CachePoliciesFlag).(string),
+		CacheSizes:             getFlagValue(ctx, CacheSizesFlag).(string),
+		CacheWindow:            getFlagValue(ctx, CacheWindowFlag).(uint64),
 		CarmenSchema:           getFlagValue(ctx, CarmenSchemaFlag).(int),
 		ChainID:                ChainID(getFlagValue(ctx, ChainIDFlag).(int)),
 		ChannelBufferSize:      getFlagValue(ctx, ChannelBufferSizeFlag).(int),
@@ -58,13 +62,16 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		DeletionDb:             getFlagValue(ctx, DeletionDbFlag).(string),
 		DiagnosticServer:       getFlagValue(ctx, DiagnosticServerFlag).(int64),
 		ErrorLogging:           getFlagValue(ctx, ErrorLoggingFlag).(string),
+		EstimateGasTolerance:   getFlagValue(ctx, EstimateGasToleranceFlag).(float64),
 		Genesis:                getFlagValue(ctx, GenesisFlag).(string),
 		IncludeStorage:         getFlagValue(ctx, IncludeStorageFlag).(bool),
 		KeepDb:                 getFlagValue(ctx, KeepDbFlag).(bool),
 		KeysNumber:             getFlagValue(ctx, KeysNumberFlag).(int64),
 		LogLevel:               getFlagValue(ctx, logger.LogLevelFlag).(string),
+		MarkovOrder:            getFlagValue(ctx, MarkovOrderFlag).(int),
 		MaxNumErrors:           getFlagValue(ctx, MaxNumErrorsFlag).(int),
 		MaxNumTransactions:     getFlagValue(ctx, MaxNumTransactionsFlag).(int),
+		MaxReportSamples:       getFlagValue(ctx, MaxReportSamplesFlag).(int),
 		MemoryBreakdown:        getFlagValue(ctx, MemoryBreakdownFlag).(bool),
 		MemoryProfile:          getFlagValue(ctx, MemoryProfileFlag).(string),
 		MicroProfiling:         getFlagValue(ctx, MicroProfilingFlag).(bool),
@@ -89,9 +96,13 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		RandomSeed:             getFlagValue(ctx, RandomSeedFlag).(int64),
 		RegisterRun:            getFlagValue(ctx, RegisterRunFlag).(string),
 		RpcRecordingPath:       getFlagValue(ctx, RpcRecordingFileFlag).(string),
+		RpcReport:              getFlagValue(ctx, RpcReportFlag).(string),
+		RpcRules:               getFlagValue(ctx, RpcRulesFlag).(string),
+		SegmentLength:          getFlagValue(ctx, SegmentLengthFlag).(int),
 		ShadowDb:               getFlagValue(ctx, ShadowDb).(bool),
 		ShadowImpl:             getFlagValue(ctx, ShadowDbImplementationFlag).(string),
 		ShadowVariant:          getFlagValue(ctx, ShadowDbVariantFlag).(string),
+		ShardLength:            getFlagValue(ctx, ShardLengthFlag).(uint64),
 		SkipMetadata:           getFlagValue(ctx, flags.SkipMetadata).(bool),
 		SkipPriming:            getFlagValue(ctx, SkipPrimingFlag).(bool),
 		SkipStateHashScrapping: getFlagValue(ctx, SkipStateHashScrappingFlag).(bool),
@@ -103,9 +114,12 @@ func createConfigFromFlags(ctx *cli.Context) *Config {
 		SyncPeriodLength:       getFlagValue(ctx, SyncPeriodLengthFlag).(uint64),
 		TargetDb:               getFlagValue(ctx, TargetDbFlag).(string),
 		TargetEpoch:            getFlagValue(ctx, TargetEpochFlag).(uint64),
+		TestPackage:            getFlagValue(ctx, TestPackageFlag).(string),
 		Trace:                  getFlagValue(ctx, TraceFlag).(bool),
 		TraceDirectory:         getFlagValue(ctx, TraceDirectoryFlag).(string),
 		TraceFile:              getFlagValue(ctx, TraceFileFlag).(string),
+		TraceTransaction:       getFlagValue(ctx, TraceTransactionFlag).(int),
+		TraceValidation:        getFlagValue(ctx, TraceValidationFlag).(bool),
 		TrackProgress:          getFlagValue(ctx, TrackProgressFlag).(bool),
 		TransactionLength:      getFlagValue(ctx, TransactionLengthFlag).(uint64),
 		UpdateBufferSize:       getFlagValue(ctx, UpdateBufferSizeFlag).(uint64),
@@ -146,6 +160,11 @@ func getFlagValue(ctx *cli.Context, flag interface{}) interface{} {
 				return ctx.Int64(f.Name)
 			}
 
+		case cli.Float64Flag:
+			if cmdFlag.Names()[0] == f.Name {
+				return ctx.Float64(f.Name)
+			}
+
 		case cli.StringFlag:
 			if cmdFlag.Names()[0] == f.Name {
 				return ctx.String(f.Name)
@@ -175,6 +194,8 @@ func getFlagValue(ctx *cli.Context, flag interface{}) interface{} {
 		return f.Value
 	case cli.Int64Flag:
 		return f.Value
+	case cli.Float64Flag:
+		return f.Value
 	case cli.StringFlag:
 		return f.Value
 	case cli.PathFlag:
@@ -40,6 +40,10 @@ var (
 		Usage: "sets an upper limit for the number of blocks an archive query may be lagging behind the head block",
 		Value: 100_000,
 	}
+	ArchiveQueryAgeLambdaFlag = cli.Float64Flag{
+		Name:  "archive-query-age-lambda",
+		Usage: "sets the lambda parameter of the exponential distribution of archive query ages relative to the maximum query age, uniform if 0",
+	}
 	ArchiveVariantFlag = cli.StringFlag{
 		Name:  "archive-variant",
 		Usage: "set the archive implementation variant for the selected DB implementation, ignored if not running in archive mode",
@@ -68,6 +72,20 @@ var (
 		Usage: "Cache limit for StateDb or Priming",
 		Value: 8192,
 	}
+	CachePoliciesFlag = cli.StringFlag{
+		Name:  "cache-policies",
+		Usage: "comma-separated list of simulated cache policies (lru, lfu, arc)",
+		Value: "lru,lfu,arc",
+	}
+	CacheSizesFlag = cli.StringFlag{
+		Name:  "cache-sizes",
+		Usage: "comma-separated list of simulated cache sizes in number of entries",
+		Value: "1024,16384,262144,1048576",
+	}
+	CacheWindowFlag = cli.Uint64Flag{
+		Name:  "cache-window",
+		Usage: "number of blocks per range reported by the cache simulation; whole range if 0",
+	}
 	ContinueOnFailureFlag = cli.BoolFlag{
 		Name:  "continue-on-failure",
 		Usage: "continue execute after validation failure detected",
@@ -115,6 +133,39 @@ var (
 		Name:  "memory-breakdown",
 		Usage: "enables printing of memory usage breakdown",
 	}
+	WeightsFlag = cli.StringFlag{
+		Name:  "weights",
+		Usage: "comma-separated weights of merged event files (default: 1 for each file)",
+	}
+	MarkovOrderFlag = cli.IntFlag{
+		Name:  "markov-order",
+		Usage: "order of the Markov chain of the stochastic model",
+		Value: 1,
+	}
+	HeldOutFlag = cli.PathFlag{
+		Name:  "held-out",
+		Usage: "events file of a held-out recording for evaluating the Markov chain",
+	}
+	FuzzSeedsFlag = cli.IntFlag{
+		Name:  "fuzz-seeds",
+		Usage: "maximal number of random seeds run by the fuzzer (0 for unlimited)",
+	}
+	FuzzArtifactFlag = cli.PathFlag{
+		Name:  "artifact",
+		Usage: "fuzzing artifact to be replayed instead of fuzzing",
+	}
+	SegmentLengthFlag = cli.IntFlag{
+		Name:  "segment-length",
+		Usage: "number of blocks per segment for detecting phases of the workload (0 disables segments)",
+	}
+	PhaseLengthFlag = cli.IntFlag{
+		Name:  "phase-length",
+		Usage: "maximal number of blocks per phase of the workload (0 disables fixed phases)",
+	}
+	PhaseThresholdFlag = cli.Float64Flag{
+		Name:  "phase-threshold",
+		Usage: "Jensen-Shannon divergence of operation frequencies that starts a new phase (0 disables change-point detection)",
+	}
 	NonceRangeFlag = cli.IntFlag{
 		Name:  "nonce-range",
 		Usage: "sets nonce range for stochastic simulation",
@@ -164,6 +215,10 @@ var (
 		Usage: "Set random seed",
 		Value: -1,
 	}
+	ShardLengthFlag = cli.Uint64Flag{
+		Name:  "shard-length",
+		Usage: "replay storage traces in parallel shards of the given number of blocks, rounded up to sync periods; disabled if 0",
+	}
 	SkipPrimingFlag = cli.BoolFlag{
 		Name:  "skip-priming",
 		Usage: "if set, DB priming should be skipped; most useful with the 'memory' DB implementation",
@@ -222,6 +277,20 @@ var (
 		Name:  "trace-dir",
 		Usage: "set storage trace directory",
 	}
+	TraceValidationFlag = cli.BoolFlag{
+		Name:  "trace-validation",
+		Usage: "record results of read operations in storage traces and validate them during replay",
+	}
+	TraceTransactionFlag = cli.IntFlag{
+		Name:  "trace-tx",
+		Usage: "select a single transaction of the first block from the storage trace, all transactions if negative",
+		Value: -1,
+	}
+	TestPackageFlag = cli.StringFlag{
+		Name:  "test-package",
+		Usage: "set the package name of generated tests",
+		Value: "state_test",
+	}
 	UpdateDbFlag = cli.PathFlag{
 		Name:  "update-db",
 		Usage: "set update-set database directory",
@@ -393,6 +462,77 @@ var (
 		Usage: "defines path to profile-db",
 		Value: "/var/opera/Aida/profile.db",
 	}
+	EstimateGasToleranceFlag = cli.Float64Flag{
+		Name:  "estimate-gas-tolerance",
+		Usage: "relative tolerance of replayed gas estimations to recorded estimations (e.g. 0.01 for 1%)",
+	}
+	RpcAddressFlag = cli.StringFlag{
+		Name:  "rpc-addr",
+		Usage: "listening address of the JSON-RPC server",
+		Value: "localhost:8545",
+	}
+	RpcUpstreamFlag = cli.StringFlag{
+		Name:  "rpc-upstream",
+		Usage: "JSON-RPC endpoint the recording proxy forwards requests to (default: API of the chain)",
+	}
+	BenchmarkModeFlag = cli.StringFlag{
+		Name:  "benchmark-mode",
+		Usage: "schedule of replayed requests (\"recorded\" timing, \"fixed\" rate or \"ramp\"ed rate)",
+		Value: "recorded",
+	}
+	BenchmarkClientsFlag = cli.IntFlag{
+		Name:  "benchmark-clients",
+		Usage: "number of concurrent clients sending requests",
+		Value: 8,
+	}
+	BenchmarkRateFlag = cli.Float64Flag{
+		Name:  "benchmark-rate",
+		Usage: "requests per second of the fixed rate, or the initial rate of the ramped rate",
+		Value: 100,
+	}
+	BenchmarkRateStepFlag = cli.Float64Flag{
+		Name:  "benchmark-rate-step",
+		Usage: "requests per second added every second to the ramped rate",
+		Value: 10,
+	}
+	BenchmarkSpeedupFlag = cli.Float64Flag{
+		Name:  "benchmark-speedup",
+		Usage: "speed-up factor of the recorded timing",
+		Value: 1,
+	}
+	RpcReportFlag = cli.PathFlag{
+		Name:  "rpc-report",
+		Usage: "path to a JSON lines report of RPC mismatches; a summary is written next to it",
+	}
+	MaxReportSamplesFlag = cli.IntFlag{
+		Name:  "max-report-samples",
+		Usage: "maximum number of reported mismatches per method, contract and error type (0 for all)",
+		Value: 100,
+	}
+	RpcRulesFlag = cli.PathFlag{
+		Name:  "rpc-rules",
+		Usage: "path to a YAML or JSON file with rules skipping or including replayed RPC requests",
+	}
+	RecordingMethodsFlag = cli.StringSliceFlag{
+		Name:  "methods",
+		Usage: "methods of kept requests (e.g. call or eth_call); all methods if not set",
+	}
+	RecordingFirstBlockFlag = cli.Uint64Flag{
+		Name:  "first-block",
+		Usage: "first recorded block of kept requests",
+	}
+	RecordingLastBlockFlag = cli.Uint64Flag{
+		Name:  "last-block",
+		Usage: "last recorded block of kept requests; unbounded if not set",
+	}
+	SplitRequestsFlag = cli.IntFlag{
+		Name:  "split-requests",
+		Usage: "maximum number of requests per split recording (0 for no limit)",
+	}
+	SplitBlocksFlag = cli.Uint64Flag{
+		Name:  "split-blocks",
+		Usage: "maximum number of blocks per split recording (0 for no limit)",
+	}
 	ErrorLoggingFlag = cli.PathFlag{
 		Name:  "err-logging",
 		Usage: "defines path to error-log-file where any PROCESSING error is recorded",